@@ -83,7 +83,7 @@ func (a *App) OpenWorkspace(path string) (string, error) {
 	}
 	a.mu.Unlock()
 
-	c, err := code.New(path, nil)
+	c, err := code.New(path, nil, nil)
 	if err != nil {
 		return "", err
 	}
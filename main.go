@@ -2,21 +2,28 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 
 	"github.com/adrianliechti/wingman-agent/server"
 	clawtui "github.com/adrianliechti/wingman-agent/tui/claw"
 	codetui "github.com/adrianliechti/wingman-agent/tui/code"
 
+	"github.com/adrianliechti/wingman-agent/pkg/agent"
+	"github.com/adrianliechti/wingman-agent/pkg/agent/hook/truncation"
 	"github.com/adrianliechti/wingman-agent/pkg/claw"
 	"github.com/adrianliechti/wingman-agent/pkg/claw/channel"
 	"github.com/adrianliechti/wingman-agent/pkg/code"
+	"github.com/adrianliechti/wingman-agent/pkg/rewind"
 	"github.com/adrianliechti/wingman-agent/pkg/session"
+	"github.com/adrianliechti/wingman-agent/pkg/telemetry"
 	"github.com/adrianliechti/wingman-agent/tui/proxy"
 
 	"github.com/adrianliechti/wingman-agent/tui/run/claude"
@@ -28,12 +35,24 @@ import (
 	"github.com/adrianliechti/wingman-agent/pkg/tui/theme"
 )
 
+// flushTraces exports pending spans; os.Exit skips the deferred flush.
+var flushTraces = func(context.Context) error { return nil }
+
 func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
 
+	shutdown, err := telemetry.Setup(ctx)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: tracing disabled: %v\n", err)
+	}
+
+	flushTraces = shutdown
+	defer shutdown(context.Background())
+
 	if len(os.Args) < 2 {
-		runTUI(ctx, "")
+		runTUI(ctx, "", nil)
 		return
 	}
 
@@ -55,21 +74,49 @@ func main() {
 	case "run":
 		runRun(ctx)
 		return
-	case "--resume":
-		sessionID := "latest"
-		if len(os.Args) > 2 {
-			sessionID = os.Args[2]
-		}
-		runTUI(ctx, sessionID)
+	case "session":
+		runSession(ctx)
+		return
+	case "exec":
+		runExec(ctx)
+		return
+	case "--resume", "--worktree":
+		sessionID, options := parseTUIArgs(os.Args[1:])
+		runTUI(ctx, sessionID, options)
 		return
 	}
 
-	runTUI(ctx, "")
+	runTUI(ctx, "", nil)
+}
+
+// parseTUIArgs handles the TUI flags, which may appear in any order:
+// --resume [id] and --worktree.
+func parseTUIArgs(args []string) (string, *code.Options) {
+	var sessionID string
+
+	options := new(code.Options)
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--worktree":
+			options.Worktree = true
+		case "--resume":
+			sessionID = "latest"
+
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				sessionID = args[i+1]
+				i++
+			}
+		}
+	}
+
+	return sessionID, options
 }
 
 func runServer(ctx context.Context) {
 	fs := flag.NewFlagSet("server", flag.ExitOnError)
 	port := fs.Int("port", 9000, "port to listen on")
+	isolated := fs.Bool("worktree", false, "run the agent in an isolated git worktree")
 	fs.Parse(os.Args[2:])
 
 	wd, err := os.Getwd()
@@ -78,13 +125,17 @@ func runServer(ctx context.Context) {
 		os.Exit(1)
 	}
 
-	c, err := code.New(wd, nil)
+	c, err := code.New(wd, nil, &code.Options{Worktree: *isolated})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 	defer c.Close()
 
+	if c.Worktree != nil {
+		fmt.Fprintf(os.Stderr, "Worktree: %s (%s)\n", c.Worktree.Path, c.Worktree.Branch)
+	}
+
 	if err := server.New(ctx, c, *port).Run(ctx); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -136,7 +187,140 @@ func runRun(ctx context.Context) {
 	}
 }
 
-func runTUI(ctx context.Context, sessionID string) {
+// execUI answers the agent's questions without a user: prompts are denied
+// unless -yes was given.
+type execUI struct {
+	yes bool
+}
+
+func (u *execUI) Ask(ctx context.Context, message string) (string, error) {
+	return "", errors.New("no user input available in exec mode")
+}
+
+func (u *execUI) Confirm(ctx context.Context, message string) (bool, error) {
+	return u.yes, nil
+}
+
+func (u *execUI) StatusUpdate(status string) {}
+
+func runExec(ctx context.Context) {
+	fs := flag.NewFlagSet("exec", flag.ExitOnError)
+	model := fs.String("model", "", "model to use")
+	schemaPath := fs.String("schema", "", "JSON Schema file the answer must match; prints the answer as JSON")
+	yes := fs.Bool("yes", false, "approve all confirmations")
+	fs.Parse(os.Args[2:])
+
+	prompt := strings.Join(fs.Args(), " ")
+
+	if prompt == "" || prompt == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		prompt = strings.TrimSpace(string(data))
+	}
+
+	if prompt == "" {
+		fmt.Fprintln(os.Stderr, "Error: missing prompt")
+		os.Exit(1)
+	}
+
+	if err := execPrompt(ctx, prompt, *model, *schemaPath, *yes); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		flushTraces(context.Background())
+		os.Exit(1)
+	}
+}
+
+func execPrompt(ctx context.Context, prompt, model, schemaPath string, yes bool) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	c, err := code.New(wd, &execUI{yes: yes}, nil)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if schemaPath != "" {
+		data, err := os.ReadFile(schemaPath)
+		if err != nil {
+			return err
+		}
+
+		var schema map[string]any
+
+		if err := json.Unmarshal(data, &schema); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", schemaPath, err)
+		}
+
+		c.Config.OutputSchema = &agent.OutputSchema{Schema: schema}
+	}
+
+	c.WarmUp()
+	c.WaitWarmUp()
+
+	if err := c.InitMCP(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	if model != "" {
+		c.Config.Model = func() string { return model }
+	} else if c.Config.Model == nil || c.Model() == "" {
+		models, err := c.Models(ctx)
+		if err != nil {
+			return err
+		}
+
+		id := code.SelectModel(models)
+		c.Config.Model = func() string { return id }
+	}
+
+	c.Config.Instructions = func() string {
+		return code.BuildInstructions(c.InstructionsData())
+	}
+
+	diagnostics := c.DiagnosticsHooks()
+	c.Config.Hooks.PreToolUse = append(c.Config.Hooks.PreToolUse, diagnostics.PreToolUse...)
+	c.Config.Hooks.PostToolUse = append(c.Config.Hooks.PostToolUse, diagnostics.PostToolUse...)
+
+	c.Config.Hooks.PostToolUse = append(c.Config.Hooks.PostToolUse,
+		truncation.New(truncation.DefaultMaxBytes, c.ScratchPath),
+	)
+
+	var answer strings.Builder
+
+	for msg, err := range c.Send(ctx, []agent.Content{{Text: prompt}}) {
+		if err != nil {
+			return err
+		}
+
+		// Only the text after the last tool round is the answer.
+		for _, content := range msg.Content {
+			if content.ToolCall != nil || content.ToolResult != nil {
+				answer.Reset()
+				break
+			}
+
+			answer.WriteString(content.Text)
+		}
+	}
+
+	if output := c.Output(); output != nil {
+		fmt.Println(string(output))
+		return nil
+	}
+
+	fmt.Println(strings.TrimSpace(answer.String()))
+
+	return nil
+}
+
+func runTUI(ctx context.Context, sessionID string, options *code.Options) {
 	theme.Auto()
 
 	wd, err := os.Getwd()
@@ -145,7 +329,7 @@ func runTUI(ctx context.Context, sessionID string) {
 		os.Exit(1)
 	}
 
-	c, err := code.New(wd, nil)
+	c, err := code.New(wd, nil, options)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -183,6 +367,152 @@ func runTUI(ctx context.Context, sessionID string) {
 	}
 }
 
+func runSession(ctx context.Context) {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, "Error: missing session command")
+		fmt.Fprintln(os.Stderr)
+		printHelp(os.Stderr)
+		os.Exit(1)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	sessionsDir := code.SessionsDir(wd)
+
+	switch os.Args[2] {
+	case "export":
+		err = runSessionExport(wd, sessionsDir, os.Args[3:])
+	case "import":
+		err = runSessionImport(wd, sessionsDir, os.Args[3:])
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown session command %q\n", os.Args[2])
+		fmt.Fprintln(os.Stderr)
+		printHelp(os.Stderr)
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runSessionExport(wd, sessionsDir string, args []string) error {
+	fs := flag.NewFlagSet("session export", flag.ExitOnError)
+	format := fs.String("format", session.FormatMarkdown, "output format: md, html or json")
+	output := fs.String("o", "", "write to file instead of stdout")
+	diff := fs.Bool("diff", false, "append uncommitted changes in this directory (against git HEAD)")
+
+	// Accept the ID before or after the flags.
+	var id string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		id, args = args[0], args[1:]
+	}
+
+	fs.Parse(args)
+
+	if id == "" {
+		id = fs.Arg(0)
+	}
+
+	if id == "" || id == "latest" {
+		sessions, err := session.List(sessionsDir)
+		if err != nil {
+			return err
+		}
+
+		if len(sessions) == 0 {
+			return fmt.Errorf("no saved sessions")
+		}
+
+		id = sessions[0].ID
+	}
+
+	s, err := session.Load(sessionsDir, id)
+	if err != nil {
+		return err
+	}
+
+	var options session.ExportOptions
+
+	// A saved session has no live baseline; a fresh one starts at HEAD, so
+	// this covers what the session left uncommitted.
+	if *diff {
+		r := rewind.New(wd)
+		defer r.Cleanup()
+
+		diffs, err := r.DiffFromBaseline()
+		if err != nil {
+			return err
+		}
+
+		for _, d := range diffs {
+			options.Diffs = append(options.Diffs, session.ExportDiff{Path: d.Path, Patch: d.Patch})
+		}
+	}
+
+	w := io.Writer(os.Stdout)
+
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		w = f
+	}
+
+	return session.Export(w, s, *format, &options)
+}
+
+func runSessionImport(wd, sessionsDir string, args []string) error {
+	fs := flag.NewFlagSet("session import", flag.ExitOnError)
+	from := fs.String("from", "", "transcript source: claude, codex or gemini (detected from file if omitted)")
+
+	var path string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		path, args = args[0], args[1:]
+	}
+
+	fs.Parse(args)
+
+	if path == "" {
+		path = fs.Arg(0)
+	}
+
+	if path == "" {
+		if *from == "" {
+			return fmt.Errorf("specify a transcript file or --from claude|codex|gemini")
+		}
+
+		found, err := session.FindTranscript(*from, wd)
+		if err != nil {
+			return err
+		}
+
+		path = found
+	}
+
+	s, err := session.ImportFile(path, *from)
+	if err != nil {
+		return err
+	}
+
+	if err := session.Store(sessionsDir, s); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Imported %d messages from %s\n", len(s.State.Messages), path)
+	fmt.Fprintf(os.Stderr, "Resume: wingman --resume %s\n", s.ID)
+
+	return nil
+}
+
 func runClaw(ctx context.Context) {
 	cfg, cleanup, err := claw.DefaultConfig()
 	if err != nil {
@@ -210,17 +540,26 @@ func printHelp(w io.Writer) {
 	fmt.Fprint(w, `wingman — AI coding agent
 
 Usage:
-  wingman [--resume [id]]      Launch the agent TUI
-  wingman server [-port N]     Run the web UI server
+  wingman [--resume [id]] [--worktree]
+                               Launch the agent TUI
+  wingman server [-port N] [-worktree]
+                               Run the web UI server
   wingman claw                 Run the claw multi-agent runner
   wingman proxy [-port N]      Run the API proxy + dashboard (requires WINGMAN_URL)
   wingman run <target> [args]  Run an external agent through wingman
+  wingman exec [-model m] [-schema file.json] [-yes] <prompt|->
+                               Run one prompt without the TUI and print the answer
+  wingman session export [id] [--format md|html|json] [-o file] [--diff]
+                               Export a saved session (default: latest)
+  wingman session import [file] [--from claude|codex|gemini]
+                               Import another agent's transcript (default: its latest for this dir)
 
 Run targets:
   claude, claude-desktop, codex, gemini, opencode
 
 Flags:
   --resume [id]   Resume the latest (or specified) saved session
+  --worktree      Work in a fresh git worktree branch; /merge or /discard at the end
   --help, -h      Show this help
 `)
 }
@@ -6,6 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"iter"
+	"strings"
+	"sync"
+
+	"github.com/google/jsonschema-go/jsonschema"
 
 	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
 )
@@ -17,32 +21,105 @@ type Agent struct {
 
 	Messages []Message
 	Usage    Usage
+
+	// TurnUsage is the usage of the last turn, across its tool rounds.
+	TurnUsage Usage
+
+	// contextTokens is the size of the conversation as of the last
+	// response, used to compact before the context window overflows.
+	contextTokens int64
+
+	// output is the validated answer of the last structured turn.
+	output json.RawMessage
+
+	// steering is input queued with Steer for the running turn.
+	steerMu  sync.Mutex
+	steering [][]Content
+
+	// schemas holds the resolved parameters of the tools called so far.
+	schemas argSchemas
 }
 
-// Models lists the available models from the API.
+// Models lists the available models from the API along with their
+// capabilities. Metadata the API reports is kept in the registry.
 func (a *Agent) Models(ctx context.Context) ([]ModelInfo, error) {
-	resp, err := a.client.Models.List(ctx)
+	models, err := a.provider.models(ctx)
+
 	if err != nil {
 		return nil, err
 	}
 
-	var models []ModelInfo
-
-	for _, m := range resp.Data {
-		models = append(models, ModelInfo{ID: m.ID})
+	for i, m := range models {
+		a.Registry.report(m.ID, m.metadata)
+		models[i].Capabilities = a.Registry.Lookup(m.ID)
 	}
 
 	return models, nil
 }
 
+// compactThreshold is the conversation size at which Send summarizes the
+// history before the next request.
+func compactThreshold(c Capabilities) int64 {
+	return int64(c.ContextWindow) * 9 / 10
+}
+
 func (a *Agent) Send(ctx context.Context, input []Content) iter.Seq2[Message, error] {
 	a.appendContextMessages()
 	a.Messages = append(a.Messages, userMessage(input))
 
 	return func(yield func(Message, error) bool) {
+		// current is the position in the fallback chain; once a turn moved
+		// to a fallback it stays there.
+		current := 0
+
+		a.output = nil
+		a.TurnUsage = Usage{}
+
+		turnModel := ""
+		if a.Config.Model != nil {
+			turnModel = a.Model()
+		}
+
+		ctx, span, yield := startTurnSpan(ctx, turnModel, yield)
+		defer func() { endTurnSpan(span, a.TurnUsage) }()
+
+		// Instructions are fixed for the turn so that all its requests share
+		// the cached prefix.
+		instructions := ""
+		if a.Instructions != nil {
+			instructions = a.Instructions()
+		}
+
+		cacheKey := ""
+		if a.CacheKey != nil {
+			cacheKey = a.CacheKey()
+		}
+
+		schema := a.OutputSchema
+		outputRetries := 0
+
+		guard := newTurnGuard(a.Limits)
+
+		var resolved *jsonschema.Resolved
+
+		if schema != nil {
+			var err error
+
+			if resolved, err = schema.resolve(); err != nil {
+				yield(Message{}, err)
+				return
+			}
+		}
+
 		for {
 			a.removeOrphanedToolMessages()
 
+			if !a.injectSteering(yield) {
+				return
+			}
+
+			a.Messages = append(a.Messages, guard.takeNudges()...)
+
 			model := ""
 			if a.Config.Model != nil {
 				model = a.Model()
@@ -53,32 +130,42 @@ func (a *Agent) Send(ctx context.Context, input []Content) iter.Seq2[Message, er
 				effort = a.Effort()
 			}
 
-			instructions := ""
-			if a.Instructions != nil {
-				instructions = a.Instructions()
-			}
+			chain := a.modelChain(model)
+			current = min(current, len(chain)-1)
+
+			caps := a.Capabilities(chain[current])
 
 			var tools []tool.Tool
-			if a.Tools != nil {
+			if a.Tools != nil && caps.Tools {
 				tools = a.Tools()
 			}
 
+			if a.contextTokens > 0 && caps.ContextWindow > 0 && a.contextTokens >= compactThreshold(caps) {
+				a.compactMessages(ctx)
+			}
+
 			req := &request{
-				model:        model,
-				effort:       effort,
 				instructions: instructions,
 				messages:     a.Messages,
 				tools:        tools,
+
+				cacheKey: cacheKey,
+				output:   schema,
 			}
 
-			resp, err := complete(ctx, a.client, req, yield)
+			resp, next, err := a.complete(ctx, req, effort, chain, current, yield)
 
 			if err != nil {
-				if !errors.Is(err, errYieldStopped) && !errors.Is(err, context.Canceled) && isRecoverableError(err) {
+				// Transient errors were already retried; others, such as an
+				// overflowing context, may go away with a shorter history.
+				// Interrupted responses are not sent again.
+				var interrupted *interruptedError
+
+				if !errors.Is(err, errYieldStopped) && !errors.Is(err, context.Canceled) && !errors.As(err, &interrupted) && isRecoverableError(err) && !isTransientError(err) {
 					a.compactMessages(ctx)
 
 					req.messages = a.Messages
-					resp, err = complete(ctx, a.client, req, yield)
+					resp, next, err = a.complete(ctx, req, effort, chain, current, yield)
 				}
 
 				if err != nil {
@@ -89,23 +176,68 @@ func (a *Agent) Send(ctx context.Context, input []Content) iter.Seq2[Message, er
 				}
 			}
 
-			a.Usage.InputTokens += resp.usage.InputTokens
-			a.Usage.CachedTokens += resp.usage.CachedTokens
-			a.Usage.OutputTokens += resp.usage.OutputTokens
+			current = next
+
+			resp.usage.Cost = a.Capabilities(chain[next]).Cost(resp.usage)
+
+			a.Usage.add(resp.usage)
+			a.TurnUsage.add(resp.usage)
+			a.contextTokens = resp.usage.InputTokens + resp.usage.OutputTokens
+
+			// The model has seen the images of earlier tool results; keeping
+			// them would resend them with every request.
+			a.Messages = withoutToolImages(a.Messages, seenImageNotice)
 			a.Messages = append(a.Messages, resp.messages...)
 
 			calls := extractToolCalls(resp.messages)
+			trip := guard.response(resp.usage, len(calls) > 0)
 
 			if len(calls) == 0 {
+				// Input that arrived during the answer continues the turn.
+				if a.hasSteering() {
+					continue
+				}
+
+				if schema == nil {
+					return
+				}
+
+				output, err := schema.parse(resolved, messageText(resp.messages))
+
+				if err == nil {
+					a.output = output
+					return
+				}
+
+				if outputRetries >= maxOutputRetries {
+					yield(Message{}, fmt.Errorf("answer does not match the output schema: %w", err))
+					return
+				}
+
+				outputRetries++
+				a.Messages = append(a.Messages, outputCorrection(err))
+
+				continue
+			}
+
+			if err := guard.enforce(ctx, trip); err != nil {
+				yield(Message{}, err)
 				return
 			}
 
+			results := len(a.Messages)
+
 			if err := a.processToolCalls(ctx, calls, tools, yield); err != nil {
 				if err != errYieldStopped {
 					yield(Message{}, err)
 				}
 				return
 			}
+
+			if err := guard.enforce(ctx, guard.results(a.Messages[results:])); err != nil {
+				yield(Message{}, err)
+				return
+			}
 		}
 	}
 }
@@ -118,6 +250,18 @@ func (a *Agent) appendContextMessages() {
 	a.Messages = append(a.Messages, a.ContextMessages()...)
 }
 
+func messageText(messages []Message) string {
+	var sb strings.Builder
+
+	for _, m := range messages {
+		for _, c := range m.Content {
+			sb.WriteString(c.Text)
+		}
+	}
+
+	return sb.String()
+}
+
 func extractToolCalls(messages []Message) []ToolCall {
 	var calls []ToolCall
 
@@ -143,25 +287,27 @@ func (a *Agent) processToolCalls(ctx context.Context, calls []ToolCall, tools []
 			return errYieldStopped
 		}
 
+		ctx, span := startToolSpan(ctx, tc, findTool(tc.Name, tools))
+
 		hc := tool.ToolCall{ID: tc.ID, Name: tc.Name, Args: tc.Args}
 
-		var result string
+		var result tool.Result
 
 		for _, h := range a.Hooks.PreToolUse {
 			r, err := h(ctx, hc)
 
 			if err != nil {
-				result = fmt.Sprintf("error: %v", err)
+				result = toolError(err)
 				break
 			}
 
 			if r != "" {
-				result = r
+				result = tool.Text(r)
 				break
 			}
 		}
 
-		if result == "" {
+		if result.Text == "" {
 			result = a.executeTool(ctx, tc, tools)
 		}
 
@@ -169,20 +315,29 @@ func (a *Agent) processToolCalls(ctx context.Context, calls []ToolCall, tools []
 			r, err := h(ctx, hc, result)
 
 			if err != nil {
-				result = fmt.Sprintf("error: %v", err)
+				result = toolError(err)
 				break
 			}
 
 			result = r
 		}
 
+		endToolSpan(span, result.Text)
+
+		var files []File
+
+		for _, f := range result.Files {
+			files = append(files, File{Name: f.Name, Data: f.Data})
+		}
+
 		resultMsg := Message{
 			Role: RoleAssistant,
 			Content: []Content{{ToolResult: &ToolResult{
 				ID:      tc.ID,
 				Name:    tc.Name,
 				Args:    tc.Args,
-				Content: result,
+				Content: result.Text,
+				Files:   files,
 			}}},
 		}
 
@@ -196,30 +351,46 @@ func (a *Agent) processToolCalls(ctx context.Context, calls []ToolCall, tools []
 	return nil
 }
 
-func (a *Agent) executeTool(ctx context.Context, tc ToolCall, tools []tool.Tool) string {
+// executeTool runs a tool call. Failures are returned as error results for
+// the model to read.
+func (a *Agent) executeTool(ctx context.Context, tc ToolCall, tools []tool.Tool) tool.Result {
 	t := findTool(tc.Name, tools)
 
 	if t == nil {
-		return fmt.Sprintf("error: unknown tool %s", tc.Name)
+		return toolError(fmt.Errorf("unknown tool %s", tc.Name))
 	}
 
 	args := make(map[string]any)
 
 	if tc.Args != "" {
 		if err := json.Unmarshal([]byte(tc.Args), &args); err != nil {
-			return fmt.Sprintf("error: failed to parse arguments: %v", err)
+			return toolError(fmt.Errorf("failed to parse arguments: %w", err))
 		}
 	}
 
+	schema, err := a.schemas.get(t.Name, t.Parameters)
+
+	if err != nil {
+		return toolError(fmt.Errorf("invalid parameters for %s: %w", tc.Name, err))
+	}
+
+	if err := validateArgs(schema, args); err != nil {
+		return toolError(fmt.Errorf("invalid arguments for %s: %w", tc.Name, err))
+	}
+
 	result, err := t.Execute(ctx, args)
 
 	if err != nil {
-		return fmt.Sprintf("error: %v", err)
+		return toolError(err)
 	}
 
 	return result
 }
 
+func toolError(err error) tool.Result {
+	return tool.Text(fmt.Sprintf("error: %v", err))
+}
+
 func findTool(name string, tools []tool.Tool) *tool.Tool {
 	for i := range tools {
 		if tools[i].Name == name {
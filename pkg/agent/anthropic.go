@@ -0,0 +1,569 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
+)
+
+const (
+	anthropicVersion   = "2023-06-01"
+	anthropicMaxTokens = 16000
+)
+
+// anthropicThinkingBudgets maps reasoning effort to a thinking token budget.
+var anthropicThinkingBudgets = map[string]int{
+	"low":    2000,
+	"medium": 8000,
+	"high":   16000,
+}
+
+// anthropicProvider talks to the Anthropic Messages API. Thinking blocks
+// are stored as reasoning without an ID, which keeps them apart from
+// Responses API reasoning items.
+type anthropicProvider struct {
+	baseURL string
+	token   string
+
+	client *http.Client
+}
+
+func newAnthropicProvider(baseURL, token string) *anthropicProvider {
+	return &anthropicProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+
+		client: http.DefaultClient,
+	}
+}
+
+// anthropicError is an error returned by the Messages API.
+type anthropicError struct {
+	StatusCode int
+
+	Type    string
+	Message string
+}
+
+func (e *anthropicError) Error() string {
+	return fmt.Sprintf("anthropic: %d %s: %s", e.StatusCode, e.Type, e.Message)
+}
+
+type anthropicRequest struct {
+	Model     string `json:"model"`
+	MaxTokens int    `json:"max_tokens"`
+
+	System   []anthropicBlock   `json:"system,omitempty"`
+	Messages []anthropicMessage `json:"messages"`
+	Tools    []anthropicTool    `json:"tools,omitempty"`
+
+	Thinking *anthropicThinking `json:"thinking,omitempty"`
+
+	Stream bool `json:"stream"`
+}
+
+type anthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+type anthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
+
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+type anthropicBlock struct {
+	Type string `json:"type"`
+
+	Text string `json:"text,omitempty"`
+
+	Source *anthropicSource `json:"source,omitempty"`
+
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	ToolUseID string           `json:"tool_use_id,omitempty"`
+	Content   []anthropicBlock `json:"content,omitempty"`
+	IsError   bool             `json:"is_error,omitempty"`
+
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+type anthropicSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicCacheControl struct {
+	Type string `json:"type"`
+}
+
+type anthropicUsage struct {
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+}
+
+type anthropicEvent struct {
+	Type  string `json:"type"`
+	Index int    `json:"index"`
+
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+
+	ContentBlock anthropicBlock `json:"content_block"`
+
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		Thinking    string `json:"thinking"`
+		Signature   string `json:"signature"`
+	} `json:"delta"`
+
+	Usage anthropicUsage `json:"usage"`
+
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (p *anthropicProvider) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("x-api-key", p.token)
+	req.Header.Set("anthropic-version", anthropicVersion)
+	req.Header.Set("content-type", "application/json")
+
+	return req, nil
+}
+
+func (p *anthropicProvider) do(req *http.Request) (*http.Response, error) {
+	resp, err := p.client.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+
+		data, _ := io.ReadAll(resp.Body)
+
+		var body struct {
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+
+		apiErr := &anthropicError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+
+		if json.Unmarshal(data, &body) == nil && body.Error.Message != "" {
+			apiErr.Type = body.Error.Type
+			apiErr.Message = body.Error.Message
+		}
+
+		return nil, apiErr
+	}
+
+	return resp, nil
+}
+
+func (p *anthropicProvider) name() string {
+	return "anthropic"
+}
+
+func (p *anthropicProvider) models(ctx context.Context) ([]ModelInfo, error) {
+	req, err := p.newRequest(ctx, http.MethodGet, "/models?limit=1000", nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	var list struct {
+		Data []json.RawMessage `json:"data"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode models: %w", err)
+	}
+
+	var models []ModelInfo
+
+	for _, raw := range list.Data {
+		var m struct {
+			ID string `json:"id"`
+		}
+
+		if json.Unmarshal(raw, &m) != nil || m.ID == "" {
+			continue
+		}
+
+		models = append(models, ModelInfo{ID: m.ID, metadata: parseModelMetadata(string(raw))})
+	}
+
+	return models, nil
+}
+
+func (p *anthropicProvider) complete(ctx context.Context, r *request, yield func(Message, error) bool) (*response, error) {
+	body := anthropicRequest{
+		Model:     r.model,
+		MaxTokens: anthropicMaxTokens,
+
+		Messages: toAnthropicMessages(r.messages),
+		Tools:    toAnthropicTools(r.tools),
+
+		Stream: true,
+	}
+
+	if r.instructions != "" {
+		body.System = []anthropicBlock{{
+			Type: "text",
+			Text: r.instructions,
+
+			CacheControl: &anthropicCacheControl{Type: "ephemeral"},
+		}}
+	}
+
+	// The Messages API has no structured output; describe the schema and
+	// rely on validation. It goes after the cached instructions so they
+	// stay shared with unstructured turns.
+	if r.output != nil {
+		body.System = append(body.System, anthropicBlock{Type: "text", Text: r.output.instructions()})
+	}
+
+	if budget, ok := anthropicThinkingBudgets[r.effort]; ok {
+		body.Thinking = &anthropicThinking{Type: "enabled", BudgetTokens: budget}
+		body.MaxTokens = budget + anthropicMaxTokens
+	}
+
+	// Stay within the model's output limit; the thinking budget must leave
+	// room for the answer.
+	if limit := r.capabilities.MaxOutputTokens; limit > 0 && body.MaxTokens > limit {
+		body.MaxTokens = limit
+
+		if body.Thinking != nil && body.Thinking.BudgetTokens >= limit {
+			body.Thinking.BudgetTokens = limit / 2
+		}
+	}
+
+	data, err := json.Marshal(body)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := p.newRequest(ctx, http.MethodPost, "/messages", bytes.NewReader(data))
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	type block struct {
+		anthropicBlock
+		input strings.Builder
+	}
+
+	blocks := make(map[int]*block)
+
+	var usage anthropicUsage
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		payload, ok := strings.CutPrefix(scanner.Text(), "data:")
+
+		if !ok {
+			continue
+		}
+
+		var e anthropicEvent
+
+		if err := json.Unmarshal([]byte(strings.TrimSpace(payload)), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse event: %w", err)
+		}
+
+		switch e.Type {
+		case "message_start":
+			usage = e.Message.Usage
+
+		case "content_block_start":
+			blocks[e.Index] = &block{anthropicBlock: e.ContentBlock}
+
+		case "content_block_delta":
+			b := blocks[e.Index]
+
+			if b == nil {
+				continue
+			}
+
+			var msg *Message
+
+			switch e.Delta.Type {
+			case "text_delta":
+				b.Text += e.Delta.Text
+				msg = &Message{Role: RoleAssistant, Content: []Content{{Text: e.Delta.Text}}}
+
+			case "thinking_delta":
+				b.Thinking += e.Delta.Thinking
+				msg = &Message{Role: RoleAssistant, Content: []Content{{Reasoning: &Reasoning{Summary: e.Delta.Thinking}}}}
+
+			case "signature_delta":
+				b.Signature += e.Delta.Signature
+
+			case "input_json_delta":
+				b.input.WriteString(e.Delta.PartialJSON)
+			}
+
+			if msg != nil && !yield(*msg, nil) {
+				return nil, errYieldStopped
+			}
+
+		case "message_delta":
+			usage.OutputTokens = e.Usage.OutputTokens
+
+		case "error":
+			status := http.StatusInternalServerError
+
+			if e.Error.Type == "overloaded_error" {
+				status = 529
+			}
+
+			return nil, &anthropicError{StatusCode: status, Type: e.Error.Type, Message: e.Error.Message}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	indexes := make([]int, 0, len(blocks))
+
+	for i := range blocks {
+		indexes = append(indexes, i)
+	}
+
+	sort.Ints(indexes)
+
+	var messages []Message
+
+	for _, i := range indexes {
+		b := blocks[i]
+
+		var c Content
+
+		switch b.Type {
+		case "text":
+			if b.Text == "" {
+				continue
+			}
+
+			c.Text = b.Text
+
+		case "thinking":
+			c.Reasoning = &Reasoning{Summary: b.Thinking, Signature: b.Signature}
+
+		case "redacted_thinking":
+			c.Reasoning = &Reasoning{Signature: b.Data}
+
+		case "tool_use":
+			args := b.input.String()
+
+			if args == "" {
+				args = "{}"
+			}
+
+			c.ToolCall = &ToolCall{ID: b.ID, Name: b.Name, Args: args}
+
+		default:
+			continue
+		}
+
+		messages = append(messages, Message{Role: RoleAssistant, Content: []Content{c}})
+	}
+
+	return &response{
+		messages: messages,
+		usage: Usage{
+			InputTokens:  usage.InputTokens + usage.CacheReadInputTokens + usage.CacheCreationInputTokens,
+			CachedTokens: usage.CacheReadInputTokens,
+			OutputTokens: usage.OutputTokens,
+		},
+	}, nil
+}
+
+func toAnthropicTools(tools []tool.Tool) []anthropicTool {
+	var result []anthropicTool
+
+	for _, t := range tools {
+		if t.Name == "" {
+			continue
+		}
+
+		schema := t.Parameters
+
+		if schema == nil {
+			schema = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+
+		result = append(result, anthropicTool{
+			Name:        t.Name,
+			Description: t.Description,
+			InputSchema: schema,
+		})
+	}
+
+	if len(result) > 0 {
+		result[len(result)-1].CacheControl = &anthropicCacheControl{Type: "ephemeral"}
+	}
+
+	return result
+}
+
+// toAnthropicMessages converts the conversation into alternating user and
+// assistant messages. Tool results become tool_result blocks of the next
+// user message; system messages are sent as user text.
+func toAnthropicMessages(messages []Message) []anthropicMessage {
+	var result []anthropicMessage
+
+	add := func(role string, b anthropicBlock) {
+		if n := len(result); n > 0 && result[n-1].Role == role {
+			result[n-1].Content = append(result[n-1].Content, b)
+			return
+		}
+
+		result = append(result, anthropicMessage{Role: role, Content: []anthropicBlock{b}})
+	}
+
+	for _, m := range messages {
+		for _, c := range m.Content {
+			switch {
+			case c.ToolResult != nil && c.ToolResult.ID != "":
+				b := anthropicBlock{
+					Type:      "tool_result",
+					ToolUseID: c.ToolResult.ID,
+					IsError:   strings.HasPrefix(c.ToolResult.Content, "error: "),
+				}
+
+				if c.ToolResult.Content != "" {
+					b.Content = append(b.Content, anthropicBlock{Type: "text", Text: c.ToolResult.Content})
+				}
+
+				for _, f := range c.ToolResult.Files {
+					if fb, ok := anthropicFileBlock(f); ok {
+						b.Content = append(b.Content, fb)
+					}
+				}
+
+				add("user", b)
+
+			case m.Role != RoleAssistant:
+				if c.Text != "" {
+					add("user", anthropicBlock{Type: "text", Text: c.Text})
+				}
+
+				if c.File != nil {
+					if fb, ok := anthropicFileBlock(*c.File); ok {
+						add("user", fb)
+					}
+				}
+
+			case c.Reasoning != nil && c.Reasoning.ID == "" && c.Reasoning.Signature != "":
+				if c.Reasoning.Summary == "" {
+					add("assistant", anthropicBlock{Type: "redacted_thinking", Data: c.Reasoning.Signature})
+				} else {
+					add("assistant", anthropicBlock{Type: "thinking", Thinking: c.Reasoning.Summary, Signature: c.Reasoning.Signature})
+				}
+
+			case c.Text != "":
+				add("assistant", anthropicBlock{Type: "text", Text: c.Text})
+
+			case c.ToolCall != nil && c.ToolCall.ID != "":
+				input := json.RawMessage(c.ToolCall.Args)
+
+				if !json.Valid(input) || !strings.HasPrefix(strings.TrimSpace(c.ToolCall.Args), "{") {
+					input = json.RawMessage("{}")
+				}
+
+				add("assistant", anthropicBlock{Type: "tool_use", ID: c.ToolCall.ID, Name: c.ToolCall.Name, Input: input})
+			}
+		}
+	}
+
+	// Cache the conversation up to the latest user turn.
+	if n := len(result); n > 0 && result[n-1].Role == "user" {
+		blocks := result[n-1].Content
+		blocks[len(blocks)-1].CacheControl = &anthropicCacheControl{Type: "ephemeral"}
+	}
+
+	return result
+}
+
+// anthropicFileBlock maps a file to an image or document block.
+func anthropicFileBlock(f File) (anthropicBlock, bool) {
+	mediaType := dataURLMediaType(f.Data)
+
+	source := &anthropicSource{Type: "url", URL: f.Data}
+
+	if mediaType != "" {
+		_, data, _ := strings.Cut(f.Data, ",")
+		source = &anthropicSource{Type: "base64", MediaType: mediaType, Data: data}
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "image/"), mediaType == "" && f.Data != "":
+		return anthropicBlock{Type: "image", Source: source}, true
+	case mediaType == "application/pdf":
+		return anthropicBlock{Type: "document", Source: source}, true
+	default:
+		return anthropicBlock{}, false
+	}
+}
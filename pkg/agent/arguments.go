@@ -0,0 +1,216 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// argSchema is a tool's parameters, resolved for validateArgs.
+type argSchema struct {
+	schema   *jsonschema.Schema
+	resolved *jsonschema.Resolved
+}
+
+// parseParams resolves tool parameters. Remote references, which some MCP
+// servers use, accept any value. Nil parameters give a nil schema.
+func parseParams(params map[string]any) (*argSchema, error) {
+	if params == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(params)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode parameters: %w", err)
+	}
+
+	var s jsonschema.Schema
+
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse parameters: %w", err)
+	}
+
+	resolved, err := s.Resolve(&jsonschema.ResolveOptions{
+		Loader: func(*url.URL) (*jsonschema.Schema, error) {
+			return &jsonschema.Schema{}, nil
+		},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve parameters: %w", err)
+	}
+
+	return &argSchema{schema: &s, resolved: resolved}, nil
+}
+
+// validateArgs checks tool arguments against the tool's parameters. Values
+// models often send with the wrong type, such as "10" for an integer or
+// null for an optional field, are coerced in place first. Built-in tools
+// ignore arguments they do not declare; see tool.Schema.
+func validateArgs(s *argSchema, args map[string]any) error {
+	if s == nil {
+		return nil
+	}
+
+	coerceArg(s.schema, args)
+
+	if err := s.resolved.Validate(args); err != nil {
+		return argumentError(err)
+	}
+
+	return nil
+}
+
+// argSchemas caches the resolved parameters of each tool by name. An entry
+// is replaced when a tool of that name comes with other parameters, e.g.
+// after an MCP server reconnected; holding the parameters keeps their
+// identity from being reused.
+type argSchemas struct {
+	mu      sync.Mutex
+	entries map[string]argSchemaEntry
+}
+
+type argSchemaEntry struct {
+	params map[string]any
+	schema *argSchema
+	err    error
+}
+
+func (c *argSchemas) get(name string, params map[string]any) (*argSchema, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[name]; ok && reflect.ValueOf(e.params).UnsafePointer() == reflect.ValueOf(params).UnsafePointer() {
+		return e.schema, e.err
+	}
+
+	s, err := parseParams(params)
+
+	if c.entries == nil {
+		c.entries = make(map[string]argSchemaEntry)
+	}
+
+	c.entries[name] = argSchemaEntry{params, s, err}
+
+	return s, err
+}
+
+// coerceArg converts v to a type the schema accepts, where that is
+// unambiguous, and returns it. Objects and arrays are converted in place.
+func coerceArg(s *jsonschema.Schema, v any) any {
+	if s == nil {
+		return v
+	}
+
+	types := s.Types
+
+	if s.Type != "" {
+		types = []string{s.Type}
+	}
+
+	accepts := func(t string) bool {
+		return len(types) == 0 || slices.Contains(types, t) || (t == "integer" && slices.Contains(types, "number"))
+	}
+
+	switch value := v.(type) {
+	case map[string]any:
+		for name, pv := range value {
+			ps := s.Properties[name]
+
+			if ps == nil {
+				continue
+			}
+
+			if pv == nil && !slices.Contains(s.Required, name) && !accepts("null") {
+				delete(value, name)
+				continue
+			}
+
+			value[name] = coerceArg(ps, pv)
+		}
+
+	case []any:
+		for i := range value {
+			value[i] = coerceArg(s.Items, value[i])
+		}
+
+	case string:
+		if accepts("string") {
+			return v
+		}
+
+		for _, t := range types {
+			switch t {
+			case "integer", "number":
+				if f, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					return f
+				}
+
+			case "boolean":
+				if b, err := strconv.ParseBool(strings.TrimSpace(value)); err == nil {
+					return b
+				}
+
+			case "array", "object":
+				var decoded any
+
+				if err := json.Unmarshal([]byte(value), &decoded); err == nil {
+					return coerceArg(s, decoded)
+				}
+
+				if t == "array" {
+					return []any{coerceArg(s.Items, value)}
+				}
+			}
+		}
+
+	case float64:
+		if !accepts("number") && accepts("string") {
+			return strconv.FormatFloat(value, 'f', -1, 64)
+		}
+
+	case bool:
+		if !accepts("boolean") && accepts("string") {
+			return strconv.FormatBool(value)
+		}
+	}
+
+	return v
+}
+
+// argumentError shortens a validation error to the failing argument, e.g.
+// "edits[].line: type: x has type "string", want "integer"".
+func argumentError(err error) error {
+	msg := err.Error()
+	path := ""
+
+	for strings.HasPrefix(msg, "validating ") {
+		i := strings.Index(msg, ": ")
+
+		if i < 0 {
+			break
+		}
+
+		path = strings.TrimPrefix(msg[len("validating "):i], "root")
+		msg = msg[i+2:]
+	}
+
+	path = strings.ReplaceAll(path, "/items", "[]")
+	path = strings.ReplaceAll(path, "/properties/", ".")
+	path = strings.Trim(path, "./")
+
+	if path == "" {
+		return errors.New(msg)
+	}
+
+	return fmt.Errorf("%s: %s", path, msg)
+}
@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
+)
+
+func TestValidateArgs(t *testing.T) {
+	type edit struct {
+		Line int    `json:"line"`
+		Text string `json:"text"`
+	}
+
+	type params struct {
+		Path   string   `json:"path" jsonschema:"File to change"`
+		Limit  int      `json:"limit,omitempty"`
+		Force  bool     `json:"force,omitempty"`
+		Labels []string `json:"labels,omitempty"`
+		Edits  []edit   `json:"edits,omitempty"`
+	}
+
+	schema, err := parseParams(tool.Schema[params]())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args string
+		want params
+		err  string
+	}{
+		{
+			args: `{"path":"a.go","limit":"10","force":"true","labels":"bug"}`,
+			want: params{Path: "a.go", Limit: 10, Force: true, Labels: []string{"bug"}},
+		},
+		{
+			args: `{"path":"a.go","limit":null,"edits":"[{\"line\":\"3\",\"text\":\"x\"}]"}`,
+			want: params{Path: "a.go", Edits: []edit{{Line: 3, Text: "x"}}},
+		},
+		{
+			args: `{"path":"a.go","file_path":"b.go","edits":[{"line":1,"text":"x","note":"y"}]}`,
+			want: params{Path: "a.go", Edits: []edit{{Line: 1, Text: "x"}}},
+		},
+		{
+			args: `{"limit":5}`,
+			err:  "path",
+		},
+		{
+			args: `{"path":"a.go","limit":"ten"}`,
+			err:  "limit: ",
+		},
+		{
+			args: `{"path":"a.go","edits":[{"line":1.5,"text":"x"}]}`,
+			err:  "edits[].line: ",
+		},
+	}
+
+	for _, tt := range tests {
+		args := make(map[string]any)
+
+		if err := json.Unmarshal([]byte(tt.args), &args); err != nil {
+			t.Fatal(err)
+		}
+
+		err := validateArgs(schema, args)
+
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: expected error about %q, got %v", tt.args, tt.err, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.args, err)
+			continue
+		}
+
+		got, err := tool.Decode[params](args)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Path != tt.want.Path || got.Limit != tt.want.Limit || got.Force != tt.want.Force || strings.Join(got.Labels, ",") != strings.Join(tt.want.Labels, ",") || len(got.Edits) != len(tt.want.Edits) || (len(got.Edits) > 0 && got.Edits[0] != tt.want.Edits[0]) {
+			t.Errorf("%s: got %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestExecuteToolRejectsInvalidArgs(t *testing.T) {
+	ran := false
+
+	// Hand-written parameters, as MCP servers report them.
+	tools := []tool.Tool{{
+		Name: "remote_lookup",
+		Parameters: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"id": map[string]any{"type": "integer"}},
+			"required":   []any{"id"},
+		},
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
+			ran = true
+			return tool.Text("ok"), nil
+		},
+	}}
+
+	a := &Agent{Config: &Config{}}
+
+	result := a.executeTool(t.Context(), ToolCall{Name: "remote_lookup", Args: `{"id":"abc"}`}, tools)
+
+	if ran || !strings.HasPrefix(result.Text, "error: invalid arguments for remote_lookup: id: ") {
+		t.Errorf("expected a validation error, got %q", result.Text)
+	}
+
+	if result := a.executeTool(t.Context(), ToolCall{Name: "remote_lookup", Args: `{"id":"42"}`}, tools); result.Text != "ok" {
+		t.Errorf("expected coerced call to run, got %q", result.Text)
+	}
+}
+
+func TestArgSchemas(t *testing.T) {
+	var schemas argSchemas
+
+	params := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"spec": map[string]any{"$ref": "https://example.com/spec.json"}},
+	}
+
+	s, err := schemas.get("remote", params)
+
+	if err != nil {
+		t.Fatalf("expected remote references to resolve, got %v", err)
+	}
+
+	if again, _ := schemas.get("remote", params); again != s {
+		t.Error("expected the resolved parameters to be cached")
+	}
+
+	if err := validateArgs(s, map[string]any{"spec": 1}); err != nil {
+		t.Errorf("expected remote references to accept any value, got %v", err)
+	}
+
+	if _, err := schemas.get("remote", map[string]any{"type": 5}); err == nil {
+		t.Error("expected an error for changed, invalid parameters")
+	}
+}
@@ -0,0 +1,349 @@
+package agent
+
+import (
+	"encoding/json"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Capabilities describes what a model supports.
+type Capabilities struct {
+	ContextWindow   int `json:"context_window,omitempty"`
+	MaxOutputTokens int `json:"max_output_tokens,omitempty"`
+
+	Vision    bool `json:"vision"`
+	Reasoning bool `json:"reasoning"`
+	Tools     bool `json:"tools"`
+
+	// InputCost, CachedInputCost and OutputCost are prices per million
+	// tokens, used for cost limits. Zero means unknown.
+	InputCost       float64 `json:"input_cost,omitempty"`
+	CachedInputCost float64 `json:"cached_input_cost,omitempty"`
+	OutputCost      float64 `json:"output_cost,omitempty"`
+}
+
+// Cost returns the price of usage with this model, or zero if the prices
+// are unknown. Cached input falls back to the input price.
+func (c Capabilities) Cost(u Usage) float64 {
+	cached := c.CachedInputCost
+
+	if cached == 0 {
+		cached = c.InputCost
+	}
+
+	return (float64(u.InputTokens-u.CachedTokens)*c.InputCost + float64(u.CachedTokens)*cached + float64(u.OutputTokens)*c.OutputCost) / 1e6
+}
+
+// CapabilityOverride changes selected capabilities of a model. Nil fields
+// keep the value from the layer below.
+type CapabilityOverride struct {
+	ContextWindow   *int `json:"context_window,omitempty"`
+	MaxOutputTokens *int `json:"max_output_tokens,omitempty"`
+
+	Vision    *bool `json:"vision,omitempty"`
+	Reasoning *bool `json:"reasoning,omitempty"`
+	Tools     *bool `json:"tools,omitempty"`
+
+	InputCost       *float64 `json:"input_cost,omitempty"`
+	CachedInputCost *float64 `json:"cached_input_cost,omitempty"`
+	OutputCost      *float64 `json:"output_cost,omitempty"`
+}
+
+func (o CapabilityOverride) apply(c Capabilities) Capabilities {
+	if o.ContextWindow != nil {
+		c.ContextWindow = *o.ContextWindow
+	}
+
+	if o.MaxOutputTokens != nil {
+		c.MaxOutputTokens = *o.MaxOutputTokens
+	}
+
+	if o.Vision != nil {
+		c.Vision = *o.Vision
+	}
+
+	if o.Reasoning != nil {
+		c.Reasoning = *o.Reasoning
+	}
+
+	if o.Tools != nil {
+		c.Tools = *o.Tools
+	}
+
+	if o.InputCost != nil {
+		c.InputCost = *o.InputCost
+	}
+
+	if o.CachedInputCost != nil {
+		c.CachedInputCost = *o.CachedInputCost
+	}
+
+	if o.OutputCost != nil {
+		c.OutputCost = *o.OutputCost
+	}
+
+	return c
+}
+
+func (o CapabilityOverride) empty() bool {
+	return o == CapabilityOverride{}
+}
+
+// defaultCapabilities applies to models nothing is known about. It is
+// permissive so unknown models behave as before: effort and images are
+// passed through and the API decides.
+var defaultCapabilities = Capabilities{
+	ContextWindow: 128000,
+
+	Vision:    true,
+	Reasoning: true,
+	Tools:     true,
+}
+
+// builtinCapabilities are the known model families, keyed by ID pattern.
+// The most specific matching pattern wins.
+var builtinCapabilities = map[string]Capabilities{
+	"gpt-5*":   {ContextWindow: 400000, MaxOutputTokens: 128000, Vision: true, Reasoning: true, Tools: true},
+	"gpt-4.1*": {ContextWindow: 1047576, MaxOutputTokens: 32768, Vision: true, Tools: true},
+	"gpt-4o*":  {ContextWindow: 128000, MaxOutputTokens: 16384, Vision: true, Tools: true},
+	"gpt-oss*": {ContextWindow: 131072, MaxOutputTokens: 131072, Reasoning: true, Tools: true},
+	"o1*":      {ContextWindow: 200000, MaxOutputTokens: 100000, Vision: true, Reasoning: true, Tools: true},
+	"o3*":      {ContextWindow: 200000, MaxOutputTokens: 100000, Vision: true, Reasoning: true, Tools: true},
+	"o4-mini*": {ContextWindow: 200000, MaxOutputTokens: 100000, Vision: true, Reasoning: true, Tools: true},
+
+	"claude-opus-4*":     {ContextWindow: 200000, MaxOutputTokens: 32000, Vision: true, Reasoning: true, Tools: true},
+	"claude-opus-4-5*":   {ContextWindow: 200000, MaxOutputTokens: 64000, Vision: true, Reasoning: true, Tools: true},
+	"claude-opus-4-6*":   {ContextWindow: 200000, MaxOutputTokens: 128000, Vision: true, Reasoning: true, Tools: true},
+	"claude-opus-4-7*":   {ContextWindow: 200000, MaxOutputTokens: 128000, Vision: true, Reasoning: true, Tools: true},
+	"claude-sonnet-4*":   {ContextWindow: 200000, MaxOutputTokens: 64000, Vision: true, Reasoning: true, Tools: true},
+	"claude-haiku-4*":    {ContextWindow: 200000, MaxOutputTokens: 64000, Vision: true, Reasoning: true, Tools: true},
+	"claude-3-7-sonnet*": {ContextWindow: 200000, MaxOutputTokens: 64000, Vision: true, Reasoning: true, Tools: true},
+	"claude-3-5*":        {ContextWindow: 200000, MaxOutputTokens: 8192, Vision: true, Tools: true},
+
+	"gemini-2.5*": {ContextWindow: 1048576, MaxOutputTokens: 65536, Vision: true, Reasoning: true, Tools: true},
+	"gemini-3*":   {ContextWindow: 1048576, MaxOutputTokens: 65536, Vision: true, Reasoning: true, Tools: true},
+
+	"deepseek-chat*":     {ContextWindow: 128000, MaxOutputTokens: 8192, Tools: true},
+	"deepseek-reasoner*": {ContextWindow: 128000, MaxOutputTokens: 65536, Reasoning: true, Tools: true},
+	"qwen3*":             {ContextWindow: 131072, Reasoning: true, Tools: true},
+}
+
+// Registry resolves model capabilities from three layers: built-in
+// defaults, metadata reported by the API's model list, and configured
+// overrides, which win. A nil *Registry uses the built-in defaults only.
+type Registry struct {
+	mu sync.RWMutex
+
+	reported  map[string]CapabilityOverride
+	overrides map[string]CapabilityOverride
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		reported:  make(map[string]CapabilityOverride),
+		overrides: make(map[string]CapabilityOverride),
+	}
+}
+
+// Override sets capabilities for models matching pattern, which is a model
+// ID or a path.Match pattern such as "llama-*".
+func (r *Registry) Override(pattern string, o CapabilityOverride) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.overrides[pattern] = o
+}
+
+// report records metadata the API returned for a model.
+func (r *Registry) report(id string, o CapabilityOverride) {
+	if r == nil || o.empty() {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reported[id] = o
+}
+
+// Lookup returns the capabilities of the model with the given ID.
+func (r *Registry) Lookup(id string) Capabilities {
+	c := defaultCapabilities
+
+	if pattern, ok := bestMatch(id, builtinCapabilities); ok {
+		c = builtinCapabilities[pattern]
+	}
+
+	if r == nil {
+		return c
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if o, ok := r.reported[id]; ok {
+		c = o.apply(c)
+	}
+
+	if pattern, ok := bestMatch(id, r.overrides); ok {
+		c = r.overrides[pattern].apply(c)
+	}
+
+	return c
+}
+
+// bestMatch returns the most specific key of m matching id: an exact match,
+// otherwise the longest matching pattern. Gateway prefixes such as
+// "openai/" are ignored when matching patterns.
+func bestMatch[V any](id string, m map[string]V) (string, bool) {
+	if _, ok := m[id]; ok {
+		return id, true
+	}
+
+	name := strings.ToLower(id)
+
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	best := ""
+
+	for pattern := range m {
+		if len(pattern) <= len(best) {
+			continue
+		}
+
+		if ok, _ := path.Match(strings.ToLower(pattern), name); ok {
+			best = pattern
+		}
+	}
+
+	return best, best != ""
+}
+
+// parseModelMetadata extracts capabilities from a model list entry. There
+// is no standard; this understands the fields common gateways and local
+// runtimes (LiteLLM, OpenRouter, vLLM, LM Studio, Anthropic) return.
+func parseModelMetadata(raw string) CapabilityOverride {
+	var m struct {
+		ContextWindow    *int `json:"context_window"`
+		ContextLength    *int `json:"context_length"`
+		MaxContextLength *int `json:"max_context_length"`
+		MaxModelLen      *int `json:"max_model_len"`
+		MaxInputTokens   *int `json:"max_input_tokens"`
+
+		MaxOutputTokens *int `json:"max_output_tokens"`
+		MaxTokens       *int `json:"max_tokens"`
+
+		SupportsVision          *bool `json:"supports_vision"`
+		SupportsReasoning       *bool `json:"supports_reasoning"`
+		SupportsFunctionCalling *bool `json:"supports_function_calling"`
+		SupportsTools           *bool `json:"supports_tools"`
+
+		// Prices per token, as LiteLLM and OpenRouter report them.
+		InputCostPerToken       *float64 `json:"input_cost_per_token"`
+		OutputCostPerToken      *float64 `json:"output_cost_per_token"`
+		CacheReadInputTokenCost *float64 `json:"cache_read_input_token_cost"`
+
+		Pricing struct {
+			Prompt         string `json:"prompt"`
+			Completion     string `json:"completion"`
+			InputCacheRead string `json:"input_cache_read"`
+		} `json:"pricing"`
+
+		Capabilities json.RawMessage `json:"capabilities"`
+	}
+
+	if json.Unmarshal([]byte(raw), &m) != nil {
+		return CapabilityOverride{}
+	}
+
+	var o CapabilityOverride
+
+	o.ContextWindow = firstInt(m.ContextWindow, m.ContextLength, m.MaxContextLength, m.MaxModelLen, m.MaxInputTokens)
+	o.MaxOutputTokens = firstInt(m.MaxOutputTokens, m.MaxTokens)
+
+	o.Vision = m.SupportsVision
+	o.Reasoning = m.SupportsReasoning
+	o.Tools = m.SupportsFunctionCalling
+
+	if m.SupportsTools != nil {
+		o.Tools = m.SupportsTools
+	}
+
+	o.InputCost = perMillion(m.InputCostPerToken, m.Pricing.Prompt)
+	o.CachedInputCost = perMillion(m.CacheReadInputTokenCost, m.Pricing.InputCacheRead)
+	o.OutputCost = perMillion(m.OutputCostPerToken, m.Pricing.Completion)
+
+	// capabilities is either a list of names or an object of flags.
+	var names []string
+	var flags map[string]any
+
+	if json.Unmarshal(m.Capabilities, &names) == nil {
+		flags = make(map[string]any)
+
+		for _, name := range names {
+			flags[name] = true
+		}
+	} else {
+		json.Unmarshal(m.Capabilities, &flags)
+	}
+
+	for name, value := range flags {
+		supported, ok := value.(bool)
+
+		if !ok {
+			// e.g. {"image_input": {"supported": true}}
+			if v, isMap := value.(map[string]any); isMap {
+				supported, ok = v["supported"].(bool)
+			}
+		}
+
+		if !ok {
+			continue
+		}
+
+		switch name {
+		case "vision", "image_input", "images":
+			o.Vision = &supported
+		case "reasoning", "thinking":
+			o.Reasoning = &supported
+		case "tools", "tool_use", "function_calling":
+			o.Tools = &supported
+		}
+	}
+
+	return o
+}
+
+// perMillion converts a per-token price, given as a number or a decimal
+// string, to a price per million tokens.
+func perMillion(value *float64, text string) *float64 {
+	if value == nil && text != "" {
+		if v, err := strconv.ParseFloat(text, 64); err == nil {
+			value = &v
+		}
+	}
+
+	if value == nil || *value < 0 {
+		return nil
+	}
+
+	// Round off the float error of scaling prices like 0.000003.
+	v := math.Round(*value*1e12) / 1e6
+
+	return &v
+}
+
+func firstInt(values ...*int) *int {
+	for _, v := range values {
+		if v != nil && *v > 0 {
+			return v
+		}
+	}
+
+	return nil
+}
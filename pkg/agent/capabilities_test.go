@@ -0,0 +1,174 @@
+package agent
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegistryLookup(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	boolPtr := func(v bool) *bool { return &v }
+
+	t.Run("builtin families", func(t *testing.T) {
+		var r *Registry
+
+		if c := r.Lookup("gpt-5.5"); c.ContextWindow != 400000 || !c.Reasoning || !c.Vision {
+			t.Errorf("unexpected gpt-5.5 capabilities: %+v", c)
+		}
+
+		if c := r.Lookup("gpt-4.1-mini"); c.Reasoning {
+			t.Errorf("gpt-4.1 should not reason: %+v", c)
+		}
+
+		if c := r.Lookup("claude-opus-4-5-20251101"); c.MaxOutputTokens != 64000 {
+			t.Errorf("expected most specific pattern to win, got %+v", c)
+		}
+
+		if c := r.Lookup("openai/gpt-4o"); c.ContextWindow != 128000 || c.Reasoning {
+			t.Errorf("expected gateway prefix to be ignored, got %+v", c)
+		}
+
+		if c := r.Lookup("some-local-model"); c != defaultCapabilities {
+			t.Errorf("expected defaults for unknown model, got %+v", c)
+		}
+	})
+
+	t.Run("layers", func(t *testing.T) {
+		r := NewRegistry()
+
+		r.report("gpt-4o", CapabilityOverride{ContextWindow: intPtr(64000)})
+
+		if c := r.Lookup("gpt-4o"); c.ContextWindow != 64000 || !c.Vision {
+			t.Errorf("expected reported metadata on top of defaults, got %+v", c)
+		}
+
+		r.Override("gpt-4*", CapabilityOverride{Vision: boolPtr(false)})
+		r.Override("gpt-4o", CapabilityOverride{ContextWindow: intPtr(32000)})
+
+		if c := r.Lookup("gpt-4o"); c.ContextWindow != 32000 || !c.Vision {
+			t.Errorf("expected exact override to win over pattern, got %+v", c)
+		}
+
+		if c := r.Lookup("gpt-4.1"); c.Vision {
+			t.Errorf("expected pattern override, got %+v", c)
+		}
+	})
+}
+
+func TestParseModelMetadata(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want Capabilities
+	}{
+		{
+			`{"id":"m","context_length":32768,"supports_vision":false,"supports_function_calling":true}`,
+			Capabilities{ContextWindow: 32768, Reasoning: true, Tools: true},
+		},
+		{
+			`{"id":"m","max_model_len":8192,"capabilities":["reasoning"]}`,
+			Capabilities{ContextWindow: 8192, Vision: true, Reasoning: true, Tools: true},
+		},
+		{
+			`{"id":"m","max_input_tokens":200000,"max_tokens":64000,"capabilities":{"image_input":{"supported":false},"thinking":{"supported":false}}}`,
+			Capabilities{ContextWindow: 200000, MaxOutputTokens: 64000, Tools: true},
+		},
+		{
+			`{"id":"m","context_length":128000,"pricing":{"prompt":"0.000003","completion":"0.000015","input_cache_read":"0.0000003"}}`,
+			Capabilities{ContextWindow: 128000, Vision: true, Reasoning: true, Tools: true, InputCost: 3, CachedInputCost: 0.3, OutputCost: 15},
+		},
+		{
+			`{"id":"m","input_cost_per_token":0.0000025,"output_cost_per_token":0.00001}`,
+			Capabilities{ContextWindow: 128000, Vision: true, Reasoning: true, Tools: true, InputCost: 2.5, OutputCost: 10},
+		},
+		{
+			`{"id":"m","object":"model"}`,
+			defaultCapabilities,
+		},
+	}
+
+	for _, tt := range tests {
+		if got := parseModelMetadata(tt.raw).apply(defaultCapabilities); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestModelsReportsMetadata(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"object":"list","data":[{"id":"local-llm","object":"model","context_window":16384,"supports_vision":false}]}`)
+	}))
+	defer srv.Close()
+
+	cfg, err := NewConfig(&Options{Backend: BackendChat, BaseURL: srv.URL + "/v1", Token: "test"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := &Agent{Config: cfg}
+
+	models, err := a.Models(t.Context())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(models) != 1 || models[0].ContextWindow != 16384 || models[0].Vision {
+		t.Fatalf("unexpected models: %+v", models)
+	}
+
+	// Derived configs share what was learned.
+	if c := cfg.Derive().Capabilities("local-llm"); c.ContextWindow != 16384 {
+		t.Errorf("expected derived config to share the registry, got %+v", c)
+	}
+}
+
+func TestCompactsNearContextWindow(t *testing.T) {
+	reply := func(text string, tokens int) []string {
+		return []string{
+			fmt.Sprintf(`{"id":"1","object":"chat.completion.chunk","model":"m","choices":[{"index":0,"delta":{"role":"assistant","content":%q},"finish_reason":"stop"}]}`, text),
+			fmt.Sprintf(`{"id":"1","object":"chat.completion.chunk","model":"m","choices":[],"usage":{"prompt_tokens":%d,"completion_tokens":1}}`, tokens),
+			`[DONE]`,
+		}
+	}
+
+	stub := &stubServer{}
+
+	srv := httptest.NewServer(stub.handler(t, "/v1/chat/completions",
+		reply("first", 95),
+		reply("summary of the task", 10),
+		reply("second", 20),
+	))
+	defer srv.Close()
+
+	cfg, err := NewConfig(&Options{Backend: BackendChat, BaseURL: srv.URL + "/v1", Token: "test"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	window := 100
+	cfg.Registry.Override("m", CapabilityOverride{ContextWindow: &window})
+	cfg.Model = func() string { return "m" }
+
+	a := &Agent{Config: cfg}
+
+	for _, input := range []string{"one", "two"} {
+		for _, err := range a.Send(t.Context(), []Content{{Text: input}}) {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if len(stub.requests) != 3 {
+		t.Fatalf("expected a summarize request before the second turn, got %d requests", len(stub.requests))
+	}
+
+	if text := a.Messages[0].Content[0].Text; text != "[Previous conversation summary]\n\nsummary of the task" {
+		t.Errorf("expected history to be compacted, got %q", text)
+	}
+}
@@ -0,0 +1,305 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/openai/openai-go/v3/shared"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
+)
+
+// chatProvider talks to the Chat Completions API, which most gateways and
+// local runtimes (llama.cpp, vLLM, Ollama) implement. Reasoning and
+// compaction items from other backends are not sent.
+type chatProvider struct {
+	client *openai.Client
+}
+
+func (p *chatProvider) name() string {
+	return "openai"
+}
+
+func (p *chatProvider) models(ctx context.Context) ([]ModelInfo, error) {
+	return listOpenAIModels(ctx, p.client)
+}
+
+func (p *chatProvider) complete(ctx context.Context, r *request, yield func(Message, error) bool) (*response, error) {
+	params := openai.ChatCompletionNewParams{
+		Model:    r.model,
+		Messages: toChatMessages(r.instructions, r.messages),
+		Tools:    toChatTools(r.tools),
+
+		StreamOptions: openai.ChatCompletionStreamOptionsParam{
+			IncludeUsage: openai.Bool(true),
+		},
+	}
+
+	if len(params.Tools) > 0 {
+		params.ParallelToolCalls = openai.Bool(true)
+	}
+
+	if r.cacheKey != "" {
+		params.PromptCacheKey = openai.String(r.cacheKey)
+	}
+
+	if r.output != nil {
+		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:   r.output.name(),
+					Schema: r.output.Schema,
+					Strict: openai.Bool(r.output.Strict),
+				},
+			},
+		}
+	}
+
+	if r.effort != "" {
+		params.ReasoningEffort = shared.ReasoningEffort(r.effort)
+	}
+
+	stream := p.client.Chat.Completions.NewStreaming(ctx, params, option.WithMaxRetries(0))
+
+	var acc openai.ChatCompletionAccumulator
+	var reasoning strings.Builder
+
+	for stream.Next() {
+		chunk := stream.Current()
+		acc.AddChunk(chunk)
+
+		for _, choice := range chunk.Choices {
+			if text := chatReasoningDelta(choice.Delta); text != "" {
+				reasoning.WriteString(text)
+
+				msg := Message{
+					Role:    RoleAssistant,
+					Content: []Content{{Reasoning: &Reasoning{Summary: text}}},
+				}
+
+				if !yield(msg, nil) {
+					return nil, errYieldStopped
+				}
+			}
+
+			if choice.Delta.Content != "" {
+				msg := Message{
+					Role:    RoleAssistant,
+					Content: []Content{{Text: choice.Delta.Content}},
+				}
+
+				if !yield(msg, nil) {
+					return nil, errYieldStopped
+				}
+			}
+		}
+	}
+
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+
+	var messages []Message
+
+	if reasoning.Len() > 0 {
+		messages = append(messages, Message{
+			Role:    RoleAssistant,
+			Content: []Content{{Reasoning: &Reasoning{Summary: reasoning.String()}}},
+		})
+	}
+
+	if len(acc.Choices) > 0 {
+		m := acc.Choices[0].Message
+
+		if m.Content != "" {
+			messages = append(messages, Message{Role: RoleAssistant, Content: []Content{{Text: m.Content}}})
+		}
+
+		if m.Refusal != "" {
+			messages = append(messages, Message{Role: RoleAssistant, Content: []Content{{Refusal: m.Refusal}}})
+		}
+
+		for _, tc := range m.ToolCalls {
+			messages = append(messages, Message{
+				Role: RoleAssistant,
+				Content: []Content{{ToolCall: &ToolCall{
+					ID:   tc.ID,
+					Name: tc.Function.Name,
+					Args: tc.Function.Arguments,
+				}}},
+			})
+		}
+	}
+
+	return &response{
+		messages: messages,
+		usage: Usage{
+			InputTokens:  acc.Usage.PromptTokens,
+			CachedTokens: acc.Usage.PromptTokensDetails.CachedTokens,
+			OutputTokens: acc.Usage.CompletionTokens,
+		},
+	}, nil
+}
+
+// chatReasoningDelta returns the reasoning text of a streamed delta. It is
+// not part of the API; runtimes send it as reasoning_content or reasoning.
+func chatReasoningDelta(delta openai.ChatCompletionChunkChoiceDelta) string {
+	for _, key := range []string{"reasoning_content", "reasoning"} {
+		field, ok := delta.JSON.ExtraFields[key]
+
+		if !ok || !field.Valid() {
+			continue
+		}
+
+		var text string
+
+		if json.Unmarshal([]byte(field.Raw()), &text) == nil && text != "" {
+			return text
+		}
+	}
+
+	return ""
+}
+
+func toChatTools(tools []tool.Tool) []openai.ChatCompletionToolUnionParam {
+	var result []openai.ChatCompletionToolUnionParam
+
+	for _, t := range tools {
+		if t.Name == "" {
+			continue
+		}
+
+		f := shared.FunctionDefinitionParam{
+			Name:       t.Name,
+			Parameters: shared.FunctionParameters(t.Parameters),
+		}
+
+		if t.Description != "" {
+			f.Description = openai.String(t.Description)
+		}
+
+		result = append(result, openai.ChatCompletionFunctionTool(f))
+	}
+
+	return result
+}
+
+// toChatMessages converts the conversation. Consecutive assistant items are
+// merged into one message, and files returned by tools follow the tool
+// messages as a user message, since tool messages only carry text.
+func toChatMessages(instructions string, messages []Message) []openai.ChatCompletionMessageParamUnion {
+	var result []openai.ChatCompletionMessageParamUnion
+
+	if instructions != "" {
+		result = append(result, openai.SystemMessage(instructions))
+	}
+
+	var assistant *openai.ChatCompletionAssistantMessageParam
+	var toolFiles []openai.ChatCompletionContentPartUnionParam
+
+	flushAssistant := func() {
+		if assistant != nil {
+			result = append(result, openai.ChatCompletionMessageParamUnion{OfAssistant: assistant})
+			assistant = nil
+		}
+	}
+
+	flushToolFiles := func() {
+		if len(toolFiles) > 0 {
+			result = append(result, openai.UserMessage(toolFiles))
+			toolFiles = nil
+		}
+	}
+
+	for _, m := range messages {
+		var parts []openai.ChatCompletionContentPartUnionParam
+		var text []string
+
+		for _, c := range m.Content {
+			switch {
+			case c.ToolResult != nil && c.ToolResult.ID != "":
+				flushAssistant()
+
+				result = append(result, openai.ToolMessage(c.ToolResult.Content, c.ToolResult.ID))
+
+				for _, f := range c.ToolResult.Files {
+					toolFiles = append(toolFiles, chatFilePart(f))
+				}
+
+			case m.Role == RoleAssistant && (c.Text != "" || c.Refusal != "" || (c.ToolCall != nil && c.ToolCall.ID != "")):
+				flushToolFiles()
+
+				if assistant == nil {
+					assistant = &openai.ChatCompletionAssistantMessageParam{}
+				}
+
+				if c.Text != "" {
+					assistant.Content.OfString = openai.String(assistant.Content.OfString.Value + c.Text)
+				}
+
+				if c.Refusal != "" {
+					assistant.Refusal = openai.String(c.Refusal)
+				}
+
+				if c.ToolCall != nil {
+					assistant.ToolCalls = append(assistant.ToolCalls, openai.ChatCompletionMessageToolCallUnionParam{
+						OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{
+							ID: c.ToolCall.ID,
+							Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{
+								Name:      c.ToolCall.Name,
+								Arguments: c.ToolCall.Args,
+							},
+						},
+					})
+				}
+
+			case m.Role != RoleAssistant && c.Text != "":
+				text = append(text, c.Text)
+				parts = append(parts, openai.TextContentPart(c.Text))
+
+			case m.Role != RoleAssistant && c.File != nil && c.File.Data != "":
+				parts = append(parts, chatFilePart(*c.File))
+			}
+		}
+
+		if len(parts) == 0 {
+			continue
+		}
+
+		flushAssistant()
+		flushToolFiles()
+
+		switch {
+		case m.Role == RoleSystem:
+			result = append(result, openai.SystemMessage(strings.Join(text, "\n")))
+		case len(parts) == len(text) && len(text) == 1:
+			result = append(result, openai.UserMessage(text[0]))
+		default:
+			result = append(result, openai.UserMessage(parts))
+		}
+	}
+
+	flushAssistant()
+	flushToolFiles()
+
+	return result
+}
+
+func chatFilePart(f File) openai.ChatCompletionContentPartUnionParam {
+	if strings.HasPrefix(dataURLMediaType(f.Data), "image/") || !strings.HasPrefix(f.Data, "data:") {
+		return openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: f.Data})
+	}
+
+	file := openai.ChatCompletionContentPartFileFileParam{
+		FileData: openai.String(f.Data),
+	}
+
+	if f.Name != "" {
+		file.Filename = openai.String(f.Name)
+	}
+
+	return openai.FileContentPart(file)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
 	"github.com/openai/openai-go/v3/responses"
 	"github.com/openai/openai-go/v3/shared"
 
@@ -18,6 +19,11 @@ type request struct {
 	instructions string
 	messages     []Message
 	tools        []tool.Tool
+
+	capabilities Capabilities
+
+	cacheKey string
+	output   *OutputSchema
 }
 
 type response struct {
@@ -25,8 +31,58 @@ type response struct {
 	usage    Usage
 }
 
-func complete(ctx context.Context, client *openai.Client, r *request, yield func(Message, error) bool) (*response, error) {
-	stream := client.Responses.NewStreaming(ctx, responses.ResponseNewParams{
+// provider is a model API. complete streams text and reasoning deltas to
+// yield and returns the finished output items; their order and shape are
+// the same for every backend.
+type provider interface {
+	complete(ctx context.Context, r *request, yield func(Message, error) bool) (*response, error)
+	models(ctx context.Context) ([]ModelInfo, error)
+
+	// name identifies the API in traces, as gen_ai.provider.name.
+	name() string
+}
+
+// responsesProvider talks to the OpenAI Responses API.
+type responsesProvider struct {
+	client *openai.Client
+}
+
+func (p *responsesProvider) name() string {
+	return "openai"
+}
+
+func (p *responsesProvider) models(ctx context.Context) ([]ModelInfo, error) {
+	return listOpenAIModels(ctx, p.client)
+}
+
+func listOpenAIModels(ctx context.Context, client *openai.Client) ([]ModelInfo, error) {
+	resp, err := client.Models.List(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var models []ModelInfo
+
+	for _, m := range resp.Data {
+		models = append(models, ModelInfo{ID: m.ID, metadata: parseModelMetadata(m.RawJSON())})
+	}
+
+	return models, nil
+}
+
+// serverCompactThreshold is the input size at which the Responses API
+// compacts the conversation itself, well before the context window is full.
+func serverCompactThreshold(c Capabilities) int64 {
+	if c.ContextWindow <= 0 {
+		return 200000
+	}
+
+	return int64(c.ContextWindow) * 8 / 10
+}
+
+func (p *responsesProvider) complete(ctx context.Context, r *request, yield func(Message, error) bool) (*response, error) {
+	params := responses.ResponseNewParams{
 		Model:        r.model,
 		Instructions: openai.String(r.instructions),
 
@@ -40,18 +96,37 @@ func complete(ctx context.Context, client *openai.Client, r *request, yield func
 
 		ContextManagement: []responses.ResponseNewParamsContextManagement{{
 			Type:             "compaction",
-			CompactThreshold: openai.Int(200000),
+			CompactThreshold: openai.Int(serverCompactThreshold(r.capabilities)),
 		}},
+	}
 
-		Include: []responses.ResponseIncludable{
+	if r.capabilities.Reasoning {
+		params.Include = []responses.ResponseIncludable{
 			responses.ResponseIncludableReasoningEncryptedContent,
-		},
+		}
 
-		Reasoning: responses.ReasoningParam{
+		params.Reasoning = responses.ReasoningParam{
 			Summary: responses.ReasoningSummaryAuto,
 			Effort:  shared.ReasoningEffort(r.effort),
-		},
-	})
+		}
+	}
+
+	if r.cacheKey != "" {
+		params.PromptCacheKey = openai.String(r.cacheKey)
+	}
+
+	if r.output != nil {
+		params.Text.Format = responses.ResponseFormatTextConfigUnionParam{
+			OfJSONSchema: &responses.ResponseFormatTextJSONSchemaConfigParam{
+				Name:   r.output.name(),
+				Schema: r.output.Schema,
+				Strict: openai.Bool(r.output.Strict),
+			},
+		}
+	}
+
+	// Retries are up to Config.complete, which can switch models.
+	stream := p.client.Responses.NewStreaming(ctx, params, option.WithMaxRetries(0))
 
 	var outputItems []responses.ResponseInputItemUnionParam
 	var usageDelta Usage
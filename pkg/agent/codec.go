@@ -1,6 +1,8 @@
 package agent
 
 import (
+	"strings"
+
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/packages/param"
 	"github.com/openai/openai-go/v3/responses"
@@ -94,9 +96,7 @@ func userToInput(m Message) []responses.ResponseInputItemUnionParam {
 			items = append(items, responses.ResponseInputItemUnionParam{
 				OfFunctionCallOutput: &responses.ResponseInputItemFunctionCallOutputParam{
 					CallID: c.ToolResult.ID,
-					Output: responses.ResponseInputItemFunctionCallOutputOutputUnionParam{
-						OfString: openai.String(c.ToolResult.Content),
-					},
+					Output: toolResultToOutput(c.ToolResult),
 				},
 			})
 		}
@@ -158,9 +158,7 @@ func assistantToInput(m Message) []responses.ResponseInputItemUnionParam {
 			items = append(items, responses.ResponseInputItemUnionParam{
 				OfFunctionCallOutput: &responses.ResponseInputItemFunctionCallOutputParam{
 					CallID: c.ToolResult.ID,
-					Output: responses.ResponseInputItemFunctionCallOutputOutputUnionParam{
-						OfString: openai.String(c.ToolResult.Content),
-					},
+					Output: toolResultToOutput(c.ToolResult),
 				},
 			})
 		}
@@ -169,6 +167,97 @@ func assistantToInput(m Message) []responses.ResponseInputItemUnionParam {
 	return items
 }
 
+// toolResultToOutput maps a tool result to a function call output. Results
+// with attached files become a list of text, image and file parts.
+func toolResultToOutput(r *ToolResult) responses.ResponseInputItemFunctionCallOutputOutputUnionParam {
+	if len(r.Files) == 0 {
+		return responses.ResponseInputItemFunctionCallOutputOutputUnionParam{
+			OfString: openai.String(r.Content),
+		}
+	}
+
+	var parts responses.ResponseFunctionCallOutputItemListParam
+
+	if r.Content != "" {
+		parts = append(parts, responses.ResponseFunctionCallOutputItemUnionParam{
+			OfInputText: &responses.ResponseInputTextContentParam{Text: r.Content},
+		})
+	}
+
+	for _, f := range r.Files {
+		if f.Data == "" {
+			continue
+		}
+
+		if strings.HasPrefix(dataURLMediaType(f.Data), "image/") {
+			parts = append(parts, responses.ResponseFunctionCallOutputItemUnionParam{
+				OfInputImage: &responses.ResponseInputImageContentParam{
+					ImageURL: openai.String(f.Data),
+					Detail:   responses.ResponseInputImageContentDetailAuto,
+				},
+			})
+
+			continue
+		}
+
+		file := &responses.ResponseInputFileContentParam{
+			FileData: openai.String(f.Data),
+		}
+
+		if f.Name != "" {
+			file.Filename = openai.String(f.Name)
+		}
+
+		parts = append(parts, responses.ResponseFunctionCallOutputItemUnionParam{OfInputFile: file})
+	}
+
+	return responses.ResponseInputItemFunctionCallOutputOutputUnionParam{
+		OfResponseFunctionCallOutputItemArray: parts,
+	}
+}
+
+// outputToToolResult restores the content and files of a function call
+// output.
+func outputToToolResult(output responses.ResponseInputItemFunctionCallOutputOutputUnionParam, r *ToolResult) {
+	r.Content = output.OfString.Value
+
+	var texts []string
+
+	for _, part := range output.OfResponseFunctionCallOutputItemArray {
+		switch {
+		case part.OfInputText != nil:
+			texts = append(texts, part.OfInputText.Text)
+
+		case part.OfInputImage != nil && part.OfInputImage.ImageURL.Value != "":
+			r.Files = append(r.Files, File{Data: part.OfInputImage.ImageURL.Value})
+
+		case part.OfInputFile != nil && part.OfInputFile.FileData.Value != "":
+			r.Files = append(r.Files, File{
+				Name: part.OfInputFile.Filename.Value,
+				Data: part.OfInputFile.FileData.Value,
+			})
+		}
+	}
+
+	if len(texts) > 0 {
+		r.Content = strings.Join(texts, "\n")
+	}
+}
+
+// dataURLMediaType returns the media type of a "data:" URL.
+func dataURLMediaType(url string) string {
+	rest, ok := strings.CutPrefix(url, "data:")
+
+	if !ok {
+		return ""
+	}
+
+	mediaType, _, _ := strings.Cut(rest, ";")
+	mediaType, _, _ = strings.Cut(mediaType, ",")
+
+	return mediaType
+}
+
 func reasoningToInput(r *Reasoning) *responses.ResponseReasoningItemParam {
 	if r == nil || r.ID == "" {
 		return nil
@@ -264,11 +353,11 @@ func toMessages(items []responses.ResponseInputItemUnionParam) []Message {
 		case item.OfFunctionCallOutput != nil:
 			tc := toolCallsByID[item.OfFunctionCallOutput.CallID]
 			tr := ToolResult{
-				ID:      item.OfFunctionCallOutput.CallID,
-				Name:    tc.Name,
-				Args:    tc.Args,
-				Content: item.OfFunctionCallOutput.Output.OfString.Value,
+				ID:   item.OfFunctionCallOutput.CallID,
+				Name: tc.Name,
+				Args: tc.Args,
 			}
+			outputToToolResult(item.OfFunctionCallOutput.Output, &tr)
 			messages = append(messages, Message{
 				Role:    RoleAssistant,
 				Content: []Content{{ToolResult: &tr}},
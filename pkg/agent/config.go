@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"fmt"
 	"os"
 	"strings"
 
@@ -11,66 +12,186 @@ import (
 	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
 )
 
+// Backend is the model API a Config talks to.
+type Backend string
+
+const (
+	BackendResponses Backend = "responses"
+	BackendChat      Backend = "chat"
+	BackendAnthropic Backend = "anthropic"
+)
+
 type Config struct {
+	provider provider
+
+	// client is the OpenAI-compatible client used for embeddings. It is nil
+	// for the Anthropic backend.
 	client *openai.Client
 
+	// Registry resolves model capabilities. It is shared by derived
+	// configs and filled from the model list when Models is called.
+	Registry *Registry
+
 	Model           func() string
 	Effort          func() string
 	Tools           func() []tool.Tool
 	Instructions    func() string
 	ContextMessages func() []Message
 
+	// SubagentModel, SummaryModel and TitleModel select the models for
+	// delegated tasks, conversation summaries and session titles. Nil uses
+	// Model; without TitleModel no titles are generated.
+	SubagentModel func() string
+	SummaryModel  func() string
+	TitleModel    func() string
+
+	// Fallbacks lists models to move to, in order, when the current model
+	// is overloaded or keeps failing.
+	Fallbacks func() []string
+
+	// CacheKey identifies requests that share a prompt prefix, such as the
+	// turns of one session, so the API can route them to the same cache.
+	CacheKey func() string
+
+	// OutputSchema, if set, requires the final answer of each turn to be
+	// JSON matching it; see Agent.Output.
+	OutputSchema *OutputSchema
+
+	// Limits protect each turn against runaway loops and spending; nil
+	// enforces none.
+	Limits *Limits
+
 	Hooks hook.Hooks
 }
 
-// Derive creates a new Config sharing the same client and model.
+// Options configures the model endpoint. Empty fields are resolved from the
+// environment.
+type Options struct {
+	// Backend selects the API; WINGMAN_API overrides the default, which is
+	// the Responses API or Anthropic when only ANTHROPIC_API_KEY is set.
+	Backend Backend
+
+	BaseURL string
+	Token   string
+}
+
+// Derive creates a new Config sharing the same client, models, fallbacks
+// and limits.
 func (c *Config) Derive() *Config {
 	return &Config{
-		client: c.client,
-		Model:  c.Model,
-		Effort: c.Effort,
+		provider: c.provider,
+		client:   c.client,
+		Registry: c.Registry,
+		Model:    c.Model,
+		Effort:   c.Effort,
+
+		SubagentModel: c.SubagentModel,
+		SummaryModel:  c.SummaryModel,
+		TitleModel:    c.TitleModel,
+		Fallbacks:     c.Fallbacks,
+
+		Limits: c.Limits,
 	}
 }
 
-func DefaultConfig() (*Config, error) {
-	client := createClient()
+// Capabilities returns the capabilities of the given model.
+func (c *Config) Capabilities(model string) Capabilities {
+	return c.Registry.Lookup(model)
+}
 
-	return &Config{
-		client: &client,
-	}, nil
+func DefaultConfig() (*Config, error) {
+	return NewConfig(nil)
 }
 
-func createClient() openai.Client {
-	if url, ok := os.LookupEnv("WINGMAN_URL"); ok {
-		baseURL := strings.TrimRight(url, "/") + "/v1"
+// NewConfig creates a Config for the endpoint described by options. A nil
+// *Options resolves everything from the environment.
+func NewConfig(options *Options) (*Config, error) {
+	if options == nil {
+		options = new(Options)
+	}
 
-		token, _ := os.LookupEnv("WINGMAN_TOKEN")
+	backend, baseURL, token := resolveEndpoint(options)
 
-		if token == "" {
-			token = "-"
-		}
+	switch backend {
+	case BackendAnthropic:
+		return &Config{
+			provider: newAnthropicProvider(baseURL, token),
+			Registry: NewRegistry(),
+		}, nil
 
-		return openai.NewClient(
+	case BackendResponses, BackendChat:
+		client := openai.NewClient(
 			option.WithBaseURL(baseURL),
 			option.WithAPIKey(token),
 		)
+
+		c := &Config{client: &client, Registry: NewRegistry()}
+
+		if backend == BackendChat {
+			c.provider = &chatProvider{client: &client}
+		} else {
+			c.provider = &responsesProvider{client: &client}
+		}
+
+		return c, nil
+
+	default:
+		return nil, fmt.Errorf("unknown backend %q: use responses, chat or anthropic", backend)
 	}
+}
 
-	if token, ok := os.LookupEnv("OPENAI_API_KEY"); ok {
-		baseURL := "https://api.openai.com/v1"
+// resolveEndpoint fills in the backend, base URL and token. WINGMAN_URL
+// takes priority, then OPENAI_API_KEY, then ANTHROPIC_API_KEY, and finally a
+// local server on port 8080.
+func resolveEndpoint(options *Options) (Backend, string, string) {
+	backend := options.Backend
 
-		if url, ok := os.LookupEnv("OPENAI_BASE_URL"); ok {
-			baseURL = url
+	if backend == "" {
+		backend = Backend(strings.ToLower(os.Getenv("WINGMAN_API")))
+	}
+
+	baseURL, token := options.BaseURL, options.Token
+
+	if baseURL == "" {
+		if url, ok := os.LookupEnv("WINGMAN_URL"); ok {
+			baseURL = strings.TrimRight(url, "/") + "/v1"
+
+			if token == "" {
+				token = os.Getenv("WINGMAN_TOKEN")
+			}
+		} else if key, ok := os.LookupEnv("OPENAI_API_KEY"); ok && backend != BackendAnthropic {
+			baseURL = "https://api.openai.com/v1"
+
+			if url, ok := os.LookupEnv("OPENAI_BASE_URL"); ok {
+				baseURL = url
+			}
+
+			if token == "" {
+				token = key
+			}
+		} else if key, ok := os.LookupEnv("ANTHROPIC_API_KEY"); ok && (backend == "" || backend == BackendAnthropic) {
+			backend = BackendAnthropic
+			baseURL = "https://api.anthropic.com/v1"
+
+			if url, ok := os.LookupEnv("ANTHROPIC_BASE_URL"); ok {
+				baseURL = strings.TrimRight(url, "/") + "/v1"
+			}
+
+			if token == "" {
+				token = key
+			}
+		} else {
+			baseURL = "http://localhost:8080/v1"
 		}
+	}
 
-		return openai.NewClient(
-			option.WithBaseURL(baseURL),
-			option.WithAPIKey(token),
-		)
+	if backend == "" {
+		backend = BackendResponses
+	}
+
+	if token == "" {
+		token = "-"
 	}
 
-	return openai.NewClient(
-		option.WithBaseURL("http://localhost:8080/v1"),
-		option.WithAPIKey("-"),
-	)
+	return backend, baseURL, token
 }
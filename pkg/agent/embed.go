@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// Embed returns one embedding per input using the configured endpoint's
+// embeddings API.
+func (c *Config) Embed(ctx context.Context, model string, inputs []string) ([][]float32, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	if c.client == nil {
+		return nil, fmt.Errorf("embeddings are not supported by this backend")
+	}
+
+	resp, err := c.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Model: model,
+
+		Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: inputs},
+
+		EncodingFormat: openai.EmbeddingNewParamsEncodingFormatFloat,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([][]float32, len(inputs))
+
+	for _, e := range resp.Data {
+		if e.Index < 0 || int(e.Index) >= len(result) {
+			return nil, fmt.Errorf("embedding index %d out of range", e.Index)
+		}
+
+		vector := make([]float32, len(e.Embedding))
+
+		for i, v := range e.Embedding {
+			vector[i] = float32(v)
+		}
+
+		result[e.Index] = vector
+	}
+
+	for i, v := range result {
+		if v == nil {
+			return nil, fmt.Errorf("missing embedding for input %d", i)
+		}
+	}
+
+	return result, nil
+}
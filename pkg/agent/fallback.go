@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+// maxModelAttempts is how often a model is tried on transient errors before
+// moving on to the next model in the chain.
+const maxModelAttempts = 3
+
+// retryDelay is the pause before trying the same model again; it grows with
+// each attempt.
+var retryDelay = time.Second
+
+// modelChain returns model followed by the configured fallbacks, without
+// duplicates or empty entries.
+func (c *Config) modelChain(model string) []string {
+	chain := []string{model}
+
+	if c.Fallbacks == nil {
+		return chain
+	}
+
+	for _, m := range c.Fallbacks() {
+		if m != "" && !slices.Contains(chain, m) {
+			chain = append(chain, m)
+		}
+	}
+
+	return chain
+}
+
+// roleModel resolves a role-specific model, falling back to Model.
+func (c *Config) roleModel(role func() string) string {
+	if role != nil {
+		if m := role(); m != "" {
+			return m
+		}
+	}
+
+	if c.Model != nil {
+		return c.Model()
+	}
+
+	return ""
+}
+
+// complete sends r to the models in chain, starting at index start. A model
+// is retried on transient errors and skipped right away when overloaded and
+// another model is left; other errors are returned as is. Once part of a
+// response was streamed, its failure is returned as an interruptedError
+// since another attempt would repeat it. It returns the index of the model
+// that answered, so a turn can stay on a fallback once it moved there.
+// Tool result images are left out for models without vision.
+func (c *Config) complete(ctx context.Context, r *request, effort string, chain []string, start int, yield func(Message, error) bool) (*response, int, error) {
+	var err error
+
+	messages := r.messages
+	defer func() { r.messages = messages }()
+
+	for i := start; i < len(chain); i++ {
+		r.model = chain[i]
+		r.capabilities = c.Capabilities(r.model)
+
+		r.messages = messages
+
+		if !r.capabilities.Vision {
+			r.messages = withoutToolImages(messages, imageNotice)
+		}
+
+		r.effort = effort
+
+		if !r.capabilities.Reasoning {
+			r.effort = ""
+		}
+
+		for attempt := range maxModelAttempts {
+			if attempt > 0 {
+				select {
+				case <-ctx.Done():
+					return nil, i, ctx.Err()
+				case <-time.After(retryDelay * time.Duration(attempt)):
+				}
+			}
+
+			var resp *response
+
+			streamed := false
+
+			spanCtx, span := c.startChatSpan(ctx, r)
+			resp, err = c.provider.complete(spanCtx, r, func(m Message, err error) bool {
+				streamed = true
+				return yield(m, err)
+			})
+			endChatSpan(span, resp, err)
+
+			if err == nil {
+				return resp, i, nil
+			}
+
+			if streamed && !errors.Is(err, errYieldStopped) {
+				return nil, i, &interruptedError{err}
+			}
+
+			if errors.Is(err, errYieldStopped) || ctx.Err() != nil || !isTransientError(err) {
+				return nil, i, err
+			}
+
+			if isOverloadedError(err) && i < len(chain)-1 {
+				break
+			}
+		}
+	}
+
+	return nil, len(chain) - 1, err
+}
+
+// interruptedError is a failure after part of the response was streamed.
+type interruptedError struct {
+	err error
+}
+
+func (e *interruptedError) Error() string { return e.err.Error() }
+func (e *interruptedError) Unwrap() error { return e.err }
@@ -0,0 +1,234 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+// statusServer answers Chat Completions requests per model: a status code
+// fails the request, 0 streams a reply naming the model and -1 drops the
+// connection after streaming part of a reply.
+func statusServer(t *testing.T, statuses map[string][]int) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var models []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+
+		json.NewDecoder(r.Body).Decode(&body)
+
+		mu.Lock()
+		models = append(models, body.Model)
+
+		status := 0
+		if s := statuses[body.Model]; len(s) > 0 {
+			status, statuses[body.Model] = s[0], s[1:]
+		}
+		mu.Unlock()
+
+		if status == -1 {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "data: %s\n\n", `{"id":"1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"role":"assistant","content":"partial"}}]}`)
+			w.(http.Flusher).Flush()
+
+			if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+				conn.Close()
+			}
+
+			return
+		}
+
+		if status != 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"error":{"message":"status %d","type":"error"}}`, status)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: %s\n\n", fmt.Sprintf(`{"id":"1","object":"chat.completion.chunk","model":%q,"choices":[{"index":0,"delta":{"role":"assistant","content":"from %s"},"finish_reason":"stop"}]}`, body.Model, body.Model))
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]string(nil), models...)
+	}
+}
+
+func sendText(t *testing.T, a *Agent) (string, error) {
+	t.Helper()
+
+	var text strings.Builder
+
+	for msg, err := range a.Send(t.Context(), []Content{{Text: "hi"}}) {
+		if err != nil {
+			return text.String(), err
+		}
+
+		for _, c := range msg.Content {
+			text.WriteString(c.Text)
+		}
+	}
+
+	return text.String(), nil
+}
+
+func TestFallback(t *testing.T) {
+	retryDelay = 0
+
+	tests := []struct {
+		name     string
+		statuses map[string][]int
+		want     string
+		requests []string
+		err      bool
+	}{
+		{
+			name:     "overloaded moves on",
+			statuses: map[string][]int{"primary": {529}},
+			want:     "from backup",
+			requests: []string{"primary", "backup"},
+		},
+		{
+			name:     "server error is retried first",
+			statuses: map[string][]int{"primary": {500}},
+			want:     "from primary",
+			requests: []string{"primary", "primary"},
+		},
+		{
+			name:     "repeated errors move on",
+			statuses: map[string][]int{"primary": {500, 502, 500}},
+			want:     "from backup",
+			requests: []string{"primary", "primary", "primary", "backup"},
+		},
+		{
+			name:     "overloaded last model is retried",
+			statuses: map[string][]int{"primary": {529}, "backup": {429}},
+			want:     "from backup",
+			requests: []string{"primary", "backup", "backup"},
+		},
+		{
+			name:     "interrupted responses are not repeated",
+			statuses: map[string][]int{"primary": {-1}},
+			want:     "partial",
+			requests: []string{"primary"},
+			err:      true,
+		},
+		{
+			name:     "authentication errors stop",
+			statuses: map[string][]int{"primary": {401}},
+			requests: []string{"primary"},
+			err:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, requests := statusServer(t, tt.statuses)
+
+			cfg, err := NewConfig(&Options{Backend: BackendChat, BaseURL: srv.URL + "/v1", Token: "test"})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cfg.Model = func() string { return "primary" }
+			cfg.Fallbacks = func() []string { return []string{"primary", "backup"} }
+
+			text, err := sendText(t, &Agent{Config: cfg})
+
+			if (err != nil) != tt.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if text != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, text)
+			}
+
+			if got := requests(); strings.Join(got, ",") != strings.Join(tt.requests, ",") {
+				t.Errorf("expected requests %v, got %v", tt.requests, got)
+			}
+		})
+	}
+}
+
+func TestRoleModels(t *testing.T) {
+	srv, requests := statusServer(t, nil)
+
+	cfg, err := NewConfig(&Options{Backend: BackendChat, BaseURL: srv.URL + "/v1", Token: "test"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Model = func() string { return "main" }
+	cfg.SummaryModel = func() string { return "small" }
+
+	a := &Agent{Config: cfg, Messages: []Message{userMessage([]Content{{Text: "fix the bug"}})}}
+
+	if _, err := a.summarizeMessages(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	if title, err := cfg.Title(t.Context(), a.Messages); err != nil || title != "" {
+		t.Errorf("expected no title without a title model, got %q, %v", title, err)
+	}
+
+	cfg.TitleModel = func() string { return "tiny" }
+
+	title, err := cfg.Title(t.Context(), a.Messages)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if title != "from tiny" {
+		t.Errorf("unexpected title %q", title)
+	}
+
+	if got := requests(); strings.Join(got, ",") != "small,tiny" {
+		t.Errorf("expected summary and title models, got %v", got)
+	}
+}
+
+func TestCleanTitle(t *testing.T) {
+	for in, want := range map[string]string{
+		`"Fix login redirect."`:      "Fix login redirect",
+		"**Refactor parser**\nextra": "Refactor parser",
+		"  Plain title  ":            "Plain title",
+		strings.Repeat("ü", 90):      strings.Repeat("ü", 77) + "...",
+	} {
+		if got := cleanTitle(in); got != want {
+			t.Errorf("cleanTitle(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTransientError(t *testing.T) {
+	for err, want := range map[error]bool{
+		io.ErrUnexpectedEOF: true,
+		fmt.Errorf("failed to read: %w", syscall.ECONNRESET): true,
+		&net.OpError{Op: "dial", Err: errors.New("refused")}: true,
+		errors.New("failed to parse response"):               false,
+	} {
+		if got := isTransientError(err); got != want {
+			t.Errorf("isTransientError(%v) = %v, want %v", err, got, want)
+		}
+	}
+}
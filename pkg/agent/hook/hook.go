@@ -13,9 +13,9 @@ import (
 type PreToolUse func(ctx context.Context, call tool.ToolCall) (string, error)
 
 // PostToolUse is called after a tool executes.
-// Receives the call and result. Return a modified result to transform it,
-// or return the same result to pass through.
-type PostToolUse func(ctx context.Context, call tool.ToolCall, result string) (string, error)
+// Receives the call and result, including attached files. Return a modified
+// result to transform it, or return the same result to pass through.
+type PostToolUse func(ctx context.Context, call tool.ToolCall, result tool.Result) (tool.Result, error)
 
 // Hooks holds the registered hook functions for an agent.
 type Hooks struct {
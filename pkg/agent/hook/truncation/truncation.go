@@ -7,6 +7,9 @@ import (
 	"path/filepath"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/adrianliechti/wingman-agent/pkg/agent/hook"
 	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
 	"github.com/adrianliechti/wingman-agent/pkg/text"
@@ -22,13 +25,15 @@ const DefaultMaxBytes = 16 * 1024
 // output is saved there and the model is told where to find it, so it can
 // re-read specific ranges instead of re-running the tool.
 func New(maxBytes int, scratchDir string) hook.PostToolUse {
-	return func(ctx context.Context, call tool.ToolCall, result string) (string, error) {
-		if len(result) <= maxBytes {
+	return func(ctx context.Context, call tool.ToolCall, result tool.Result) (tool.Result, error) {
+		if len(result.Text) <= maxBytes {
 			return result, nil
 		}
 
-		totalBytes := len(result)
-		truncated := text.TruncateMiddle(result, maxBytes)
+		totalBytes := len(result.Text)
+		truncated := text.TruncateMiddle(result.Text, maxBytes)
+
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Key(tool.TruncatedKey).Bool(true))
 
 		var notice string
 
@@ -36,7 +41,7 @@ func New(maxBytes int, scratchDir string) hook.PostToolUse {
 			name := fmt.Sprintf("result-%d.txt", time.Now().UnixNano())
 			path := filepath.Join(scratchDir, name)
 
-			if err := os.WriteFile(path, []byte(result), 0644); err == nil {
+			if err := os.WriteFile(path, []byte(result.Text), 0644); err == nil {
 				notice = fmt.Sprintf("[Output truncated: %d bytes — head and tail kept, middle elided. Full output saved to %s; use `read` on that path to retrieve a specific range.]\n\n", totalBytes, path)
 			}
 		}
@@ -45,6 +50,8 @@ func New(maxBytes int, scratchDir string) hook.PostToolUse {
 			notice = fmt.Sprintf("[Output truncated: %d bytes — head and tail kept, middle elided. Re-run with a narrower scope if you need the omitted section.]\n\n", totalBytes)
 		}
 
-		return notice + truncated, nil
+		result.Text = notice + truncated
+
+		return result, nil
 	}
 }
@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"slices"
+	"strings"
+)
+
+// imageNotice replaces tool result images a model cannot see.
+const imageNotice = "[The image was not sent: the model does not support images.]"
+
+// seenImageNotice replaces tool result images the model has already seen.
+const seenImageNotice = "[The image was shown earlier and has been removed from the history.]"
+
+func isImageFile(f File) bool {
+	return strings.HasPrefix(f.Data, "data:image/")
+}
+
+// withoutToolImages returns messages with the images of tool results
+// replaced by notice. Messages are copied on change, so the history the
+// caller holds stays untouched.
+func withoutToolImages(messages []Message, notice string) []Message {
+	var result []Message
+
+	for i, m := range messages {
+		stripped, ok := stripToolImages(m, notice)
+
+		if !ok {
+			if result != nil {
+				result = append(result, m)
+			}
+
+			continue
+		}
+
+		if result == nil {
+			result = append(make([]Message, 0, len(messages)), messages[:i]...)
+		}
+
+		result = append(result, stripped)
+	}
+
+	if result == nil {
+		return messages
+	}
+
+	return result
+}
+
+func stripToolImages(m Message, notice string) (Message, bool) {
+	var content []Content
+
+	for i, c := range m.Content {
+		if c.ToolResult == nil || !slices.ContainsFunc(c.ToolResult.Files, isImageFile) {
+			if content != nil {
+				content = append(content, c)
+			}
+
+			continue
+		}
+
+		if content == nil {
+			content = append(make([]Content, 0, len(m.Content)), m.Content[:i]...)
+		}
+
+		r := *c.ToolResult
+		r.Files = nil
+
+		for _, f := range c.ToolResult.Files {
+			if !isImageFile(f) {
+				r.Files = append(r.Files, f)
+			}
+		}
+
+		r.Content = strings.TrimSpace(r.Content + "\n\n" + notice)
+
+		c.ToolResult = &r
+		content = append(content, c)
+	}
+
+	if content == nil {
+		return m, false
+	}
+
+	m.Content = content
+
+	return m, true
+}
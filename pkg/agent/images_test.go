@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWithoutToolImages(t *testing.T) {
+	messages := []Message{
+		userMessage([]Content{{Text: "look"}, {File: &File{Data: "data:image/png;base64,AA=="}}}),
+		{Role: RoleAssistant, Content: []Content{{ToolResult: &ToolResult{
+			ID:      "call_1",
+			Content: "Image a.png is attached.",
+			Files: []File{
+				{Name: "a.png", Data: "data:image/png;base64,AA=="},
+				{Name: "b.pdf", Data: "data:application/pdf;base64,AA=="},
+			},
+		}}}},
+	}
+
+	got := withoutToolImages(messages, imageNotice)
+
+	if len(got[0].Content) != 2 {
+		t.Errorf("expected user images to be kept, got %+v", got[0].Content)
+	}
+
+	r := got[1].Content[0].ToolResult
+
+	if len(r.Files) != 1 || r.Files[0].Name != "b.pdf" {
+		t.Errorf("expected only the pdf to be kept, got %+v", r.Files)
+	}
+
+	if !strings.HasSuffix(r.Content, imageNotice) {
+		t.Errorf("expected notice, got %q", r.Content)
+	}
+
+	if len(messages[1].Content[0].ToolResult.Files) != 2 {
+		t.Error("expected the original messages to be unchanged")
+	}
+
+	if again := withoutToolImages(got, imageNotice); &again[0] != &got[0] {
+		t.Error("expected messages without images to be returned as is")
+	}
+}
@@ -0,0 +1,285 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
+)
+
+// LimitAction is what a turn does when it reaches a limit.
+type LimitAction string
+
+const (
+	// LimitStop ends the turn with a LimitError.
+	LimitStop LimitAction = "stop"
+
+	// LimitNudge tells the model once and grants another allowance; the
+	// next trip of the same limit stops the turn.
+	LimitNudge LimitAction = "nudge"
+
+	// LimitAsk lets the user extend the limit through Elicit and stops the
+	// turn if they decline.
+	LimitAsk LimitAction = "ask"
+)
+
+// Limits protect a turn against runaway loops and spending. Zero fields are
+// not enforced.
+type Limits struct {
+	// MaxToolRounds is the number of model responses with tool calls in a
+	// turn.
+	MaxToolRounds int
+
+	// MaxTokens and MaxCost cap the input and output tokens, and their
+	// price in USD, across a turn. Cost needs model prices; see
+	// Capabilities.
+	MaxTokens int64
+	MaxCost   float64
+
+	// MaxRepeats is how often a tool may be called with the same arguments
+	// and get the same result or error in a turn.
+	MaxRepeats int
+
+	// Action defaults to LimitStop, which is also used for LimitAsk
+	// without Elicit.
+	Action LimitAction
+	Elicit *tool.Elicitation
+}
+
+// LimitError reports a turn stopped by a limit.
+type LimitError struct {
+	// Limit is "tool_rounds", "tokens", "cost" or "repeats".
+	Limit  string
+	Reason string
+}
+
+func (e *LimitError) Error() string {
+	return "turn stopped: " + e.Reason
+}
+
+// limitTrip is a limit reached by a turn. key identifies the repeated call
+// for repeat limits.
+type limitTrip struct {
+	limit  string
+	reason string
+	nudge  string
+	key    string
+}
+
+// turnGuard tracks a turn against its limits. A nil guard enforces nothing.
+type turnGuard struct {
+	limits *Limits
+
+	rounds int
+	tokens int64
+	cost   float64
+
+	maxRounds int
+	maxTokens int64
+	maxCost   float64
+
+	repeats map[string]int
+	nudged  map[string]bool
+
+	// nudges are messages for the model, added before the next request so
+	// they do not split tool calls from their results.
+	nudges []Message
+}
+
+func newTurnGuard(l *Limits) *turnGuard {
+	if l == nil {
+		return nil
+	}
+
+	return &turnGuard{
+		limits: l,
+
+		maxRounds: l.MaxToolRounds,
+		maxTokens: l.MaxTokens,
+		maxCost:   l.MaxCost,
+
+		repeats: make(map[string]int),
+		nudged:  make(map[string]bool),
+	}
+}
+
+// response accounts a model response and reports a reached token, cost or
+// round limit. Rounds count only responses that call tools.
+func (g *turnGuard) response(u Usage, toolCalls bool) *limitTrip {
+	if g == nil {
+		return nil
+	}
+
+	g.tokens += u.InputTokens + u.OutputTokens
+	g.cost += u.Cost
+
+	if g.maxTokens > 0 && g.tokens > g.maxTokens {
+		return &limitTrip{
+			limit:  "tokens",
+			reason: fmt.Sprintf("used %d tokens, over the limit of %d", g.tokens, g.maxTokens),
+			nudge:  "This task has used its token budget. Stop exploring and give your answer now with what you have.",
+		}
+	}
+
+	if g.maxCost > 0 && g.cost > g.maxCost {
+		return &limitTrip{
+			limit:  "cost",
+			reason: fmt.Sprintf("cost $%.2f, over the limit of $%.2f", g.cost, g.maxCost),
+			nudge:  "This task has used its cost budget. Stop exploring and give your answer now with what you have.",
+		}
+	}
+
+	if !toolCalls {
+		return nil
+	}
+
+	g.rounds++
+
+	if g.maxRounds > 0 && g.rounds > g.maxRounds {
+		return &limitTrip{
+			limit:  "tool_rounds",
+			reason: fmt.Sprintf("reached %d tool rounds", g.maxRounds),
+			nudge:  fmt.Sprintf("You have used %d rounds of tool calls on this task. Wrap up: finish with what you have, or explain what is blocking you.", g.maxRounds),
+		}
+	}
+
+	return nil
+}
+
+// results accounts the tool results of a round and reports a call repeated
+// more often than allowed.
+func (g *turnGuard) results(messages []Message) *limitTrip {
+	if g == nil || g.limits.MaxRepeats <= 0 {
+		return nil
+	}
+
+	for _, m := range messages {
+		for _, c := range m.Content {
+			r := c.ToolResult
+
+			if r == nil {
+				continue
+			}
+
+			failed := strings.HasPrefix(r.Content, "error: ")
+			key := r.Name + "\x00" + canonicalArgs(r.Args) + "\x00" + r.Content
+
+			g.repeats[key]++
+
+			if n := g.repeats[key]; n > g.limits.MaxRepeats {
+				trip := &limitTrip{
+					limit:  "repeats",
+					reason: fmt.Sprintf("%s was called %d times with the same arguments and result", r.Name, n),
+					nudge:  fmt.Sprintf("You called %s %d times with the same arguments and got the same result. Repeating it will not help; try a different approach or explain what is blocking you.", r.Name, n),
+					key:    key,
+				}
+
+				if failed {
+					trip.reason = fmt.Sprintf("%s failed %d times with the same error", r.Name, n)
+					trip.nudge = fmt.Sprintf("%s failed %d times with the same error. Repeating it will not help; try a different approach or explain what is blocking you.", r.Name, n)
+				}
+
+				return trip
+			}
+		}
+	}
+
+	return nil
+}
+
+// extend grants another allowance for the tripped limit.
+func (g *turnGuard) extend(t *limitTrip) {
+	switch t.limit {
+	case "tool_rounds":
+		g.maxRounds = g.rounds + g.limits.MaxToolRounds
+	case "tokens":
+		g.maxTokens = g.tokens + g.limits.MaxTokens
+	case "cost":
+		g.maxCost = g.cost + g.limits.MaxCost
+	case "repeats":
+		g.repeats[t.key] = 0
+	}
+}
+
+// takeNudges returns and clears the pending nudge messages.
+func (g *turnGuard) takeNudges() []Message {
+	if g == nil {
+		return nil
+	}
+
+	nudges := g.nudges
+	g.nudges = nil
+
+	return nudges
+}
+
+// enforce applies the configured action to a tripped limit. It returns an
+// error if the turn must stop.
+func (g *turnGuard) enforce(ctx context.Context, t *limitTrip) error {
+	if g == nil || t == nil {
+		return nil
+	}
+
+	stop := &LimitError{Limit: t.limit, Reason: t.reason}
+
+	switch g.limits.Action {
+	case LimitNudge:
+		if g.nudged[t.limit] {
+			return stop
+		}
+
+		g.nudged[t.limit] = true
+		g.extend(t)
+
+		g.nudges = append(g.nudges, Message{
+			Role:   RoleUser,
+			Hidden: true,
+
+			Content: []Content{{Text: t.nudge}},
+		})
+
+		return nil
+
+	case LimitAsk:
+		if g.limits.Elicit == nil || g.limits.Elicit.Confirm == nil {
+			return stop
+		}
+
+		ok, err := g.limits.Elicit.Confirm(ctx, fmt.Sprintf("Limit reached: %s. Continue?", t.reason))
+
+		if err != nil {
+			return fmt.Errorf("failed to confirm limit: %w", err)
+		}
+
+		if !ok {
+			return stop
+		}
+
+		g.extend(t)
+
+		return nil
+
+	default:
+		return stop
+	}
+}
+
+// canonicalArgs normalizes JSON arguments so that calls differing only in
+// key order or whitespace compare equal.
+func canonicalArgs(args string) string {
+	var v any
+
+	if err := json.Unmarshal([]byte(args), &v); err != nil {
+		return args
+	}
+
+	data, err := json.Marshal(v)
+
+	if err != nil {
+		return args
+	}
+
+	return string(data)
+}
@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
+)
+
+func toolReply(name, args string) []string {
+	return []string{
+		fmt.Sprintf(`{"id":"1","object":"chat.completion.chunk","model":"m","choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":%q,"arguments":%q}}]},"finish_reason":"tool_calls"}]}`, name, args),
+		`[DONE]`,
+	}
+}
+
+func limitsAgent(t *testing.T, limits *Limits, streams ...[]string) (*Agent, *stubServer) {
+	t.Helper()
+
+	stub := &stubServer{}
+
+	srv := httptest.NewServer(stub.handler(t, "/v1/chat/completions", streams...))
+	t.Cleanup(srv.Close)
+
+	cfg, err := NewConfig(&Options{Backend: BackendChat, BaseURL: srv.URL + "/v1", Token: "test"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Model = func() string { return "m" }
+	cfg.Tools = func() []tool.Tool { return []tool.Tool{echoTool()} }
+	cfg.Limits = limits
+
+	return &Agent{Config: cfg}, stub
+}
+
+func TestLimitsStopToolRounds(t *testing.T) {
+	a, stub := limitsAgent(t, &Limits{MaxToolRounds: 2},
+		toolReply("echo", `{"text":"a"}`),
+		toolReply("echo", `{"text":"b"}`),
+		toolReply("echo", `{"text":"c"}`),
+	)
+
+	_, err := sendText(t, a)
+
+	var limit *LimitError
+
+	if !errors.As(err, &limit) || limit.Limit != "tool_rounds" {
+		t.Fatalf("expected tool round limit, got %v", err)
+	}
+
+	if len(stub.requests) != 3 {
+		t.Errorf("expected 3 requests, got %d", len(stub.requests))
+	}
+
+	var results int
+
+	for _, m := range a.Messages {
+		for _, c := range m.Content {
+			if c.ToolResult != nil {
+				results++
+			}
+		}
+	}
+
+	if results != 2 {
+		t.Errorf("expected the third round not to run, got %d results", results)
+	}
+}
+
+func TestLimitsNudgeRepeatedCalls(t *testing.T) {
+	a, stub := limitsAgent(t, &Limits{MaxRepeats: 2, Action: LimitNudge},
+		toolReply("echo", `{"text":"same"}`),
+		toolReply("echo", `{ "text": "same" }`),
+		toolReply("echo", `{"text":"same"}`),
+		textReply("giving up"),
+	)
+
+	text, err := sendText(t, a)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if text != "giving up" {
+		t.Errorf("unexpected answer %q", text)
+	}
+
+	messages := stub.requests[3]["messages"].([]any)
+	last := messages[len(messages)-1].(map[string]any)
+
+	if last["role"] != "user" || !strings.Contains(fmt.Sprint(last["content"]), "echo 3 times") {
+		t.Errorf("expected a nudge before the next request, got %v", last)
+	}
+}
+
+func TestLimitsAsk(t *testing.T) {
+	var questions []string
+
+	limits := &Limits{
+		MaxToolRounds: 1,
+		Action:        LimitAsk,
+		Elicit: &tool.Elicitation{
+			Confirm: func(ctx context.Context, message string) (bool, error) {
+				questions = append(questions, message)
+				return len(questions) == 1, nil
+			},
+		},
+	}
+
+	a, stub := limitsAgent(t, limits,
+		toolReply("echo", `{"text":"a"}`),
+		toolReply("echo", `{"text":"b"}`),
+		toolReply("echo", `{"text":"c"}`),
+		toolReply("echo", `{"text":"d"}`),
+	)
+
+	_, err := sendText(t, a)
+
+	var limit *LimitError
+
+	if !errors.As(err, &limit) {
+		t.Fatalf("expected limit error, got %v", err)
+	}
+
+	if len(questions) != 2 || !strings.Contains(questions[0], "1 tool rounds") {
+		t.Errorf("unexpected questions %q", questions)
+	}
+
+	// Approving grants another round on top of the ones used.
+	if len(stub.requests) != 4 {
+		t.Errorf("expected the turn to continue once, got %d requests", len(stub.requests))
+	}
+}
+
+func TestLimitsCost(t *testing.T) {
+	g := newTurnGuard(&Limits{MaxCost: 1})
+	caps := Capabilities{InputCost: 3, CachedInputCost: 0.3, OutputCost: 15}
+
+	usage := Usage{InputTokens: 200000, CachedTokens: 100000, OutputTokens: 10000}
+	usage.Cost = caps.Cost(usage)
+
+	if trip := g.response(usage, true); trip != nil {
+		t.Fatalf("unexpected trip at $%.2f", g.cost)
+	}
+
+	usage = Usage{InputTokens: 200000, OutputTokens: 10000}
+	usage.Cost = caps.Cost(usage)
+
+	if trip := g.response(usage, true); trip == nil || trip.limit != "cost" {
+		t.Fatalf("expected cost limit at $%.2f, got %+v", g.cost, trip)
+	}
+}
@@ -2,6 +2,11 @@ package agent
 
 type ModelInfo struct {
 	ID string
+
+	Capabilities
+
+	// metadata holds the capabilities the API reported for the model.
+	metadata CapabilityOverride
 }
 
 type MessageRole string
@@ -41,6 +46,26 @@ type Usage struct {
 	InputTokens  int64 `json:"input_tokens"`
 	CachedTokens int64 `json:"cached_tokens"`
 	OutputTokens int64 `json:"output_tokens"`
+
+	// Cost is the price in USD, counting only responses from models with
+	// known prices.
+	Cost float64 `json:"cost,omitempty"`
+}
+
+// CacheHitRatio is the share of input tokens read from the prompt cache.
+func (u Usage) CacheHitRatio() float64 {
+	if u.InputTokens <= 0 {
+		return 0
+	}
+
+	return float64(u.CachedTokens) / float64(u.InputTokens)
+}
+
+func (u *Usage) add(o Usage) {
+	u.InputTokens += o.InputTokens
+	u.CachedTokens += o.CachedTokens
+	u.OutputTokens += o.OutputTokens
+	u.Cost += o.Cost
 }
 
 type ToolCall struct {
@@ -57,6 +82,7 @@ type ToolResult struct {
 	Args string `json:"args,omitempty"`
 
 	Content string `json:"content,omitempty"`
+	Files   []File `json:"files,omitempty"`
 }
 
 type Reasoning struct {
@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// maxOutputRetries is how often the model is asked to correct a final
+// answer that does not match the output schema.
+const maxOutputRetries = 2
+
+// OutputSchema constrains the final answer of a turn to JSON matching a
+// JSON Schema. Tool calls are unaffected.
+type OutputSchema struct {
+	// Name identifies the schema to the API; it defaults to "output".
+	Name string
+
+	Schema map[string]any
+
+	// Strict asks the API to enforce the schema while generating. It
+	// supports only a subset of JSON Schema; answers are validated either
+	// way.
+	Strict bool
+}
+
+func (o *OutputSchema) name() string {
+	if o.Name == "" {
+		return "output"
+	}
+
+	return o.Name
+}
+
+// instructions describes the schema for backends without native structured
+// output.
+func (o *OutputSchema) instructions() string {
+	data, _ := json.Marshal(o.Schema)
+
+	return "Your final answer must be a single JSON value matching this JSON Schema, with no other text:\n" + string(data)
+}
+
+func (o *OutputSchema) resolve() (*jsonschema.Resolved, error) {
+	data, err := json.Marshal(o.Schema)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode output schema: %w", err)
+	}
+
+	var s jsonschema.Schema
+
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid output schema: %w", err)
+	}
+
+	resolved, err := s.Resolve(nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid output schema: %w", err)
+	}
+
+	return resolved, nil
+}
+
+// parse extracts the JSON answer from text, tolerating a surrounding code
+// fence, and validates it against the schema.
+func (o *OutputSchema) parse(resolved *jsonschema.Resolved, text string) (json.RawMessage, error) {
+	text = strings.TrimSpace(text)
+
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+		text = strings.TrimSpace(text)
+	}
+
+	var value any
+
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return nil, fmt.Errorf("answer is not valid JSON: %w", err)
+	}
+
+	if err := resolved.Validate(value); err != nil {
+		return nil, err
+	}
+
+	return json.RawMessage(text), nil
+}
+
+// outputCorrection asks the model to fix an answer that failed validation.
+func outputCorrection(err error) Message {
+	return Message{
+		Role:   RoleUser,
+		Hidden: true,
+
+		Content: []Content{{Text: fmt.Sprintf("Your answer does not match the required JSON Schema: %v\nReply again with only the corrected JSON.", err)}},
+	}
+}
+
+// Output returns the validated JSON answer of the last turn when an
+// OutputSchema was set.
+func (a *Agent) Output() json.RawMessage {
+	return a.output
+}
+
+// Structured runs a turn whose final answer must match the JSON Schema
+// inferred from T and decodes it.
+func Structured[T any](ctx context.Context, a *Agent, input []Content) (T, error) {
+	var result T
+
+	s, err := jsonschema.For[T](nil)
+
+	if err != nil {
+		return result, fmt.Errorf("failed to infer output schema: %w", err)
+	}
+
+	data, err := json.Marshal(s)
+
+	if err != nil {
+		return result, fmt.Errorf("failed to encode output schema: %w", err)
+	}
+
+	var schema map[string]any
+
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return result, fmt.Errorf("failed to encode output schema: %w", err)
+	}
+
+	previous := a.OutputSchema
+	a.OutputSchema = &OutputSchema{Schema: schema}
+	defer func() { a.OutputSchema = previous }()
+
+	a.output = nil
+
+	for _, err := range a.Send(ctx, input) {
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if len(a.output) == 0 {
+		return result, errors.New("agent finished without structured output")
+	}
+
+	if err := json.Unmarshal(a.output, &result); err != nil {
+		return result, fmt.Errorf("failed to decode output: %w", err)
+	}
+
+	return result, nil
+}
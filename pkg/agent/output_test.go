@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func textReply(text string) []string {
+	return []string{
+		fmt.Sprintf(`{"id":"1","object":"chat.completion.chunk","model":"m","choices":[{"index":0,"delta":{"role":"assistant","content":%q},"finish_reason":"stop"}]}`, text),
+		`[DONE]`,
+	}
+}
+
+func TestStructuredOutput(t *testing.T) {
+	type result struct {
+		Files []string `json:"files"`
+		Count int      `json:"count"`
+	}
+
+	stub := &stubServer{}
+
+	srv := httptest.NewServer(stub.handler(t, "/v1/chat/completions",
+		textReply(`{"files":["a.go"],"count":"one"}`),
+		textReply("```json\n{\"files\":[\"a.go\"],\"count\":1}\n```"),
+	))
+	defer srv.Close()
+
+	cfg, err := NewConfig(&Options{Backend: BackendChat, BaseURL: srv.URL + "/v1", Token: "test"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Model = func() string { return "m" }
+
+	a := &Agent{Config: cfg}
+
+	got, err := Structured[result](t.Context(), a, []Content{{Text: "list files"}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Count != 1 || len(got.Files) != 1 || got.Files[0] != "a.go" {
+		t.Errorf("unexpected result %+v", got)
+	}
+
+	if len(stub.requests) != 2 {
+		t.Fatalf("expected a correction request, got %d requests", len(stub.requests))
+	}
+
+	format, _ := stub.requests[0]["response_format"].(map[string]any)
+
+	if format["type"] != "json_schema" {
+		t.Errorf("expected json_schema response format, got %v", stub.requests[0]["response_format"])
+	}
+
+	last := a.Messages[len(a.Messages)-2]
+
+	if !last.Hidden || !strings.Contains(last.Content[0].Text, "count") {
+		t.Errorf("expected hidden correction naming the invalid field, got %+v", last)
+	}
+
+	if a.OutputSchema != nil {
+		t.Error("expected output schema to be restored")
+	}
+}
+
+func TestStructuredOutputRetriesExhausted(t *testing.T) {
+	stub := &stubServer{}
+
+	srv := httptest.NewServer(stub.handler(t, "/v1/chat/completions",
+		textReply("not json"),
+		textReply("still not json"),
+		textReply("never json"),
+	))
+	defer srv.Close()
+
+	cfg, err := NewConfig(&Options{Backend: BackendChat, BaseURL: srv.URL + "/v1", Token: "test"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Model = func() string { return "m" }
+	cfg.OutputSchema = &OutputSchema{Schema: map[string]any{"type": "object"}}
+
+	a := &Agent{Config: cfg}
+
+	if _, err := sendText(t, a); err == nil || !strings.Contains(err.Error(), "output schema") {
+		t.Fatalf("expected schema error, got %v", err)
+	}
+
+	if a.Output() != nil {
+		t.Errorf("expected no output, got %s", a.Output())
+	}
+
+	if len(stub.requests) != 1+maxOutputRetries {
+		t.Errorf("expected %d requests, got %d", 1+maxOutputRetries, len(stub.requests))
+	}
+}
@@ -0,0 +1,403 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
+)
+
+// stubServer answers successive model requests with the given SSE bodies
+// and records the decoded request bodies.
+type stubServer struct {
+	mu       sync.Mutex
+	requests []map[string]any
+}
+
+func (s *stubServer) handler(t *testing.T, path string, streams ...[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+
+		s.mu.Lock()
+		n := len(s.requests)
+		s.requests = append(s.requests, body)
+		s.mu.Unlock()
+
+		if n >= len(streams) {
+			t.Errorf("unexpected request %d", n+1)
+			http.Error(w, "unexpected", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+
+		for _, event := range streams[n] {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+	}
+}
+
+func echoTool() tool.Tool {
+	return tool.Tool{
+		Name:       "echo",
+		Parameters: map[string]any{"type": "object", "properties": map[string]any{"text": map[string]any{"type": "string"}}},
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
+			return tool.Text(fmt.Sprint(args["text"])), nil
+		},
+	}
+}
+
+func runTurn(t *testing.T, cfg *Config) (*Agent, string) {
+	t.Helper()
+
+	cfg.Instructions = func() string { return "be brief" }
+	cfg.Tools = func() []tool.Tool { return []tool.Tool{echoTool()} }
+
+	a := &Agent{Config: cfg}
+
+	var text strings.Builder
+
+	for msg, err := range a.Send(context.Background(), []Content{{Text: "hi"}}) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, c := range msg.Content {
+			text.WriteString(c.Text)
+		}
+	}
+
+	return a, text.String()
+}
+
+func TestChatProvider(t *testing.T) {
+	stub := &stubServer{}
+
+	srv := httptest.NewServer(stub.handler(t, "/v1/chat/completions",
+		[]string{
+			`{"id":"1","object":"chat.completion.chunk","model":"m","choices":[{"index":0,"delta":{"role":"assistant","reasoning_content":"thinking"}}]}`,
+			`{"id":"1","object":"chat.completion.chunk","model":"m","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"echo","arguments":"{\"text\":"}}]}}]}`,
+			`{"id":"1","object":"chat.completion.chunk","model":"m","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"pong\"}"}}]},"finish_reason":"tool_calls"}]}`,
+			`{"id":"1","object":"chat.completion.chunk","model":"m","choices":[],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`,
+			`[DONE]`,
+		},
+		[]string{
+			`{"id":"2","object":"chat.completion.chunk","model":"m","choices":[{"index":0,"delta":{"role":"assistant","content":"Hello"}}]}`,
+			`{"id":"2","object":"chat.completion.chunk","model":"m","choices":[{"index":0,"delta":{"content":" there"},"finish_reason":"stop"}]}`,
+			`{"id":"2","object":"chat.completion.chunk","model":"m","choices":[],"usage":{"prompt_tokens":20,"completion_tokens":2,"total_tokens":22,"prompt_tokens_details":{"cached_tokens":8}}}`,
+			`[DONE]`,
+		},
+	))
+	defer srv.Close()
+
+	cfg, err := NewConfig(&Options{Backend: BackendChat, BaseURL: srv.URL + "/v1", Token: "test"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, text := runTurn(t, cfg)
+
+	if text != "Hello there" {
+		t.Errorf("expected streamed text, got %q", text)
+	}
+
+	if a.Usage.InputTokens != 30 || a.Usage.CachedTokens != 8 || a.Usage.OutputTokens != 7 {
+		t.Errorf("unexpected usage: %+v", a.Usage)
+	}
+
+	if len(stub.requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(stub.requests))
+	}
+
+	messages, _ := json.Marshal(stub.requests[1]["messages"])
+
+	for _, want := range []string{
+		`{"content":"be brief","role":"system"}`,
+		`"tool_calls":[{"function":{"arguments":"{\"text\":\"pong\"}","name":"echo"},"id":"call_1","type":"function"}]`,
+		`{"content":"pong","role":"tool","tool_call_id":"call_1"}`,
+	} {
+		if !strings.Contains(string(messages), want) {
+			t.Errorf("expected %s in follow-up messages, got %s", want, messages)
+		}
+	}
+}
+
+func TestAnthropicProvider(t *testing.T) {
+	stub := &stubServer{}
+
+	srv := httptest.NewServer(stub.handler(t, "/v1/messages",
+		[]string{
+			`{"type":"message_start","message":{"usage":{"input_tokens":10,"cache_read_input_tokens":4,"output_tokens":1}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"let me see"}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"echo","input":{}}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"text\":\"pong\"}"}}`,
+			`{"type":"content_block_stop","index":1}`,
+			`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":12}}`,
+			`{"type":"message_stop"}`,
+		},
+		[]string{
+			`{"type":"message_start","message":{"usage":{"input_tokens":30,"output_tokens":1}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Done"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":3}}`,
+			`{"type":"message_stop"}`,
+		},
+	))
+	defer srv.Close()
+
+	cfg, err := NewConfig(&Options{Backend: BackendAnthropic, BaseURL: srv.URL + "/v1", Token: "test"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Effort = func() string { return "low" }
+
+	a, text := runTurn(t, cfg)
+
+	if text != "Done" {
+		t.Errorf("expected streamed text, got %q", text)
+	}
+
+	if a.Usage.InputTokens != 44 || a.Usage.CachedTokens != 4 || a.Usage.OutputTokens != 15 {
+		t.Errorf("unexpected usage: %+v", a.Usage)
+	}
+
+	if len(stub.requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(stub.requests))
+	}
+
+	first := stub.requests[0]
+
+	if thinking, _ := first["thinking"].(map[string]any); thinking["budget_tokens"] != float64(2000) {
+		t.Errorf("expected thinking budget for low effort, got %v", first["thinking"])
+	}
+
+	messages, _ := json.Marshal(stub.requests[1]["messages"])
+
+	for _, want := range []string{
+		`{"content":[{"signature":"sig","thinking":"let me see","type":"thinking"},{"id":"toolu_1","input":{"text":"pong"},"name":"echo","type":"tool_use"}],"role":"assistant"}`,
+		`{"cache_control":{"type":"ephemeral"},"content":[{"text":"pong","type":"text"}],"tool_use_id":"toolu_1","type":"tool_result"}`,
+	} {
+		if !strings.Contains(string(messages), want) {
+			t.Errorf("expected %s in follow-up messages, got %s", want, messages)
+		}
+	}
+}
+
+func TestAnthropicError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+	}))
+	defer srv.Close()
+
+	cfg, _ := NewConfig(&Options{Backend: BackendAnthropic, BaseURL: srv.URL + "/v1", Token: "bad"})
+
+	_, err := cfg.provider.complete(context.Background(), &request{model: "m"}, func(Message, error) bool { return true })
+
+	if err == nil || !strings.Contains(err.Error(), "invalid x-api-key") {
+		t.Fatalf("expected API error, got %v", err)
+	}
+
+	if isRecoverableError(err) {
+		t.Error("authentication errors should not be recoverable")
+	}
+}
+
+func clearEndpointEnv(t *testing.T) {
+	for _, key := range []string{"WINGMAN_URL", "WINGMAN_TOKEN", "WINGMAN_API", "OPENAI_API_KEY", "OPENAI_BASE_URL", "ANTHROPIC_API_KEY", "ANTHROPIC_BASE_URL"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+}
+
+func TestResolveEndpoint(t *testing.T) {
+	t.Run("anthropic key selects anthropic", func(t *testing.T) {
+		clearEndpointEnv(t)
+		t.Setenv("ANTHROPIC_API_KEY", "sk-ant")
+
+		backend, baseURL, token := resolveEndpoint(&Options{})
+
+		if backend != BackendAnthropic || baseURL != "https://api.anthropic.com/v1" || token != "sk-ant" {
+			t.Errorf("got %s %s %s", backend, baseURL, token)
+		}
+	})
+
+	t.Run("wingman url with chat api", func(t *testing.T) {
+		clearEndpointEnv(t)
+		t.Setenv("WINGMAN_URL", "http://gateway/")
+		t.Setenv("WINGMAN_API", "chat")
+
+		backend, baseURL, token := resolveEndpoint(&Options{})
+
+		if backend != BackendChat || baseURL != "http://gateway/v1" || token != "-" {
+			t.Errorf("got %s %s %s", backend, baseURL, token)
+		}
+	})
+
+	t.Run("unknown backend", func(t *testing.T) {
+		clearEndpointEnv(t)
+
+		if _, err := NewConfig(&Options{Backend: "soap"}); err == nil {
+			t.Error("expected error for unknown backend")
+		}
+	})
+}
+
+func TestPromptCaching(t *testing.T) {
+	stub := &stubServer{}
+
+	srv := httptest.NewServer(stub.handler(t, "/v1/chat/completions",
+		[]string{
+			`{"id":"1","object":"chat.completion.chunk","model":"m","choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"echo","arguments":"{\"text\":\"pong\"}"}}]},"finish_reason":"tool_calls"}]}`,
+			`{"id":"1","object":"chat.completion.chunk","model":"m","choices":[],"usage":{"prompt_tokens":100,"completion_tokens":10,"prompt_tokens_details":{"cached_tokens":0}}}`,
+			`[DONE]`,
+		},
+		[]string{
+			`{"id":"2","object":"chat.completion.chunk","model":"m","choices":[{"index":0,"delta":{"role":"assistant","content":"done"},"finish_reason":"stop"}]}`,
+			`{"id":"2","object":"chat.completion.chunk","model":"m","choices":[],"usage":{"prompt_tokens":100,"completion_tokens":5,"prompt_tokens_details":{"cached_tokens":100}}}`,
+			`[DONE]`,
+		},
+	))
+	defer srv.Close()
+
+	cfg, err := NewConfig(&Options{Backend: BackendChat, BaseURL: srv.URL + "/v1", Token: "test"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+
+	cfg.Model = func() string { return "m" }
+	cfg.Tools = func() []tool.Tool { return []tool.Tool{echoTool()} }
+	cfg.CacheKey = func() string { return "session-1" }
+	cfg.Instructions = func() string {
+		calls++
+		return fmt.Sprintf("instructions %d", calls)
+	}
+
+	a := &Agent{Config: cfg}
+
+	if _, err := sendText(t, a); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 1 {
+		t.Errorf("expected instructions to be built once per turn, got %d", calls)
+	}
+
+	for i, req := range stub.requests {
+		if req["prompt_cache_key"] != "session-1" {
+			t.Errorf("request %d: expected prompt_cache_key, got %v", i, req["prompt_cache_key"])
+		}
+
+		if system := req["messages"].([]any)[0].(map[string]any); system["content"] != "instructions 1" {
+			t.Errorf("request %d: expected stable instructions, got %v", i, system["content"])
+		}
+	}
+
+	if a.TurnUsage.InputTokens != 200 || a.TurnUsage.CachedTokens != 100 {
+		t.Errorf("unexpected turn usage %+v", a.TurnUsage)
+	}
+
+	if ratio := a.TurnUsage.CacheHitRatio(); ratio != 0.5 {
+		t.Errorf("expected cache hit ratio 0.5, got %v", ratio)
+	}
+}
+
+func TestSteering(t *testing.T) {
+	stub := &stubServer{}
+
+	srv := httptest.NewServer(stub.handler(t, "/v1/chat/completions",
+		[]string{
+			`{"id":"1","object":"chat.completion.chunk","model":"m","choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"steer","arguments":"{}"}}]},"finish_reason":"tool_calls"}]}`,
+			`[DONE]`,
+		},
+		[]string{
+			`{"id":"2","object":"chat.completion.chunk","model":"m","choices":[{"index":0,"delta":{"role":"assistant","content":"first"},"finish_reason":"stop"}]}`,
+			`[DONE]`,
+		},
+		[]string{
+			`{"id":"3","object":"chat.completion.chunk","model":"m","choices":[{"index":0,"delta":{"role":"assistant","content":"second"},"finish_reason":"stop"}]}`,
+			`[DONE]`,
+		},
+	))
+	defer srv.Close()
+
+	cfg, err := NewConfig(&Options{Backend: BackendChat, BaseURL: srv.URL + "/v1", Token: "test"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := &Agent{Config: cfg}
+
+	cfg.Model = func() string { return "m" }
+	cfg.Tools = func() []tool.Tool {
+		return []tool.Tool{{
+			Name: "steer",
+			Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
+				a.Steer([]Content{{Text: "use tabs"}})
+				return tool.Text("ok"), nil
+			},
+		}}
+	}
+
+	var steered []string
+
+	for msg, err := range a.Send(t.Context(), []Content{{Text: "format it"}}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if msg.Role == RoleUser {
+			steered = append(steered, msg.Content[0].Text)
+			continue
+		}
+
+		if len(msg.Content) > 0 && msg.Content[0].Text == "first" {
+			a.Steer([]Content{{Text: "also update docs"}})
+		}
+	}
+
+	if strings.Join(steered, ",") != "use tabs,also update docs" {
+		t.Errorf("unexpected steered messages %v", steered)
+	}
+
+	if len(stub.requests) != 3 {
+		t.Fatalf("expected the turn to continue after steering, got %d requests", len(stub.requests))
+	}
+
+	for i, want := range map[int]string{1: "use tabs", 2: "also update docs"} {
+		messages := stub.requests[i]["messages"].([]any)
+		last := messages[len(messages)-1].(map[string]any)
+
+		if last["role"] != "user" || !strings.Contains(fmt.Sprint(last["content"]), want) {
+			t.Errorf("request %d: expected steered message last, got %v", i, last)
+		}
+	}
+
+	if leftover := a.TakeSteering(); leftover != nil {
+		t.Errorf("expected no leftover steering, got %v", leftover)
+	}
+}
@@ -4,19 +4,37 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"strings"
+	"syscall"
 
 	"github.com/openai/openai-go/v3"
-	"github.com/openai/openai-go/v3/responses"
 )
 
-func isRecoverableError(err error) bool {
+// apiStatus returns the HTTP status of an API error.
+func apiStatus(err error) (int, bool) {
 	var apiErr *openai.Error
-	if !errors.As(err, &apiErr) {
+	var anthropicErr *anthropicError
+
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.StatusCode, true
+	case errors.As(err, &anthropicErr):
+		return anthropicErr.StatusCode, true
+	default:
+		return 0, false
+	}
+}
+
+func isRecoverableError(err error) bool {
+	status, ok := apiStatus(err)
+
+	if !ok {
 		return true
 	}
 
-	switch apiErr.StatusCode {
+	switch status {
 	case 401, 403:
 		return false
 	default:
@@ -24,6 +42,27 @@ func isRecoverableError(err error) bool {
 	}
 }
 
+// isTransientError reports errors worth retrying with the same request:
+// network failures, rate limits and server errors. Anything else, such as a
+// response that fails to parse, would fail the same way again.
+func isTransientError(err error) bool {
+	if status, ok := apiStatus(err); ok {
+		return status == 408 || status == 409 || status == 429 || status >= 500
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// isOverloadedError reports errors meaning the model cannot take requests
+// right now, where waiting is less useful than switching models.
+func isOverloadedError(err error) bool {
+	status, _ := apiStatus(err)
+
+	return status == 429 || status == 503 || status == 529
+}
+
 func (a *Agent) removeOrphanedToolMessages() {
 
 	callIDs := make(map[string]bool)
@@ -86,6 +125,8 @@ func (a *Agent) removeOrphanedToolMessages() {
 }
 
 func (a *Agent) compactMessages(ctx context.Context) {
+	a.contextTokens = 0
+
 	summary, err := a.summarizeMessages(ctx)
 	if err != nil || summary == "" {
 		a.removeAllToolMessages()
@@ -132,24 +173,15 @@ func (a *Agent) summarizeMessages(ctx context.Context) (string, error) {
 		return "", nil
 	}
 
-	model := ""
-	if a.Config.Model != nil {
-		model = a.Model()
-	}
+	model := a.roleModel(a.SummaryModel)
 
-	resp, err := a.client.Responses.New(ctx, responses.ResponseNewParams{
-		Model: model,
-		Instructions: openai.String(
-			"Summarize the following conversation between a user and an AI assistant. " +
-				"Preserve all important context: what the user asked, what was done, what files were modified, " +
-				"key decisions made, and the current state of the task. " +
-				"Be concise but complete. Format as a briefing the assistant can use to continue the conversation.",
-		),
-		Input: responses.ResponseNewParamsInputUnion{
-			OfString: openai.String(sb.String()),
-		},
-		Store: openai.Bool(false),
-	})
+	resp, _, err := a.complete(ctx, &request{
+		instructions: "Summarize the following conversation between a user and an AI assistant. " +
+			"Preserve all important context: what the user asked, what was done, what files were modified, " +
+			"key decisions made, and the current state of the task. " +
+			"Be concise but complete. Format as a briefing the assistant can use to continue the conversation.",
+		messages: []Message{userMessage([]Content{{Text: sb.String()}})},
+	}, "", a.modelChain(model), 0, func(Message, error) bool { return true })
 
 	if err != nil {
 		return "", err
@@ -158,13 +190,9 @@ func (a *Agent) summarizeMessages(ctx context.Context) (string, error) {
 	var result strings.Builder
 	result.WriteString("[Previous conversation summary]\n\n")
 
-	for _, item := range resp.Output {
-		msg := item.AsMessage()
-		for _, part := range msg.Content {
-			text := part.AsOutputText()
-			if text.Text != "" {
-				result.WriteString(text.Text)
-			}
+	for _, m := range resp.messages {
+		for _, c := range m.Content {
+			result.WriteString(c.Text)
 		}
 	}
 
@@ -0,0 +1,47 @@
+package agent
+
+// Steer queues input for the running turn, e.g. a follow-up the user typed
+// while the agent works. Send adds it to the conversation before its next
+// model request, once pending tool results are in, and yields it as a user
+// message. If the model was about to finish, the turn continues. It is safe
+// to call from other goroutines.
+func (a *Agent) Steer(input []Content) {
+	a.steerMu.Lock()
+	defer a.steerMu.Unlock()
+
+	a.steering = append(a.steering, input)
+}
+
+// TakeSteering removes and returns input queued with Steer that no turn
+// picked up, e.g. because the turn was cancelled.
+func (a *Agent) TakeSteering() [][]Content {
+	a.steerMu.Lock()
+	defer a.steerMu.Unlock()
+
+	steering := a.steering
+	a.steering = nil
+
+	return steering
+}
+
+func (a *Agent) hasSteering() bool {
+	a.steerMu.Lock()
+	defer a.steerMu.Unlock()
+
+	return len(a.steering) > 0
+}
+
+// injectSteering appends the queued input to the conversation and reports
+// each message to yield.
+func (a *Agent) injectSteering(yield func(Message, error) bool) bool {
+	for _, input := range a.TakeSteering() {
+		m := userMessage(input)
+		a.Messages = append(a.Messages, m)
+
+		if !yield(m, nil) {
+			return false
+		}
+	}
+
+	return true
+}
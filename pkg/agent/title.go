@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"context"
+	"strings"
+)
+
+const titleInstructions = "Write a short title of at most six words for a conversation that starts with the message below. " +
+	"Reply with the title only, without quotes or trailing punctuation."
+
+// Title generates a short title for a conversation using TitleModel. It
+// returns "" when no title model is configured or the conversation has no
+// user message yet.
+func (c *Config) Title(ctx context.Context, messages []Message) (string, error) {
+	if c.TitleModel == nil || c.TitleModel() == "" {
+		return "", nil
+	}
+
+	var text string
+
+	for _, m := range messages {
+		if m.Role != RoleUser || m.Hidden {
+			continue
+		}
+
+		for _, c := range m.Content {
+			text += c.Text
+		}
+
+		if text != "" {
+			break
+		}
+	}
+
+	if text == "" {
+		return "", nil
+	}
+
+	resp, _, err := c.complete(ctx, &request{
+		instructions: titleInstructions,
+		messages:     []Message{userMessage([]Content{{Text: truncate(text, 2000)}})},
+	}, "", c.modelChain(c.TitleModel()), 0, func(Message, error) bool { return true })
+
+	if err != nil {
+		return "", err
+	}
+
+	var title strings.Builder
+
+	for _, m := range resp.messages {
+		for _, c := range m.Content {
+			title.WriteString(c.Text)
+		}
+	}
+
+	return cleanTitle(title.String()), nil
+}
+
+func cleanTitle(s string) string {
+	s = strings.TrimSpace(s)
+
+	if i := strings.IndexAny(s, "\n\r"); i >= 0 {
+		s = s[:i]
+	}
+
+	s = strings.Trim(s, "\"'`*#. ")
+
+	if r := []rune(s); len(r) > 80 {
+		s = string(r[:77]) + "..."
+	}
+
+	return s
+}
@@ -8,6 +8,10 @@ import (
 	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
 )
 
+type askArgs struct {
+	Question string `json:"question" jsonschema:"The question to ask the user. Be specific and concise."`
+}
+
 func Tools(elicit *tool.Elicitation) []tool.Tool {
 	if elicit == nil || elicit.Ask == nil {
 		return nil
@@ -28,27 +32,26 @@ func Tools(elicit *tool.Elicitation) []tool.Tool {
 		Description: description,
 		Effect:      tool.StaticEffect(tool.EffectReadOnly),
 
-		Parameters: map[string]any{
-			"type": "object",
+		Parameters: tool.Schema[askArgs](),
 
-			"properties": map[string]any{
-				"question": map[string]any{
-					"type":        "string",
-					"description": "The question to ask the user. Be specific and concise.",
-				},
-			},
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
+			a, err := tool.Decode[askArgs](args)
 
-			"required": []string{"question"},
-		},
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			if a.Question == "" {
+				return tool.Result{}, fmt.Errorf("question is required")
+			}
 
-		Execute: func(ctx context.Context, args map[string]any) (string, error) {
-			question, ok := args["question"].(string)
+			answer, err := elicit.Ask(ctx, a.Question)
 
-			if !ok || question == "" {
-				return "", fmt.Errorf("question is required")
+			if err != nil {
+				return tool.Result{}, err
 			}
 
-			return elicit.Ask(ctx, question)
+			return tool.Text(answer), nil
 		},
 
 		Hidden: true,
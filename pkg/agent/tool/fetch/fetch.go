@@ -16,6 +16,10 @@ import (
 
 const maxFetchBytes = 100 * 1024 // 100KB max content
 
+type fetchArgs struct {
+	URL string `json:"url" jsonschema:"The URL to fetch content from"`
+}
+
 func Tools() []tool.Tool {
 	description := strings.Join([]string{
 		"Fetch content from a URL and return it as text. HTML pages are converted to readable text.",
@@ -33,33 +37,32 @@ func Tools() []tool.Tool {
 		Description: description,
 		Effect:      tool.StaticEffect(tool.EffectReadOnly),
 
-		Parameters: map[string]any{
-			"type": "object",
+		Parameters: tool.Schema[fetchArgs](),
 
-			"properties": map[string]any{
-				"url": map[string]any{
-					"type":        "string",
-					"description": "The URL to fetch content from",
-				},
-			},
-
-			"required": []string{"url"},
-		},
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
+			a, err := tool.Decode[fetchArgs](args)
 
-		Execute: func(ctx context.Context, args map[string]any) (string, error) {
-			urlStr, ok := args["url"].(string)
+			if err != nil {
+				return tool.Result{}, err
+			}
 
-			if !ok || urlStr == "" {
-				return "", fmt.Errorf("url is required")
+			if a.URL == "" {
+				return tool.Result{}, fmt.Errorf("url is required")
 			}
 
 			wingmanURL := os.Getenv("WINGMAN_URL")
 
 			if wingmanURL == "" {
-				return "", fmt.Errorf("fetch is not available: WINGMAN_URL is not configured")
+				return tool.Result{}, fmt.Errorf("fetch is not available: WINGMAN_URL is not configured")
+			}
+
+			text, err := extractWingman(ctx, wingmanURL, os.Getenv("WINGMAN_TOKEN"), a.URL)
+
+			if err != nil {
+				return tool.Result{}, err
 			}
 
-			return extractWingman(ctx, wingmanURL, os.Getenv("WINGMAN_TOKEN"), urlStr)
+			return tool.Text(text), nil
 		},
 	}}
 }
@@ -8,13 +8,15 @@ import (
 
 // Tools returns the standard fs tools. allowedReadRoots are absolute paths
 // outside the workspace that the read tool is permitted to access (e.g. the
-// user's personal skill directories). Write, edit, find, ls, and grep stay
-// strictly sandboxed to the workspace.
+// user's personal skill directories). Write, edit, apply_patch,
+// notebook_edit, ls, find and grep stay strictly sandboxed to the workspace.
 func Tools(root *os.Root, allowedReadRoots ...string) []tool.Tool {
 	return []tool.Tool{
 		ReadTool(root, allowedReadRoots...),
 		WriteTool(root),
 		EditTool(root),
+		PatchTool(root),
+		NotebookEditTool(root),
 		LsTool(root),
 		FindTool(root),
 		GrepTool(root),
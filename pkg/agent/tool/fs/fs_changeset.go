@@ -0,0 +1,199 @@
+package fs
+
+import (
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FileChange is the planned state of one file in a Changeset.
+type FileChange struct {
+	Original string
+	Content  string
+
+	Existed bool
+	Deleted bool
+	Mode    iofs.FileMode
+
+	bom    string
+	ending string
+}
+
+// Changed reports whether committing writes or deletes the file.
+func (f *FileChange) Changed() bool {
+	if f.Deleted {
+		return f.Existed
+	}
+
+	return !f.Existed || f.Content != f.Original
+}
+
+// Changeset stages changes to files under a root so they can be validated
+// together before anything touches the disk. Commit writes them all or, if
+// a write fails, restores the files already written.
+type Changeset struct {
+	root *os.Root
+
+	// normalize strips byte order marks and converts line endings to LF on
+	// load; both are restored on write.
+	normalize bool
+
+	files map[string]*FileChange
+	order []string
+}
+
+// NewChangeset returns an empty changeset for files under root.
+func NewChangeset(root *os.Root) *Changeset {
+	return &Changeset{
+		root:  root,
+		files: make(map[string]*FileChange),
+	}
+}
+
+// Paths returns the paths loaded so far, in load order.
+func (c *Changeset) Paths() []string {
+	return c.order
+}
+
+// File returns the planned state of a loaded path, or nil.
+func (c *Changeset) File(path string) *FileChange {
+	return c.files[path]
+}
+
+// Load returns the planned state of a path relative to the root, reading it
+// on first use. Missing files are an error unless allowMissing is set; they
+// are then returned as deleted.
+func (c *Changeset) Load(path string, allowMissing bool) (*FileChange, error) {
+	if state, ok := c.files[path]; ok {
+		if state.Deleted && !allowMissing {
+			return nil, fmt.Errorf("file not found: %s", path)
+		}
+
+		return state, nil
+	}
+
+	state := &FileChange{Mode: 0644, ending: "\n"}
+
+	data, err := c.root.ReadFile(path)
+
+	switch {
+	case err == nil:
+		content := string(data)
+
+		if c.normalize {
+			state.bom, content = stripBom(content)
+			state.ending = detectLineEnding(content)
+			content = normalizeToLF(content)
+		}
+
+		state.Existed = true
+		state.Original = content
+		state.Content = content
+
+		if info, err := c.root.Stat(path); err == nil {
+			state.Mode = info.Mode().Perm()
+		}
+
+	case os.IsNotExist(err) && allowMissing:
+		state.Deleted = true
+
+	default:
+		return nil, pathError("read file", path, path, c.root.Name(), err)
+	}
+
+	c.files[path] = state
+	c.order = append(c.order, path)
+
+	return state, nil
+}
+
+// Commit writes the planned changes and returns the paths that changed. If
+// a write fails, files already written are restored so the workspace is
+// left as it was.
+func (c *Changeset) Commit() ([]string, error) {
+	var changed []string
+
+	rollback := func() {
+		for _, path := range changed {
+			state := c.files[path]
+
+			if state.Existed {
+				c.root.WriteFile(path, c.encode(state, state.Original), state.Mode)
+			} else {
+				c.root.Remove(path)
+			}
+		}
+	}
+
+	for _, path := range c.order {
+		state := c.files[path]
+
+		if !state.Changed() {
+			continue
+		}
+
+		if state.Deleted {
+			if err := c.root.Remove(path); err != nil {
+				rollback()
+				return nil, fmt.Errorf("failed to delete %s: %w", path, err)
+			}
+
+			changed = append(changed, path)
+			continue
+		}
+
+		if dir := filepath.Dir(path); dir != "." {
+			if err := c.root.MkdirAll(dir, 0755); err != nil {
+				rollback()
+				return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+			}
+		}
+
+		if err := c.root.WriteFile(path, c.encode(state, state.Content), state.Mode); err != nil {
+			rollback()
+			return nil, fmt.Errorf("failed to write file %s: %w", path, err)
+		}
+
+		changed = append(changed, path)
+	}
+
+	return changed, nil
+}
+
+// Move plans moving from to to, which must not exist.
+func (c *Changeset) Move(from, to string) error {
+	state, err := c.Load(from, false)
+
+	if err != nil {
+		return err
+	}
+
+	target, err := c.Load(to, true)
+
+	if err != nil {
+		return err
+	}
+
+	if !target.Deleted {
+		return fmt.Errorf("cannot move %s to %s: target already exists", from, to)
+	}
+
+	target.Deleted = false
+	target.Content = state.Content
+	target.Mode = state.Mode
+	target.bom = state.bom
+	target.ending = state.ending
+
+	state.Deleted = true
+
+	return nil
+}
+
+func (c *Changeset) encode(state *FileChange, content string) []byte {
+	if !c.normalize {
+		return []byte(content)
+	}
+
+	return []byte(state.bom + restoreLineEndings(content, state.ending))
+}
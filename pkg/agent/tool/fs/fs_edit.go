@@ -2,8 +2,11 @@ package fs
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
@@ -15,7 +18,7 @@ func EditTool(root *os.Root) tool.Tool {
 		Effect: tool.StaticEffect(tool.EffectMutates),
 
 		Description: strings.Join([]string{
-			"Performs exact string replacements in files. This is the preferred tool for modifying existing files.",
+			"Performs exact string replacements or line-based edits in files. This is the preferred tool for modifying existing files.",
 			"",
 			"Usage:",
 			"- You must use `read` at least once on a file before editing it.",
@@ -23,27 +26,20 @@ func EditTool(root *os.Root) tool.Tool {
 			"- The edit will FAIL if old_text is not unique in the file. Either provide more surrounding context to make it unique, or use replace_all to change every occurrence.",
 			"- Use the smallest old_text that is uniquely identifying — usually 2-4 adjacent lines. Avoid pasting 10+ lines of context when less will work.",
 			"- Use replace_all for renaming variables, functions, or other identifiers across a file.",
+			"- When old_text is hard to reproduce exactly, edit by line number instead: start_line/end_line replace that range with new_text (empty new_text deletes it), insert_before_line/insert_after_line insert new_text (insert_after_line=0 inserts at the top). Line edits require expected_hash, the hash shown at the end of the `read` output; they fail if the file changed since.",
+			"- To make several changes to one file in one call, pass them as `edits`. Line numbers in a batch all refer to the file as read; edits must not overlap. Either all apply or none.",
 			"- ALWAYS prefer editing existing files over writing new ones.",
 			"- NEVER use the shell tool (sed, awk) for file edits — use this tool instead.",
 			"- After a successful edit, the diff in the result is authoritative. Do not re-read the file unless you need context the diff doesn't show.",
 		}, "\n"),
 
-		Parameters: map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"path":        map[string]any{"type": "string", "description": "File path relative to the working directory"},
-				"old_text":    map[string]any{"type": "string", "description": "Exact text to find and replace. Must be unique unless replace_all is true."},
-				"new_text":    map[string]any{"type": "string", "description": "Text to replace the old text with. Must be different from old_text."},
-				"replace_all": map[string]any{"type": "boolean", "description": "Replace all occurrences of old_text instead of just the first. Useful for renaming variables. (default: false)"},
-			},
-			"required": []string{"path", "old_text", "new_text"},
-		},
+		Parameters: editParameters(),
 
-		Execute: func(ctx context.Context, args map[string]any) (string, error) {
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 			pathArg, ok := args["path"].(string)
 
 			if !ok || pathArg == "" {
-				return "", fmt.Errorf("path is required")
+				return tool.Result{}, fmt.Errorf("path is required")
 			}
 
 			workingDir := root.Name()
@@ -51,82 +47,65 @@ func EditTool(root *os.Root) tool.Tool {
 			normalizedPath, err := ensurePathInWorkspace(pathArg, workingDir, "edit file")
 
 			if err != nil {
-				return "", err
+				return tool.Result{}, err
 			}
 
-			oldText, ok := args["old_text"].(string)
-
-			if !ok || oldText == "" {
-				return "", fmt.Errorf("old_text is required")
+			if isNotebook(pathArg) {
+				return tool.Result{}, fmt.Errorf("%s is a Jupyter notebook; use notebook_edit to change its cells", pathArg)
 			}
 
-			newText, ok := args["new_text"].(string)
+			specs, err := parseEditSpecs(args)
 
-			if !ok {
-				return "", fmt.Errorf("new_text is required")
+			if err != nil {
+				return tool.Result{}, err
 			}
 
 			contentBytes, err := root.ReadFile(normalizedPath)
 
 			if err != nil {
-				return "", pathError("read file", pathArg, normalizedPath, workingDir, err)
+				return tool.Result{}, pathError("read file", pathArg, normalizedPath, workingDir, err)
 			}
 
-			bom, content := stripBom(string(contentBytes))
-			originalEnding := detectLineEnding(content)
-			normalizedContent := normalizeToLF(content)
-			normalizedOldText := normalizeToLF(oldText)
-			normalizedNewText := normalizeToLF(newText)
+			expectedHash, _ := args["expected_hash"].(string)
 
-			replaceAll := false
-			if ra, ok := args["replace_all"].(bool); ok {
-				replaceAll = ra
+			if expectedHash == "" && slices.ContainsFunc(specs, editSpec.lineBased) {
+				return tool.Result{}, fmt.Errorf("expected_hash is required for line-based edits: pass the hash shown at the end of the read output for %s", pathArg)
 			}
 
-			matchResult := fuzzyFindText(normalizedContent, normalizedOldText)
-
-			if !matchResult.found {
-				// Provide a helpful snippet of what the file actually contains near the beginning
-				preview := normalizedContent
-				if len(preview) > 200 {
-					preview = preview[:200] + "..."
+			if expectedHash != "" {
+				if hash := fileHash(contentBytes); !strings.EqualFold(strings.TrimSpace(expectedHash), hash) {
+					return tool.Result{}, fmt.Errorf("%s has changed since it was read (hash is %s, expected %s). Read the file again and retry the edit", pathArg, hash, expectedHash)
 				}
-				return "", fmt.Errorf("could not find old_text in %s. Make sure it matches exactly (including whitespace and newlines). File starts with:\n%s", pathArg, preview)
 			}
 
-			fuzzyContent := normalizeForFuzzyMatch(normalizedContent)
-			fuzzyOldText := normalizeForFuzzyMatch(normalizedOldText)
-			occurrences := strings.Count(fuzzyContent, fuzzyOldText)
+			bom, content := stripBom(string(contentBytes))
+			originalEnding := detectLineEnding(content)
+			baseContent := normalizeToLF(content)
 
-			if occurrences > 1 && !replaceAll {
-				return "", fmt.Errorf("found %d occurrences of the text in %s. The text must be unique — provide more context to make it unique, or set replace_all=true to replace all occurrences", occurrences, pathArg)
-			}
+			var changes []textChange
 
-			baseContent := matchResult.contentForReplacement
-			var newContent string
+			for i, spec := range specs {
+				c, err := spec.resolve(baseContent, pathArg)
 
-			if replaceAll {
-				// For replace_all, use the fuzzy-normalized version to find all occurrences
-				// but apply the replacement on the original content
-				if matchResult.usedFuzzyMatch {
-					// When fuzzy matching was used, we need to iteratively replace
-					newContent = baseContent
-					for {
-						mr := fuzzyFindText(newContent, normalizedOldText)
-						if !mr.found {
-							break
-						}
-						newContent = mr.contentForReplacement[:mr.index] + normalizedNewText + mr.contentForReplacement[mr.index+mr.matchLength:]
+				if err != nil {
+					if len(specs) > 1 {
+						return tool.Result{}, fmt.Errorf("edit %d: %w", i+1, err)
 					}
-				} else {
-					newContent = strings.ReplaceAll(baseContent, normalizedOldText, normalizedNewText)
+
+					return tool.Result{}, err
 				}
-			} else {
-				newContent = baseContent[:matchResult.index] + normalizedNewText + baseContent[matchResult.index+matchResult.matchLength:]
+
+				changes = append(changes, c...)
+			}
+
+			newContent, err := applyTextChanges(baseContent, changes)
+
+			if err != nil {
+				return tool.Result{}, err
 			}
 
 			if baseContent == newContent {
-				return "", fmt.Errorf("no changes made to %s. The replacement produced identical content", pathArg)
+				return tool.Result{}, fmt.Errorf("no changes made to %s. The replacement produced identical content", pathArg)
 			}
 
 			finalContent := bom + restoreLineEndings(newContent, originalEnding)
@@ -134,22 +113,317 @@ func EditTool(root *os.Root) tool.Tool {
 			outFile, err := root.Create(normalizedPath)
 
 			if err != nil {
-				return "", pathError("write file", pathArg, normalizedPath, workingDir, err)
+				return tool.Result{}, pathError("write file", pathArg, normalizedPath, workingDir, err)
 			}
 			if _, err := outFile.WriteString(finalContent); err != nil {
 				outFile.Close()
-				return "", fmt.Errorf("failed to write file: %w", err)
+				return tool.Result{}, fmt.Errorf("failed to write file: %w", err)
 			}
 
 			if err := outFile.Close(); err != nil {
-				return "", fmt.Errorf("failed to close file: %w", err)
+				return tool.Result{}, fmt.Errorf("failed to close file: %w", err)
 			}
 
 			diff := generateDiffString(baseContent, newContent)
 
-			result := fmt.Sprintf("Successfully replaced text in %s.\n\n%s", pathArg, diff)
+			summary := fmt.Sprintf("Successfully replaced text in %s.", pathArg)
 
-			return result, nil
+			if len(specs) > 1 {
+				summary = fmt.Sprintf("Successfully applied %d edits to %s.", len(specs), pathArg)
+			} else if specs[0].replaceAll {
+				summary = fmt.Sprintf("Successfully replaced %d occurrences in %s.", len(changes), pathArg)
+			} else if specs[0].lineBased() {
+				summary = fmt.Sprintf("Successfully edited %s.", pathArg)
+			}
+
+			return tool.Result{
+				Text:    fmt.Sprintf("%s New hash: %s\n\n%s", summary, fileHash([]byte(finalContent)), diff),
+				Changed: []string{normalizedPath},
+			}, nil
+		},
+	}
+}
+
+// editParameters accepts a single edit at the top level or a batch in
+// edits. Every edit needs new_text, but the top level can only say so in
+// the description since a batch leaves it out there.
+func editParameters() map[string]any {
+	properties := editProperties()
+
+	properties["path"] = map[string]any{"type": "string", "description": "File path relative to the working directory"}
+	properties["expected_hash"] = map[string]any{"type": "string", "description": "File hash from the read output. Required for line-based edits; optional otherwise."}
+	properties["edits"] = map[string]any{
+		"type":        "array",
+		"description": "Several edits to apply at once, instead of the single-edit fields. Without edits, new_text is required.",
+		"items": map[string]any{
+			"type":       "object",
+			"properties": editProperties(),
+			"required":   []string{"new_text"},
 		},
 	}
+
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   []string{"path"},
+	}
+}
+
+func editProperties() map[string]any {
+	return map[string]any{
+		"old_text":           map[string]any{"type": "string", "description": "Exact text to find and replace. Must be unique unless replace_all is true."},
+		"new_text":           map[string]any{"type": "string", "description": "Text to replace the old text or line range with, or to insert. Must be different from old_text."},
+		"replace_all":        map[string]any{"type": "boolean", "description": "Replace all occurrences of old_text instead of just the first. Useful for renaming variables. (default: false)"},
+		"start_line":         map[string]any{"type": "integer", "description": "First line (1-based) of the range to replace with new_text"},
+		"end_line":           map[string]any{"type": "integer", "description": "Last line (inclusive) of the range to replace. Defaults to start_line."},
+		"insert_before_line": map[string]any{"type": "integer", "description": "Insert new_text before this line (1-based)"},
+		"insert_after_line":  map[string]any{"type": "integer", "description": "Insert new_text after this line (1-based; 0 inserts at the top)"},
+	}
+}
+
+// fileHash identifies a file's content for line-based edits.
+func fileHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:6])
+}
+
+type editMode int
+
+const (
+	editReplaceText editMode = iota
+	editReplaceLines
+	editInsertBefore
+	editInsertAfter
+)
+
+type editSpec struct {
+	mode editMode
+
+	oldText    string
+	newText    string
+	replaceAll bool
+
+	startLine int
+	endLine   int
+}
+
+func (s editSpec) lineBased() bool {
+	return s.mode != editReplaceText
+}
+
+// textChange replaces content[start:end] with text.
+type textChange struct {
+	start int
+	end   int
+	text  string
+}
+
+func parseEditSpecs(args map[string]any) ([]editSpec, error) {
+	if raw, ok := args["edits"].([]any); ok && len(raw) > 0 {
+		var specs []editSpec
+
+		for i, item := range raw {
+			m, ok := item.(map[string]any)
+
+			if !ok {
+				return nil, fmt.Errorf("edit %d: must be an object", i+1)
+			}
+
+			spec, err := parseEditSpec(m)
+
+			if err != nil {
+				return nil, fmt.Errorf("edit %d: %w", i+1, err)
+			}
+
+			specs = append(specs, spec)
+		}
+
+		return specs, nil
+	}
+
+	spec, err := parseEditSpec(args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []editSpec{spec}, nil
+}
+
+func parseEditSpec(args map[string]any) (editSpec, error) {
+	newText, ok := args["new_text"].(string)
+
+	if !ok {
+		return editSpec{}, fmt.Errorf("new_text is required")
+	}
+
+	spec := editSpec{newText: normalizeToLF(newText)}
+
+	line := func(key string) (int, bool) {
+		v, ok := args[key].(float64)
+		return int(v), ok
+	}
+
+	if start, ok := line("start_line"); ok {
+		spec.mode = editReplaceLines
+		spec.startLine = start
+		spec.endLine = start
+
+		if end, ok := line("end_line"); ok {
+			spec.endLine = end
+		}
+
+		return spec, nil
+	}
+
+	if n, ok := line("insert_before_line"); ok {
+		spec.mode = editInsertBefore
+		spec.startLine = n
+		return spec, nil
+	}
+
+	if n, ok := line("insert_after_line"); ok {
+		spec.mode = editInsertAfter
+		spec.startLine = n
+		return spec, nil
+	}
+
+	oldText, ok := args["old_text"].(string)
+
+	if !ok || oldText == "" {
+		return editSpec{}, fmt.Errorf("old_text is required (or start_line, insert_before_line or insert_after_line for line-based edits)")
+	}
+
+	spec.oldText = normalizeToLF(oldText)
+	spec.replaceAll, _ = args["replace_all"].(bool)
+
+	return spec, nil
+}
+
+// resolve locates the edit in content, the file as read.
+func (s editSpec) resolve(content, pathArg string) ([]textChange, error) {
+	lines := strings.Count(content, "\n") + 1
+
+	switch s.mode {
+	case editReplaceLines:
+		if s.startLine < 1 || s.endLine < s.startLine || s.endLine > lines {
+			return nil, fmt.Errorf("line range %d-%d is outside %s (%d lines)", s.startLine, s.endLine, pathArg, lines)
+		}
+
+		start := lineOffset(content, s.startLine)
+		end := lineOffset(content, s.endLine+1)
+		text := s.newText
+
+		// Keep the line structure: the replacement ends with a newline
+		// exactly when the replaced range did.
+		if text != "" && strings.HasSuffix(content[start:end], "\n") && !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+
+		return []textChange{{start, end, text}}, nil
+
+	case editInsertBefore, editInsertAfter:
+		line := s.startLine
+
+		if s.mode == editInsertAfter {
+			line++
+		}
+
+		if line < 1 || line > lines+1 {
+			return nil, fmt.Errorf("line %d is outside %s (%d lines)", s.startLine, pathArg, lines)
+		}
+
+		pos := lineOffset(content, line)
+		text := s.newText
+
+		if !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+
+		// Inserting after a last line that has no newline.
+		if pos == len(content) && content != "" && !strings.HasSuffix(content, "\n") {
+			text = "\n" + strings.TrimSuffix(text, "\n")
+		}
+
+		return []textChange{{pos, pos, text}}, nil
+	}
+
+	matchResult := fuzzyFindText(content, s.oldText)
+
+	if !matchResult.found {
+		// Provide a helpful snippet of what the file actually contains near the beginning
+		preview := content
+		if len(preview) > 200 {
+			preview = preview[:200] + "..."
+		}
+		return nil, fmt.Errorf("could not find old_text in %s. Make sure it matches exactly (including whitespace and newlines). File starts with:\n%s", pathArg, preview)
+	}
+
+	// Near matches only count when the text is nowhere in the file
+	// verbatim; otherwise they could be similar code that must stay.
+	find := func(content string) (int, int) {
+		i := strings.Index(content, s.oldText)
+		return i, len(s.oldText)
+	}
+
+	occurrences := strings.Count(content, s.oldText)
+
+	if matchResult.usedFuzzyMatch {
+		find = func(content string) (int, int) {
+			r := fuzzyFindText(content, s.oldText)
+
+			if !r.found {
+				return -1, 0
+			}
+
+			return r.index, r.matchLength
+		}
+
+		occurrences = strings.Count(normalizeForFuzzyMatch(content), normalizeForFuzzyMatch(s.oldText))
+	}
+
+	if occurrences > 1 && !s.replaceAll {
+		return nil, fmt.Errorf("found %d occurrences of the text in %s. The text must be unique — provide more context to make it unique, or set replace_all=true to replace all occurrences", occurrences, pathArg)
+	}
+
+	changes := []textChange{{matchResult.index, matchResult.index + matchResult.matchLength, s.newText}}
+
+	if !s.replaceAll {
+		return changes, nil
+	}
+
+	for from := changes[0].end; from < len(content); {
+		i, n := find(content[from:])
+
+		if i < 0 || n == 0 {
+			break
+		}
+
+		start := from + i
+		changes = append(changes, textChange{start, start + n, s.newText})
+		from = start + n
+	}
+
+	return changes, nil
+}
+
+// applyTextChanges applies changes made against the same content. They are
+// applied back to front so earlier offsets stay valid; overlaps are errors.
+func applyTextChanges(content string, changes []textChange) (string, error) {
+	sorted := slices.Clone(changes)
+
+	slices.SortStableFunc(sorted, func(a, b textChange) int { return a.start - b.start })
+
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].start < sorted[i-1].end {
+			return "", fmt.Errorf("edits overlap: combine the changes around offset %d into one edit", sorted[i].start)
+		}
+	}
+
+	for i := len(sorted) - 1; i >= 0; i-- {
+		c := sorted[i]
+		content = content[:c.start] + c.text + content[c.end:]
+	}
+
+	return content, nil
 }
@@ -41,13 +41,13 @@ func FindTool(root *os.Root) tool.Tool {
 			"required": []string{"pattern"},
 		},
 
-		Execute: func(ctx context.Context, args map[string]any) (string, error) {
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 			startTime := time.Now()
 
 			pattern, ok := args["pattern"].(string)
 
 			if !ok || pattern == "" {
-				return "", fmt.Errorf("pattern is required")
+				return tool.Result{}, fmt.Errorf("pattern is required")
 			}
 
 			searchDir := "."
@@ -61,7 +61,7 @@ func FindTool(root *os.Root) tool.Tool {
 			searchDirFS, err := ensurePathInWorkspaceFS(searchDir, workingDir, "search")
 
 			if err != nil {
-				return "", err
+				return tool.Result{}, err
 			}
 
 			limit := DefaultFindLimit
@@ -73,11 +73,11 @@ func FindTool(root *os.Root) tool.Tool {
 			info, err := root.Stat(searchDirFS)
 
 			if err != nil {
-				return "", pathError("stat path", searchDir, searchDirFS, workingDir, err)
+				return tool.Result{}, pathError("stat path", searchDir, searchDirFS, workingDir, err)
 			}
 
 			if !info.IsDir() {
-				return "", fmt.Errorf("path is not a directory: %s", searchDir)
+				return tool.Result{}, fmt.Errorf("path is not a directory: %s", searchDir)
 			}
 
 			fsys := root.FS()
@@ -88,7 +88,7 @@ func FindTool(root *os.Root) tool.Tool {
 			}
 			var results []fileResult
 
-			err = walkWorkspace(ctx, fsys, searchDirFS, func(path, relPath string) error {
+			err = WalkWorkspace(ctx, fsys, searchDirFS, func(path, relPath string) error {
 				matched, err := doublestar.Match(pattern, relPath)
 
 				if err != nil {
@@ -110,13 +110,13 @@ func FindTool(root *os.Root) tool.Tool {
 			})
 
 			if err != nil && err != filepath.SkipAll {
-				return "", fmt.Errorf("failed to search directory: %w", err)
+				return tool.Result{}, fmt.Errorf("failed to search directory: %w", err)
 			}
 
 			totalMatches := len(results)
 
 			if totalMatches == 0 {
-				return "No files found matching pattern", nil
+				return tool.Text("No files found matching pattern"), nil
 			}
 
 			// Sort by modification time (newest first). The walk visits every match
@@ -158,7 +158,7 @@ func FindTool(root *os.Root) tool.Tool {
 
 			truncatedOutput += fmt.Sprintf("\n\n[%s]", strings.Join(notices, ". "))
 
-			return truncatedOutput, nil
+			return tool.Text(truncatedOutput), nil
 		},
 	}
 }
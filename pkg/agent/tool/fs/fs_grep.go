@@ -100,11 +100,11 @@ func GrepTool(root *os.Root) tool.Tool {
 			"required": []string{"pattern"},
 		},
 
-		Execute: func(ctx context.Context, args map[string]any) (string, error) {
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 			pattern, ok := args["pattern"].(string)
 
 			if !ok || pattern == "" {
-				return "", fmt.Errorf("pattern is required")
+				return tool.Result{}, fmt.Errorf("pattern is required")
 			}
 
 			searchPath := "."
@@ -118,7 +118,7 @@ func GrepTool(root *os.Root) tool.Tool {
 			searchPathFS, err := ensurePathInWorkspaceFS(searchPath, workingDir, "search")
 
 			if err != nil {
-				return "", err
+				return tool.Result{}, err
 			}
 
 			glob := ""
@@ -205,14 +205,14 @@ func GrepTool(root *os.Root) tool.Tool {
 			re, err := regexp.Compile(regexPattern)
 
 			if err != nil {
-				return "", fmt.Errorf("invalid regex pattern: %w", err)
+				return tool.Result{}, fmt.Errorf("invalid regex pattern: %w", err)
 			}
 
 			// Check if path exists
 			info, err := root.Stat(searchPathFS)
 
 			if err != nil {
-				return "", pathError("stat path", searchPath, searchPathFS, workingDir, err)
+				return tool.Result{}, pathError("stat path", searchPath, searchPathFS, workingDir, err)
 			}
 
 			fsys := root.FS()
@@ -222,13 +222,13 @@ func GrepTool(root *os.Root) tool.Tool {
 				matches := searchFileWithContext(fsys, searchPathFS, re, beforeContext, afterContext, headLimit+resultOffset, multiline)
 
 				if len(matches) == 0 {
-					return "No matches found", nil
+					return tool.Text("No matches found"), nil
 				}
 
 				// Apply offset
 				if resultOffset > 0 {
 					if resultOffset >= len(matches) {
-						return "No matches found (offset beyond results)", nil
+						return tool.Text("No matches found (offset beyond results)"), nil
 					}
 					matches = matches[resultOffset:]
 				}
@@ -237,14 +237,14 @@ func GrepTool(root *os.Root) tool.Tool {
 				}
 
 				if outputMode == "files_with_matches" {
-					return filepath.FromSlash(searchPathFS), nil
+					return tool.Text(filepath.FromSlash(searchPathFS)), nil
 				}
 
 				if outputMode == "count" {
-					return fmt.Sprintf("%s:%d", filepath.FromSlash(searchPathFS), len(matches)), nil
+					return tool.Text(fmt.Sprintf("%s:%d", filepath.FromSlash(searchPathFS), len(matches))), nil
 				}
 
-				return strings.Join(matches, "\n"), nil
+				return tool.Text(strings.Join(matches, "\n")), nil
 			}
 
 			var results []string
@@ -259,7 +259,7 @@ func GrepTool(root *os.Root) tool.Tool {
 			}
 			var fileMatches []fileMatch
 
-			err = walkWorkspace(ctx, fsys, searchPathFS, func(path, relPath string) error {
+			err = WalkWorkspace(ctx, fsys, searchPathFS, func(path, relPath string) error {
 				// Check glob pattern
 				if glob != "" {
 					matched, _ := doublestar.Match(glob, pathpkg.Base(path))
@@ -352,7 +352,7 @@ func GrepTool(root *os.Root) tool.Tool {
 			})
 
 			if err != nil && err != filepath.SkipAll {
-				return "", fmt.Errorf("search failed: %w", err)
+				return tool.Result{}, fmt.Errorf("search failed: %w", err)
 			}
 
 			// Build output based on mode
@@ -361,7 +361,7 @@ func GrepTool(root *os.Root) tool.Tool {
 			switch outputMode {
 			case "files_with_matches":
 				if len(fileMatches) == 0 {
-					return "No matches found", nil
+					return tool.Text("No matches found"), nil
 				}
 				paths := make([]string, len(fileMatches))
 				for i, fm := range fileMatches {
@@ -371,7 +371,7 @@ func GrepTool(root *os.Root) tool.Tool {
 
 			case "count":
 				if len(fileMatches) == 0 {
-					return "No matches found", nil
+					return tool.Text("No matches found"), nil
 				}
 				lines := make([]string, len(fileMatches))
 				for i, fm := range fileMatches {
@@ -381,7 +381,7 @@ func GrepTool(root *os.Root) tool.Tool {
 
 			default: // "content"
 				if len(results) == 0 {
-					return "No matches found", nil
+					return tool.Text("No matches found"), nil
 				}
 				output = strings.Join(results, "\n")
 			}
@@ -405,7 +405,7 @@ func GrepTool(root *os.Root) tool.Tool {
 				output += fmt.Sprintf("\n\n[%s]", strings.Join(notices, ". "))
 			}
 
-			return output, nil
+			return tool.Text(output), nil
 		},
 	}
 }
@@ -12,6 +12,11 @@ import (
 
 const DefaultListLimit = 500
 
+type lsArgs struct {
+	Path  string `json:"path,omitempty" jsonschema:"Path to the directory to list (defaults to current directory)"`
+	Limit int    `json:"limit,omitempty" jsonschema:"Maximum number of entries to return"`
+}
+
 func LsTool(root *os.Root) tool.Tool {
 	return tool.Tool{
 		Name:   "ls",
@@ -29,19 +34,19 @@ func LsTool(root *os.Root) tool.Tool {
 			"- Use `ls` only when you genuinely need to inspect a directory's immediate contents and don't yet know what's there.",
 		}, "\n"),
 
-		Parameters: map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"path":  map[string]any{"type": "string", "description": "Path to the directory to list (defaults to current directory)"},
-				"limit": map[string]any{"type": "integer", "description": "Maximum number of entries to return"},
-			},
-		},
+		Parameters: tool.Schema[lsArgs](),
+
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
+			a, err := tool.Decode[lsArgs](args)
+
+			if err != nil {
+				return tool.Result{}, err
+			}
 
-		Execute: func(ctx context.Context, args map[string]any) (string, error) {
 			pathArg := "."
 
-			if p, ok := args["path"].(string); ok && p != "" {
-				pathArg = p
+			if a.Path != "" {
+				pathArg = a.Path
 			}
 
 			workingDir := root.Name()
@@ -49,40 +54,40 @@ func LsTool(root *os.Root) tool.Tool {
 			normalizedPath, err := ensurePathInWorkspace(pathArg, workingDir, "list directory")
 
 			if err != nil {
-				return "", err
+				return tool.Result{}, err
 			}
 
 			limit := DefaultListLimit
 
-			if l, ok := args["limit"].(float64); ok && l > 0 {
-				limit = int(l)
+			if a.Limit > 0 {
+				limit = a.Limit
 			}
 
 			info, err := root.Stat(normalizedPath)
 
 			if err != nil {
-				return "", pathError("stat path", pathArg, normalizedPath, workingDir, err)
+				return tool.Result{}, pathError("stat path", pathArg, normalizedPath, workingDir, err)
 			}
 
 			if !info.IsDir() {
-				return "", fmt.Errorf("path is not a directory: %s", pathArg)
+				return tool.Result{}, fmt.Errorf("path is not a directory: %s", pathArg)
 			}
 
 			dir, err := root.Open(normalizedPath)
 
 			if err != nil {
-				return "", pathError("open directory", pathArg, normalizedPath, workingDir, err)
+				return tool.Result{}, pathError("open directory", pathArg, normalizedPath, workingDir, err)
 			}
 			defer dir.Close()
 
 			entries, err := dir.ReadDir(-1)
 
 			if err != nil {
-				return "", fmt.Errorf("failed to read directory: %w", err)
+				return tool.Result{}, fmt.Errorf("failed to read directory: %w", err)
 			}
 
 			if len(entries) == 0 {
-				return "(empty directory)", nil
+				return tool.Text("(empty directory)"), nil
 			}
 
 			sort.Slice(entries, func(i, j int) bool {
@@ -122,7 +127,7 @@ func LsTool(root *os.Root) tool.Tool {
 				output += fmt.Sprintf("\n\n[%s]", strings.Join(notices, ". "))
 			}
 
-			return output, nil
+			return tool.Text(output), nil
 		},
 	}
 }
@@ -0,0 +1,346 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
+)
+
+const (
+	// Larger images are scaled down before they are attached; files above
+	// maxImageFileSize are not read at all.
+	maxImageSize      = 4 * 1024 * 1024
+	maxImageFileSize  = 20 * 1024 * 1024
+	maxImageDimension = 2000
+
+	maxPDFSize  = 32 * 1024 * 1024
+	maxPDFPages = 20
+)
+
+var imageTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+var pdfPagesRe = regexp.MustCompile(`(?m)^Pages:\s+(\d+)`)
+
+func isImage(path string) bool {
+	_, ok := imageTypes[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+func isPDF(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".pdf")
+}
+
+func dataURL(mediaType string, data []byte) string {
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// readImage attaches an image to the tool result. Images larger than
+// maxImageDimension or maxImageSize are scaled down.
+func readImage(path string, content []byte) (tool.Result, error) {
+	if len(content) > maxImageFileSize {
+		return tool.Result{}, fmt.Errorf("image %s is too large (%d KB, limit %d KB)", path, len(content)/1024, maxImageFileSize/1024)
+	}
+
+	mediaType := imageTypes[strings.ToLower(filepath.Ext(path))]
+
+	description := fmt.Sprintf("Image %s (%s, %d KB)", path, mediaType, (len(content)+1023)/1024)
+
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(content)); err == nil {
+		description = fmt.Sprintf("Image %s (%s, %dx%d, %d KB)", path, mediaType, cfg.Width, cfg.Height, (len(content)+1023)/1024)
+
+		if cfg.Width > maxImageDimension || cfg.Height > maxImageDimension || len(content) > maxImageSize {
+			scaled, scaledType, size, err := scaleImage(content)
+
+			if err != nil {
+				return tool.Result{}, fmt.Errorf("failed to scale image %s: %w", path, err)
+			}
+
+			content, mediaType = scaled, scaledType
+			description += fmt.Sprintf(", scaled to %dx%d", size.X, size.Y)
+		}
+	}
+
+	if len(content) > maxImageSize {
+		return tool.Result{}, fmt.Errorf("image %s is too large (%d KB, limit %d KB)", path, len(content)/1024, maxImageSize/1024)
+	}
+
+	return tool.Result{
+		Text:  description + " is attached.",
+		Files: []tool.File{{Name: filepath.Base(path), Data: dataURL(mediaType, content)}},
+	}, nil
+}
+
+// scaleImage shrinks an image to fit maxImageDimension, averaging the
+// pixels it merges. The result is a PNG, or a JPEG when the source was one
+// or the PNG would exceed maxImageSize.
+func scaleImage(content []byte) ([]byte, string, image.Point, error) {
+	src, format, err := image.Decode(bytes.NewReader(content))
+
+	if err != nil {
+		return nil, "", image.Point{}, err
+	}
+
+	b := src.Bounds()
+	scale := min(1, float64(maxImageDimension)/float64(max(b.Dx(), b.Dy())))
+
+	size := image.Pt(max(1, int(float64(b.Dx())*scale)), max(1, int(float64(b.Dy())*scale)))
+
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rectangle{Max: size})
+
+	for y := range size.Y {
+		y0, y1 := y*b.Dy()/size.Y, max((y+1)*b.Dy()/size.Y, y*b.Dy()/size.Y+1)
+
+		for x := range size.X {
+			x0, x1 := x*b.Dx()/size.X, max((x+1)*b.Dx()/size.X, x*b.Dx()/size.X+1)
+
+			var sum [4]int
+
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride+x0*4 : sy*rgba.Stride+x1*4]
+
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+
+			n := (y1 - y0) * (x1 - x0)
+			i := dst.PixOffset(x, y)
+
+			for c := range sum {
+				dst.Pix[i+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+
+	if format != "jpeg" {
+		if err := png.Encode(&buf, dst); err != nil {
+			return nil, "", image.Point{}, err
+		}
+
+		if buf.Len() <= maxImageSize {
+			return buf.Bytes(), "image/png", size, nil
+		}
+
+		buf.Reset()
+	}
+
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, "", image.Point{}, err
+	}
+
+	return buf.Bytes(), "image/jpeg", size, nil
+}
+
+// readPDF returns the text of the selected pages. Pages without a text
+// layer are rendered as images; without poppler-utils installed the whole
+// document is attached for the model to read.
+func readPDF(ctx context.Context, path string, content []byte, pages string) (tool.Result, error) {
+	if len(content) > maxPDFSize {
+		return tool.Result{}, fmt.Errorf("PDF %s is too large (%d KB, limit %d KB)", path, len(content)/1024, maxPDFSize/1024)
+	}
+
+	first, last, err := parsePages(pages)
+
+	if err != nil {
+		return tool.Result{}, err
+	}
+
+	if _, err := exec.LookPath("pdftotext"); err != nil {
+		return attachPDF(path, content, pages != "")
+	}
+
+	tmp, err := os.CreateTemp("", "wingman-*.pdf")
+
+	if err != nil {
+		return tool.Result{}, fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return tool.Result{}, fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	tmp.Close()
+
+	total := pdfPageCount(ctx, tmp.Name())
+
+	if total > 0 {
+		if first > total {
+			return tool.Result{}, fmt.Errorf("page %d is beyond end of %s (%d pages)", first, path, total)
+		}
+
+		last = min(last, total)
+	}
+
+	out, err := exec.CommandContext(ctx, "pdftotext", "-layout", "-f", strconv.Itoa(first), "-l", strconv.Itoa(last), tmp.Name(), "-").Output()
+
+	if err != nil {
+		return tool.Result{}, fmt.Errorf("failed to extract text from %s: %w", path, err)
+	}
+
+	if strings.TrimSpace(strings.ReplaceAll(string(out), "\f", "")) == "" {
+		return renderPDFPages(ctx, path, tmp.Name(), content, first, last)
+	}
+
+	var sb strings.Builder
+
+	for i, page := range strings.Split(strings.TrimRight(string(out), "\f\n"), "\f") {
+		fmt.Fprintf(&sb, "--- page %d ---\n%s\n", first+i, strings.TrimRight(page, "\n"))
+	}
+
+	output, truncated := truncateHead(strings.TrimRight(sb.String(), "\n"))
+
+	if truncated || (total > 0 && last < total) {
+		notice := fmt.Sprintf("\n\n[Pages %d-%d", first, last)
+
+		if total > 0 {
+			notice += fmt.Sprintf(" of %d", total)
+		}
+
+		if truncated {
+			notice += ", output truncated"
+		}
+
+		output += notice + fmt.Sprintf(`. Use pages="%d-%d" to continue]`, last+1, min(last+maxPDFPages, max(total, last+1)))
+	}
+
+	return tool.Text(output), nil
+}
+
+// renderPDFPages attaches the pages as PNG images, for scanned documents
+// without a text layer.
+func renderPDFPages(ctx context.Context, path, file string, content []byte, first, last int) (tool.Result, error) {
+	if _, err := exec.LookPath("pdftoppm"); err != nil {
+		return attachPDF(path, content, false)
+	}
+
+	dir, err := os.MkdirTemp("", "wingman-pdf-*")
+
+	if err != nil {
+		return tool.Result{}, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	if err := exec.CommandContext(ctx, "pdftoppm", "-png", "-r", "100", "-f", strconv.Itoa(first), "-l", strconv.Itoa(last), file, filepath.Join(dir, "page")).Run(); err != nil {
+		return tool.Result{}, fmt.Errorf("failed to render %s: %w", path, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+
+	if err != nil {
+		return tool.Result{}, fmt.Errorf("failed to read rendered pages: %w", err)
+	}
+
+	var files []tool.File
+
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+
+		if err != nil {
+			return tool.Result{}, fmt.Errorf("failed to read rendered page: %w", err)
+		}
+
+		files = append(files, tool.File{Name: e.Name(), Data: dataURL("image/png", data)})
+	}
+
+	return tool.Result{
+		Text:  fmt.Sprintf("PDF %s has no text layer; pages %d-%d are attached as images.", path, first, first+len(files)-1),
+		Files: files,
+	}, nil
+}
+
+func attachPDF(path string, content []byte, pagesIgnored bool) (tool.Result, error) {
+	result := fmt.Sprintf("PDF %s (%d KB) is attached.", path, (len(content)+1023)/1024)
+
+	if pagesIgnored {
+		result += " Page selection needs poppler-utils (pdftotext); the whole document was attached."
+	}
+
+	return tool.Result{
+		Text:  result,
+		Files: []tool.File{{Name: filepath.Base(path), Data: dataURL("application/pdf", content)}},
+	}, nil
+}
+
+func pdfPageCount(ctx context.Context, file string) int {
+	out, err := exec.CommandContext(ctx, "pdfinfo", file).Output()
+
+	if err != nil {
+		return 0
+	}
+
+	m := pdfPagesRe.FindSubmatch(out)
+
+	if m == nil {
+		return 0
+	}
+
+	n, _ := strconv.Atoi(string(m[1]))
+
+	return n
+}
+
+// parsePages parses a page selection like "3" or "1-5". The default is the
+// first maxPDFPages pages.
+func parsePages(pages string) (int, int, error) {
+	pages = strings.TrimSpace(pages)
+
+	if pages == "" {
+		return 1, maxPDFPages, nil
+	}
+
+	from, to, isRange := strings.Cut(pages, "-")
+
+	first, err := strconv.Atoi(strings.TrimSpace(from))
+
+	if err != nil || first < 1 {
+		return 0, 0, fmt.Errorf("invalid pages %q: use a page number or a range like 1-5", pages)
+	}
+
+	last := first
+
+	if isRange {
+		last, err = strconv.Atoi(strings.TrimSpace(to))
+
+		if err != nil || last < first {
+			return 0, 0, fmt.Errorf("invalid pages %q: use a page number or a range like 1-5", pages)
+		}
+	}
+
+	if last-first+1 > maxPDFPages {
+		return 0, 0, fmt.Errorf("too many pages %q: read at most %d pages at a time", pages, maxPDFPages)
+	}
+
+	return first, last, nil
+}
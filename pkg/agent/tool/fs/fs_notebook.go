@@ -0,0 +1,564 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
+)
+
+const maxNotebookOutput = 4000
+
+var ansiRe = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// notebookImageTypes are the output types returned as image attachments.
+var notebookImageTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// notebookTextTypes are the rich output types shown as text, in order of
+// preference.
+var notebookTextTypes = []string{"text/plain", "text/markdown", "application/json", "text/html", "text/latex"}
+
+func isNotebook(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".ipynb")
+}
+
+// multiline is notebook text, stored either as a string or as a list of
+// lines. Other JSON values (e.g. application/json outputs) keep their raw
+// encoding.
+type multiline string
+
+func (m *multiline) UnmarshalJSON(data []byte) error {
+	var s string
+
+	if err := json.Unmarshal(data, &s); err == nil {
+		*m = multiline(s)
+		return nil
+	}
+
+	var lines []string
+
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*m = multiline(strings.Join(lines, ""))
+		return nil
+	}
+
+	*m = multiline(data)
+	return nil
+}
+
+type notebookFile struct {
+	Metadata struct {
+		KernelSpec struct {
+			DisplayName string `json:"display_name"`
+		} `json:"kernelspec"`
+
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+
+	Cells []notebookCell `json:"cells"`
+}
+
+type notebookCell struct {
+	ID       string `json:"id"`
+	CellType string `json:"cell_type"`
+
+	Source         multiline        `json:"source"`
+	ExecutionCount *int             `json:"execution_count"`
+	Outputs        []notebookOutput `json:"outputs"`
+}
+
+type notebookOutput struct {
+	OutputType string `json:"output_type"`
+
+	Name string               `json:"name"`
+	Text multiline            `json:"text"`
+	Data map[string]multiline `json:"data"`
+
+	EName     string   `json:"ename"`
+	EValue    string   `json:"evalue"`
+	Traceback []string `json:"traceback"`
+}
+
+// cellRef is how a cell is referred to: its ID, or its position for
+// notebooks that predate cell IDs.
+func cellRef(id string, index int) string {
+	if id != "" {
+		return id
+	}
+
+	return fmt.Sprintf("cell-%d", index+1)
+}
+
+// renderNotebook formats the cells from offset (0-based) on, up to limit
+// cells, with their outputs. Image outputs are returned as files to attach.
+func renderNotebook(data []byte, offset, limit int) (string, []tool.File, error) {
+	var nb notebookFile
+
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return "", nil, fmt.Errorf("failed to parse notebook: %w", err)
+	}
+
+	total := len(nb.Cells)
+
+	kernel := nb.Metadata.KernelSpec.DisplayName
+
+	if kernel == "" {
+		kernel = nb.Metadata.LanguageInfo.Name
+	}
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "Notebook with %d cells", total)
+
+	if kernel != "" {
+		fmt.Fprintf(&sb, " (%s)", kernel)
+	}
+
+	sb.WriteString("\n")
+
+	if total == 0 {
+		return sb.String() + "(no cells)", nil, nil
+	}
+
+	if offset >= total {
+		return "", nil, fmt.Errorf("offset %d is beyond end of notebook (%d cells)", offset+1, total)
+	}
+
+	end := total
+
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+
+	var files []tool.File
+
+	shown := offset
+
+	for i := offset; i < end; i++ {
+		block, cellFiles := renderCell(nb.Cells[i], i)
+
+		if i > offset && (sb.Len()+len(block) > DefaultMaxBytes || strings.Count(sb.String()+block, "\n") > DefaultMaxLines) {
+			break
+		}
+
+		sb.WriteString(block)
+		files = append(files, cellFiles...)
+		shown = i + 1
+	}
+
+	output, truncated := truncateHead(strings.TrimRight(sb.String(), "\n"))
+
+	if truncated || shown < total {
+		output += fmt.Sprintf("\n\n[Cells %d-%d of %d. Use offset=%d to continue]", offset+1, shown, total, shown+1)
+	}
+
+	return output, files, nil
+}
+
+func renderCell(cell notebookCell, index int) (string, []tool.File) {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "\n--- cell %d [%s] id=%s", index+1, cell.CellType, cellRef(cell.ID, index))
+
+	if cell.ExecutionCount != nil {
+		fmt.Fprintf(&sb, " execution_count=%d", *cell.ExecutionCount)
+	}
+
+	sb.WriteString(" ---\n")
+
+	if source := strings.TrimRight(string(cell.Source), "\n"); source != "" {
+		sb.WriteString(source + "\n")
+	}
+
+	var files []tool.File
+
+	for j, out := range cell.Outputs {
+		text, file := renderOutput(out, fmt.Sprintf("cell-%d-output-%d", index+1, j+1))
+
+		if file != nil {
+			files = append(files, *file)
+		}
+
+		kind := out.OutputType
+
+		if out.Name != "" {
+			kind += ":" + out.Name
+		}
+
+		fmt.Fprintf(&sb, "--- output %d [%s] ---\n%s\n", j+1, kind, text)
+	}
+
+	return sb.String(), files
+}
+
+func renderOutput(out notebookOutput, name string) (string, *tool.File) {
+	switch out.OutputType {
+	case "stream":
+		return truncateOutput(string(out.Text)), nil
+
+	case "error":
+		text := out.EName + ": " + out.EValue
+
+		if len(out.Traceback) > 0 {
+			text = ansiRe.ReplaceAllString(strings.Join(out.Traceback, "\n"), "")
+		}
+
+		return truncateOutput(text), nil
+	}
+
+	for _, mime := range notebookImageTypes {
+		if data, ok := out.Data[mime]; ok {
+			ext := "." + strings.TrimPrefix(mime, "image/")
+			b64 := strings.Join(strings.Fields(string(data)), "")
+
+			file := &tool.File{
+				Name: name + ext,
+				Data: "data:" + mime + ";base64," + b64,
+			}
+
+			return fmt.Sprintf("[%s image: %s]", mime, file.Name), file
+		}
+	}
+
+	for _, mime := range notebookTextTypes {
+		if data, ok := out.Data[mime]; ok {
+			return truncateOutput(string(data)), nil
+		}
+	}
+
+	var types []string
+
+	for mime := range out.Data {
+		types = append(types, mime)
+	}
+
+	slices.Sort(types)
+
+	return fmt.Sprintf("[%s output not shown]", strings.Join(types, ", ")), nil
+}
+
+func truncateOutput(text string) string {
+	text = strings.TrimRight(text, "\n")
+
+	if len(text) <= maxNotebookOutput {
+		return text
+	}
+
+	return text[:maxNotebookOutput] + fmt.Sprintf("\n... (%d more bytes)", len(text)-maxNotebookOutput)
+}
+
+type notebookEditArgs struct {
+	Path      string  `json:"path" jsonschema:"Notebook path relative to the working directory"`
+	CellID    string  `json:"cell_id,omitempty" jsonschema:"ID of the cell to edit, as shown by read"`
+	NewSource *string `json:"new_source,omitempty" jsonschema:"New cell source (for replace and insert)"`
+	CellType  string  `json:"cell_type,omitempty" jsonschema:"Cell type. Required for insert; changes the type on replace."`
+	EditMode  string  `json:"edit_mode,omitempty" jsonschema:"Kind of edit (default: replace)"`
+}
+
+func NotebookEditTool(root *os.Root) tool.Tool {
+	return tool.Tool{
+		Name:   "notebook_edit",
+		Effect: tool.StaticEffect(tool.EffectMutates),
+
+		Description: strings.Join([]string{
+			"Replace, insert or delete a cell in a Jupyter notebook (.ipynb). Use this instead of `edit` or `write` for notebooks.",
+			"",
+			"Usage:",
+			"- Read the notebook first; `read` shows each cell's id.",
+			"- edit_mode=replace (default) replaces the source of cell_id. Outputs of a replaced code cell are cleared.",
+			"- edit_mode=insert adds a new cell of cell_type after cell_id, or at the top when cell_id is omitted.",
+			"- edit_mode=delete removes cell_id.",
+		}, "\n"),
+
+		Parameters: tool.Enum(tool.Enum(tool.Schema[notebookEditArgs](),
+			"cell_type", "code", "markdown", "raw"),
+			"edit_mode", "replace", "insert", "delete"),
+
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
+			a, err := tool.Decode[notebookEditArgs](args)
+
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			pathArg := a.Path
+
+			if pathArg == "" {
+				return tool.Result{}, fmt.Errorf("path is required")
+			}
+
+			if !isNotebook(pathArg) {
+				return tool.Result{}, fmt.Errorf("%s is not a notebook (.ipynb); use edit instead", pathArg)
+			}
+
+			workingDir := root.Name()
+
+			normalizedPath, err := ensurePathInWorkspace(pathArg, workingDir, "edit notebook")
+
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			cellID, cellType, mode := a.CellID, a.CellType, a.EditMode
+
+			var newSource string
+
+			if a.NewSource != nil {
+				newSource = *a.NewSource
+			}
+
+			if mode == "" {
+				mode = "replace"
+			}
+
+			if cellType != "" && cellType != "code" && cellType != "markdown" && cellType != "raw" {
+				return tool.Result{}, fmt.Errorf("invalid cell_type %q: must be code, markdown or raw", cellType)
+			}
+
+			data, err := root.ReadFile(normalizedPath)
+
+			if err != nil {
+				return tool.Result{}, pathError("read file", pathArg, normalizedPath, workingDir, err)
+			}
+
+			nb, err := parseNotebook(data)
+
+			if err != nil {
+				return tool.Result{}, fmt.Errorf("failed to parse notebook %s: %w", pathArg, err)
+			}
+
+			cells, _ := nb["cells"].([]any)
+
+			index := -1
+
+			if cellID != "" {
+				if index = findCell(cells, cellID); index < 0 {
+					return tool.Result{}, fmt.Errorf("cell %q not found in %s", cellID, pathArg)
+				}
+			}
+
+			var summary string
+
+			switch mode {
+			case "replace":
+				if index < 0 {
+					return tool.Result{}, fmt.Errorf("cell_id is required for replace")
+				}
+
+				if a.NewSource == nil {
+					return tool.Result{}, fmt.Errorf("new_source is required for replace")
+				}
+
+				cell, ok := cells[index].(map[string]any)
+
+				if !ok {
+					return tool.Result{}, fmt.Errorf("cell %q is malformed", cellID)
+				}
+
+				if cellType != "" {
+					cell["cell_type"] = cellType
+				}
+
+				cell["source"] = splitSource(newSource)
+				normalizeCell(cell)
+
+				summary = fmt.Sprintf("Replaced cell %s in %s.", cellID, pathArg)
+
+			case "insert":
+				if cellType == "" {
+					return tool.Result{}, fmt.Errorf("cell_type is required for insert")
+				}
+
+				cell := map[string]any{
+					"cell_type": cellType,
+					"metadata":  map[string]any{},
+					"source":    splitSource(newSource),
+				}
+
+				if notebookHasIDs(nb) {
+					cell["id"] = newCellID(cells)
+				}
+
+				normalizeCell(cell)
+
+				cells = slices.Insert(cells, index+1, any(cell))
+
+				id, _ := cell["id"].(string)
+				summary = fmt.Sprintf("Inserted %s cell %s at position %d in %s.", cellType, cellRef(id, index+1), index+2, pathArg)
+
+			case "delete":
+				if index < 0 {
+					return tool.Result{}, fmt.Errorf("cell_id is required for delete")
+				}
+
+				cells = slices.Delete(cells, index, index+1)
+
+				summary = fmt.Sprintf("Deleted cell %s from %s.", cellID, pathArg)
+
+			default:
+				return tool.Result{}, fmt.Errorf("invalid edit_mode %q: must be replace, insert or delete", mode)
+			}
+
+			nb["cells"] = cells
+
+			out, err := encodeNotebook(nb, data)
+
+			if err != nil {
+				return tool.Result{}, fmt.Errorf("failed to encode notebook: %w", err)
+			}
+
+			if err := root.WriteFile(normalizedPath, out, 0644); err != nil {
+				return tool.Result{}, pathError("write file", pathArg, normalizedPath, workingDir, err)
+			}
+
+			return tool.Result{
+				Text:    fmt.Sprintf("%s The notebook now has %d cells.", summary, len(cells)),
+				Changed: []string{normalizedPath},
+			}, nil
+		},
+	}
+}
+
+// parseNotebook decodes a notebook generically so fields the tool doesn't
+// know about survive an edit.
+func parseNotebook(data []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var nb map[string]any
+
+	if err := dec.Decode(&nb); err != nil {
+		return nil, err
+	}
+
+	if v, _ := nb["nbformat"].(json.Number); v.String() != "4" {
+		return nil, fmt.Errorf("unsupported nbformat %v (only version 4 is supported)", nb["nbformat"])
+	}
+
+	if _, ok := nb["cells"].([]any); !ok {
+		return nil, fmt.Errorf("notebook has no cells list")
+	}
+
+	return nb, nil
+}
+
+// encodeNotebook writes the notebook the way Jupyter does: sorted keys,
+// unescaped HTML, and the original file's indentation and trailing newline.
+func encodeNotebook(nb map[string]any, original []byte) ([]byte, error) {
+	indent := " "
+
+	if lines := bytes.SplitN(original, []byte("\n"), 3); len(lines) > 1 {
+		if trimmed := bytes.TrimLeft(lines[1], " \t"); len(trimmed) < len(lines[1]) {
+			indent = string(lines[1][:len(lines[1])-len(trimmed)])
+		}
+	}
+
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+
+	if err := enc.Encode(nb); err != nil {
+		return nil, err
+	}
+
+	out := buf.Bytes()
+
+	if !bytes.HasSuffix(original, []byte("\n")) {
+		out = bytes.TrimSuffix(out, []byte("\n"))
+	}
+
+	return out, nil
+}
+
+// findCell returns the index of the cell with the given ID, falling back to
+// the positional "cell-N" form for cells without one.
+func findCell(cells []any, id string) int {
+	for i, c := range cells {
+		if cell, ok := c.(map[string]any); ok && cell["id"] == id {
+			return i
+		}
+	}
+
+	if n, err := strconv.Atoi(strings.TrimPrefix(id, "cell-")); err == nil && n >= 1 && n <= len(cells) {
+		return n - 1
+	}
+
+	return -1
+}
+
+// notebookHasIDs reports whether cells carry IDs, which nbformat requires
+// from 4.5 on.
+func notebookHasIDs(nb map[string]any) bool {
+	if minor, ok := nb["nbformat_minor"].(json.Number); ok {
+		if n, err := minor.Int64(); err == nil && n >= 5 {
+			return true
+		}
+	}
+
+	cells, _ := nb["cells"].([]any)
+
+	for _, c := range cells {
+		if cell, ok := c.(map[string]any); ok && cell["id"] != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newCellID(cells []any) string {
+	for {
+		b := make([]byte, 4)
+		rand.Read(b)
+
+		id := hex.EncodeToString(b)
+
+		if findCell(cells, id) < 0 {
+			return id
+		}
+	}
+}
+
+// normalizeCell keeps the fields valid for the cell's type. Code cells get
+// fresh outputs since their source changed.
+func normalizeCell(cell map[string]any) {
+	if _, ok := cell["metadata"]; !ok {
+		cell["metadata"] = map[string]any{}
+	}
+
+	if cell["cell_type"] == "code" {
+		cell["outputs"] = []any{}
+		cell["execution_count"] = nil
+		delete(cell, "attachments")
+		return
+	}
+
+	delete(cell, "outputs")
+	delete(cell, "execution_count")
+}
+
+// splitSource splits text into lines that keep their newline, as Jupyter
+// stores cell source.
+func splitSource(text string) []string {
+	lines := strings.SplitAfter(normalizeToLF(text), "\n")
+
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
@@ -0,0 +1,725 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
+)
+
+type patchArgs struct {
+	Patch string `json:"patch" jsonschema:"The patch text, in unified diff or *** Begin Patch format"`
+}
+
+func PatchTool(root *os.Root) tool.Tool {
+	return tool.Tool{
+		Name:   "apply_patch",
+		Effect: tool.StaticEffect(tool.EffectMutates),
+
+		Description: strings.Join([]string{
+			"Applies a patch that adds, updates, deletes or moves any number of files in one atomic step. Either every change applies or nothing is written.",
+			"",
+			"Usage:",
+			"- Use for changes spanning several files or several places in one file (e.g. a rename across the codebase). For a single small change, `edit` is simpler.",
+			"- Accepts a unified diff (as produced by `git diff`) or this patch format:",
+			"",
+			"*** Begin Patch",
+			"*** Add File: path/to/new.go",
+			"+line of the new file",
+			"*** Update File: path/to/existing.go",
+			"*** Move to: path/to/renamed.go",
+			"@@ func Example",
+			" context line",
+			"-removed line",
+			"+added line",
+			"*** Delete File: path/to/old.go",
+			"*** End Patch",
+			"",
+			"- In updates, lines starting with a space are context, `-` removes and `+` adds. Include about 3 lines of context around each change so it can be located; `@@` may name an enclosing function or class to disambiguate.",
+			"- Hunk line numbers are optional. Whitespace and quote style differences in context lines are tolerated.",
+			"- If any hunk doesn't match, the error names it and no file is modified.",
+		}, "\n"),
+
+		Parameters: tool.Schema[patchArgs](),
+
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
+			a, err := tool.Decode[patchArgs](args)
+
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			if strings.TrimSpace(a.Patch) == "" {
+				return tool.Result{}, fmt.Errorf("patch is required")
+			}
+
+			ops, err := parsePatch(a.Patch)
+
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			if len(ops) == 0 {
+				return tool.Result{}, fmt.Errorf("patch contains no file changes")
+			}
+
+			changes := NewChangeset(root)
+			changes.normalize = true
+
+			plan := &patchPlan{root: root, changes: changes}
+
+			for _, op := range ops {
+				if err := plan.apply(op); err != nil {
+					return tool.Result{}, err
+				}
+			}
+
+			changed, err := changes.Commit()
+
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			return tool.Result{
+				Text:    plan.summary(),
+				Changed: changed,
+			}, nil
+		},
+	}
+}
+
+type patchKind int
+
+const (
+	patchUpdate patchKind = iota
+	patchAdd
+	patchDelete
+)
+
+// patchOp is one file change of a patch.
+type patchOp struct {
+	kind   patchKind
+	path   string
+	moveTo string
+
+	// lines is the content of an added file.
+	lines []string
+	hunks []patchHunk
+}
+
+// patchHunk is a run of context (' '), removed ('-') and added ('+')
+// lines. anchor (a line to find first) and line (the 1-based old start
+// line) only help locate it.
+type patchHunk struct {
+	anchor string
+	line   int
+	eof    bool
+
+	lines []patchLine
+}
+
+type patchLine struct {
+	op   byte
+	text string
+}
+
+// old returns the lines the hunk expects in the file.
+func (h patchHunk) old() []string {
+	var lines []string
+
+	for _, l := range h.lines {
+		if l.op != '+' {
+			lines = append(lines, l.text)
+		}
+	}
+
+	return lines
+}
+
+// new returns the lines the hunk leaves in the file.
+func (h patchHunk) new() []string {
+	var lines []string
+
+	for _, l := range h.lines {
+		if l.op != '-' {
+			lines = append(lines, l.text)
+		}
+	}
+
+	return lines
+}
+
+// replacement renders the new lines for a match. Context lines keep the
+// file's own text, which may differ from the patch in whitespace or quotes
+// when the match was fuzzy.
+func (h patchHunk) replacement(matched string) string {
+	original := strings.Split(strings.TrimSuffix(matched, "\n"), "\n")
+
+	var b strings.Builder
+	i := 0
+
+	for _, l := range h.lines {
+		switch l.op {
+		case ' ':
+			text := l.text
+			if i < len(original) {
+				text = original[i]
+			}
+			b.WriteString(text + "\n")
+			i++
+		case '-':
+			i++
+		case '+':
+			b.WriteString(l.text + "\n")
+		}
+	}
+
+	return b.String()
+}
+
+func parsePatch(patch string) ([]patchOp, error) {
+	patch = normalizeToLF(patch)
+	lines := strings.Split(strings.TrimRight(patch, "\n"), "\n")
+
+	for _, line := range lines {
+		if strings.HasPrefix(line, "*** Begin Patch") || strings.HasPrefix(line, "*** Update File:") ||
+			strings.HasPrefix(line, "*** Add File:") || strings.HasPrefix(line, "*** Delete File:") {
+			return parseV4A(lines)
+		}
+	}
+
+	return parseUnified(lines)
+}
+
+// parseV4A parses the "*** Begin Patch" format.
+func parseV4A(lines []string) ([]patchOp, error) {
+	var ops []patchOp
+	var op *patchOp
+	var hunk *patchHunk
+
+	flush := func() {
+		if op == nil {
+			return
+		}
+
+		if hunk != nil {
+			op.hunks = appendHunk(op.hunks, *hunk)
+		}
+
+		ops = append(ops, *op)
+		op, hunk = nil, nil
+	}
+
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "*** Begin Patch"):
+			continue
+
+		case strings.HasPrefix(line, "*** End Patch"):
+			flush()
+
+		case strings.HasPrefix(line, "*** Add File:"):
+			flush()
+			op = &patchOp{kind: patchAdd, path: strings.TrimSpace(strings.TrimPrefix(line, "*** Add File:"))}
+
+		case strings.HasPrefix(line, "*** Delete File:"):
+			flush()
+			op = &patchOp{kind: patchDelete, path: strings.TrimSpace(strings.TrimPrefix(line, "*** Delete File:"))}
+
+		case strings.HasPrefix(line, "*** Update File:"):
+			flush()
+			op = &patchOp{kind: patchUpdate, path: strings.TrimSpace(strings.TrimPrefix(line, "*** Update File:"))}
+
+		case op == nil:
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+
+			return nil, fmt.Errorf("line %d: expected a file header like \"*** Update File: path\", got %q", i+1, line)
+
+		case strings.HasPrefix(line, "*** Move to:"):
+			op.moveTo = strings.TrimSpace(strings.TrimPrefix(line, "*** Move to:"))
+
+		case strings.HasPrefix(line, "*** End of File"):
+			if hunk != nil {
+				hunk.eof = true
+			}
+
+		case op.kind == patchAdd:
+			if !strings.HasPrefix(line, "+") {
+				return nil, fmt.Errorf("line %d: lines of an added file must start with '+', got %q", i+1, line)
+			}
+
+			op.lines = append(op.lines, line[1:])
+
+		case op.kind == patchDelete:
+			if strings.TrimSpace(line) != "" {
+				return nil, fmt.Errorf("line %d: unexpected content after delete of %s", i+1, op.path)
+			}
+
+		case strings.HasPrefix(line, "@@"):
+			if hunk != nil {
+				op.hunks = appendHunk(op.hunks, *hunk)
+			}
+
+			hunk = &patchHunk{anchor: strings.TrimSpace(strings.TrimPrefix(line, "@@"))}
+
+		default:
+			if hunk == nil {
+				hunk = &patchHunk{}
+			}
+
+			if err := addHunkLine(hunk, line); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+		}
+	}
+
+	flush()
+
+	return ops, nil
+}
+
+// parseUnified parses unified diffs, including git's rename headers.
+func parseUnified(lines []string) ([]patchOp, error) {
+	var ops []patchOp
+	var op *patchOp
+	var hunk *patchHunk
+
+	var renameFrom, renameTo string
+
+	flushHunk := func() {
+		if op != nil && hunk != nil {
+			op.hunks = appendHunk(op.hunks, *hunk)
+		}
+
+		hunk = nil
+	}
+
+	flush := func() {
+		flushHunk()
+
+		if op != nil {
+			if op.kind == patchAdd {
+				for _, h := range op.hunks {
+					op.lines = append(op.lines, h.new()...)
+				}
+
+				op.hunks = nil
+			}
+
+			ops = append(ops, *op)
+		}
+
+		op = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+			renameFrom, renameTo = "", ""
+
+		case strings.HasPrefix(line, "rename from "):
+			renameFrom = strings.TrimPrefix(line, "rename from ")
+
+		case strings.HasPrefix(line, "rename to "):
+			renameTo = strings.TrimPrefix(line, "rename to ")
+
+			// A pure rename has no ---/+++ headers.
+			if i+1 >= len(lines) || !strings.HasPrefix(lines[i+1], "--- ") {
+				flush()
+				ops = append(ops, patchOp{kind: patchUpdate, path: renameFrom, moveTo: renameTo})
+				renameFrom, renameTo = "", ""
+			}
+
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			flush()
+
+			oldPath := diffPath(strings.TrimPrefix(line, "--- "), "a/")
+			newPath := diffPath(strings.TrimPrefix(lines[i+1], "+++ "), "b/")
+			i++
+
+			switch {
+			case oldPath == "" && newPath == "":
+				return nil, fmt.Errorf("line %d: diff header names no file", i)
+			case oldPath == "":
+				op = &patchOp{kind: patchAdd, path: newPath}
+			case newPath == "":
+				op = &patchOp{kind: patchDelete, path: oldPath}
+			default:
+				op = &patchOp{kind: patchUpdate, path: oldPath}
+
+				if newPath != oldPath {
+					op.moveTo = newPath
+				}
+			}
+
+			if renameFrom != "" && renameTo != "" && op.kind == patchUpdate {
+				op.path, op.moveTo = renameFrom, renameTo
+			}
+
+		case strings.HasPrefix(line, "@@"):
+			if op == nil {
+				return nil, fmt.Errorf("line %d: hunk without a ---/+++ file header", i+1)
+			}
+
+			flushHunk()
+			hunk = &patchHunk{line: hunkStart(line)}
+
+		case hunk != nil:
+			if strings.HasPrefix(line, `\`) {
+				continue
+			}
+
+			if err := addHunkLine(hunk, line); err != nil {
+				// Anything else ends the hunk (e.g. trailing commentary).
+				flushHunk()
+			}
+		}
+	}
+
+	flush()
+
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("no file changes found: expected a unified diff (---/+++ headers with @@ hunks) or a *** Begin Patch block")
+	}
+
+	return ops, nil
+}
+
+// appendHunk adds a non-empty hunk. Trailing blank context is dropped: it
+// is usually the separator before the next file rather than part of the
+// hunk, and context is optional anyway.
+func appendHunk(hunks []patchHunk, h patchHunk) []patchHunk {
+	for len(h.lines) > 0 && h.lines[len(h.lines)-1] == (patchLine{' ', ""}) {
+		h.lines = h.lines[:len(h.lines)-1]
+	}
+
+	if len(h.lines) == 0 {
+		return hunks
+	}
+
+	return append(hunks, h)
+}
+
+func addHunkLine(hunk *patchHunk, line string) error {
+	switch {
+	case line == "":
+		// Editors and models often strip the space of empty context lines.
+		hunk.lines = append(hunk.lines, patchLine{' ', ""})
+	case line[0] == ' ', line[0] == '-', line[0] == '+':
+		hunk.lines = append(hunk.lines, patchLine{line[0], line[1:]})
+	default:
+		return fmt.Errorf("hunk lines must start with ' ', '-' or '+', got %q", line)
+	}
+
+	return nil
+}
+
+// diffPath extracts the path from a ---/+++ header value.
+func diffPath(value, prefix string) string {
+	if i := strings.Index(value, "\t"); i >= 0 {
+		value = value[:i]
+	}
+
+	value = strings.TrimSpace(value)
+
+	if value == "/dev/null" {
+		return ""
+	}
+
+	return strings.TrimPrefix(value, prefix)
+}
+
+// hunkStart returns the old start line of a "@@ -l,s +l,s @@" header.
+func hunkStart(header string) int {
+	fields := strings.Fields(header)
+
+	if len(fields) < 2 || !strings.HasPrefix(fields[1], "-") {
+		return 0
+	}
+
+	start, _, _ := strings.Cut(fields[1][1:], ",")
+	n, _ := strconv.Atoi(start)
+
+	return n
+}
+
+// patchPlan stages patch operations in a changeset; nothing touches the
+// disk until every operation validated.
+type patchPlan struct {
+	root    *os.Root
+	changes *Changeset
+
+	notes []string
+}
+
+func (p *patchPlan) apply(op patchOp) error {
+	path, err := p.path(op.path, "patch file")
+
+	if err != nil {
+		return err
+	}
+
+	switch op.kind {
+	case patchAdd:
+		state, err := p.changes.Load(path, true)
+
+		if err != nil {
+			return err
+		}
+
+		if !state.Deleted {
+			return fmt.Errorf("cannot add %s: file already exists", op.path)
+		}
+
+		state.Deleted = false
+		state.Content = strings.Join(op.lines, "\n")
+
+		if len(op.lines) > 0 {
+			state.Content += "\n"
+		}
+
+		return nil
+
+	case patchDelete:
+		state, err := p.changes.Load(path, false)
+
+		if err != nil {
+			return err
+		}
+
+		state.Deleted = true
+		p.notes = append(p.notes, "Deleted "+filepath.ToSlash(path))
+
+		return nil
+	}
+
+	state, err := p.changes.Load(path, false)
+
+	if err != nil {
+		return err
+	}
+
+	if len(op.hunks) > 0 {
+		content, err := applyHunks(state.Content, op.hunks)
+
+		if err != nil {
+			return fmt.Errorf("failed to patch %s: %w", op.path, err)
+		}
+
+		state.Content = content
+	}
+
+	if op.moveTo == "" {
+		return nil
+	}
+
+	target, err := p.path(op.moveTo, "move file")
+
+	if err != nil {
+		return err
+	}
+
+	if err := p.changes.Move(path, target); err != nil {
+		return err
+	}
+
+	p.notes = append(p.notes, fmt.Sprintf("Moved %s → %s", filepath.ToSlash(path), filepath.ToSlash(target)))
+
+	return nil
+}
+
+// path validates a patch path and returns it relative to the root.
+func (p *patchPlan) path(pathArg, action string) (string, error) {
+	if pathArg == "" {
+		return "", fmt.Errorf("patch names a file without a path")
+	}
+
+	path, err := ensurePathInWorkspace(pathArg, p.root.Name(), action)
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Clean(path), nil
+}
+
+// summary lists a diff per written file, followed by deletes and moves.
+func (p *patchPlan) summary() string {
+	var b strings.Builder
+	changed := 0
+
+	for _, path := range p.changes.Paths() {
+		state := p.changes.File(path)
+
+		if state.Deleted || !state.Changed() {
+			continue
+		}
+
+		changed++
+		fmt.Fprintf(&b, "%s:\n%s\n", filepath.ToSlash(path), generateDiffString(state.Original, state.Content))
+	}
+
+	for _, note := range p.notes {
+		changed++
+		b.WriteString(note + "\n")
+	}
+
+	return fmt.Sprintf("Successfully applied patch (%d changes).\n\n%s", changed, strings.TrimRight(b.String(), "\n"))
+}
+
+// applyHunks applies the hunks in order. Each hunk's old lines are located
+// with fuzzyFindText at a line boundary after the previous hunk; the line
+// number or anchor of the hunk picks between several matches.
+func applyHunks(content string, hunks []patchHunk) (string, error) {
+	trailingNewline := content == "" || strings.HasSuffix(content, "\n")
+
+	work := content
+	if !trailingNewline {
+		work += "\n"
+	}
+
+	cursor := 0
+	delta := 0
+
+	for i, h := range hunks {
+		if h.anchor != "" {
+			if r := fuzzyFindText(work[cursor:], h.anchor); r.found {
+				end := cursor + r.index + r.matchLength
+
+				if nl := strings.IndexByte(work[end:], '\n'); nl >= 0 {
+					cursor = end + nl + 1
+				} else {
+					cursor = len(work)
+				}
+			}
+		}
+
+		oldLines, newLines := h.old(), h.new()
+		oldText := joinPatchLines(oldLines)
+
+		if oldText == "" {
+			pos := len(work)
+
+			switch {
+			case h.anchor != "":
+				pos = cursor
+			case h.line > 0:
+				// "@@ -5,0 +6,2 @@" inserts after old line 5.
+				pos = lineOffset(work, h.line+delta+1)
+			}
+
+			newText := joinPatchLines(newLines)
+
+			work = work[:pos] + newText + work[pos:]
+			cursor = pos + len(newText)
+			delta += len(newLines)
+
+			continue
+		}
+
+		matches := findLineMatches(work, oldText, cursor)
+
+		if len(matches) == 0 && cursor > 0 {
+			matches = findLineMatches(work, oldText, 0)
+		}
+
+		if len(matches) == 0 {
+			return "", fmt.Errorf("hunk %d: could not find these lines:\n%s", i+1, strings.TrimRight(oldText, "\n"))
+		}
+
+		m := matches[0]
+
+		switch {
+		case h.eof:
+			m = matches[len(matches)-1]
+
+		case h.line > 0:
+			want := lineOffset(work, h.line+delta)
+
+			for _, c := range matches[1:] {
+				if abs(c[0]-want) < abs(m[0]-want) {
+					m = c
+				}
+			}
+		}
+
+		newText := h.replacement(work[m[0] : m[0]+m[1]])
+
+		work = work[:m[0]] + newText + work[m[0]+m[1]:]
+		cursor = m[0] + len(newText)
+		delta += len(newLines) - len(oldLines)
+	}
+
+	if !trailingNewline && strings.HasSuffix(work, "\n") {
+		work = work[:len(work)-1]
+	}
+
+	return work, nil
+}
+
+// findLineMatches returns the [offset, length] of every match of text that
+// starts at a line boundary at or after from.
+func findLineMatches(content, text string, from int) [][2]int {
+	var matches [][2]int
+
+	for from <= len(content) && len(matches) < 100 {
+		r := fuzzyFindText(content[from:], text)
+
+		if !r.found {
+			break
+		}
+
+		pos := from + r.index
+
+		if pos == 0 || content[pos-1] == '\n' {
+			matches = append(matches, [2]int{pos, r.matchLength})
+		}
+
+		from = pos + 1
+	}
+
+	return matches
+}
+
+func joinPatchLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// lineOffset returns the byte offset of the 1-based line, clamped to the
+// content.
+func lineOffset(content string, line int) int {
+	offset := 0
+
+	for n := 1; n < line; n++ {
+		nl := strings.IndexByte(content[offset:], '\n')
+
+		if nl < 0 {
+			return len(content)
+		}
+
+		offset += nl + 1
+	}
+
+	return offset
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
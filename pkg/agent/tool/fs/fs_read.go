@@ -29,6 +29,9 @@ func ReadTool(root *os.Root, allowedReadRoots ...string) tool.Tool {
 			"- Read multiple files in parallel by calling this tool multiple times in one response.",
 			"- Prefer `grep` to locate relevant code before reading entire files. Often a single `grep` returns enough context that no `read` is needed.",
 			"- When editing text from read output, preserve the exact indentation as shown AFTER the line number prefix. Never include line numbers in old_text.",
+			"- Images (PNG, JPEG, GIF, WebP) are returned so you can see them. PDFs are returned as text per page; use pages for long documents.",
+			"- Jupyter notebooks (.ipynb) are shown as numbered cells with their outputs; offset and limit count cells. Change them with `notebook_edit`.",
+			"- The output ends with the file's hash. Pass it as expected_hash to `edit` for line-based edits.",
 		}, "\n"),
 
 		Parameters: map[string]any{
@@ -37,22 +40,39 @@ func ReadTool(root *os.Root, allowedReadRoots ...string) tool.Tool {
 				"path":   map[string]any{"type": "string", "description": "File path relative to the working directory, or an absolute path inside an allowed root (e.g. a discovered skill directory). Paths beginning with `~/` are expanded to the user's home directory."},
 				"offset": map[string]any{"type": "integer", "description": "Line number to start reading from (1-based)"},
 				"limit":  map[string]any{"type": "integer", "description": "Maximum number of lines to read. Only provide if the file is too large to read at once."},
+				"pages":  map[string]any{"type": "string", "description": fmt.Sprintf("PDF page range to read, e.g. \"3\" or \"1-5\" (at most %d pages)", maxPDFPages)},
 			},
 			"required": []string{"path"},
 		},
 
-		Execute: func(ctx context.Context, args map[string]any) (string, error) {
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 			pathArg, ok := args["path"].(string)
 
 			if !ok || pathArg == "" {
-				return "", fmt.Errorf("path is required")
+				return tool.Result{}, fmt.Errorf("path is required")
 			}
 
 			workingDir := root.Name()
 			expanded := expandHome(pathArg)
 
+			if isImage(expanded) || isPDF(expanded) {
+				content, err := readFromAllowedLocation(root, workingDir, expanded, allowedReadRoots)
+
+				if err != nil {
+					return tool.Result{}, err
+				}
+
+				if isImage(expanded) {
+					return readImage(pathArg, content)
+				}
+
+				pages, _ := args["pages"].(string)
+
+				return readPDF(ctx, pathArg, content, pages)
+			}
+
 			if isBinaryFile(expanded) {
-				return "", fmt.Errorf("cannot read %s: file appears to be binary (extension %q). Use the shell tool with an appropriate viewer if you really need to inspect it", pathArg, filepath.Ext(expanded))
+				return tool.Result{}, fmt.Errorf("cannot read %s: file appears to be binary (extension %q). Use the shell tool with an appropriate viewer if you really need to inspect it", pathArg, filepath.Ext(expanded))
 			}
 
 			limit := 0
@@ -68,10 +88,26 @@ func ReadTool(root *os.Root, allowedReadRoots ...string) tool.Tool {
 
 			content, err := readFromAllowedLocation(root, workingDir, expanded, allowedReadRoots)
 			if err != nil {
-				return "", err
+				return tool.Result{}, err
 			}
 
-			return formatRead(content, offset, limit)
+			if isNotebook(expanded) {
+				text, files, err := renderNotebook(content, offset, limit)
+
+				if err != nil {
+					return tool.Result{}, err
+				}
+
+				return tool.Result{Text: text, Files: files}, nil
+			}
+
+			text, err := formatRead(content, offset, limit)
+
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			return tool.Text(text), nil
 		},
 	}
 }
@@ -126,10 +162,13 @@ func expandHome(path string) string {
 	return path
 }
 
-// formatRead applies the truncation + line-numbering display logic.
+// formatRead applies the truncation + line-numbering display logic and
+// appends the file hash that line-based edits are checked against.
 func formatRead(content []byte, offset, limit int) (string, error) {
+	hash := fmt.Sprintf("\n\n[hash: %s]", fileHash(content))
+
 	if len(content) == 0 {
-		return "(empty file)", nil
+		return "(empty file)" + hash, nil
 	}
 
 	lines := strings.Split(string(content), "\n")
@@ -162,8 +201,8 @@ func formatRead(content []byte, offset, limit int) (string, error) {
 		notice := fmt.Sprintf("\n\n[Lines %d-%d of %d", offset+1, endLine, total)
 		notice += fmt.Sprintf(". Use offset=%d to continue]", endLine+1)
 
-		return output + notice, nil
+		return output + notice + hash, nil
 	}
 
-	return output, nil
+	return output + hash, nil
 }
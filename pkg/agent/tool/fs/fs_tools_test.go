@@ -1,11 +1,17 @@
 package fs
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"image"
+	"image/png"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -59,7 +65,7 @@ func TestReadTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if !strings.Contains(result, "line1") || !strings.Contains(result, "line5") {
+		if !strings.Contains(result.Text, "line1") || !strings.Contains(result.Text, "line5") {
 			t.Errorf("expected full content, got: %s", result)
 		}
 	})
@@ -74,11 +80,11 @@ func TestReadTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if strings.Contains(result, "line1") || strings.Contains(result, "line2") {
+		if strings.Contains(result.Text, "line1") || strings.Contains(result.Text, "line2") {
 			t.Errorf("offset should skip first lines, got: %s", result)
 		}
 
-		if !strings.Contains(result, "line3") {
+		if !strings.Contains(result.Text, "line3") {
 			t.Errorf("should contain line3, got: %s", result)
 		}
 	})
@@ -93,7 +99,7 @@ func TestReadTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if !strings.Contains(result, "line1") {
+		if !strings.Contains(result.Text, "line1") {
 			t.Errorf("should contain line1, got: %s", result)
 		}
 	})
@@ -131,17 +137,17 @@ func TestReadTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if !strings.Contains(result, "line1") {
+		if !strings.Contains(result.Text, "line1") {
 			t.Errorf("expected content, got: %s", result)
 		}
 	})
 
 	t.Run("read rejects binary files", func(t *testing.T) {
 		// Create a fake "binary" file by extension. We shouldn't even try to read it.
-		os.WriteFile(filepath.Join(tmpDir, "logo.png"), []byte("\x89PNG\r\n\x1a\n"), 0644)
+		os.WriteFile(filepath.Join(tmpDir, "archive.zip"), []byte("PK\x03\x04"), 0644)
 
 		_, err := readTool.Execute(context.Background(), map[string]any{
-			"path": "logo.png",
+			"path": "archive.zip",
 		})
 
 		if err == nil {
@@ -152,6 +158,91 @@ func TestReadTool(t *testing.T) {
 			t.Errorf("expected 'binary' in error, got: %v", err)
 		}
 	})
+
+	t.Run("read attaches images", func(t *testing.T) {
+		var buf bytes.Buffer
+		png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 3, 2)))
+		os.WriteFile(filepath.Join(tmpDir, "logo.png"), buf.Bytes(), 0644)
+
+		result, err := readTool.Execute(context.Background(), map[string]any{
+			"path": "logo.png",
+		})
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !strings.Contains(result.Text, "3x2") {
+			t.Errorf("expected image dimensions, got: %s", result)
+		}
+
+		if f := result.Files; len(f) != 1 || !strings.HasPrefix(f[0].Data, "data:image/png;base64,") {
+			t.Errorf("expected png attachment, got: %+v", f)
+		}
+	})
+
+	t.Run("read scales large images", func(t *testing.T) {
+		var buf bytes.Buffer
+		png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2500, 100)))
+		os.WriteFile(filepath.Join(tmpDir, "banner.png"), buf.Bytes(), 0644)
+
+		result, err := readTool.Execute(context.Background(), map[string]any{
+			"path": "banner.png",
+		})
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !strings.Contains(result.Text, "scaled to 2000x80") {
+			t.Errorf("expected scaled dimensions, got: %s", result)
+		}
+
+		data, _ := strings.CutPrefix(result.Files[0].Data, "data:image/png;base64,")
+		raw, _ := base64.StdEncoding.DecodeString(data)
+
+		if cfg, err := png.DecodeConfig(bytes.NewReader(raw)); err != nil || cfg.Width != 2000 || cfg.Height != 80 {
+			t.Errorf("expected a 2000x80 png, got %+v, %v", cfg, err)
+		}
+	})
+
+	t.Run("read attaches pdf without poppler", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+
+		os.WriteFile(filepath.Join(tmpDir, "spec.pdf"), []byte("%PDF-1.4\n%%EOF\n"), 0644)
+
+		result, err := readTool.Execute(context.Background(), map[string]any{
+			"path": "spec.pdf",
+		})
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !strings.Contains(result.Text, "attached") {
+			t.Errorf("expected attachment notice, got: %s", result)
+		}
+
+		if f := result.Files; len(f) != 1 || !strings.HasPrefix(f[0].Data, "data:application/pdf;base64,") || f[0].Name != "spec.pdf" {
+			t.Errorf("expected pdf attachment, got: %+v", f)
+		}
+	})
+
+	t.Run("parse pdf pages", func(t *testing.T) {
+		for pages, want := range map[string][2]int{"": {1, maxPDFPages}, "3": {3, 3}, "2-5": {2, 5}} {
+			first, last, err := parsePages(pages)
+
+			if err != nil || first != want[0] || last != want[1] {
+				t.Errorf("parsePages(%q) = %d, %d, %v; want %v", pages, first, last, err, want)
+			}
+		}
+
+		for _, pages := range []string{"0", "5-2", "x", "1-100"} {
+			if _, _, err := parsePages(pages); err == nil {
+				t.Errorf("parsePages(%q): expected error", pages)
+			}
+		}
+	})
 }
 
 func TestWriteTool(t *testing.T) {
@@ -170,7 +261,7 @@ func TestWriteTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if !strings.Contains(result, "Created") {
+		if !strings.Contains(result.Text, "Created") {
 			t.Errorf("expected 'Created' message, got: %s", result)
 		}
 
@@ -266,6 +357,30 @@ func TestEditTool(t *testing.T) {
 
 	editTool := EditTool(root)
 
+	t.Run("schema", func(t *testing.T) {
+		props := editTool.Parameters["properties"].(map[string]any)
+		items := props["edits"].(map[string]any)["items"].(map[string]any)
+		itemProps := items["properties"].(map[string]any)
+
+		for _, name := range []string{"old_text", "new_text", "replace_all", "start_line", "end_line", "insert_before_line", "insert_after_line"} {
+			if _, ok := props[name]; !ok {
+				t.Errorf("expected %s at the top level", name)
+			}
+
+			if _, ok := itemProps[name]; !ok {
+				t.Errorf("expected %s in edits items", name)
+			}
+		}
+
+		if required := editTool.Parameters["required"].([]string); !slices.Equal(required, []string{"path"}) {
+			t.Errorf("unexpected required fields: %v", required)
+		}
+
+		if required := items["required"].([]string); !slices.Equal(required, []string{"new_text"}) {
+			t.Errorf("unexpected required edit fields: %v", required)
+		}
+	})
+
 	t.Run("simple edit", func(t *testing.T) {
 		// Create test file
 		testFile := filepath.Join(tmpDir, "edit_test.txt")
@@ -289,7 +404,7 @@ func TestEditTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if !strings.Contains(result, "Successfully") {
+		if !strings.Contains(result.Text, "Successfully") {
 			t.Errorf("expected success message, got: %s", result)
 		}
 
@@ -385,6 +500,32 @@ func TestEditTool(t *testing.T) {
 		}
 	})
 
+	t.Run("replace_all leaves near matches of an exact match", func(t *testing.T) {
+		testFile := filepath.Join(tmpDir, "replace_all_test.txt")
+		os.WriteFile(testFile, []byte("say(\"hi\")\nsay(\u201chi\u201d)\nsay(\"hi\")\n"), 0644)
+
+		result, err := editTool.Execute(context.Background(), map[string]any{
+			"path":        "replace_all_test.txt",
+			"old_text":    `say("hi")`,
+			"new_text":    `say("bye")`,
+			"replace_all": true,
+		})
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !strings.Contains(result.Text, "replaced 2 occurrences") {
+			t.Errorf("expected the replacement count, got: %s", result.Text)
+		}
+
+		content, _ := os.ReadFile(testFile)
+
+		if want := "say(\"bye\")\nsay(\u201chi\u201d)\nsay(\"bye\")\n"; string(content) != want {
+			t.Errorf("expected %q, got %q", want, content)
+		}
+	})
+
 	t.Run("edit fails for no match", func(t *testing.T) {
 		testFile := filepath.Join(tmpDir, "nomatch_test.txt")
 		os.WriteFile(testFile, []byte("hello world"), 0644)
@@ -408,6 +549,554 @@ func TestEditTool(t *testing.T) {
 		}
 	})
 
+	readHash := func(t *testing.T, path string) string {
+		t.Helper()
+
+		result, err := ReadTool(root).Execute(context.Background(), map[string]any{
+			"path": path,
+		})
+
+		if err != nil {
+			t.Fatalf("unexpected read error: %v", err)
+		}
+
+		_, hash, ok := strings.Cut(result.Text, "[hash: ")
+
+		if !ok {
+			t.Fatalf("expected hash in read output, got: %s", result)
+		}
+
+		return strings.TrimSuffix(hash, "]")
+	}
+
+	t.Run("replace line range", func(t *testing.T) {
+		testFile := filepath.Join(tmpDir, "lines_test.txt")
+		os.WriteFile(testFile, []byte("one\ntwo\nthree\nfour\n"), 0644)
+
+		_, err := editTool.Execute(context.Background(), map[string]any{
+			"path":          "lines_test.txt",
+			"start_line":    float64(2),
+			"end_line":      float64(3),
+			"new_text":      "TWO",
+			"expected_hash": readHash(t, "lines_test.txt"),
+		})
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		content, _ := os.ReadFile(testFile)
+
+		if string(content) != "one\nTWO\nfour\n" {
+			t.Errorf("unexpected content: %q", content)
+		}
+	})
+
+	t.Run("line edits require hash", func(t *testing.T) {
+		testFile := filepath.Join(tmpDir, "nohash_test.txt")
+		os.WriteFile(testFile, []byte("one\ntwo\n"), 0644)
+
+		_, err := editTool.Execute(context.Background(), map[string]any{
+			"path":       "nohash_test.txt",
+			"start_line": float64(1),
+			"new_text":   "ONE",
+		})
+
+		if err == nil || !strings.Contains(err.Error(), "expected_hash") {
+			t.Errorf("expected missing hash error, got: %v", err)
+		}
+	})
+
+	t.Run("stale hash is rejected", func(t *testing.T) {
+		testFile := filepath.Join(tmpDir, "stale_test.txt")
+		os.WriteFile(testFile, []byte("one\ntwo\n"), 0644)
+
+		hash := readHash(t, "stale_test.txt")
+		os.WriteFile(testFile, []byte("one\nchanged\n"), 0644)
+
+		_, err := editTool.Execute(context.Background(), map[string]any{
+			"path":              "stale_test.txt",
+			"insert_after_line": float64(1),
+			"new_text":          "inserted",
+			"expected_hash":     hash,
+		})
+
+		if err == nil || !strings.Contains(err.Error(), "changed since it was read") {
+			t.Errorf("expected stale hash error, got: %v", err)
+		}
+
+		content, _ := os.ReadFile(testFile)
+
+		if string(content) != "one\nchanged\n" {
+			t.Errorf("file should be untouched, got: %q", content)
+		}
+	})
+
+	t.Run("insert before and after", func(t *testing.T) {
+		testFile := filepath.Join(tmpDir, "insert_test.txt")
+		os.WriteFile(testFile, []byte("a\nb"), 0644)
+
+		hash := readHash(t, "insert_test.txt")
+
+		result, err := editTool.Execute(context.Background(), map[string]any{
+			"path":          "insert_test.txt",
+			"expected_hash": hash,
+			"edits": []any{
+				map[string]any{"insert_after_line": float64(0), "new_text": "top"},
+				map[string]any{"insert_before_line": float64(2), "new_text": "mid"},
+				map[string]any{"insert_after_line": float64(2), "new_text": "end"},
+			},
+		})
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !strings.Contains(result.Text, "3 edits") {
+			t.Errorf("expected batch summary, got: %s", result)
+		}
+
+		content, _ := os.ReadFile(testFile)
+
+		if string(content) != "top\na\nmid\nb\nend" {
+			t.Errorf("unexpected content: %q", content)
+		}
+	})
+
+	t.Run("batch mixes text and line edits", func(t *testing.T) {
+		testFile := filepath.Join(tmpDir, "batch_test.txt")
+		os.WriteFile(testFile, []byte("func a() {}\nfunc b() {}\nfunc c() {}\n"), 0644)
+
+		_, err := editTool.Execute(context.Background(), map[string]any{
+			"path":          "batch_test.txt",
+			"expected_hash": readHash(t, "batch_test.txt"),
+			"edits": []any{
+				map[string]any{"old_text": "func a", "new_text": "func x"},
+				map[string]any{"start_line": float64(3), "new_text": ""},
+			},
+		})
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		content, _ := os.ReadFile(testFile)
+
+		if string(content) != "func x() {}\nfunc b() {}\n" {
+			t.Errorf("unexpected content: %q", content)
+		}
+	})
+
+	t.Run("failing batch edit leaves file untouched", func(t *testing.T) {
+		testFile := filepath.Join(tmpDir, "batchfail_test.txt")
+		os.WriteFile(testFile, []byte("one\ntwo\n"), 0644)
+
+		_, err := editTool.Execute(context.Background(), map[string]any{
+			"path": "batchfail_test.txt",
+			"edits": []any{
+				map[string]any{"old_text": "one", "new_text": "ONE"},
+				map[string]any{"old_text": "missing", "new_text": "x"},
+			},
+		})
+
+		if err == nil || !strings.Contains(err.Error(), "edit 2") {
+			t.Errorf("expected error for second edit, got: %v", err)
+		}
+
+		content, _ := os.ReadFile(testFile)
+
+		if string(content) != "one\ntwo\n" {
+			t.Errorf("file should be untouched, got: %q", content)
+		}
+	})
+
+	t.Run("overlapping edits are rejected", func(t *testing.T) {
+		testFile := filepath.Join(tmpDir, "overlap_test.txt")
+		os.WriteFile(testFile, []byte("one\ntwo\nthree\n"), 0644)
+
+		_, err := editTool.Execute(context.Background(), map[string]any{
+			"path":          "overlap_test.txt",
+			"expected_hash": readHash(t, "overlap_test.txt"),
+			"edits": []any{
+				map[string]any{"start_line": float64(1), "end_line": float64(2), "new_text": "x"},
+				map[string]any{"old_text": "two", "new_text": "y"},
+			},
+		})
+
+		if err == nil || !strings.Contains(err.Error(), "overlap") {
+			t.Errorf("expected overlap error, got: %v", err)
+		}
+	})
+}
+
+func TestPatchTool(t *testing.T) {
+	root, tmpDir, cleanup := createTestRoot(t)
+	defer cleanup()
+
+	patchTool := PatchTool(root)
+
+	readFile := func(name string) string {
+		data, _ := os.ReadFile(filepath.Join(tmpDir, name))
+		return string(data)
+	}
+
+	t.Run("v4a multi-file patch", func(t *testing.T) {
+		os.WriteFile(filepath.Join(tmpDir, "a.go"), []byte("package a\n\nfunc Old() {}\n\nfunc Other() {}\n"), 0644)
+		os.WriteFile(filepath.Join(tmpDir, "b.go"), []byte("package b\n\nvar _ = a.Old\n"), 0644)
+		os.WriteFile(filepath.Join(tmpDir, "gone.go"), []byte("package gone\n"), 0644)
+		os.WriteFile(filepath.Join(tmpDir, "move.go"), []byte("package move\n"), 0644)
+
+		patch := strings.Join([]string{
+			"*** Begin Patch",
+			"*** Update File: a.go",
+			"@@ package a",
+			"",
+			"-func Old() {}",
+			"+func New() {}",
+			"*** Update File: b.go",
+			"-var _ = a.Old",
+			"+var _ = a.New",
+			"*** Add File: sub/c.go",
+			"+package sub",
+			"*** Delete File: gone.go",
+			"*** Update File: move.go",
+			"*** Move to: moved/move.go",
+			"*** End Patch",
+		}, "\n")
+
+		result, err := patchTool.Execute(context.Background(), map[string]any{"patch": patch})
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got := readFile("a.go"); got != "package a\n\nfunc New() {}\n\nfunc Other() {}\n" {
+			t.Errorf("a.go = %q", got)
+		}
+
+		if got := readFile("b.go"); got != "package b\n\nvar _ = a.New\n" {
+			t.Errorf("b.go = %q", got)
+		}
+
+		if got := readFile("sub/c.go"); got != "package sub\n" {
+			t.Errorf("sub/c.go = %q", got)
+		}
+
+		if _, err := os.Stat(filepath.Join(tmpDir, "gone.go")); !os.IsNotExist(err) {
+			t.Error("gone.go was not deleted")
+		}
+
+		if got := readFile("moved/move.go"); got != "package move\n" {
+			t.Errorf("moved/move.go = %q", got)
+		}
+
+		for _, want := range []string{"a.go:\n-3 func Old() {}\n+3 func New() {}", "Deleted gone.go", "Moved move.go → moved/move.go"} {
+			if !strings.Contains(result.Text, want) {
+				t.Errorf("result missing %q:\n%s", want, result)
+			}
+		}
+	})
+
+	t.Run("unified diff with line hints", func(t *testing.T) {
+		os.WriteFile(filepath.Join(tmpDir, "dup.txt"), []byte("x\nsame\ny\nsame\nz\n"), 0644)
+
+		patch := strings.Join([]string{
+			"--- a/dup.txt",
+			"+++ b/dup.txt",
+			"@@ -4,1 +4,1 @@",
+			"-same",
+			"+changed",
+			"@@ -5,0 +6,1 @@",
+			"+end",
+		}, "\n")
+
+		if _, err := patchTool.Execute(context.Background(), map[string]any{"patch": patch}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got := readFile("dup.txt"); got != "x\nsame\ny\nchanged\nz\nend\n" {
+			t.Errorf("dup.txt = %q", got)
+		}
+	})
+
+	t.Run("preserves crlf and tolerates fuzzy context", func(t *testing.T) {
+		os.WriteFile(filepath.Join(tmpDir, "crlf.txt"), []byte("say \u201chi\u201d\r\nbye\r\n"), 0644)
+
+		patch := "*** Begin Patch\n*** Update File: crlf.txt\n say \"hi\"\n-bye\n+ciao\n*** End Patch"
+
+		if _, err := patchTool.Execute(context.Background(), map[string]any{"patch": patch}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got := readFile("crlf.txt"); got != "say \u201chi\u201d\r\nciao\r\n" {
+			t.Errorf("crlf.txt = %q", got)
+		}
+	})
+
+	t.Run("failed hunk leaves every file untouched", func(t *testing.T) {
+		os.WriteFile(filepath.Join(tmpDir, "one.txt"), []byte("one\n"), 0644)
+		os.WriteFile(filepath.Join(tmpDir, "two.txt"), []byte("two\n"), 0644)
+
+		patch := strings.Join([]string{
+			"*** Begin Patch",
+			"*** Update File: one.txt",
+			"-one",
+			"+ONE",
+			"*** Add File: three.txt",
+			"+three",
+			"*** Update File: two.txt",
+			"-missing",
+			"+TWO",
+			"*** End Patch",
+		}, "\n")
+
+		_, err := patchTool.Execute(context.Background(), map[string]any{"patch": patch})
+
+		if err == nil || !strings.Contains(err.Error(), "two.txt") {
+			t.Fatalf("expected error naming two.txt, got %v", err)
+		}
+
+		if got := readFile("one.txt"); got != "one\n" {
+			t.Errorf("one.txt was modified: %q", got)
+		}
+
+		if _, err := os.Stat(filepath.Join(tmpDir, "three.txt")); !os.IsNotExist(err) {
+			t.Error("three.txt was created despite failed patch")
+		}
+	})
+
+	t.Run("rejects paths outside workspace", func(t *testing.T) {
+		patch := "*** Begin Patch\n*** Add File: " + filepath.Join(filepath.Dir(tmpDir), "outside.txt") + "\n+x\n*** End Patch"
+
+		if _, err := patchTool.Execute(context.Background(), map[string]any{"patch": patch}); err == nil {
+			t.Error("expected error for path outside workspace")
+		}
+	})
+
+	t.Run("add fails for existing file", func(t *testing.T) {
+		os.WriteFile(filepath.Join(tmpDir, "exists.txt"), []byte("x\n"), 0644)
+
+		patch := "*** Begin Patch\n*** Add File: exists.txt\n+y\n*** End Patch"
+
+		if _, err := patchTool.Execute(context.Background(), map[string]any{"patch": patch}); err == nil {
+			t.Error("expected error when adding an existing file")
+		}
+	})
+}
+
+func TestChangesetRollback(t *testing.T) {
+	root, tmpDir, cleanup := createTestRoot(t)
+	defer cleanup()
+
+	os.WriteFile(filepath.Join(tmpDir, "first.txt"), []byte("one\n"), 0644)
+
+	changes := NewChangeset(root)
+
+	first, err := changes.Load("first.txt", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first.Content = "two\n"
+
+	second, err := changes.Load("second.txt", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second.Deleted = false
+	second.Content = "new\n"
+
+	// Something else creates a directory in the way before the commit.
+	os.MkdirAll(filepath.Join(tmpDir, "second.txt", "sub"), 0755)
+
+	if _, err := changes.Commit(); err == nil {
+		t.Fatal("expected error when a file cannot be written")
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, "first.txt")); string(data) != "one\n" {
+		t.Errorf("first.txt was not restored: %q", data)
+	}
+}
+
+const testNotebook = `{
+ "cells": [
+  {
+   "cell_type": "markdown",
+   "id": "intro",
+   "metadata": {},
+   "source": ["# Sales <2024>\n", "Quarterly numbers"]
+  },
+  {
+   "cell_type": "code",
+   "execution_count": 2,
+   "id": "plot",
+   "metadata": {"tags": ["keep"]},
+   "outputs": [
+    {"name": "stdout", "output_type": "stream", "text": ["total: 42\n"]},
+    {"data": {"image/png": "iVBORw0KGgo=\n", "text/plain": ["<Figure>"]}, "metadata": {}, "output_type": "display_data"}
+   ],
+   "source": ["print('total:', 42)\n", "plt.show()"]
+  }
+ ],
+ "metadata": {"kernelspec": {"display_name": "Python 3", "language": "python", "name": "python3"}},
+ "nbformat": 4,
+ "nbformat_minor": 5
+}
+`
+
+func TestNotebook(t *testing.T) {
+	root, tmpDir, cleanup := createTestRoot(t)
+	defer cleanup()
+
+	testFile := filepath.Join(tmpDir, "sales.ipynb")
+
+	t.Run("render cells and outputs", func(t *testing.T) {
+		text, files, err := renderNotebook([]byte(testNotebook), 0, 0)
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, want := range []string{"Notebook with 2 cells (Python 3)", "--- cell 1 [markdown] id=intro ---", "# Sales <2024>", "--- cell 2 [code] id=plot execution_count=2 ---", "total: 42", "[image/png image: cell-2-output-2.png]"} {
+			if !strings.Contains(text, want) {
+				t.Errorf("expected %q in output, got:\n%s", want, text)
+			}
+		}
+
+		if len(files) != 1 || files[0].Data != "data:image/png;base64,iVBORw0KGgo=" {
+			t.Errorf("expected one png attachment, got: %+v", files)
+		}
+	})
+
+	t.Run("read renders notebooks", func(t *testing.T) {
+		os.WriteFile(testFile, []byte(testNotebook), 0644)
+
+		result, err := ReadTool(root).Execute(context.Background(), map[string]any{
+			"path":   "sales.ipynb",
+			"offset": float64(2),
+		})
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if strings.Contains(result.Text, "cell 1 ") || !strings.Contains(result.Text, "cell 2 ") {
+			t.Errorf("expected only the second cell, got:\n%s", result)
+		}
+	})
+
+	t.Run("edit rejects notebooks", func(t *testing.T) {
+		_, err := EditTool(root).Execute(context.Background(), map[string]any{
+			"path":     "sales.ipynb",
+			"old_text": "42",
+			"new_text": "43",
+		})
+
+		if err == nil || !strings.Contains(err.Error(), "notebook_edit") {
+			t.Errorf("expected notebook error, got: %v", err)
+		}
+	})
+
+	notebookEdit := NotebookEditTool(root)
+
+	cells := func(t *testing.T) []map[string]any {
+		t.Helper()
+
+		data, _ := os.ReadFile(testFile)
+
+		var nb struct {
+			Cells []map[string]any `json:"cells"`
+		}
+
+		if err := json.Unmarshal(data, &nb); err != nil {
+			t.Fatalf("notebook is no longer valid JSON: %v\n%s", err, data)
+		}
+
+		return nb.Cells
+	}
+
+	t.Run("replace clears outputs and keeps metadata", func(t *testing.T) {
+		os.WriteFile(testFile, []byte(testNotebook), 0644)
+
+		_, err := notebookEdit.Execute(context.Background(), map[string]any{
+			"path":       "sales.ipynb",
+			"cell_id":    "plot",
+			"new_source": "print('total:', 43)\n",
+		})
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		cell := cells(t)[1]
+
+		if src := cell["source"].([]any); len(src) != 1 || src[0] != "print('total:', 43)\n" {
+			t.Errorf("unexpected source: %v", cell["source"])
+		}
+
+		if outputs := cell["outputs"].([]any); len(outputs) != 0 || cell["execution_count"] != nil {
+			t.Errorf("expected cleared outputs, got: %v", cell)
+		}
+
+		if cell["metadata"].(map[string]any)["tags"] == nil {
+			t.Errorf("expected metadata to survive, got: %v", cell["metadata"])
+		}
+
+		data, _ := os.ReadFile(testFile)
+
+		if !strings.Contains(string(data), "<2024>") || !strings.HasPrefix(string(data), "{\n \"cells\"") {
+			t.Errorf("expected Jupyter formatting, got:\n%s", data)
+		}
+	})
+
+	t.Run("insert and delete cells", func(t *testing.T) {
+		os.WriteFile(testFile, []byte(testNotebook), 0644)
+
+		result, err := notebookEdit.Execute(context.Background(), map[string]any{
+			"path":       "sales.ipynb",
+			"cell_id":    "intro",
+			"edit_mode":  "insert",
+			"cell_type":  "code",
+			"new_source": "import pandas as pd",
+		})
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		got := cells(t)
+
+		if len(got) != 3 || got[1]["cell_type"] != "code" || got[1]["id"] == nil || got[1]["outputs"] == nil {
+			t.Fatalf("expected a valid code cell at position 2, got: %v (%s)", got, result)
+		}
+
+		_, err = notebookEdit.Execute(context.Background(), map[string]any{
+			"path":      "sales.ipynb",
+			"cell_id":   "intro",
+			"edit_mode": "delete",
+		})
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got := cells(t); len(got) != 2 || got[0]["id"] == "intro" {
+			t.Errorf("expected intro to be deleted, got: %v", got)
+		}
+	})
+
+	t.Run("unknown cell", func(t *testing.T) {
+		_, err := notebookEdit.Execute(context.Background(), map[string]any{
+			"path":       "sales.ipynb",
+			"cell_id":    "missing",
+			"new_source": "x",
+		})
+
+		if err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("expected not found error, got: %v", err)
+		}
+	})
 }
 
 func TestLsTool(t *testing.T) {
@@ -430,11 +1119,11 @@ func TestLsTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if !strings.Contains(result, "file1.txt") {
+		if !strings.Contains(result.Text, "file1.txt") {
 			t.Errorf("expected file1.txt, got: %s", result)
 		}
 
-		if !strings.Contains(result, "subdir/") {
+		if !strings.Contains(result.Text, "subdir/") {
 			t.Errorf("expected subdir/ (with trailing slash), got: %s", result)
 		}
 	})
@@ -446,7 +1135,7 @@ func TestLsTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if !strings.Contains(result, ".hidden") {
+		if !strings.Contains(result.Text, ".hidden") {
 			t.Errorf("expected .hidden file, got: %s", result)
 		}
 	})
@@ -460,7 +1149,7 @@ func TestLsTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if !strings.Contains(result, "nested.txt") {
+		if !strings.Contains(result.Text, "nested.txt") {
 			t.Errorf("expected nested.txt, got: %s", result)
 		}
 	})
@@ -475,7 +1164,7 @@ func TestLsTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if !strings.Contains(result, "empty directory") {
+		if !strings.Contains(result.Text, "empty directory") {
 			t.Errorf("expected empty directory message, got: %s", result)
 		}
 	})
@@ -529,15 +1218,15 @@ func TestFindTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if !strings.Contains(result, "main.go") {
+		if !strings.Contains(result.Text, "main.go") {
 			t.Errorf("expected main.go, got: %s", result)
 		}
 
-		if !strings.Contains(result, "app.go") {
+		if !strings.Contains(result.Text, "app.go") {
 			t.Errorf("expected app.go, got: %s", result)
 		}
 
-		if !strings.Contains(result, "util.go") {
+		if !strings.Contains(result.Text, "util.go") {
 			t.Errorf("expected util.go, got: %s", result)
 		}
 	})
@@ -551,7 +1240,7 @@ func TestFindTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if strings.Contains(result, "node_modules") {
+		if strings.Contains(result.Text, "node_modules") {
 			t.Errorf("should not include node_modules, got: %s", result)
 		}
 	})
@@ -565,7 +1254,7 @@ func TestFindTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if strings.Contains(result, "debug.log") {
+		if strings.Contains(result.Text, "debug.log") {
 			t.Errorf("should respect gitignore and exclude .log files, got: %s", result)
 		}
 	})
@@ -580,11 +1269,11 @@ func TestFindTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if strings.Contains(result, "main.go") {
+		if strings.Contains(result.Text, "main.go") {
 			t.Errorf("should not include files outside src, got: %s", result)
 		}
 
-		if !strings.Contains(result, "app.go") {
+		if !strings.Contains(result.Text, "app.go") {
 			t.Errorf("expected app.go, got: %s", result)
 		}
 	})
@@ -598,7 +1287,7 @@ func TestFindTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if !strings.Contains(result, "No files found") {
+		if !strings.Contains(result.Text, "No files found") {
 			t.Errorf("expected 'No files found', got: %s", result)
 		}
 	})
@@ -613,11 +1302,11 @@ func TestFindTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if !strings.Contains(result, "main.go") {
+		if !strings.Contains(result.Text, "main.go") {
 			t.Errorf("expected main.go, got: %s", result)
 		}
 
-		if !strings.Contains(result, "app.go") {
+		if !strings.Contains(result.Text, "app.go") {
 			t.Errorf("expected app.go, got: %s", result)
 		}
 	})
@@ -650,16 +1339,16 @@ func TestFindTool(t *testing.T) {
 
 		// Newest 3 are the last three alphabetically: ar.tmp, as.tmp, at.tmp.
 		for _, want := range []string{"ar.tmp", "as.tmp", "at.tmp"} {
-			if !strings.Contains(result, want) {
+			if !strings.Contains(result.Text, want) {
 				t.Errorf("expected newest file %s in result, got: %s", want, result)
 			}
 		}
 		// Older files must be excluded.
-		if strings.Contains(result, "aa.tmp") || strings.Contains(result, "ab.tmp") {
+		if strings.Contains(result.Text, "aa.tmp") || strings.Contains(result.Text, "ab.tmp") {
 			t.Errorf("oldest files leaked in despite limit=3, got: %s", result)
 		}
 		// And the notice must surface that we truncated.
-		if !strings.Contains(result, "20 files found, showing newest 3") {
+		if !strings.Contains(result.Text, "20 files found, showing newest 3") {
 			t.Errorf("expected truncation notice, got: %s", result)
 		}
 	})
@@ -685,11 +1374,11 @@ func TestGrepTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if !strings.Contains(result, "file1.go") {
+		if !strings.Contains(result.Text, "file1.go") {
 			t.Errorf("expected file1.go in results, got: %s", result)
 		}
 
-		if !strings.Contains(result, "Hello") {
+		if !strings.Contains(result.Text, "Hello") {
 			t.Errorf("expected 'Hello' in results, got: %s", result)
 		}
 	})
@@ -703,7 +1392,7 @@ func TestGrepTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if !strings.Contains(result, "Hello()") || !strings.Contains(result, "World()") {
+		if !strings.Contains(result.Text, "Hello()") || !strings.Contains(result.Text, "World()") {
 			t.Errorf("expected function matches, got: %s", result)
 		}
 	})
@@ -718,7 +1407,7 @@ func TestGrepTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if !strings.Contains(result, "Hello") || !strings.Contains(result, "hello") {
+		if !strings.Contains(result.Text, "Hello") || !strings.Contains(result.Text, "hello") {
 			t.Errorf("expected case-insensitive matches, got: %s", result)
 		}
 	})
@@ -733,7 +1422,7 @@ func TestGrepTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if strings.Contains(result, "readme.md") {
+		if strings.Contains(result.Text, "readme.md") {
 			t.Errorf("should not include markdown files, got: %s", result)
 		}
 	})
@@ -748,11 +1437,11 @@ func TestGrepTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 		// Should include the match line
-		if !strings.Contains(result, "func Hello") {
+		if !strings.Contains(result.Text, "func Hello") {
 			t.Errorf("expected match line, got: %s", result)
 		}
 		// Context should include lines around the match
-		lines := strings.Split(result, "\n")
+		lines := strings.Split(result.Text, "\n")
 
 		if len(lines) < 2 {
 			t.Errorf("expected multiple lines with context, got: %s", result)
@@ -768,7 +1457,7 @@ func TestGrepTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if !strings.Contains(result, "No matches") {
+		if !strings.Contains(result.Text, "No matches") {
 			t.Errorf("expected 'No matches', got: %s", result)
 		}
 	})
@@ -783,11 +1472,11 @@ func TestGrepTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if !strings.Contains(result, "readme.md") {
+		if !strings.Contains(result.Text, "readme.md") {
 			t.Errorf("expected readme.md match, got: %s", result)
 		}
 
-		if strings.Contains(result, "file1.go") {
+		if strings.Contains(result.Text, "file1.go") {
 			t.Errorf("should only search single file, got: %s", result)
 		}
 	})
@@ -802,11 +1491,11 @@ func TestGrepTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if !strings.Contains(result, "file1.go") {
+		if !strings.Contains(result.Text, "file1.go") {
 			t.Errorf("expected file1.go in results, got: %s", result)
 		}
 
-		if !strings.Contains(result, "Hello") {
+		if !strings.Contains(result.Text, "Hello") {
 			t.Errorf("expected 'Hello' in results, got: %s", result)
 		}
 	})
@@ -825,7 +1514,7 @@ func TestGrepTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if !strings.Contains(result, "multi.go") || !strings.Contains(result, "field") {
+		if !strings.Contains(result.Text, "multi.go") || !strings.Contains(result.Text, "field") {
 			t.Errorf("expected multi.go and matched 'field' line, got: %s", result)
 		}
 
@@ -839,7 +1528,7 @@ func TestGrepTool(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if !strings.Contains(nonMulti, "No matches") {
+		if !strings.Contains(nonMulti.Text, "No matches") {
 			t.Errorf("non-multiline should not match across lines, got: %s", nonMulti)
 		}
 	})
@@ -874,7 +1563,7 @@ func TestPathHandlingCrossplatform(t *testing.T) {
 			t.Fatalf("unexpected error reading: %v", err)
 		}
 
-		if !strings.Contains(result, "test") {
+		if !strings.Contains(result.Text, "test") {
 			t.Errorf("expected content, got: %s", result)
 		}
 	})
@@ -899,7 +1588,7 @@ func TestTools(t *testing.T) {
 
 	tools := Tools(root)
 
-	expectedNames := []string{"read", "write", "edit", "ls", "find", "grep"}
+	expectedNames := []string{"read", "write", "edit", "apply_patch", "notebook_edit", "ls", "find", "grep"}
 
 	if len(tools) != len(expectedNames) {
 		t.Errorf("expected %d tools, got %d", len(expectedNames), len(tools))
@@ -959,7 +1648,7 @@ func TestFindSkipsSymlinks(t *testing.T) {
 	}
 
 	// Should find regular files but not follow the circular symlink infinitely
-	if !strings.Contains(result, "root.txt") && !strings.Contains(result, "file.txt") {
+	if !strings.Contains(result.Text, "root.txt") && !strings.Contains(result.Text, "file.txt") {
 		t.Errorf("expected txt files in results, got: %s", result)
 	}
 }
@@ -995,7 +1684,7 @@ func TestGrepSkipsSymlinks(t *testing.T) {
 	}
 
 	// Should find the match
-	if !strings.Contains(result, "searchme") {
+	if !strings.Contains(result.Text, "searchme") {
 		t.Errorf("expected 'searchme' in results, got: %s", result)
 	}
 }
@@ -11,9 +11,9 @@ import (
 	"strings"
 	"unicode/utf8"
 
-	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
-	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/adrianliechti/wingman-agent/pkg/text"
 
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
 )
 
 const (
@@ -316,44 +316,7 @@ func fuzzyFindText(content, oldText string) fuzzyMatchResult {
 }
 
 func generateDiffString(oldContent, newContent string) string {
-	dmp := diffmatchpatch.New()
-
-	// Create line-based diff for better readability
-	oldLines, newLines, lineArray := dmp.DiffLinesToChars(oldContent, newContent)
-	diffs := dmp.DiffMain(oldLines, newLines, false)
-	diffs = dmp.DiffCharsToLines(diffs, lineArray)
-	diffs = dmp.DiffCleanupSemantic(diffs)
-
-	var output strings.Builder
-	oldLineNum := 1
-	newLineNum := 1
-
-	for _, diff := range diffs {
-		lines := strings.Split(diff.Text, "\n")
-
-		// Remove empty last element from split if text ends with newline
-		if len(lines) > 0 && lines[len(lines)-1] == "" {
-			lines = lines[:len(lines)-1]
-		}
-
-		switch diff.Type {
-		case diffmatchpatch.DiffEqual:
-			oldLineNum += len(lines)
-			newLineNum += len(lines)
-		case diffmatchpatch.DiffDelete:
-			for _, line := range lines {
-				fmt.Fprintf(&output, "-%d %s\n", oldLineNum, line)
-				oldLineNum++
-			}
-		case diffmatchpatch.DiffInsert:
-			for _, line := range lines {
-				fmt.Fprintf(&output, "+%d %s\n", newLineNum, line)
-				newLineNum++
-			}
-		}
-	}
-
-	return output.String()
+	return text.LineDiff(oldContent, newContent)
 }
 
 // Common ignore directories that should be skipped during file traversal
@@ -453,10 +416,11 @@ func loadGitignore(fsys fs.FS, domain []string) []gitignore.Pattern {
 	return patterns
 }
 
-// walkWorkspace traverses files under root, respecting gitignore and default ignore dirs.
-// It skips symlinks and calls onFile for each non-ignored file.
+// WalkWorkspace traverses files under root, respecting gitignore and default ignore dirs.
+// It skips symlinks and calls onFile for each non-ignored file with its fsys
+// path and its slash-separated path relative to root.
 // Returning filepath.SkipAll from onFile stops traversal.
-func walkWorkspace(ctx context.Context, fsys fs.FS, root string, onFile func(path, relPath string) error) error {
+func WalkWorkspace(ctx context.Context, fsys fs.FS, root string, onFile func(path, relPath string) error) error {
 	var allPatterns []gitignore.Pattern
 	allPatterns = append(allPatterns, loadGitignore(fsys, nil)...)
 	matcher := gitignore.NewMatcher(allPatterns)
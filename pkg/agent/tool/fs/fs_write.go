@@ -35,11 +35,11 @@ func WriteTool(root *os.Root) tool.Tool {
 			"required": []string{"path", "content"},
 		},
 
-		Execute: func(ctx context.Context, args map[string]any) (string, error) {
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 			pathArg, ok := args["path"].(string)
 
 			if !ok || pathArg == "" {
-				return "", fmt.Errorf("path is required")
+				return tool.Result{}, fmt.Errorf("path is required")
 			}
 
 			workingDir := root.Name()
@@ -47,13 +47,13 @@ func WriteTool(root *os.Root) tool.Tool {
 			normalizedPath, err := ensurePathInWorkspace(pathArg, workingDir, "write file")
 
 			if err != nil {
-				return "", err
+				return tool.Result{}, err
 			}
 
 			content, ok := args["content"].(string)
 
 			if !ok {
-				return "", fmt.Errorf("content is required")
+				return tool.Result{}, fmt.Errorf("content is required")
 			}
 
 			// Check if file exists before writing (for create vs update reporting)
@@ -64,23 +64,23 @@ func WriteTool(root *os.Root) tool.Tool {
 
 			if dir != "." && dir != "" {
 				if err := root.MkdirAll(dir, 0755); err != nil {
-					return "", pathError("create directory", pathArg, normalizedPath, workingDir, err)
+					return tool.Result{}, pathError("create directory", pathArg, normalizedPath, workingDir, err)
 				}
 			}
 
 			file, err := root.Create(normalizedPath)
 
 			if err != nil {
-				return "", pathError("create file", pathArg, normalizedPath, workingDir, err)
+				return tool.Result{}, pathError("create file", pathArg, normalizedPath, workingDir, err)
 			}
 
 			if _, err := file.WriteString(content); err != nil {
 				file.Close()
-				return "", fmt.Errorf("failed to write file: %w", err)
+				return tool.Result{}, fmt.Errorf("failed to write file: %w", err)
 			}
 
 			if err := file.Close(); err != nil {
-				return "", fmt.Errorf("failed to close file: %w", err)
+				return tool.Result{}, fmt.Errorf("failed to close file: %w", err)
 			}
 
 			action := "Updated"
@@ -88,9 +88,10 @@ func WriteTool(root *os.Root) tool.Tool {
 				action = "Created"
 			}
 
-			result := fmt.Sprintf("%s %s (%d bytes)", action, pathArg, len(content))
-
-			return result, nil
+			return tool.Result{
+				Text:    fmt.Sprintf("%s %s (%d bytes)", action, pathArg, len(content)),
+				Changed: []string{normalizedPath},
+			}, nil
 		},
 	}
 }
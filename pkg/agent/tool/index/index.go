@@ -0,0 +1,87 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
+	"github.com/adrianliechti/wingman-agent/pkg/index"
+)
+
+const (
+	defaultLimit = 8
+	maxLimit     = 30
+)
+
+type searchArgs struct {
+	Query string `json:"query" jsonschema:"Natural language description of the code to find"`
+	Limit int    `json:"limit,omitempty" jsonschema:"Maximum number of results (default 8, max 30)"`
+}
+
+// Tools returns the semantic_search tool backed by idx.
+func Tools(idx *index.Index) []tool.Tool {
+	description := strings.Join([]string{
+		"Search the workspace by meaning using a semantic code index.",
+		"",
+		"Usage:",
+		"- Use for conceptual questions (\"where are auth tokens refreshed?\", \"how is retry handled?\") when you don't know the exact identifiers.",
+		"- Prefer grep for exact names, strings or regex patterns.",
+		"- Returns the best matching code chunks with their file path and line range; read the file for full context.",
+		"- The index is refreshed for changed files before each search.",
+	}, "\n")
+
+	return []tool.Tool{{
+		Name:        "semantic_search",
+		Description: description,
+		Effect:      tool.StaticEffect(tool.EffectReadOnly),
+
+		Parameters: tool.Schema[searchArgs](),
+
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
+			a, err := tool.Decode[searchArgs](args)
+
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			if strings.TrimSpace(a.Query) == "" {
+				return tool.Result{}, fmt.Errorf("query is required")
+			}
+
+			limit := defaultLimit
+
+			if a.Limit > 0 {
+				limit = min(a.Limit, maxLimit)
+			}
+
+			results, err := idx.Search(ctx, a.Query, limit)
+
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			if len(results) == 0 {
+				return tool.Text("No matches found."), nil
+			}
+
+			return tool.Text(formatResults(results)), nil
+		},
+	}}
+}
+
+func formatResults(results []index.Result) string {
+	var sb strings.Builder
+
+	for i, r := range results {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+
+		fmt.Fprintf(&sb, "%s:%d-%d (score %.2f)\n", r.Path, r.StartLine, r.EndLine, r.Score)
+		sb.WriteString(r.Content)
+		sb.WriteString("\n")
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
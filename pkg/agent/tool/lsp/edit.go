@@ -0,0 +1,210 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent/tool/fs"
+	"github.com/adrianliechti/wingman-agent/pkg/lsp"
+	"github.com/adrianliechti/wingman-agent/pkg/text"
+)
+
+// editPlan stages workspace edits in a changeset; nothing touches the disk
+// until every change validated.
+type editPlan struct {
+	root    *os.Root
+	changes *fs.Changeset
+
+	renames [][2]string
+}
+
+// applyWorkspaceEdits validates and applies workspace edits through the
+// sandboxed root. Either every file is updated or none is: if a write
+// fails, the files already written are restored. It returns a summary with
+// a line diff per changed file, and the paths written.
+func applyWorkspaceEdits(root *os.Root, edits ...lsp.WorkspaceEdit) (string, []string, error) {
+	plan := &editPlan{
+		root:    root,
+		changes: fs.NewChangeset(root),
+	}
+
+	for _, edit := range edits {
+		if err := plan.add(edit); err != nil {
+			return "", nil, err
+		}
+	}
+
+	changed, err := plan.changes.Commit()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var b strings.Builder
+	var written []string
+
+	for _, path := range changed {
+		state := plan.changes.File(path)
+
+		if state.Deleted {
+			fmt.Fprintf(&b, "Deleted %s\n\n", filepath.ToSlash(path))
+			continue
+		}
+
+		fmt.Fprintf(&b, "%s:\n%s\n", filepath.ToSlash(path), text.LineDiff(state.Original, state.Content))
+		written = append(written, path)
+	}
+
+	for _, r := range plan.renames {
+		fmt.Fprintf(&b, "Renamed %s → %s\n", filepath.ToSlash(r[0]), filepath.ToSlash(r[1]))
+	}
+
+	return strings.TrimRight(b.String(), "\n"), written, nil
+}
+
+// syncDocuments keeps the server's view of written files in sync with what
+// is now on disk.
+func syncDocuments(ctx context.Context, session *lsp.Session, root *os.Root, paths []string) {
+	for _, path := range paths {
+		session.OpenDocument(ctx, filepath.Join(root.Name(), path))
+	}
+}
+
+// MutatingTools lists the tools that change files through workspace edits.
+var MutatingTools = []string{
+	"rename_lsp_symbol",
+	"apply_lsp_code_action",
+	"format_lsp_document",
+}
+
+func (p *editPlan) add(edit lsp.WorkspaceEdit) error {
+	if len(edit.DocumentChanges) > 0 {
+		for _, change := range edit.DocumentChanges {
+			if err := p.addChange(change); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	uris := make([]string, 0, len(edit.Changes))
+	for uri := range edit.Changes {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+
+	for _, uri := range uris {
+		if err := p.edit(uri, edit.Changes[uri]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *editPlan) addChange(change lsp.DocumentChange) error {
+	if change.TextDocument != nil {
+		return p.edit(change.TextDocument.URI, change.Edits)
+	}
+
+	switch change.Kind {
+	case "create":
+		path, err := p.path(change.URI)
+		if err != nil {
+			return err
+		}
+
+		state, err := p.changes.Load(path, true)
+		if err != nil {
+			return err
+		}
+
+		if state.Deleted {
+			state.Deleted = false
+			state.Content = ""
+		}
+
+		return nil
+
+	case "rename":
+		oldPath, err := p.path(change.OldURI)
+		if err != nil {
+			return err
+		}
+
+		newPath, err := p.path(change.NewURI)
+		if err != nil {
+			return err
+		}
+
+		if err := p.changes.Move(oldPath, newPath); err != nil {
+			return err
+		}
+
+		p.renames = append(p.renames, [2]string{oldPath, newPath})
+		return nil
+
+	case "delete":
+		path, err := p.path(change.URI)
+		if err != nil {
+			return err
+		}
+
+		state, err := p.changes.Load(path, false)
+		if err != nil {
+			return err
+		}
+
+		state.Deleted = true
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported workspace change %q", change.Kind)
+	}
+}
+
+func (p *editPlan) edit(uri string, edits []lsp.TextEdit) error {
+	path, err := p.path(uri)
+	if err != nil {
+		return err
+	}
+
+	state, err := p.changes.Load(path, false)
+	if err != nil {
+		return err
+	}
+
+	content, err := lsp.ApplyTextEdits(state.Content, edits)
+	if err != nil {
+		return fmt.Errorf("failed to apply edits to %s: %w", path, err)
+	}
+
+	state.Content = content
+	return nil
+}
+
+// path maps a document URI to a path relative to the root, rejecting
+// anything outside of it.
+func (p *editPlan) path(uri string) (string, error) {
+	abs := lsp.URIToPath(uri)
+	base := p.root.Name()
+
+	rel, err := filepath.Rel(base, abs)
+
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		// The server may report symlink-resolved paths.
+		if resolved, rerr := filepath.EvalSymlinks(base); rerr == nil {
+			rel, err = filepath.Rel(resolved, abs)
+		}
+	}
+
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(rel) {
+		return "", fmt.Errorf("edit touches %s, which is outside the workspace", abs)
+	}
+
+	return rel, nil
+}
@@ -0,0 +1,113 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/adrianliechti/wingman-agent/pkg/lsp"
+)
+
+func TestApplyWorkspaceEdits(t *testing.T) {
+	dir := t.TempDir()
+
+	os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc Foo() {}\n"), 0644)
+	os.MkdirAll(filepath.Join(dir, "b"), 0755)
+	os.WriteFile(filepath.Join(dir, "b", "b.go"), []byte("package b\n\nvar _ = a.Foo\n"), 0644)
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+
+	rename := func(line, start, end int) lsp.TextEdit {
+		return lsp.TextEdit{
+			Range: lsp.Range{
+				Start: lsp.Position{Line: line, Character: start},
+				End:   lsp.Position{Line: line, Character: end},
+			},
+			NewText: "Bar",
+		}
+	}
+
+	edit := lsp.WorkspaceEdit{
+		Changes: map[string][]lsp.TextEdit{
+			lsp.FileURI(filepath.Join(dir, "a.go")):      {rename(2, 5, 8)},
+			lsp.FileURI(filepath.Join(dir, "b", "b.go")): {rename(2, 10, 13)},
+		},
+	}
+
+	_, written, err := applyWorkspaceEdits(root, edit)
+	if err != nil {
+		t.Fatalf("applyWorkspaceEdits: %v", err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dir, "a.go")); string(data) != "package a\n\nfunc Bar() {}\n" {
+		t.Errorf("a.go = %q", data)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dir, "b", "b.go")); string(data) != "package b\n\nvar _ = a.Bar\n" {
+		t.Errorf("b/b.go = %q", data)
+	}
+
+	want := []string{"a.go", filepath.Join("b", "b.go")}
+
+	if !slices.Equal(written, want) {
+		t.Errorf("written = %v, want %v", written, want)
+	}
+}
+
+func TestApplyWorkspaceEditsAtomic(t *testing.T) {
+	dir := t.TempDir()
+
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\n"), 0644)
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+
+	edit := lsp.WorkspaceEdit{
+		DocumentChanges: []lsp.DocumentChange{
+			{
+				TextDocument: &lsp.OptionalVersionedTextDocumentIdentifier{URI: lsp.FileURI(filepath.Join(dir, "a.txt"))},
+				Edits:        []lsp.TextEdit{{Range: lsp.Range{End: lsp.Position{Character: 3}}, NewText: "two"}},
+			},
+			{
+				TextDocument: &lsp.OptionalVersionedTextDocumentIdentifier{URI: lsp.FileURI(filepath.Join(dir, "missing.txt"))},
+				Edits:        []lsp.TextEdit{{NewText: "x"}},
+			},
+		},
+	}
+
+	if _, _, err := applyWorkspaceEdits(root, edit); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(data) != "one\n" {
+		t.Errorf("a.txt was modified despite failed edit: %q", data)
+	}
+}
+
+func TestApplyWorkspaceEditsOutsideRoot(t *testing.T) {
+	dir := t.TempDir()
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+
+	edit := lsp.WorkspaceEdit{
+		Changes: map[string][]lsp.TextEdit{
+			lsp.FileURI(filepath.Join(filepath.Dir(dir), "outside.txt")): {{NewText: "x"}},
+		},
+	}
+
+	if _, _, err := applyWorkspaceEdits(root, edit); err == nil {
+		t.Fatal("expected error for edit outside the workspace")
+	}
+}
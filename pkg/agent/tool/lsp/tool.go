@@ -10,8 +10,9 @@ import (
 	"github.com/adrianliechti/wingman-agent/pkg/lsp"
 )
 
-// NewTools creates the LSP tools for coding agents.
-func NewTools(manager *lsp.Manager) []tool.Tool {
+// NewTools creates the LSP tools for coding agents. Tools that change files
+// write through root.
+func NewTools(manager *lsp.Manager, root *os.Root) []tool.Tool {
 	return []tool.Tool{
 		diagnosticsTool(manager),
 		definitionTool(manager),
@@ -19,7 +20,13 @@ func NewTools(manager *lsp.Manager) []tool.Tool {
 		implementationTool(manager),
 		hoverTool(manager),
 		symbolsTool(manager),
+		typeDefinitionTool(manager),
+		signatureHelpTool(manager),
 		hierarchyTool(manager),
+		renameTool(manager, root),
+		codeActionsTool(manager),
+		applyCodeActionTool(manager, root),
+		formatTool(manager, root),
 	}
 }
 
@@ -37,30 +44,30 @@ func diagnosticsTool(manager *lsp.Manager) tool.Tool {
 				},
 			},
 		},
-		Execute: func(ctx context.Context, args map[string]any) (string, error) {
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 			path, _ := args["path"].(string)
 
 			if path == "" {
-				return manager.WorkspaceDiagnostics(ctx)
+				return textResult(manager.WorkspaceDiagnostics(ctx))
 			}
 
 			path = absPath(manager.WorkingDir(), path)
 
 			if _, err := os.Stat(path); os.IsNotExist(err) {
-				return "", fmt.Errorf("file not found: %s", path)
+				return tool.Result{}, fmt.Errorf("file not found: %s", path)
 			}
 
 			session, err := manager.GetSession(ctx, path)
 			if err != nil {
-				return "", err
+				return tool.Result{}, err
 			}
 
 			uri, err := session.OpenDocument(ctx, path)
 			if err != nil {
-				return "", err
+				return tool.Result{}, err
 			}
 
-			return session.Diagnostics(ctx, uri, path)
+			return textResult(session.Diagnostics(ctx, uri, path))
 		},
 	}
 }
@@ -71,18 +78,18 @@ func definitionTool(manager *lsp.Manager) tool.Tool {
 		Description: "Find the definition of a symbol at a given position.",
 		Effect:      tool.StaticEffect(tool.EffectReadOnly),
 		Parameters:  positionParams(),
-		Execute: func(ctx context.Context, args map[string]any) (string, error) {
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 			path, line, column, err := parsePositionArgs(manager.WorkingDir(), args)
 			if err != nil {
-				return "", err
+				return tool.Result{}, err
 			}
 
 			session, uri, err := openFile(ctx, manager, path)
 			if err != nil {
-				return "", err
+				return tool.Result{}, err
 			}
 
-			return session.Definition(ctx, uri, line, column)
+			return textResult(session.Definition(ctx, uri, line, column))
 		},
 	}
 }
@@ -93,18 +100,18 @@ func referencesTool(manager *lsp.Manager) tool.Tool {
 		Description: "Find all references to a symbol at a given position across the workspace.",
 		Effect:      tool.StaticEffect(tool.EffectReadOnly),
 		Parameters:  positionParams(),
-		Execute: func(ctx context.Context, args map[string]any) (string, error) {
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 			path, line, column, err := parsePositionArgs(manager.WorkingDir(), args)
 			if err != nil {
-				return "", err
+				return tool.Result{}, err
 			}
 
 			session, uri, err := openFile(ctx, manager, path)
 			if err != nil {
-				return "", err
+				return tool.Result{}, err
 			}
 
-			return session.References(ctx, uri, line, column)
+			return textResult(session.References(ctx, uri, line, column))
 		},
 	}
 }
@@ -115,18 +122,18 @@ func implementationTool(manager *lsp.Manager) tool.Tool {
 		Description: "Find implementations of an interface or abstract method at a given position.",
 		Effect:      tool.StaticEffect(tool.EffectReadOnly),
 		Parameters:  positionParams(),
-		Execute: func(ctx context.Context, args map[string]any) (string, error) {
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 			path, line, column, err := parsePositionArgs(manager.WorkingDir(), args)
 			if err != nil {
-				return "", err
+				return tool.Result{}, err
 			}
 
 			session, uri, err := openFile(ctx, manager, path)
 			if err != nil {
-				return "", err
+				return tool.Result{}, err
 			}
 
-			return session.Implementation(ctx, uri, line, column)
+			return textResult(session.Implementation(ctx, uri, line, column))
 		},
 	}
 }
@@ -137,18 +144,18 @@ func hoverTool(manager *lsp.Manager) tool.Tool {
 		Description: "Get hover information (type info, documentation) for a symbol at a given position.",
 		Effect:      tool.StaticEffect(tool.EffectReadOnly),
 		Parameters:  positionParams(),
-		Execute: func(ctx context.Context, args map[string]any) (string, error) {
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 			path, line, column, err := parsePositionArgs(manager.WorkingDir(), args)
 			if err != nil {
-				return "", err
+				return tool.Result{}, err
 			}
 
 			session, uri, err := openFile(ctx, manager, path)
 			if err != nil {
-				return "", err
+				return tool.Result{}, err
 			}
 
-			return session.Hover(ctx, uri, line, column)
+			return textResult(session.Hover(ctx, uri, line, column))
 		},
 	}
 }
@@ -156,7 +163,7 @@ func hoverTool(manager *lsp.Manager) tool.Tool {
 func symbolsTool(manager *lsp.Manager) tool.Tool {
 	return tool.Tool{
 		Name:        "find_lsp_symbols",
-		Description: "Get symbols. With a path: returns the symbol outline (functions, classes, variables) of that file. Without a path: searches symbols across the entire workspace by query.",
+		Description: "Get symbols. With a path: returns the symbol outline (functions, classes, variables) of that file. Without a path: finds symbols by name across the whole workspace without knowing which file defines them. Matching is fuzzy (e.g. \"NewSrv\" finds NewServer); best matches come first, and dependencies and vendored code are excluded.",
 		Effect:      tool.StaticEffect(tool.EffectReadOnly),
 		Parameters: map[string]any{
 			"type": "object",
@@ -167,35 +174,78 @@ func symbolsTool(manager *lsp.Manager) tool.Tool {
 				},
 				"query": map[string]any{
 					"type":        "string",
-					"description": "Search query for workspace-wide symbol search. Used when path is omitted.",
+					"description": "Symbol name or fuzzy abbreviation for workspace-wide symbol search. Used when path is omitted.",
+				},
+				"limit": map[string]any{
+					"type":        "integer",
+					"description": fmt.Sprintf("Maximum number of workspace results (default: %d)", lsp.DefaultSymbolLimit),
 				},
 			},
 		},
-		Execute: func(ctx context.Context, args map[string]any) (string, error) {
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 			path, _ := args["path"].(string)
 			query, _ := args["query"].(string)
 
 			if path == "" {
-				return manager.WorkspaceSymbols(ctx, query)
+				return textResult(manager.WorkspaceSymbols(ctx, query, intArg(args, "limit")))
 			}
 
 			path = absPath(manager.WorkingDir(), path)
 
 			if _, err := os.Stat(path); os.IsNotExist(err) {
-				return "", fmt.Errorf("file not found: %s", path)
+				return tool.Result{}, fmt.Errorf("file not found: %s", path)
 			}
 
-			session, err := manager.GetSession(ctx, path)
+			session, uri, err := openFile(ctx, manager, path)
 			if err != nil {
-				return "", err
+				return tool.Result{}, err
 			}
 
-			uri, err := session.OpenDocument(ctx, path)
+			return textResult(session.DocumentSymbols(ctx, uri, path))
+		},
+	}
+}
+
+func typeDefinitionTool(manager *lsp.Manager) tool.Tool {
+	return tool.Tool{
+		Name:        "find_lsp_type_definition",
+		Description: "Find the definition of the type of the symbol at a given position (e.g. the struct or class a variable holds).",
+		Effect:      tool.StaticEffect(tool.EffectReadOnly),
+		Parameters:  positionParams(),
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
+			path, line, column, err := parsePositionArgs(manager.WorkingDir(), args)
 			if err != nil {
-				return "", err
+				return tool.Result{}, err
 			}
 
-			return session.DocumentSymbols(ctx, uri, path)
+			session, uri, err := openFile(ctx, manager, path)
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			return textResult(session.TypeDefinition(ctx, uri, line, column))
+		},
+	}
+}
+
+func signatureHelpTool(manager *lsp.Manager) tool.Tool {
+	return tool.Tool{
+		Name:        "get_lsp_signature_help",
+		Description: "Get the signature (parameters, documentation) of the function or method being called at a given position. Place the position inside the call's parentheses.",
+		Effect:      tool.StaticEffect(tool.EffectReadOnly),
+		Parameters:  positionParams(),
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
+			path, line, column, err := parsePositionArgs(manager.WorkingDir(), args)
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			session, uri, err := openFile(ctx, manager, path)
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			return textResult(session.SignatureHelp(ctx, uri, line, column))
 		},
 	}
 }
@@ -228,29 +278,38 @@ func hierarchyTool(manager *lsp.Manager) tool.Tool {
 			},
 			"required": []string{"path", "line", "column", "direction"},
 		},
-		Execute: func(ctx context.Context, args map[string]any) (string, error) {
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 			path, line, column, err := parsePositionArgs(manager.WorkingDir(), args)
 			if err != nil {
-				return "", err
+				return tool.Result{}, err
 			}
 
 			direction, _ := args["direction"].(string)
 			if direction != "incoming" && direction != "outgoing" {
-				return "", fmt.Errorf("direction must be 'incoming' or 'outgoing'")
+				return tool.Result{}, fmt.Errorf("direction must be 'incoming' or 'outgoing'")
 			}
 
 			session, uri, err := openFile(ctx, manager, path)
 			if err != nil {
-				return "", err
+				return tool.Result{}, err
 			}
 
-			return session.CallHierarchy(ctx, uri, line, column, direction == "incoming")
+			return textResult(session.CallHierarchy(ctx, uri, line, column, direction == "incoming"))
 		},
 	}
 }
 
 // --- helpers ---
 
+// textResult wraps the text of a session query as a tool result.
+func textResult(text string, err error) (tool.Result, error) {
+	if err != nil {
+		return tool.Result{}, err
+	}
+
+	return tool.Text(text), nil
+}
+
 func positionParams() map[string]any {
 	return map[string]any{
 		"type": "object",
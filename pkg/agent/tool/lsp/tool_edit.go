@@ -0,0 +1,402 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
+	"github.com/adrianliechti/wingman-agent/pkg/lsp"
+)
+
+type renameArgs struct {
+	Path    string `json:"path" jsonschema:"File path relative to the working directory"`
+	Line    int    `json:"line" jsonschema:"Line number (0-based)"`
+	Column  int    `json:"column" jsonschema:"Column number (0-based)"`
+	NewName string `json:"new_name" jsonschema:"New name for the symbol"`
+}
+
+// rangeArgs is a line range of a file.
+type rangeArgs struct {
+	Path      string `json:"path" jsonschema:"File path relative to the working directory"`
+	StartLine int    `json:"start_line" jsonschema:"First line of the range (0-based)"`
+	EndLine   *int   `json:"end_line,omitempty" jsonschema:"Last line of the range (0-based, inclusive). Defaults to start_line."`
+}
+
+type applyCodeActionArgs struct {
+	rangeArgs
+
+	Title string `json:"title,omitempty" jsonschema:"Title of the code action to apply, as listed by get_lsp_code_actions"`
+	Index *int   `json:"index,omitempty" jsonschema:"Index of the code action to apply, as listed by get_lsp_code_actions. Used when title is omitted."`
+}
+
+type formatArgs struct {
+	Path      string `json:"path" jsonschema:"File path relative to the working directory"`
+	StartLine *int   `json:"start_line,omitempty" jsonschema:"First line to format (0-based). Omit to format the whole file."`
+	EndLine   *int   `json:"end_line,omitempty" jsonschema:"Last line to format (0-based, inclusive). Defaults to start_line."`
+}
+
+func renameTool(manager *lsp.Manager, root *os.Root) tool.Tool {
+	return tool.Tool{
+		Name:        "rename_lsp_symbol",
+		Description: "Rename the symbol at a given position across the workspace using the language server. Updates every reference and returns a diff per changed file.",
+		Effect:      tool.StaticEffect(tool.EffectMutates),
+		Parameters:  tool.Schema[renameArgs](),
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
+			a, err := tool.Decode[renameArgs](args)
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			path, err := existingPath(manager.WorkingDir(), a.Path)
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			if a.NewName == "" {
+				return tool.Result{}, fmt.Errorf("new_name is required")
+			}
+
+			session, uri, err := openFile(ctx, manager, path)
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			edit, err := session.Rename(ctx, uri, a.Line, a.Column, a.NewName)
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			if edit == nil {
+				return tool.Result{}, fmt.Errorf("no renameable symbol at this position")
+			}
+
+			diff, written, err := applyWorkspaceEdits(root, *edit)
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			syncDocuments(ctx, session, root, written)
+
+			if diff == "" {
+				return tool.Text("No changes made"), nil
+			}
+
+			return tool.Result{
+				Text:    fmt.Sprintf("Successfully renamed symbol to %s.\n\n%s", a.NewName, diff),
+				Changed: written,
+			}, nil
+		},
+	}
+}
+
+func codeActionsTool(manager *lsp.Manager) tool.Tool {
+	return tool.Tool{
+		Name:        "get_lsp_code_actions",
+		Description: "List the code actions (quick fixes, refactorings, source actions) the language server offers for a line range. Apply one with apply_lsp_code_action.",
+		Effect:      tool.StaticEffect(tool.EffectReadOnly),
+		Parameters:  tool.Schema[rangeArgs](),
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
+			a, err := tool.Decode[rangeArgs](args)
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			session, uri, rng, err := openRange(ctx, manager, a)
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			actions, err := session.CodeActions(ctx, uri, rng)
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			if len(actions) == 0 {
+				return tool.Text("No code actions available"), nil
+			}
+
+			var b strings.Builder
+			fmt.Fprintf(&b, "Code actions (%d found):\n", len(actions))
+
+			for i, a := range actions {
+				fmt.Fprintf(&b, "  %d. %s", i, a.Title)
+
+				if a.Kind != "" {
+					fmt.Fprintf(&b, " [%s]", a.Kind)
+				}
+
+				if a.IsPreferred {
+					b.WriteString(" (preferred)")
+				}
+
+				b.WriteString("\n")
+			}
+
+			return tool.Text(strings.TrimRight(b.String(), "\n")), nil
+		},
+	}
+}
+
+func applyCodeActionTool(manager *lsp.Manager, root *os.Root) tool.Tool {
+	return tool.Tool{
+		Name:        "apply_lsp_code_action",
+		Description: "Apply a code action (quick fix, refactoring, organize imports) offered by the language server for a line range. Returns a diff per changed file.",
+		Effect:      tool.StaticEffect(tool.EffectMutates),
+		Parameters:  tool.Schema[applyCodeActionArgs](),
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
+			a, err := tool.Decode[applyCodeActionArgs](args)
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			session, uri, rng, err := openRange(ctx, manager, a.rangeArgs)
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			actions, err := session.CodeActions(ctx, uri, rng)
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			action, err := selectCodeAction(actions, a.Title, a.Index)
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			if action.Edit == nil && action.Command == nil {
+				if resolved, err := session.ResolveCodeAction(ctx, action); err == nil {
+					action = resolved
+				}
+			}
+
+			var diffs, written []string
+
+			apply := func(edit lsp.WorkspaceEdit) error {
+				diff, files, err := applyWorkspaceEdits(root, edit)
+				if err != nil {
+					return err
+				}
+
+				if diff != "" {
+					diffs = append(diffs, diff)
+				}
+
+				written = append(written, files...)
+				return nil
+			}
+
+			// The edit goes first; commands then typically ask the client to
+			// apply their changes via workspace/applyEdit while they run.
+			if action.Edit != nil {
+				if err := apply(*action.Edit); err != nil {
+					return tool.Result{}, err
+				}
+			}
+
+			if action.Command != nil {
+				err := session.ExecuteCommand(ctx, *action.Command, apply)
+
+				if err != nil {
+					syncDocuments(ctx, session, root, written)
+					return tool.Result{}, fmt.Errorf("failed to execute command %s: %w", action.Command.Command, err)
+				}
+			}
+
+			syncDocuments(ctx, session, root, written)
+
+			diff := strings.Join(diffs, "\n")
+
+			if diff == "" {
+				return tool.Text(fmt.Sprintf("Applied %q. No files changed.", action.Title)), nil
+			}
+
+			return tool.Result{
+				Text:    fmt.Sprintf("Successfully applied %q.\n\n%s", action.Title, diff),
+				Changed: written,
+			}, nil
+		},
+	}
+}
+
+func formatTool(manager *lsp.Manager, root *os.Root) tool.Tool {
+	return tool.Tool{
+		Name:        "format_lsp_document",
+		Description: "Format a file, or a line range of it, using the language server's formatter. Returns the resulting diff.",
+		Effect:      tool.StaticEffect(tool.EffectMutates),
+		Parameters:  tool.Schema[formatArgs](),
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
+			a, err := tool.Decode[formatArgs](args)
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			path, err := existingPath(manager.WorkingDir(), a.Path)
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			session, uri, err := openFile(ctx, manager, path)
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			options := formattingOptions(path)
+
+			var textEdits []lsp.TextEdit
+
+			if a.StartLine != nil {
+				start := *a.StartLine
+				end := start
+
+				if a.EndLine != nil {
+					end = *a.EndLine
+				}
+
+				rng := lsp.Range{
+					Start: lsp.Position{Line: start},
+					End:   lsp.Position{Line: end + 1},
+				}
+
+				textEdits, err = session.FormatRange(ctx, uri, rng, options)
+			} else {
+				textEdits, err = session.Format(ctx, uri, options)
+			}
+
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			diff, written, err := applyWorkspaceEdits(root, lsp.WorkspaceEdit{
+				Changes: map[string][]lsp.TextEdit{uri: textEdits},
+			})
+
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			syncDocuments(ctx, session, root, written)
+
+			if diff == "" {
+				return tool.Text("File is already formatted"), nil
+			}
+
+			return tool.Result{
+				Text:    fmt.Sprintf("Successfully formatted %s.\n\n%s", a.Path, diff),
+				Changed: written,
+			}, nil
+		},
+	}
+}
+
+// --- helpers ---
+
+// existingPath resolves path against the working directory and checks that
+// the file exists.
+func existingPath(workingDir, path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("path is required")
+	}
+
+	path = absPath(workingDir, path)
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "", fmt.Errorf("file not found: %s", path)
+	}
+
+	return path, nil
+}
+
+func openRange(ctx context.Context, manager *lsp.Manager, a rangeArgs) (*lsp.Session, string, lsp.Range, error) {
+	path, err := existingPath(manager.WorkingDir(), a.Path)
+	if err != nil {
+		return nil, "", lsp.Range{}, err
+	}
+
+	start := a.StartLine
+	end := start
+
+	if a.EndLine != nil {
+		end = *a.EndLine
+	}
+
+	if end < start {
+		return nil, "", lsp.Range{}, fmt.Errorf("end_line must not be before start_line")
+	}
+
+	session, uri, err := openFile(ctx, manager, path)
+	if err != nil {
+		return nil, "", lsp.Range{}, err
+	}
+
+	rng := lsp.Range{
+		Start: lsp.Position{Line: start},
+		End:   lsp.Position{Line: end + 1},
+	}
+
+	return session, uri, rng, nil
+}
+
+func selectCodeAction(actions []lsp.CodeAction, title string, index *int) (lsp.CodeAction, error) {
+	if len(actions) == 0 {
+		return lsp.CodeAction{}, fmt.Errorf("no code actions available for this range")
+	}
+
+	if title != "" {
+		for _, a := range actions {
+			if a.Title == title {
+				return a, nil
+			}
+		}
+
+		for _, a := range actions {
+			if strings.EqualFold(a.Title, title) {
+				return a, nil
+			}
+		}
+
+		return lsp.CodeAction{}, fmt.Errorf("no code action titled %q. Use get_lsp_code_actions to list the available actions", title)
+	}
+
+	if index == nil {
+		return lsp.CodeAction{}, fmt.Errorf("title or index is required")
+	}
+
+	if *index < 0 || *index >= len(actions) {
+		return lsp.CodeAction{}, fmt.Errorf("index %d out of range (%d actions available)", *index, len(actions))
+	}
+
+	return actions[*index], nil
+}
+
+// formattingOptions guesses indentation from the file, since the formatter
+// honors the client's settings for languages without a canonical style.
+func formattingOptions(path string) lsp.FormattingOptions {
+	options := lsp.FormattingOptions{TabSize: 4, InsertSpaces: true}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return options
+	}
+
+	for line := range strings.SplitSeq(string(data), "\n") {
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			options.InsertSpaces = false
+			return options
+
+		case strings.HasPrefix(line, "  "):
+			indent := len(line) - len(strings.TrimLeft(line, " "))
+
+			if indent == 2 || indent == 4 {
+				options.TabSize = indent
+			}
+
+			return options
+		}
+	}
+
+	return options
+}
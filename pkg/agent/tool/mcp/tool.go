@@ -2,6 +2,7 @@ package mcp
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -76,13 +77,13 @@ func convertTool(serverName string, session *sdkmcp.ClientSession, mcpTool sdkmc
 
 		Parameters: params,
 
-		Execute: func(ctx context.Context, args map[string]any) (string, error) {
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 			return callTool(ctx, session, mcpTool.Name, args)
 		},
 	}
 }
 
-func callTool(ctx context.Context, session *sdkmcp.ClientSession, name string, args map[string]any) (string, error) {
+func callTool(ctx context.Context, session *sdkmcp.ClientSession, name string, args map[string]any) (tool.Result, error) {
 	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 
@@ -92,14 +93,31 @@ func callTool(ctx context.Context, session *sdkmcp.ClientSession, name string, a
 	})
 
 	if err != nil {
-		return "", fmt.Errorf("MCP tool call failed: %w", err)
+		return tool.Result{}, fmt.Errorf("MCP tool call failed: %w", err)
 	}
 
 	if result.IsError {
-		return "", fmt.Errorf("MCP tool returned error: %s", extractText(result.Content))
+		return tool.Result{}, fmt.Errorf("MCP tool returned error: %s", extractText(result.Content))
 	}
 
-	return extractText(result.Content), nil
+	return tool.Result{
+		Text:  extractText(result.Content),
+		Files: extractImages(result.Content),
+	}, nil
+}
+
+func extractImages(content []sdkmcp.Content) []tool.File {
+	var files []tool.File
+
+	for _, c := range content {
+		if image, ok := c.(*sdkmcp.ImageContent); ok && len(image.Data) > 0 {
+			files = append(files, tool.File{
+				Data: "data:" + image.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(image.Data),
+			})
+		}
+	}
+
+	return files
 }
 
 func extractText(content []sdkmcp.Content) string {
@@ -0,0 +1,391 @@
+package outline
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+)
+
+// Decl is a declaration found in a file. Lines are 1-based and inclusive;
+// Depth is the nesting level (e.g. 1 for a method inside a class).
+type Decl struct {
+	StartLine int
+	EndLine   int
+	Depth     int
+
+	Kind      string
+	Name      string
+	Signature string
+}
+
+// declKeywords maps keywords that introduce a declaration to its kind.
+var declKeywords = map[string]string{
+	"func":     "func",
+	"function": "func",
+	"def":      "func",
+	"fn":       "func",
+	"fun":      "func",
+	"sub":      "func",
+
+	"type":      "type",
+	"class":     "type",
+	"struct":    "type",
+	"interface": "type",
+	"enum":      "type",
+	"trait":     "type",
+	"impl":      "type",
+	"object":    "type",
+	"record":    "type",
+	"protocol":  "type",
+	"union":     "type",
+	"module":    "type",
+	"namespace": "type",
+}
+
+// controlKeywords can't start a declaration line; a call after them is not
+// a definition.
+var controlKeywords = map[string]bool{
+	"if": true, "else": true, "for": true, "foreach": true, "while": true, "do": true,
+	"switch": true, "case": true, "return": true, "throw": true, "new": true,
+	"await": true, "yield": true, "go": true, "defer": true, "delete": true,
+	"sizeof": true, "typeof": true, "catch": true, "using": true, "lock": true,
+}
+
+var identRe = regexp.MustCompile(`^[\p{L}_$][\p{L}\p{N}_$]*`)
+
+const (
+	maxSignatureLen  = 120
+	maxOpenLookahead = 20
+)
+
+// token is a lexer token confined to a single line.
+type token struct {
+	typ   chroma.TokenType
+	value string
+}
+
+type line struct {
+	tokens []token
+	text   string
+
+	depthStart int
+	depthEnd   int
+	opens      bool
+}
+
+// Extract returns the declarations in content, or ok=false when no lexer
+// matches the file name.
+func Extract(filename, content string) (decls []Decl, ok bool) {
+	lexer := lexers.Match(filename)
+
+	if lexer == nil {
+		lexer = lexers.Analyse(content)
+	}
+
+	if lexer == nil {
+		return nil, false
+	}
+
+	iter, err := lexer.Tokenise(nil, content)
+
+	if err != nil {
+		return nil, false
+	}
+
+	lines := splitLines(iter.Tokens())
+	indentBased := lexer.Config().Name == "Python"
+
+	type open struct {
+		index   int
+		typeish bool
+		body    int
+	}
+
+	var stack []open
+
+	for i := range lines {
+		for len(stack) > 0 && decls[stack[len(stack)-1].index].EndLine < i+1 {
+			stack = stack[:len(stack)-1]
+		}
+
+		inType := false
+
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			inType = top.typeish && (indentBased || lines[i].depthStart == top.body)
+		}
+
+		kind, name := declaration(lines[i].tokens, lines[i].depthStart == 0, inType)
+
+		if name == "" {
+			continue
+		}
+
+		end := i
+
+		if indentBased || strings.HasSuffix(strings.TrimSpace(lines[i].text), ":") {
+			end = indentEnd(lines, i)
+		} else {
+			end = braceEnd(lines, i)
+		}
+
+		decls = append(decls, Decl{
+			StartLine: i + 1,
+			EndLine:   end + 1,
+			Depth:     len(stack),
+
+			Kind:      kind,
+			Name:      name,
+			Signature: signature(lines[i].text),
+		})
+
+		stack = append(stack, open{
+			index:   len(decls) - 1,
+			typeish: kind == "type",
+			body:    lines[i].depthStart + 1,
+		})
+	}
+
+	return decls, true
+}
+
+// splitLines distributes tokens over source lines and tracks the brace depth
+// at each line, ignoring braces in comments and strings.
+func splitLines(tokens []chroma.Token) []line {
+	lines := []line{{}}
+	depth := 0
+
+	for _, t := range tokens {
+		parts := strings.Split(t.Value, "\n")
+
+		for j, part := range parts {
+			if j > 0 {
+				lines[len(lines)-1].depthEnd = depth
+				lines = append(lines, line{depthStart: depth})
+			}
+
+			if part == "" {
+				continue
+			}
+
+			cur := &lines[len(lines)-1]
+			cur.text += part
+
+			if strings.TrimSpace(part) != "" && !t.Type.InCategory(chroma.Comment) {
+				cur.tokens = append(cur.tokens, token{typ: t.Type, value: strings.TrimSpace(part)})
+			}
+
+			if t.Type.InCategory(chroma.Comment) || t.Type.InSubCategory(chroma.LiteralString) {
+				continue
+			}
+
+			for _, r := range part {
+				switch r {
+				case '{':
+					depth++
+					if depth > cur.depthStart {
+						cur.opens = true
+					}
+				case '}':
+					depth--
+				}
+			}
+		}
+	}
+
+	lines[len(lines)-1].depthEnd = depth
+
+	// A trailing newline leaves an empty last line that isn't part of the file.
+	if last := lines[len(lines)-1]; last.text == "" && len(lines) > 1 {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// declaration recognizes a declaration at the start of a line and returns
+// its kind and name. Keyword-introduced declarations (func, class, fn, def)
+// are recognized anywhere they're preceded only by modifiers; keyword-less
+// ones (C functions, Java and TypeScript methods) only at the top level or
+// directly inside a type body.
+func declaration(tokens []token, topLevel, inType bool) (string, string) {
+	for i, t := range tokens {
+		if !t.typ.InCategory(chroma.Keyword) {
+			break
+		}
+
+		kind, ok := declKeywords[strings.ToLower(t.value)]
+
+		if !ok {
+			if controlKeywords[t.value] {
+				return "", ""
+			}
+			continue
+		}
+
+		return kind, declName(tokens[i+1:])
+	}
+
+	if !topLevel && !inType {
+		return "", ""
+	}
+
+	for i, t := range tokens {
+		switch {
+		case t.typ.InCategory(chroma.Keyword):
+			if controlKeywords[t.value] {
+				return "", ""
+			}
+
+		case t.typ.InCategory(chroma.Name):
+			if i+1 >= len(tokens) || tokens[i+1].value != "(" {
+				continue
+			}
+
+			// Top-level calls in scripts have no return type or modifier
+			// in front; definitions in C-like languages do.
+			if !inType && (t.typ != chroma.NameFunction || i == 0) {
+				return "", ""
+			}
+
+			if name := identRe.FindString(t.value); name != "" {
+				return "func", name
+			}
+
+			return "", ""
+
+		case t.value == "*" || t.value == "&" || t.value == "::" || t.value == "<" || t.value == ">" || t.value == "[" || t.value == "]" || t.value == ",":
+
+		default:
+			return "", ""
+		}
+	}
+
+	return "", ""
+}
+
+// declName returns the declared name following a declaration keyword,
+// skipping a Go method receiver.
+func declName(tokens []token) string {
+	if len(tokens) > 0 && tokens[0].value == "(" {
+		depth := 0
+
+		for i, t := range tokens {
+			switch t.value {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			}
+
+			if depth == 0 {
+				tokens = tokens[i+1:]
+				break
+			}
+		}
+	}
+
+	for _, t := range tokens {
+		if !t.typ.InCategory(chroma.Name) {
+			if t.typ.InCategory(chroma.Keyword) {
+				continue
+			}
+			return ""
+		}
+
+		return identRe.FindString(t.value)
+	}
+
+	return ""
+}
+
+// braceEnd returns the last line of a declaration whose body is delimited
+// by braces, or start when it has no body. The opening brace may follow a
+// multi-line parameter list or sit on the next line by itself.
+func braceEnd(lines []line, start int) int {
+	base := lines[start].depthStart
+	parens := 0
+
+	for i := start; i < len(lines) && i < start+maxOpenLookahead; i++ {
+		if lines[i].opens {
+			for j := i; j < len(lines); j++ {
+				if lines[j].depthEnd <= base {
+					return j
+				}
+			}
+
+			return len(lines) - 1
+		}
+
+		for _, t := range lines[i].tokens {
+			switch t.value {
+			case "(", "[":
+				parens++
+			case ")", "]":
+				parens--
+			}
+		}
+
+		if parens > 0 {
+			continue
+		}
+
+		if i+1 < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i+1].text), "{") {
+			continue
+		}
+
+		break
+	}
+
+	return start
+}
+
+// indentEnd returns the last non-blank line indented deeper than start.
+func indentEnd(lines []line, start int) int {
+	indent := indentation(lines[start].text)
+	end := start
+
+	for i := start + 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i].text) == "" {
+			continue
+		}
+
+		if indentation(lines[i].text) <= indent {
+			break
+		}
+
+		end = i
+	}
+
+	return end
+}
+
+func indentation(s string) int {
+	n := 0
+
+	for _, r := range s {
+		switch r {
+		case ' ':
+			n++
+		case '\t':
+			n += 4
+		default:
+			return n
+		}
+	}
+
+	return n
+}
+
+func signature(text string) string {
+	s := strings.TrimSpace(text)
+	s = strings.TrimSpace(strings.TrimSuffix(s, "{"))
+
+	if r := []rune(s); len(r) > maxSignatureLen {
+		s = string(r[:maxSignatureLen]) + "…"
+	}
+
+	return s
+}
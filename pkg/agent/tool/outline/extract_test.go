@@ -0,0 +1,179 @@
+package outline
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func summarize(decls []Decl) string {
+	var lines []string
+
+	for _, d := range decls {
+		lines = append(lines, fmt.Sprintf("%s%s %s %d-%d", strings.Repeat("  ", d.Depth), d.Kind, d.Name, d.StartLine, d.EndLine))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		file    string
+		content string
+		want    string
+	}{
+		{
+			file: "a.go",
+			content: `package a
+
+// T holds "{" in a comment.
+type T struct {
+	X int
+}
+
+type ID string
+
+func (t *T) Method(a int) error {
+	if check(a) {
+		return nil
+	}
+	s := "}"
+	_ = s
+	return nil
+}
+
+func New(
+	x int,
+) *T {
+	fn := func() {}
+	fn()
+	return &T{X: x}
+}
+`,
+			want: `type T 4-6
+type ID 8-8
+func Method 10-17
+func New 19-25`,
+		},
+		{
+			file: "a.py",
+			content: `import os
+
+class Shape(Base):
+    def area(self):
+        return 0
+
+    async def draw(self):
+        render(self)
+
+
+def main():
+    print("hi")
+
+main()
+`,
+			want: `type Shape 3-8
+  func area 4-5
+  func draw 7-8
+func main 11-12`,
+		},
+		{
+			file: "A.java",
+			content: `package a;
+
+public class A {
+    private int x = 1;
+
+    public void foo(int x) {
+        bar(x);
+    }
+
+    static int baz() { return 1; }
+}
+`,
+			want: `type A 3-11
+  func foo 6-8
+  func baz 10-10`,
+		},
+		{
+			file: "a.ts",
+			content: `export class A {
+  foo(x: number): void {
+    bar(x);
+  }
+}
+
+export function f() {
+  return 1;
+}
+
+interface I {
+  a: string;
+}
+`,
+			want: `type A 1-5
+  func foo 2-4
+func f 7-9
+type I 11-13`,
+		},
+		{
+			file: "a.rs",
+			content: `pub struct S {
+    a: i32,
+}
+
+impl S {
+    pub fn new() -> Self {
+        S { a: 1 }
+    }
+}
+`,
+			want: `type S 1-3
+type S 5-9
+  func new 6-8`,
+		},
+		{
+			file: "a.c",
+			content: `#include <stdio.h>
+
+static int foo(int x)
+{
+    return bar(x);
+}
+
+int main(void) {
+    foo(1);
+}
+`,
+			want: `func foo 3-6
+func main 8-10`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			decls, ok := Extract(tt.file, tt.content)
+
+			if !ok {
+				t.Fatal("no lexer found")
+			}
+
+			if got := summarize(decls); got != tt.want {
+				t.Errorf("Extract() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatDecls(t *testing.T) {
+	decls := []Decl{
+		{StartLine: 1, EndLine: 5, Signature: "class A:"},
+		{StartLine: 2, EndLine: 2, Depth: 1, Signature: "def f(self): pass"},
+	}
+
+	want := "a.py (5 lines)\n1-5: class A:\n  2: def f(self): pass"
+
+	if got := formatDecls("a.py", 5, decls); got != want {
+		t.Errorf("formatDecls() = %q, want %q", got, want)
+	}
+}
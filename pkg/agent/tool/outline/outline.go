@@ -0,0 +1,145 @@
+package outline
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
+	"github.com/adrianliechti/wingman-agent/pkg/lsp"
+)
+
+type outlineArgs struct {
+	Path string `json:"path" jsonschema:"File path relative to the working directory"`
+}
+
+// Tools returns the outline tool. Files are read through root; manager
+// resolves the LSP manager at call time and may return nil.
+func Tools(root *os.Root, manager func() *lsp.Manager) []tool.Tool {
+	description := strings.Join([]string{
+		"Show the structure of a file: its functions, types, classes and methods with their line ranges.",
+		"",
+		"Usage:",
+		"- Use this before reading a large file to find the part you need, then read just that line range.",
+		"- Works for most languages from syntax alone; uses the language server for files the syntax outline can't handle.",
+		"- Nested declarations (e.g. methods in a class) are indented under their parent.",
+	}, "\n")
+
+	return []tool.Tool{{
+		Name:        "outline",
+		Description: description,
+		Effect:      tool.StaticEffect(tool.EffectReadOnly),
+
+		Parameters: tool.Schema[outlineArgs](),
+
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
+			a, err := tool.Decode[outlineArgs](args)
+
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			path := a.Path
+
+			if path == "" {
+				return tool.Result{}, fmt.Errorf("path is required")
+			}
+
+			rel, err := relPath(root.Name(), path)
+
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			data, err := root.ReadFile(rel)
+
+			if err != nil {
+				return tool.Result{}, fmt.Errorf("failed to read file %s: %w", path, err)
+			}
+
+			content := string(data)
+
+			if decls, ok := Extract(rel, content); ok && len(decls) > 0 {
+				return tool.Text(formatDecls(filepath.ToSlash(rel), strings.Count(content, "\n")+1, decls)), nil
+			}
+
+			if m := lspManager(manager); m != nil {
+				abs := filepath.Join(root.Name(), rel)
+
+				if m.FindServer(abs) != nil {
+					session, err := m.GetSession(ctx, abs)
+
+					if err != nil {
+						return tool.Result{}, err
+					}
+
+					uri, err := session.OpenDocument(ctx, abs)
+
+					if err != nil {
+						return tool.Result{}, err
+					}
+
+					text, err := session.DocumentSymbols(ctx, uri, abs)
+
+					if err != nil {
+						return tool.Result{}, err
+					}
+
+					return tool.Text(text), nil
+				}
+			}
+
+			return tool.Text("No declarations found."), nil
+		},
+	}}
+}
+
+func lspManager(manager func() *lsp.Manager) *lsp.Manager {
+	if manager == nil {
+		return nil
+	}
+
+	return manager()
+}
+
+// relPath maps path to a path relative to the workspace root, rejecting
+// anything outside of it.
+func relPath(rootPath, path string) (string, error) {
+	rel := filepath.Clean(path)
+
+	if filepath.IsAbs(rel) {
+		r, err := filepath.Rel(rootPath, rel)
+
+		if err != nil {
+			return "", fmt.Errorf("path is outside the workspace: %s", path)
+		}
+
+		rel = r
+	}
+
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("path is outside the workspace: %s", path)
+	}
+
+	return rel, nil
+}
+
+func formatDecls(path string, lines int, decls []Decl) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%s (%d lines)\n", path, lines)
+
+	for _, d := range decls {
+		sb.WriteString(strings.Repeat("  ", d.Depth))
+
+		if d.StartLine == d.EndLine {
+			fmt.Fprintf(&sb, "%d: %s\n", d.StartLine, d.Signature)
+		} else {
+			fmt.Fprintf(&sb, "%d-%d: %s\n", d.StartLine, d.EndLine, d.Signature)
+		}
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,87 @@
+package tool
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// Schema returns tool parameters for arguments that decode into the struct
+// T. Field descriptions come from jsonschema tags; fields without omitempty
+// are required. Unknown arguments are allowed and ignored, as with
+// parameters declared by hand. It panics if T has no JSON Schema, as
+// parameters are declared when the tools are built.
+func Schema[T any]() map[string]any {
+	s, err := jsonschema.For[T](nil)
+
+	if err != nil {
+		panic(fmt.Sprintf("tool: invalid parameters: %v", err))
+	}
+
+	data, err := json.Marshal(s)
+
+	if err != nil {
+		panic(fmt.Sprintf("tool: invalid parameters: %v", err))
+	}
+
+	var params map[string]any
+
+	if err := json.Unmarshal(data, &params); err != nil {
+		panic(fmt.Sprintf("tool: invalid parameters: %v", err))
+	}
+
+	allowAdditional(params)
+
+	return params
+}
+
+// Enum limits the named property of params to values and returns params.
+func Enum(params map[string]any, name string, values ...string) map[string]any {
+	properties, _ := params["properties"].(map[string]any)
+
+	if p, ok := properties[name].(map[string]any); ok {
+		p["enum"] = values
+	}
+
+	return params
+}
+
+// allowAdditional removes the additionalProperties: false that schemas
+// inferred from structs carry on every object.
+func allowAdditional(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		if v["additionalProperties"] == false {
+			delete(v, "additionalProperties")
+		}
+
+		for _, child := range v {
+			allowAdditional(child)
+		}
+
+	case []any:
+		for _, child := range v {
+			allowAdditional(child)
+		}
+	}
+}
+
+// Decode converts arguments into T, typically the struct given to Schema.
+// Arguments are validated against the parameters before a tool runs, so
+// this only fails if they were declared by hand.
+func Decode[T any](args map[string]any) (T, error) {
+	var result T
+
+	data, err := json.Marshal(args)
+
+	if err != nil {
+		return result, fmt.Errorf("failed to encode arguments: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &result); err != nil {
+		return result, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	return result, nil
+}
@@ -15,6 +15,10 @@ import (
 	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
 )
 
+type searchArgs struct {
+	Query string `json:"query" jsonschema:"The search query"`
+}
+
 func Tools() []tool.Tool {
 	description := strings.Join([]string{
 		"Search the web for information. Use this when the answer requires up-to-date information beyond the model's knowledge cutoff.",
@@ -31,33 +35,32 @@ func Tools() []tool.Tool {
 		Description: description,
 		Effect:      tool.StaticEffect(tool.EffectReadOnly),
 
-		Parameters: map[string]any{
-			"type": "object",
+		Parameters: tool.Schema[searchArgs](),
 
-			"properties": map[string]any{
-				"query": map[string]any{
-					"type":        "string",
-					"description": "The search query",
-				},
-			},
-
-			"required": []string{"query"},
-		},
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
+			a, err := tool.Decode[searchArgs](args)
 
-		Execute: func(ctx context.Context, args map[string]any) (string, error) {
-			query, ok := args["query"].(string)
+			if err != nil {
+				return tool.Result{}, err
+			}
 
-			if !ok || query == "" {
-				return "", fmt.Errorf("query is required")
+			if a.Query == "" {
+				return tool.Result{}, fmt.Errorf("query is required")
 			}
 
 			wingmanURL := os.Getenv("WINGMAN_URL")
 
 			if wingmanURL == "" {
-				return "", fmt.Errorf("search is not available: WINGMAN_URL is not configured")
+				return tool.Result{}, fmt.Errorf("search is not available: WINGMAN_URL is not configured")
+			}
+
+			text, err := searchWingman(ctx, wingmanURL, os.Getenv("WINGMAN_TOKEN"), a.Query)
+
+			if err != nil {
+				return tool.Result{}, err
 			}
 
-			return searchWingman(ctx, wingmanURL, os.Getenv("WINGMAN_TOKEN"), query)
+			return tool.Text(text), nil
 		},
 	}}
 }
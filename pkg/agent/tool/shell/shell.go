@@ -69,8 +69,14 @@ func Tools(workDir string, elicit *tool.Elicitation) []tool.Tool {
 			"required": []string{"command"},
 		},
 
-		Execute: func(ctx context.Context, args map[string]any) (string, error) {
-			return executeShell(ctx, workDir, elicit, args)
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
+			output, err := executeShell(ctx, workDir, elicit, args)
+
+			if err != nil {
+				return tool.Result{}, err
+			}
+
+			return tool.Text(output), nil
 		},
 	}}
 }
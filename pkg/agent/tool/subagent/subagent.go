@@ -30,6 +30,7 @@ func Tools(cfg *agent.Config) []tool.Tool {
 		"- Be specific: include file paths, function names, exact requirements, constraints, and desired output shape.",
 		"- Do not ask the agent to synthesize from another agent's findings. Do the synthesis yourself, then delegate a precise next task if needed.",
 		"- Bad: \"Find the bug.\" Good: \"In /src/api/handler.go, the CreateUser function returns 500 on duplicate emails. Find where the error is swallowed and suggest a fix.\"",
+		"- Pass output_schema when you need a typed result, e.g. a list of findings to process further. The answer is then returned as JSON matching it.",
 	}, "\n")
 
 	return []tool.Tool{{
@@ -45,19 +46,29 @@ func Tools(cfg *agent.Config) []tool.Tool {
 					"type":        "string",
 					"description": "A clear, self-contained task description for the agent. Include all necessary context since it has no access to the current conversation.",
 				},
+
+				"output_schema": map[string]any{
+					"type":        "object",
+					"description": "Optional JSON Schema the agent's final answer must match. The result is returned as JSON.",
+				},
 			},
 
 			"required": []string{"prompt"},
 		},
 
-		Execute: func(ctx context.Context, args map[string]any) (string, error) {
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 			prompt, ok := args["prompt"].(string)
 
 			if !ok || prompt == "" {
-				return "", fmt.Errorf("prompt is required")
+				return tool.Result{}, fmt.Errorf("prompt is required")
 			}
 
 			subcfg := cfg.Derive()
+
+			if cfg.SubagentModel != nil {
+				subcfg.Model = cfg.SubagentModel
+			}
+
 			subcfg.Instructions = func() string { return instructions }
 			subcfg.Tools = func() []tool.Tool {
 				if cfg.Tools == nil {
@@ -77,13 +88,17 @@ func Tools(cfg *agent.Config) []tool.Tool {
 				return filtered
 			}
 
+			if schema, ok := args["output_schema"].(map[string]any); ok && len(schema) > 0 {
+				subcfg.OutputSchema = &agent.OutputSchema{Schema: schema}
+			}
+
 			sub := &agent.Agent{Config: subcfg}
 
 			var result strings.Builder
 
 			for msg, err := range sub.Send(ctx, []agent.Content{{Text: prompt}}) {
 				if err != nil {
-					return "", fmt.Errorf("agent error: %w", err)
+					return tool.Result{}, fmt.Errorf("agent error: %w", err)
 				}
 
 				for _, c := range msg.Content {
@@ -93,13 +108,17 @@ func Tools(cfg *agent.Config) []tool.Tool {
 				}
 			}
 
+			if output := sub.Output(); output != nil {
+				return tool.Text(string(output)), nil
+			}
+
 			text := strings.TrimSpace(result.String())
 
 			if text == "" {
-				return "Sub-agent completed but produced no output.", nil
+				return tool.Text("Sub-agent completed but produced no output."), nil
 			}
 
-			return text, nil
+			return tool.Text(text), nil
 		},
 	}}
 }
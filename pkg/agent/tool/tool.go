@@ -23,11 +23,33 @@ type Tool struct {
 	Name        string
 	Description string
 	Parameters  map[string]any
-	Execute     func(ctx context.Context, args map[string]any) (string, error)
+	Execute     func(ctx context.Context, args map[string]any) (Result, error)
 	Hidden      bool
 	Effect      func(args map[string]any) Effect
 }
 
+// Result is the output of a tool call: text for the model, images or
+// documents attached to it, and the files it changed.
+type Result struct {
+	Text  string
+	Files []File
+
+	// Changed lists the paths, relative to the workspace, that the call
+	// wrote or deleted.
+	Changed []string
+}
+
+// Text returns a result with text only.
+func Text(text string) Result {
+	return Result{Text: text}
+}
+
+// File is an image or document a tool attaches to its text result.
+type File struct {
+	Name string
+	Data string // base64 data URL, e.g. "data:image/png;base64,..."
+}
+
 // ToolCall describes a pending tool invocation.
 type ToolCall struct {
 	ID   string `json:"id"`
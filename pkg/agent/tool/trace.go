@@ -0,0 +1,6 @@
+package tool
+
+// TruncatedKey is the span attribute telling whether a hook cut the result
+// of a tool call short. The agent sets it to false on every tool span; the
+// truncation hook sets it once it truncates.
+const TruncatedKey = "wingman.tool.truncated"
@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
+)
+
+// tracer reports turns, model calls and tool calls following the GenAI
+// semantic conventions. It uses the global provider, so spans are dropped
+// unless the program set one up, e.g. with telemetry.Setup.
+func tracer() trace.Tracer {
+	return otel.Tracer("github.com/adrianliechti/wingman-agent/pkg/agent")
+}
+
+// toolEffectKey is the effect of a tool call, e.g. "read_only".
+const toolEffectKey = attribute.Key("wingman.tool.effect")
+
+// startTurnSpan starts the span of a Send turn and returns a yield that
+// records errors on it.
+func startTurnSpan(ctx context.Context, model string, yield func(Message, error) bool) (context.Context, trace.Span, func(Message, error) bool) {
+	attrs := []attribute.KeyValue{semconv.GenAIOperationNameInvokeAgent}
+
+	if model != "" {
+		attrs = append(attrs, semconv.GenAIRequestModel(model))
+	}
+
+	ctx, span := tracer().Start(ctx, "invoke_agent", trace.WithSpanKind(trace.SpanKindInternal), trace.WithAttributes(attrs...))
+
+	return ctx, span, func(m Message, err error) bool {
+		if err != nil {
+			endSpanError(span, err)
+		}
+
+		return yield(m, err)
+	}
+}
+
+// endTurnSpan ends a turn span with the usage of the turn.
+func endTurnSpan(span trace.Span, usage Usage) {
+	span.SetAttributes(usageAttributes(usage)...)
+	span.End()
+}
+
+// startChatSpan starts the span of a model request.
+func (c *Config) startChatSpan(ctx context.Context, r *request) (context.Context, trace.Span) {
+	return tracer().Start(ctx, "chat "+r.model, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.GenAIOperationNameChat,
+		semconv.GenAIProviderNameKey.String(c.provider.name()),
+		semconv.GenAIRequestModel(r.model),
+	))
+}
+
+// endChatSpan ends a model request span with its usage or error.
+func endChatSpan(span trace.Span, resp *response, err error) {
+	if err != nil && !errors.Is(err, errYieldStopped) {
+		endSpanError(span, err)
+	}
+
+	if resp != nil {
+		span.SetAttributes(usageAttributes(resp.usage)...)
+	}
+
+	span.End()
+}
+
+// startToolSpan starts the span of a tool call.
+func startToolSpan(ctx context.Context, tc ToolCall, t *tool.Tool) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		semconv.GenAIOperationNameExecuteTool,
+		semconv.GenAIToolName(tc.Name),
+		semconv.GenAIToolCallID(tc.ID),
+		semconv.GenAIToolType("function"),
+		attribute.Key(tool.TruncatedKey).Bool(false),
+	}
+
+	if t != nil && t.Effect != nil {
+		args := make(map[string]any)
+		json.Unmarshal([]byte(tc.Args), &args)
+
+		attrs = append(attrs, toolEffectKey.String(string(t.Effect(args))))
+	}
+
+	return tracer().Start(ctx, "execute_tool "+tc.Name, trace.WithSpanKind(trace.SpanKindInternal), trace.WithAttributes(attrs...))
+}
+
+// endToolSpan ends a tool call span, marking error results as failed.
+func endToolSpan(span trace.Span, result string) {
+	if msg, ok := strings.CutPrefix(result, "error: "); ok {
+		span.SetStatus(codes.Error, msg)
+		span.SetAttributes(semconv.ErrorTypeKey.String("tool_error"))
+	}
+
+	span.End()
+}
+
+func endSpanError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+
+	if status, ok := apiStatus(err); ok {
+		span.SetAttributes(semconv.ErrorTypeKey.String(strconv.Itoa(status)))
+	} else {
+		span.SetAttributes(semconv.ErrorType(err))
+	}
+}
+
+func usageAttributes(u Usage) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.GenAIUsageInputTokens(int(u.InputTokens)),
+		semconv.GenAIUsageOutputTokens(int(u.OutputTokens)),
+		semconv.GenAIUsageCacheReadInputTokens(int(u.CachedTokens)),
+	}
+}
@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent/hook/truncation"
+	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	stub := &stubServer{}
+
+	srv := httptest.NewServer(stub.handler(t, "/v1/chat/completions",
+		[]string{
+			`{"id":"1","object":"chat.completion.chunk","model":"m","choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"echo","arguments":"{\"text\":\"a result longer than the truncation limit\"}"}},{"index":1,"id":"call_2","type":"function","function":{"name":"missing","arguments":"{}"}}]},"finish_reason":"tool_calls"}]}`,
+			`{"id":"1","object":"chat.completion.chunk","model":"m","choices":[],"usage":{"prompt_tokens":100,"completion_tokens":10,"prompt_tokens_details":{"cached_tokens":40}}}`,
+			`[DONE]`,
+		},
+		textReply("done"),
+	))
+	defer srv.Close()
+
+	cfg, err := NewConfig(&Options{Backend: BackendChat, BaseURL: srv.URL + "/v1", Token: "test"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	echo := echoTool()
+	echo.Effect = tool.StaticEffect(tool.EffectReadOnly)
+
+	cfg.Model = func() string { return "m" }
+	cfg.Tools = func() []tool.Tool { return []tool.Tool{echo} }
+	cfg.Hooks.PostToolUse = append(cfg.Hooks.PostToolUse, truncation.New(30, ""))
+
+	if _, err := sendText(t, &Agent{Config: cfg}); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	var names []string
+
+	for _, s := range recorder.Ended() {
+		spans[s.Name()] = s
+		names = append(names, s.Name())
+	}
+
+	if strings.Join(names, ",") != "chat m,execute_tool echo,execute_tool missing,chat m,invoke_agent" {
+		t.Fatalf("unexpected spans %v", names)
+	}
+
+	turn := spans["invoke_agent"]
+
+	for _, name := range names[:4] {
+		if s := spans[name]; s.Parent().SpanID() != turn.SpanContext().SpanID() {
+			t.Errorf("expected %s to be part of the turn", name)
+		}
+	}
+
+	attrs := func(s sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+		m := make(map[attribute.Key]attribute.Value)
+
+		for _, kv := range s.Attributes() {
+			m[kv.Key] = kv.Value
+		}
+
+		return m
+	}
+
+	if a := attrs(turn); a["gen_ai.operation.name"].AsString() != "invoke_agent" || a["gen_ai.usage.input_tokens"].AsInt64() != 100 || a["gen_ai.usage.cache_read.input_tokens"].AsInt64() != 40 {
+		t.Errorf("unexpected turn attributes %v", a)
+	}
+
+	if a := attrs(spans["execute_tool echo"]); a["gen_ai.tool.call.id"].AsString() != "call_1" || a["wingman.tool.effect"].AsString() != "read_only" || !a["wingman.tool.truncated"].AsBool() {
+		t.Errorf("unexpected tool attributes %v", a)
+	}
+
+	if s := spans["execute_tool missing"]; s.Status().Code != codes.Error {
+		t.Errorf("expected failed tool call to be an error, got %v", s.Status())
+	}
+}
+
+func TestTracingRecordsErrors(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	srv, _ := statusServer(t, map[string][]int{"m": {401}})
+
+	cfg, err := NewConfig(&Options{Backend: BackendChat, BaseURL: srv.URL + "/v1", Token: "test"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Model = func() string { return "m" }
+
+	if _, err := sendText(t, &Agent{Config: cfg}); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	for _, s := range recorder.Ended() {
+		if s.Status().Code != codes.Error {
+			t.Errorf("expected %s to fail, got %v", s.Name(), s.Status())
+		}
+
+		for _, kv := range s.Attributes() {
+			if kv.Key == "error.type" && kv.Value.AsString() != "401" {
+				t.Errorf("expected status as error type, got %s", kv.Value.AsString())
+			}
+		}
+	}
+
+	if len(recorder.Ended()) != 2 {
+		t.Errorf("expected turn and chat spans, got %d", len(recorder.Ended()))
+	}
+}
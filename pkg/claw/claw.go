@@ -25,12 +25,18 @@ import (
 	"github.com/adrianliechti/wingman-agent/pkg/claw/prompt"
 	"github.com/adrianliechti/wingman-agent/pkg/claw/tool/manage"
 	"github.com/adrianliechti/wingman-agent/pkg/claw/tool/schedule"
+	"github.com/adrianliechti/wingman-agent/pkg/code/diagnostics"
+	"github.com/adrianliechti/wingman-agent/pkg/lsp"
 )
 
 // managedAgent holds a registered agent and its per-agent resources.
 type managedAgent struct {
 	name  string
 	agent *agent.Agent
+
+	// lsp only starts language servers once the agent edits a source file,
+	// to report errors the edit introduced.
+	lsp *lsp.Manager
 }
 
 // Claw is the main orchestrator — the Go equivalent of nanoclaw's index.ts.
@@ -73,6 +79,11 @@ func (c *Claw) Run(ctx context.Context) error {
 
 	c.runCtx = ctx
 
+	defer c.agents.Range(func(_, v any) bool {
+		v.(*managedAgent).lsp.Close()
+		return true
+	})
+
 	// Start per-agent schedulers
 	c.agents.Range(func(k, v any) bool {
 		go c.startScheduler(ctx, k.(string), v.(*managedAgent))
@@ -125,7 +136,9 @@ func (c *Claw) DeleteAgent(name string) error {
 		return fmt.Errorf("cannot delete the main agent")
 	}
 
-	c.agents.LoadAndDelete(name)
+	if ma, ok := c.agents.LoadAndDelete(name); ok {
+		ma.(*managedAgent).lsp.Close()
+	}
 
 	return c.config.Memory.RemoveAgent(name)
 }
@@ -161,6 +174,15 @@ func (c *Claw) loadAgent(name string) (*managedAgent, error) {
 	// retrieve the elided middle.
 	scratchDir := filepath.Join(workDir, ".scratch")
 	_ = os.MkdirAll(scratchDir, 0755)
+	// Report errors introduced by file edits back to the model, ahead of
+	// truncation so they share its cap.
+	lspManager := lsp.NewManager(workDir)
+	diagnosticHooks := diagnostics.New(diagnostics.Sources{
+		LSP: func() *lsp.Manager { return lspManager },
+	})
+	cfg.Hooks.PreToolUse = append(cfg.Hooks.PreToolUse, diagnosticHooks.PreToolUse...)
+	cfg.Hooks.PostToolUse = append(cfg.Hooks.PostToolUse, diagnosticHooks.PostToolUse...)
+
 	cfg.Hooks.PostToolUse = append(cfg.Hooks.PostToolUse,
 		truncation.New(truncation.DefaultMaxBytes, scratchDir),
 	)
@@ -204,6 +226,8 @@ func (c *Claw) loadAgent(name string) (*managedAgent, error) {
 	ma := &managedAgent{
 		name:  name,
 		agent: a,
+
+		lsp: lspManager,
 	}
 	c.agents.Store(name, ma)
 	return ma, nil
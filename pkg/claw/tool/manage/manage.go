@@ -66,20 +66,20 @@ func Tools(mgr AgentManager, store *memory.Store) []tool.Tool {
 				},
 				"required": []string{"name"},
 			},
-			Execute: func(ctx context.Context, args map[string]any) (string, error) {
+			Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 				name, _ := args["name"].(string)
 				if name == "" {
-					return "", fmt.Errorf("name is required")
+					return tool.Result{}, fmt.Errorf("name is required")
 				}
 
 				if err := mgr.CreateAgent(name); err != nil {
-					return "", err
+					return tool.Result{}, err
 				}
 
 				// Write AGENTS.md
 				if instructions, ok := args["instructions"].(string); ok && instructions != "" {
 					if err := store.WriteAgent(name, instructions); err != nil {
-						return "", fmt.Errorf("agent created but failed to write AGENTS.md: %w", err)
+						return tool.Result{}, fmt.Errorf("agent created but failed to write AGENTS.md: %w", err)
 					}
 				}
 
@@ -111,7 +111,7 @@ func Tools(mgr AgentManager, store *memory.Store) []tool.Tool {
 					}
 
 					if err := schedule.SaveTasks(agentDir, tasks); err != nil {
-						return "", fmt.Errorf("agent created but failed to save tasks: %w", err)
+						return tool.Result{}, fmt.Errorf("agent created but failed to save tasks: %w", err)
 					}
 				}
 
@@ -127,7 +127,7 @@ func Tools(mgr AgentManager, store *memory.Store) []tool.Tool {
 					fmt.Fprintf(&result, "tasks.yaml: %d task(s) scheduled\n", len(taskList))
 				}
 
-				return result.String(), nil
+				return tool.Text(result.String()), nil
 			},
 		},
 		{
@@ -143,17 +143,17 @@ func Tools(mgr AgentManager, store *memory.Store) []tool.Tool {
 				},
 				"required": []string{"name"},
 			},
-			Execute: func(ctx context.Context, args map[string]any) (string, error) {
+			Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 				name, _ := args["name"].(string)
 				if name == "" {
-					return "", fmt.Errorf("name is required")
+					return tool.Result{}, fmt.Errorf("name is required")
 				}
 
 				if err := mgr.DeleteAgent(name); err != nil {
-					return "", err
+					return tool.Result{}, err
 				}
 
-				return fmt.Sprintf("Agent %q deleted.", name), nil
+				return tool.Text(fmt.Sprintf("Agent %q deleted.", name)), nil
 			},
 		},
 	}
@@ -56,20 +56,20 @@ func Tools(agentDir string) []tool.Tool {
 				},
 				"required": []string{"prompt", "schedule"},
 			},
-			Execute: func(ctx context.Context, args map[string]any) (string, error) {
+			Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 				prompt, _ := args["prompt"].(string)
 				sched, _ := args["schedule"].(string)
 
 				if prompt == "" {
-					return "", fmt.Errorf("prompt is required")
+					return tool.Result{}, fmt.Errorf("prompt is required")
 				}
 
 				if sched == "" {
-					return "", fmt.Errorf("schedule is required")
+					return tool.Result{}, fmt.Errorf("schedule is required")
 				}
 
 				if err := validateSchedule(sched); err != nil {
-					return "", err
+					return tool.Result{}, err
 				}
 
 				task := Task{
@@ -84,10 +84,10 @@ func Tools(agentDir string) []tool.Tool {
 				tasks = append(tasks, task)
 
 				if err := SaveTasks(agentDir, tasks); err != nil {
-					return "", err
+					return tool.Result{}, err
 				}
 
-				return fmt.Sprintf("Task %s scheduled (%s): %s", task.ID, sched, prompt), nil
+				return tool.Text(fmt.Sprintf("Task %s scheduled (%s): %s", task.ID, sched, prompt)), nil
 			},
 		},
 		{
@@ -97,11 +97,11 @@ func Tools(agentDir string) []tool.Tool {
 				"type":       "object",
 				"properties": map[string]any{},
 			},
-			Execute: func(ctx context.Context, args map[string]any) (string, error) {
+			Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 				tasks := LoadTasks(agentDir)
 
 				if len(tasks) == 0 {
-					return "No tasks scheduled.", nil
+					return tool.Text("No tasks scheduled."), nil
 				}
 
 				now := time.Now()
@@ -118,7 +118,7 @@ func Tools(agentDir string) []tool.Tool {
 						t.ID, t.Prompt, t.Schedule, t.Status, nextStr)
 				}
 
-				return b.String(), nil
+				return tool.Text(b.String()), nil
 			},
 		},
 		{
@@ -134,9 +134,15 @@ func Tools(agentDir string) []tool.Tool {
 				},
 				"required": []string{"id"},
 			},
-			Execute: func(ctx context.Context, args map[string]any) (string, error) {
+			Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 				id, _ := args["id"].(string)
-				return updateStatus(agentDir, id, "paused")
+				text, err := updateStatus(agentDir, id, "paused")
+
+				if err != nil {
+					return tool.Result{}, err
+				}
+
+				return tool.Text(text), nil
 			},
 		},
 		{
@@ -152,9 +158,15 @@ func Tools(agentDir string) []tool.Tool {
 				},
 				"required": []string{"id"},
 			},
-			Execute: func(ctx context.Context, args map[string]any) (string, error) {
+			Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 				id, _ := args["id"].(string)
-				return updateStatus(agentDir, id, "active")
+				text, err := updateStatus(agentDir, id, "active")
+
+				if err != nil {
+					return tool.Result{}, err
+				}
+
+				return tool.Text(text), nil
 			},
 		},
 		{
@@ -170,10 +182,10 @@ func Tools(agentDir string) []tool.Tool {
 				},
 				"required": []string{"id"},
 			},
-			Execute: func(ctx context.Context, args map[string]any) (string, error) {
+			Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 				id, _ := args["id"].(string)
 				if id == "" {
-					return "", fmt.Errorf("id is required")
+					return tool.Result{}, fmt.Errorf("id is required")
 				}
 
 				tasks := LoadTasks(agentDir)
@@ -186,14 +198,14 @@ func Tools(agentDir string) []tool.Tool {
 				}
 
 				if len(kept) == len(tasks) {
-					return "", fmt.Errorf("task %s not found", id)
+					return tool.Result{}, fmt.Errorf("task %s not found", id)
 				}
 
 				if err := SaveTasks(agentDir, kept); err != nil {
-					return "", err
+					return tool.Result{}, err
 				}
 
-				return fmt.Sprintf("Task %s removed.", id), nil
+				return tool.Text(fmt.Sprintf("Task %s removed.", id)), nil
 			},
 		},
 		{
@@ -209,10 +221,10 @@ func Tools(agentDir string) []tool.Tool {
 				},
 				"required": []string{"id"},
 			},
-			Execute: func(ctx context.Context, args map[string]any) (string, error) {
+			Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 				id, _ := args["id"].(string)
 				if id == "" {
-					return "", fmt.Errorf("id is required")
+					return tool.Result{}, fmt.Errorf("id is required")
 				}
 
 				tasks := LoadTasks(agentDir)
@@ -223,11 +235,11 @@ func Tools(agentDir string) []tool.Tool {
 						tasks[i].LastRun = &now
 						SaveTasks(agentDir, tasks)
 
-						return fmt.Sprintf("Task triggered. Execute now:\n\n%s", tasks[i].Prompt), nil
+						return tool.Text(fmt.Sprintf("Task triggered. Execute now:\n\n%s", tasks[i].Prompt)), nil
 					}
 				}
 
-				return "", fmt.Errorf("task %s not found", id)
+				return tool.Result{}, fmt.Errorf("task %s not found", id)
 			},
 		},
 	}
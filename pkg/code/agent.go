@@ -2,7 +2,6 @@ package code
 
 import (
 	"context"
-	"crypto/sha256"
 	"embed"
 	"fmt"
 	iofs "io/fs"
@@ -10,26 +9,35 @@ import (
 	"path/filepath"
 	"runtime"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/adrianliechti/wingman-agent/pkg/agent"
+	"github.com/adrianliechti/wingman-agent/pkg/agent/hook"
 	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
 	"github.com/adrianliechti/wingman-agent/pkg/agent/tool/ask"
 	"github.com/adrianliechti/wingman-agent/pkg/agent/tool/fetch"
 	"github.com/adrianliechti/wingman-agent/pkg/agent/tool/fs"
+	indextool "github.com/adrianliechti/wingman-agent/pkg/agent/tool/index"
 	lsptool "github.com/adrianliechti/wingman-agent/pkg/agent/tool/lsp"
 	toolmcp "github.com/adrianliechti/wingman-agent/pkg/agent/tool/mcp"
+	"github.com/adrianliechti/wingman-agent/pkg/agent/tool/outline"
 	"github.com/adrianliechti/wingman-agent/pkg/agent/tool/search"
 	"github.com/adrianliechti/wingman-agent/pkg/agent/tool/shell"
 	"github.com/adrianliechti/wingman-agent/pkg/agent/tool/subagent"
 	"github.com/adrianliechti/wingman-agent/pkg/code/bridge"
+	"github.com/adrianliechti/wingman-agent/pkg/code/diagnostics"
 	"github.com/adrianliechti/wingman-agent/pkg/code/prompt"
+	"github.com/adrianliechti/wingman-agent/pkg/code/repomap"
+	"github.com/adrianliechti/wingman-agent/pkg/index"
 	"github.com/adrianliechti/wingman-agent/pkg/lsp"
 	"github.com/adrianliechti/wingman-agent/pkg/mcp"
 	"github.com/adrianliechti/wingman-agent/pkg/rewind"
+	"github.com/adrianliechti/wingman-agent/pkg/session"
 	"github.com/adrianliechti/wingman-agent/pkg/skill"
+	"github.com/adrianliechti/wingman-agent/pkg/worktree"
 
 	"github.com/go-git/go-git/v5"
 )
@@ -39,7 +47,7 @@ import (
 // keeping the predicate in one place ensures both features agree on what
 // "this is a project" means.
 func isGitRepo(dir string) bool {
-	_, err := git.PlainOpen(dir)
+	_, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
 	return err == nil
 }
 
@@ -83,6 +91,13 @@ type UI interface {
 	StatusUpdate(status string)
 }
 
+// Options configures optional agent behavior. A nil *Options uses defaults.
+type Options struct {
+	// Worktree runs the session in a fresh git worktree on its own branch
+	// instead of editing the user's checkout in place.
+	Worktree bool
+}
+
 type Agent struct {
 	*agent.Agent
 
@@ -93,6 +108,9 @@ type Agent struct {
 
 	Skills []skill.Skill
 
+	// Settings is the merged user and project configuration.
+	Settings *Settings
+
 	MCP *mcp.Manager
 	// LSP is set by WarmUp when the workspace is a supported git repo;
 	// nil otherwise. Callers nil-check before use.
@@ -104,6 +122,21 @@ type Agent struct {
 	Rewind *rewind.Manager
 	Bridge *bridge.Bridge
 
+	// Index is the semantic code index, set when WINGMAN_EMBEDDING_MODEL is
+	// configured. WarmUp starts building it in the background.
+	Index       *index.Index
+	indexCancel context.CancelFunc
+
+	// RepoMap is the repository overview for the system prompt, set when
+	// WINGMAN_REPO_MAP is enabled. WarmUp builds it for git repos.
+	RepoMap *repomap.Map
+
+	// Worktree is set when the session runs in an isolated git worktree
+	// (Options.Worktree). Root, RootPath, rewind and LSP all point into it;
+	// MemoryPath stays keyed by the original checkout so sessions and memory
+	// are shared across worktrees of the same repo.
+	Worktree *worktree.Worktree
+
 	// warmupDone is closed when WarmUp completes (success or otherwise) so
 	// callers can await its readiness without polling.
 	warmupOnce sync.Once
@@ -115,20 +148,59 @@ type Agent struct {
 	mcpTools  []tool.Tool
 	lspTools  []tool.Tool
 
-	lastMemoryHash string
-	mu             sync.Mutex
+	// promptDate, promptRepoMap and promptMemory are the parts of the
+	// instructions that change on their own; see promptSnapshot.
+	promptDate    string
+	promptRepoMap string
+	promptMemory  string
+
+	titled map[string]bool
+	mu     sync.Mutex
 }
 
-func New(workDir string, ui UI) (*Agent, error) {
+func New(workDir string, ui UI, options *Options) (*Agent, error) {
+	if options == nil {
+		options = new(Options)
+	}
+
 	agentCfg, err := agent.DefaultConfig()
 
 	if err != nil {
 		return nil, err
 	}
 
+	settings, err := LoadSettings(workDir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	settings.apply(agentCfg)
+
+	memoryDir := projectMemoryDir(workDir)
+
+	var wt *worktree.Worktree
+
+	if options.Worktree {
+		wt, err = worktree.Create(context.Background(), workDir, filepath.Join(filepath.Dir(memoryDir), "worktrees"))
+
+		if err != nil {
+			return nil, err
+		}
+
+		workDir = wt.Path
+	}
+
+	removeWorktree := func() {
+		if wt != nil {
+			wt.Remove(context.Background())
+		}
+	}
+
 	root, err := os.OpenRoot(workDir)
 
 	if err != nil {
+		removeWorktree()
 		return nil, fmt.Errorf("failed to open workspace root: %w", err)
 	}
 
@@ -136,14 +208,14 @@ func New(workDir string, ui UI) (*Agent, error) {
 
 	if err := os.MkdirAll(scratchDir, 0755); err != nil {
 		root.Close()
+		removeWorktree()
 		return nil, fmt.Errorf("failed to create scratch directory: %w", err)
 	}
 
-	memoryDir := projectMemoryDir(workDir)
-
 	if err := os.MkdirAll(memoryDir, 0755); err != nil {
 		os.RemoveAll(scratchDir)
 		root.Close()
+		removeWorktree()
 		return nil, fmt.Errorf("failed to create memory directory: %w", err)
 	}
 
@@ -164,6 +236,8 @@ func New(workDir string, ui UI) (*Agent, error) {
 		},
 	}
 
+	agentCfg.Limits.Elicit = elicit
+
 	// Skill precedence (later overrides earlier):
 	//   bundled  → shipped with the binary, hidden from catalog until invoked
 	//   personal → ~/.claude/skills, ~/.wingman/skills (user-wide)
@@ -207,6 +281,23 @@ func New(workDir string, ui UI) (*Agent, error) {
 		subagent.Tools(agentCfg),
 	)
 
+	// The semantic index is opt-in: it embeds the whole workspace, which
+	// costs tokens and needs an embeddings model on the endpoint.
+	var idx *index.Index
+
+	if model := os.Getenv("WINGMAN_EMBEDDING_MODEL"); model != "" {
+		embed := func(ctx context.Context, inputs []string) ([][]float32, error) {
+			return agentCfg.Embed(ctx, model, inputs)
+		}
+
+		idx = index.New(root, embed, &index.Options{
+			Path:  filepath.Join(memoryDir, "index", "vectors.gob"),
+			Model: model,
+		})
+
+		baseTools = append(baseTools, indextool.Tools(idx)...)
+	}
+
 	mcpManager, _ := mcp.Load(filepath.Join(workDir, "mcp.json"))
 
 	a := &Agent{
@@ -219,15 +310,29 @@ func New(workDir string, ui UI) (*Agent, error) {
 
 		Skills: mergedSkills,
 
+		Settings: settings,
+
 		MCP: mcpManager,
 
+		Index: idx,
+
+		Worktree: wt,
+
 		warmupDone: make(chan struct{}),
 
 		baseTools: baseTools,
 	}
 
+	a.baseTools = append(a.baseTools, outline.Tools(root, a.lspManager)...)
+
+	if tokens, ok := repoMapTokens(); ok {
+		a.RepoMap = repomap.New(root, &repomap.Options{
+			Path:   filepath.Join(filepath.Dir(memoryDir), "repomap.json"),
+			Tokens: tokens,
+		})
+	}
+
 	agentCfg.Tools = a.tools
-	agentCfg.ContextMessages = a.memoryContextMessages
 
 	return a, nil
 }
@@ -259,13 +364,26 @@ func (a *Agent) WarmUp() {
 		var lspTools []tool.Tool
 		if isGitRepo(a.RootPath) {
 			lspManager = lsp.NewManager(a.RootPath)
-			lspTools = lsptool.NewTools(lspManager)
+			lspTools = lsptool.NewTools(lspManager, a.Root)
+		}
+
+		if a.RepoMap != nil && isGitRepo(a.RootPath) {
+			go a.RepoMap.Refresh(context.Background())
+		}
+
+		var indexCancel context.CancelFunc
+		if a.Index != nil {
+			ctx, cancel := context.WithCancel(context.Background())
+			indexCancel = cancel
+
+			go a.Index.Update(ctx)
 		}
 
 		a.mu.Lock()
 		a.Rewind = rewindManager
 		a.LSP = lspManager
 		a.lspTools = lspTools
+		a.indexCancel = indexCancel
 		a.mu.Unlock()
 	})
 }
@@ -338,16 +456,37 @@ func planModeTools(tools []tool.Tool) []tool.Tool {
 	return filtered
 }
 
-func planModeEffectExecute(t tool.Tool) func(context.Context, map[string]any) (string, error) {
-	return func(ctx context.Context, args map[string]any) (string, error) {
+func planModeEffectExecute(t tool.Tool) func(context.Context, map[string]any) (tool.Result, error) {
+	return func(ctx context.Context, args map[string]any) (tool.Result, error) {
 		if t.Effect == nil || t.Effect(args) != tool.EffectReadOnly {
-			return "", fmt.Errorf("plan mode only allows read-only tool calls")
+			return tool.Result{}, fmt.Errorf("plan mode only allows read-only tool calls")
 		}
 
 		return t.Execute(ctx, args)
 	}
 }
 
+// lspManager returns the LSP manager, which WarmUp and RestartRewind may
+// replace during the session.
+func (a *Agent) lspManager() *lsp.Manager {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.LSP
+}
+
+// DiagnosticsHooks returns hooks that append diagnostics introduced by file
+// changes to the tool results. Frontends register them on Config.Hooks.
+func (a *Agent) DiagnosticsHooks() hook.Hooks {
+	return diagnostics.New(diagnostics.Sources{
+		LSP: a.lspManager,
+		Bridge: func() *bridge.Bridge {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			return a.Bridge
+		},
+	})
+}
+
 // IsGitRepo reports whether the agent's working directory is currently a git
 // repo. Re-evaluated on each call so callers can react to `git init` (or
 // `rm -rf .git`) happening mid-session.
@@ -383,7 +522,7 @@ func (a *Agent) SyncProjectMode() {
 	oldLSP := a.LSP
 	if isGitRepo(a.RootPath) {
 		a.LSP = lsp.NewManager(a.RootPath)
-		a.lspTools = lsptool.NewTools(a.LSP)
+		a.lspTools = lsptool.NewTools(a.LSP, a.Root)
 	} else {
 		a.LSP = nil
 		a.lspTools = nil
@@ -407,6 +546,10 @@ func (a *Agent) Close() {
 		a.LSP.Close()
 	}
 
+	if a.indexCancel != nil {
+		a.indexCancel()
+	}
+
 	if a.Rewind != nil {
 		a.Rewind.Cleanup()
 	}
@@ -418,6 +561,47 @@ func (a *Agent) Close() {
 	if a.Root != nil {
 		a.Root.Close()
 	}
+
+	// A worktree the session never touched is pure clutter; one with changes
+	// is kept so the user can still merge or inspect the branch later.
+	if a.Worktree != nil {
+		if changed, err := a.Worktree.HasChanges(context.Background()); err == nil && !changed {
+			if a.Worktree.Remove(context.Background()) == nil {
+				a.Worktree = nil
+			}
+		}
+	}
+}
+
+// MergeWorktree squash-merges the session's worktree branch into the user's
+// checkout (left staged, not committed) and removes the worktree. The agent's
+// workspace is gone afterwards, so callers should end the session.
+func (a *Agent) MergeWorktree(ctx context.Context, message string) error {
+	if a.Worktree == nil {
+		return fmt.Errorf("session is not running in a worktree")
+	}
+
+	if err := a.Worktree.Merge(ctx, message); err != nil {
+		return err
+	}
+
+	a.Worktree = nil
+	return nil
+}
+
+// DiscardWorktree removes the session's worktree and branch, dropping every
+// change made in it. Like MergeWorktree, it ends the usable session.
+func (a *Agent) DiscardWorktree(ctx context.Context) error {
+	if a.Worktree == nil {
+		return fmt.Errorf("session is not running in a worktree")
+	}
+
+	if err := a.Worktree.Remove(ctx); err != nil {
+		return err
+	}
+
+	a.Worktree = nil
+	return nil
 }
 
 // Path accessors
@@ -425,10 +609,8 @@ func (a *Agent) Close() {
 // Memory and plan content
 
 const (
-	memoryFileName      = "MEMORY.md"
-	memoryMaxBytes      = 25 * 1024
-	memoryContextPrefix = "Current MEMORY.md:\n\n"
-	memoryContextEmpty  = "MEMORY.md is currently empty."
+	memoryFileName = "MEMORY.md"
+	memoryMaxBytes = 25 * 1024
 )
 
 func (a *Agent) MemoryContent() string {
@@ -454,65 +636,6 @@ func (a *Agent) MemoryContent() string {
 	return content
 }
 
-func (a *Agent) memoryContextMessages() []agent.Message {
-	content := a.MemoryContent()
-	messageText := ""
-	if content != "" {
-		messageText = memoryContextPrefix + content
-	}
-
-	sum := sha256.Sum256([]byte(content))
-	hash := string(sum[:])
-
-	a.mu.Lock()
-	defer a.mu.Unlock()
-
-	prevHash := a.lastMemoryHash
-	if hash == a.lastMemoryHash {
-		return nil
-	}
-	a.lastMemoryHash = hash
-
-	if messageText == "" {
-		messageText = memoryContextEmpty
-		if prevHash == "" && a.latestMemoryContextText() == "" {
-			return nil
-		}
-	}
-
-	if prevHash == "" && a.latestMemoryContextText() == messageText {
-		return nil
-	}
-
-	return []agent.Message{{
-		Role:   agent.RoleUser,
-		Hidden: true,
-		Content: []agent.Content{{
-			Text: messageText,
-		}},
-	}}
-}
-
-func (a *Agent) latestMemoryContextText() string {
-	if a.Agent == nil {
-		return ""
-	}
-
-	for i := len(a.Messages) - 1; i >= 0; i-- {
-		m := a.Messages[i]
-		if !m.Hidden || m.Role != agent.RoleUser || len(m.Content) != 1 {
-			continue
-		}
-
-		text := m.Content[0].Text
-		if strings.HasPrefix(text, memoryContextPrefix) || text == memoryContextEmpty {
-			return text
-		}
-	}
-
-	return ""
-}
-
 // Instructions
 
 func BuildInstructions(data prompt.SectionData) string {
@@ -526,15 +649,19 @@ func BuildInstructions(data prompt.SectionData) string {
 }
 
 func (a *Agent) InstructionsData() prompt.SectionData {
+	date, repoMap, memory := a.promptSnapshot()
+
 	data := prompt.SectionData{
 		PlanMode:            a.PlanMode,
-		Date:                time.Now().Format("January 2, 2006"),
+		Date:                date,
 		OS:                  runtime.GOOS,
 		Arch:                runtime.GOARCH,
 		WorkingDir:          a.RootPath,
 		MemoryDir:           a.MemoryPath,
+		MemoryContent:       memory,
 		Skills:              skill.FormatForPrompt(a.Skills),
 		ProjectInstructions: ReadProjectInstructions(a.RootPath),
+		RepositoryMap:       repoMap,
 	}
 
 	if a.Bridge != nil && a.Bridge.IsConnected() {
@@ -544,8 +671,103 @@ func (a *Agent) InstructionsData() prompt.SectionData {
 	return data
 }
 
+// promptSnapshot returns the date, repository map and MEMORY.md for the
+// instructions. They change on their own, and any change to the
+// instructions invalidates the provider's prompt cache for the whole
+// history, so they are taken once per conversation: until the model first
+// answered, or again after the history was compacted. Memory the agent
+// writes meanwhile is already in the conversation.
+func (a *Agent) promptSnapshot() (string, string, string) {
+	fresh := true
+
+	if a.Agent != nil {
+		for _, m := range a.Messages {
+			if m.Role == agent.RoleAssistant {
+				fresh = false
+				break
+			}
+		}
+	}
+
+	var repoMap string
+
+	if a.RepoMap != nil && a.IsGitRepo() {
+		repoMap = a.RepoMap.String()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if fresh || a.promptDate == "" {
+		a.promptDate = time.Now().Format("January 2, 2006")
+		a.promptMemory = a.MemoryContent()
+	}
+
+	// The map may still be building when the conversation starts.
+	if fresh || a.promptRepoMap == "" {
+		a.promptRepoMap = repoMap
+	}
+
+	return a.promptDate, a.promptRepoMap, a.promptMemory
+}
+
+// repoMapTokens reads WINGMAN_REPO_MAP: a token budget, or any other
+// non-false value for the default budget.
+func repoMapTokens() (int, bool) {
+	value := strings.TrimSpace(os.Getenv("WINGMAN_REPO_MAP"))
+
+	switch strings.ToLower(value) {
+	case "", "0", "false", "off", "no":
+		return 0, false
+	}
+
+	if tokens, err := strconv.Atoi(value); err == nil && tokens > 0 {
+		return tokens, true
+	}
+
+	return repomap.DefaultTokens, true
+}
+
 // Helpers
 
+// TitleSession names a saved session with the title model, once per
+// session. It does nothing when no title model is configured. Pass a copy of
+// the messages; it is meant to run in the background.
+func (a *Agent) TitleSession(ctx context.Context, sessionsDir, sessionID string, messages []agent.Message) (bool, error) {
+	if a.TitleModel == nil {
+		return false, nil
+	}
+
+	a.mu.Lock()
+	if a.titled[sessionID] {
+		a.mu.Unlock()
+		return false, nil
+	}
+	if a.titled == nil {
+		a.titled = make(map[string]bool)
+	}
+	a.titled[sessionID] = true
+	a.mu.Unlock()
+
+	title, err := a.Title(ctx, messages)
+
+	if err != nil || title == "" {
+		return false, err
+	}
+
+	if err := session.SetTitle(sessionsDir, sessionID, title); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// SessionsDir returns where sessions for workingDir are stored, for callers
+// (like the session CLI) that don't need a full Agent.
+func SessionsDir(workingDir string) string {
+	return filepath.Join(filepath.Dir(projectMemoryDir(workingDir)), "sessions")
+}
+
 func projectMemoryDir(workingDir string) string {
 	home, err := os.UserHomeDir()
 	if err != nil {
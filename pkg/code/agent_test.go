@@ -1,7 +1,12 @@
 package code
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
@@ -21,9 +26,9 @@ func TestPlanModeToolsFilterMutations(t *testing.T) {
 		{
 			Name:   "shell",
 			Effect: shell.ClassifyEffect,
-			Execute: func(ctx context.Context, args map[string]any) (string, error) {
+			Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
 				calledShell = true
-				return "ok", nil
+				return tool.Text("ok"), nil
 			},
 		},
 	})
@@ -72,8 +77,8 @@ func TestPlanModeShellRejectsMutatingSafeCommands(t *testing.T) {
 	execute := planModeEffectExecute(tool.Tool{
 		Name:   "shell",
 		Effect: shell.ClassifyEffect,
-		Execute: func(ctx context.Context, args map[string]any) (string, error) {
-			return "ok", nil
+		Execute: func(ctx context.Context, args map[string]any) (tool.Result, error) {
+			return tool.Text("ok"), nil
 		},
 	})
 
@@ -94,74 +99,158 @@ func TestPlanModeShellRejectsMutatingSafeCommands(t *testing.T) {
 	}
 }
 
-func TestMemoryContextMessagesOnlyInjectChanges(t *testing.T) {
+func TestMemoryPrefixStableAcrossTurns(t *testing.T) {
+	var requests [][]json.RawMessage
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []json.RawMessage `json:"messages"`
+		}
+
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body.Messages)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"id":"1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+
+	defer srv.Close()
+
+	cfg, err := agent.NewConfig(&agent.Options{Backend: agent.BackendChat, BaseURL: srv.URL + "/v1", Token: "test"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	dir := t.TempDir()
-	a := &Agent{MemoryPath: dir}
+	a := &Agent{Agent: &agent.Agent{Config: cfg}, MemoryPath: dir}
+
+	cfg.Model = func() string { return "test" }
+	cfg.Instructions = func() string { return BuildInstructions(a.InstructionsData()) }
+
+	memory := filepath.Join(dir, memoryFileName)
+
+	for _, content := range []string{"remember this", "remember that"} {
+		if err := os.WriteFile(memory, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
 
-	if got := a.memoryContextMessages(); got != nil {
-		t.Fatalf("initial empty memory should not inject, got %#v", got)
+		for _, err := range a.Send(t.Context(), []agent.Content{{Text: "hi"}}) {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
 	}
 
-	if err := os.WriteFile(filepath.Join(dir, memoryFileName), []byte("remember this"), 0644); err != nil {
-		t.Fatal(err)
+	first, second := requests[0], requests[1]
+
+	if len(second) <= len(first) {
+		t.Fatalf("expected the second request to extend the first, got %d and %d messages", len(first), len(second))
 	}
 
-	got := a.memoryContextMessages()
-	if len(got) != 1 {
-		t.Fatalf("memory change should inject one message, got %#v", got)
+	for i := range first {
+		if !bytes.Equal(first[i], second[i]) {
+			t.Errorf("message %d changed between turns:\n%s\n%s", i, first[i], second[i])
+		}
 	}
-	if !got[0].Hidden || got[0].Role != agent.RoleUser {
-		t.Fatalf("memory context should be hidden user context, got %#v", got[0])
+
+	if !strings.Contains(string(first[0]), "remember this") {
+		t.Errorf("expected MEMORY.md in the instructions, got %s", first[0])
 	}
-	if got[0].Content[0].Text != memoryContextPrefix+"remember this" {
-		t.Fatalf("unexpected memory context: %q", got[0].Content[0].Text)
+
+	for _, m := range second {
+		if strings.Contains(string(m), "remember that") {
+			t.Errorf("expected MEMORY.md to stay as snapshotted for the conversation, got %s", m)
+		}
 	}
+}
+
+func TestLoadSettings(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	workDir := t.TempDir()
+
+	write := func(path, content string) {
+		os.MkdirAll(filepath.Dir(path), 0755)
 
-	if got := a.memoryContextMessages(); got != nil {
-		t.Fatalf("unchanged memory should not inject, got %#v", got)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
 	}
 
-	if err := os.WriteFile(filepath.Join(dir, memoryFileName), []byte(""), 0644); err != nil {
+	write(filepath.Join(home, ".wingman", SettingsFile), `{"model": "big", "title_model": "tiny", "models": {"local-*": {"context_window": 8192}, "shared": {"vision": true}}}`)
+	write(filepath.Join(workDir, SettingsFile), `{"model": "project", "fallback_models": ["backup"], "models": {"shared": {"vision": false, "input_cost": 3}}, "limits": {"max_tool_rounds": 50, "max_repeats": -1}}`)
+
+	settings, err := LoadSettings(workDir)
+
+	if err != nil {
 		t.Fatal(err)
 	}
 
-	got = a.memoryContextMessages()
-	if len(got) != 1 {
-		t.Fatalf("cleared memory should inject one update, got %#v", got)
+	cfg := &agent.Config{Registry: agent.NewRegistry()}
+	settings.apply(cfg)
+
+	if c := cfg.Capabilities("local-llama"); c.ContextWindow != 8192 {
+		t.Errorf("expected user pattern override, got %+v", c)
 	}
-	if !got[0].Hidden || got[0].Content[0].Text != memoryContextEmpty {
-		t.Fatalf("unexpected cleared-memory context: %#v", got[0])
+
+	if c := cfg.Capabilities("shared"); c.Vision {
+		t.Errorf("expected project settings to win, got %+v", c)
 	}
-}
 
-func TestMemoryContextMessagesUsesSavedHiddenSnapshotOnResume(t *testing.T) {
-	dir := t.TempDir()
-	if err := os.WriteFile(filepath.Join(dir, memoryFileName), []byte("remember this"), 0644); err != nil {
-		t.Fatal(err)
+	if cfg.Model() != "project" || cfg.TitleModel() != "tiny" || cfg.SubagentModel != nil {
+		t.Errorf("unexpected role models: %q %q", cfg.Model(), cfg.TitleModel())
 	}
 
-	a := &Agent{
-		Agent:      &agent.Agent{},
-		MemoryPath: dir,
+	if fallbacks := cfg.Fallbacks(); len(fallbacks) != 1 || fallbacks[0] != "backup" {
+		t.Errorf("unexpected fallbacks: %v", fallbacks)
 	}
-	a.Messages = []agent.Message{{
-		Role:   agent.RoleUser,
-		Hidden: true,
-		Content: []agent.Content{{
-			Text: memoryContextPrefix + "remember this",
-		}},
-	}}
 
-	if got := a.memoryContextMessages(); got != nil {
-		t.Fatalf("unchanged saved memory snapshot should not reinject, got %#v", got)
+	if c := cfg.Capabilities("shared"); c.InputCost != 3 {
+		t.Errorf("expected price override, got %+v", c)
 	}
 
-	if err := os.WriteFile(filepath.Join(dir, memoryFileName), []byte("changed"), 0644); err != nil {
-		t.Fatal(err)
+	if l := cfg.Limits; l.MaxToolRounds != 50 || l.MaxRepeats != 0 || l.Action != agent.LimitAsk {
+		t.Errorf("unexpected limits: %+v", l)
+	}
+
+	write(filepath.Join(workDir, SettingsFile), `{"limits": {"action": "retry"}}`)
+
+	if _, err := LoadSettings(workDir); err == nil {
+		t.Error("expected error for invalid limit action")
 	}
 
-	got := a.memoryContextMessages()
-	if len(got) != 1 || got[0].Content[0].Text != memoryContextPrefix+"changed" {
-		t.Fatalf("changed memory should inject new snapshot, got %#v", got)
+	write(filepath.Join(workDir, SettingsFile), `{"models": `)
+
+	if _, err := LoadSettings(workDir); err == nil {
+		t.Error("expected error for invalid settings")
+	}
+}
+
+func TestPromptSnapshotStableWithinConversation(t *testing.T) {
+	a := &Agent{Agent: &agent.Agent{}}
+
+	date, _, _ := a.promptSnapshot()
+
+	if date == "" {
+		t.Fatal("expected a date for a new conversation")
+	}
+
+	a.Messages = []agent.Message{
+		{Role: agent.RoleUser, Content: []agent.Content{{Text: "hi"}}},
+		{Role: agent.RoleAssistant, Content: []agent.Content{{Text: "hello"}}},
+	}
+	a.promptDate = "January 1, 2000"
+
+	if date, _, _ := a.promptSnapshot(); date != "January 1, 2000" {
+		t.Errorf("expected the date to stay fixed once the model answered, got %q", date)
+	}
+
+	a.Messages = a.Messages[:1]
+
+	if date, _, _ := a.promptSnapshot(); date == "January 1, 2000" {
+		t.Error("expected a fresh date for a new or compacted conversation")
 	}
 }
@@ -0,0 +1,212 @@
+// Package diagnostics feeds compiler and linter errors introduced by a file
+// change back to the model, appended to the result of the tool that made it.
+package diagnostics
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent/hook"
+	"github.com/adrianliechti/wingman-agent/pkg/agent/tool"
+	lsptool "github.com/adrianliechti/wingman-agent/pkg/agent/tool/lsp"
+	"github.com/adrianliechti/wingman-agent/pkg/code/bridge"
+	"github.com/adrianliechti/wingman-agent/pkg/lsp"
+)
+
+// Sources resolves the diagnostic providers at call time, since frontends
+// may replace them during a session (e.g. when rewind restarts the LSP
+// manager or an IDE connects). Either may return nil.
+type Sources struct {
+	LSP    func() *lsp.Manager
+	Bridge func() *bridge.Bridge
+}
+
+type checker struct {
+	sources Sources
+	tracker *lsp.DiagnosticTracker
+}
+
+// New returns hooks that append new diagnostics to the results of edit,
+// write, apply_patch and the mutating LSP tools. A connected IDE bridge is preferred over
+// the local language servers. Only diagnostics that weren't there before the
+// change, and weren't reported already, are included.
+func New(sources Sources) hook.Hooks {
+	c := &checker{
+		sources: sources,
+		tracker: lsp.NewDiagnosticTracker(),
+	}
+
+	return hook.Hooks{
+		PreToolUse:  []hook.PreToolUse{c.pre},
+		PostToolUse: []hook.PostToolUse{c.post},
+	}
+}
+
+// pre records baselines for LSP mutations. Those tools sync the server
+// themselves, so post can no longer see what the diagnostics were before.
+func (c *checker) pre(ctx context.Context, call tool.ToolCall) (string, error) {
+	if !slices.Contains(lsptool.MutatingTools, call.Name) || c.bridge() != nil {
+		return "", nil
+	}
+
+	manager := c.lsp()
+	if manager == nil {
+		return "", nil
+	}
+
+	for _, session := range manager.Sessions() {
+		for _, uri := range session.OpenedDocURIs() {
+			c.tracker.SetBaseline(uri, session.PushDiagnostics(uri))
+		}
+	}
+
+	return "", nil
+}
+
+func (c *checker) post(ctx context.Context, call tool.ToolCall, result tool.Result) (tool.Result, error) {
+	if strings.HasPrefix(result.Text, "error:") {
+		return result, nil
+	}
+
+	// The LSP tools sync the server themselves.
+	synced := slices.Contains(lsptool.MutatingTools, call.Name)
+
+	if !synced && call.Name != "edit" && call.Name != "write" && call.Name != "apply_patch" {
+		return result, nil
+	}
+
+	var reports []string
+
+	for _, path := range result.Changed {
+		if report := c.check(ctx, path, synced); report != "" {
+			reports = append(reports, strings.TrimRight(report, "\n"))
+		}
+	}
+
+	if len(reports) == 0 {
+		return result, nil
+	}
+
+	result.Text += "\n\nNew diagnostics after this change:\n" + strings.Join(reports, "\n")
+
+	return result, nil
+}
+
+func (c *checker) check(ctx context.Context, path string, synced bool) string {
+	manager := c.lsp()
+	b := c.bridge()
+
+	if manager == nil && b == nil {
+		return ""
+	}
+
+	absPath := path
+
+	if !filepath.IsAbs(absPath) && manager != nil {
+		absPath = filepath.Join(manager.WorkingDir(), path)
+	}
+
+	// Files deleted by the change have nothing to report.
+	if _, err := os.Stat(absPath); err != nil {
+		return ""
+	}
+
+	if b != nil {
+		return bridgeDiagnostics(ctx, b, absPath)
+	}
+
+	return c.localDiagnostics(ctx, manager, absPath, path, synced)
+}
+
+func bridgeDiagnostics(ctx context.Context, b *bridge.Bridge, absPath string) string {
+	b.NotifyFileUpdated(ctx, absPath)
+
+	// Give the IDE time to re-analyze the file after notification
+	time.Sleep(500 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := b.GetDiagnostics(ctx, absPath)
+	if err != nil || result == "" || result == "[]" {
+		return ""
+	}
+
+	return result
+}
+
+func (c *checker) localDiagnostics(ctx context.Context, manager *lsp.Manager, absPath, path string, synced bool) string {
+	if manager.FindServer(absPath) == nil {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	uri := lsp.FileURI(absPath)
+
+	session, err := manager.GetSession(ctx, absPath)
+	if err != nil {
+		return ""
+	}
+
+	// Capture baseline diagnostics before syncing the changed file content.
+	// This lets us diff against what existed before the edit. LSP mutations
+	// already synced the file; their baseline was taken in pre.
+	if !synced {
+		baselineDiags := session.PushDiagnostics(uri)
+		if len(baselineDiags) == 0 {
+			baselineDiags = session.CollectDiagnostics(ctx, uri)
+		}
+		c.tracker.SetBaseline(uri, baselineDiags)
+	}
+
+	// Clear push diagnostics so we get fresh ones after the change
+	session.ClearPushDiagnostics(uri)
+
+	// Now sync the updated file content to the LSP server (sends didChange + didSave)
+	if _, err := session.OpenDocument(ctx, absPath); err != nil {
+		return ""
+	}
+
+	// Wait for new diagnostics
+	diags := session.WaitForDiagnostics(ctx, uri)
+	if len(diags) == 0 {
+		return ""
+	}
+
+	// Filter to only new diagnostics, sort by severity, cap volume
+	newDiags := c.tracker.FilterNew(uri, diags)
+	if len(newDiags) == 0 {
+		return ""
+	}
+
+	// Mark as delivered for cross-turn deduplication
+	c.tracker.MarkDelivered(uri, newDiags)
+
+	return lsp.FormatNewDiagnostics(newDiags, absPath, manager.WorkingDir())
+}
+
+func (c *checker) lsp() *lsp.Manager {
+	if c.sources.LSP == nil {
+		return nil
+	}
+	return c.sources.LSP()
+}
+
+// bridge returns the IDE bridge if one is connected.
+func (c *checker) bridge() *bridge.Bridge {
+	if c.sources.Bridge == nil {
+		return nil
+	}
+
+	b := c.sources.Bridge()
+	if b == nil || !b.IsConnected() {
+		return nil
+	}
+	return b
+}
@@ -1,5 +1,7 @@
 package code
 
+import "github.com/adrianliechti/wingman-agent/pkg/agent"
+
 // Model is a curated entry in wingman's UI model picker. It carries both the
 // upstream provider's ID and a friendly display name.
 type Model struct {
@@ -36,3 +38,28 @@ func ModelName(id string) string {
 	}
 	return id
 }
+
+// SelectModel picks the default model from those the API offers: the first
+// curated model, otherwise the first model that can call tools. It returns
+// "" when there is nothing to choose from.
+func SelectModel(models []agent.ModelInfo) string {
+	for _, allowed := range AvailableModels {
+		for _, m := range models {
+			if m.ID == allowed.ID && m.Tools {
+				return m.ID
+			}
+		}
+	}
+
+	for _, m := range models {
+		if m.Tools {
+			return m.ID
+		}
+	}
+
+	if len(models) > 0 {
+		return models[0].ID
+	}
+
+	return ""
+}
@@ -28,18 +28,24 @@ var sectionSkills string
 //go:embed section_project.txt
 var sectionProject string
 
+//go:embed section_repomap.txt
+var sectionRepoMap string
+
 //go:embed section_bridge.txt
 var sectionBridge string
 
+// sectionTemplates are ordered from most to least stable, so sessions share
+// as long a cached prompt prefix as possible.
 var sectionTemplates = []struct {
 	title string
 	tmpl  *template.Template
 }{
-	{"Environment", template.Must(template.New("environment").Parse(sectionEnvironment))},
 	{"Memory", template.Must(template.New("memory").Parse(sectionMemory))},
 	{"Session Plan", template.Must(template.New("plan").Parse(sectionPlan))},
 	{"Skills", template.Must(template.New("skills").Parse(sectionSkills))},
 	{"Project Guidelines", template.Must(template.New("project").Parse(sectionProject))},
+	{"Environment", template.Must(template.New("environment").Parse(sectionEnvironment))},
+	{"Repository Map", template.Must(template.New("repomap").Parse(sectionRepoMap))},
 	{"Bridge", template.Must(template.New("bridge").Parse(sectionBridge))},
 }
 
@@ -53,6 +59,7 @@ type SectionData struct {
 	MemoryContent       string
 	Skills              string
 	ProjectInstructions string
+	RepositoryMap       string
 	BridgeInstructions  string
 }
 
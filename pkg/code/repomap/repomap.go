@@ -0,0 +1,512 @@
+// Package repomap builds a compact, ranked overview of a repository for the
+// system prompt: the directory layout plus the most referenced files and
+// their declarations, cut to a token budget.
+//
+// Declarations come from the syntax outline. Files are re-parsed only when
+// their size or modification time changes, and the rendered map is cached
+// on disk keyed by the git HEAD it was built for.
+package repomap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	iofs "io/fs"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-git/go-git/v5"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent/tool/fs"
+	"github.com/adrianliechti/wingman-agent/pkg/agent/tool/outline"
+)
+
+const (
+	DefaultTokens = 2048
+
+	maxFileSize     = 256 * 1024
+	maxFiles        = 5000
+	maxDirs         = 30
+	maxFileSymbols  = 12
+	refreshInterval = time.Minute
+)
+
+var identRe = regexp.MustCompile(`[\p{L}_][\p{L}\p{N}_]{2,}`)
+
+// Options configures a Map. A nil *Options keeps the map in memory with the
+// default token budget.
+type Options struct {
+	// Path is the file the map is cached in between sessions.
+	Path string
+
+	// Tokens is the approximate size budget of the rendered map.
+	Tokens int
+}
+
+type Map struct {
+	root *os.Root
+
+	path   string
+	tokens int
+
+	mu        sync.Mutex
+	refreshMu sync.Mutex
+
+	head  string
+	text  string
+	files map[string]*fileEntry
+
+	scanned    time.Time
+	refreshing bool
+}
+
+type fileEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+
+	Symbols []symbol `json:"symbols,omitempty"`
+
+	// Idents are the distinct identifiers used in the file, which tell how
+	// often other files' symbols are referenced.
+	Idents []string `json:"idents,omitempty"`
+}
+
+type symbol struct {
+	Name      string `json:"name"`
+	Line      int    `json:"line"`
+	Depth     int    `json:"depth,omitempty"`
+	Signature string `json:"signature"`
+}
+
+type cache struct {
+	Head  string                `json:"head"`
+	Text  string                `json:"text"`
+	Files map[string]*fileEntry `json:"files"`
+}
+
+func New(root *os.Root, options *Options) *Map {
+	if options == nil {
+		options = new(Options)
+	}
+
+	m := &Map{
+		root: root,
+
+		path:   options.Path,
+		tokens: options.Tokens,
+
+		files: make(map[string]*fileEntry),
+	}
+
+	if m.tokens <= 0 {
+		m.tokens = DefaultTokens
+	}
+
+	m.load()
+
+	return m
+}
+
+// String returns the current map without blocking. When the last scan is
+// older than a minute a refresh starts in the background; its result shows
+// up in a later call.
+func (m *Map) String() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.refreshing && time.Since(m.scanned) > refreshInterval {
+		m.refreshing = true
+
+		go func() {
+			m.Refresh(context.Background())
+
+			m.mu.Lock()
+			m.refreshing = false
+			m.mu.Unlock()
+		}()
+	}
+
+	return m.text
+}
+
+// Refresh rescans the workspace, re-parses changed files and re-renders the
+// map when anything, including HEAD, changed.
+func (m *Map) Refresh(ctx context.Context) error {
+	m.refreshMu.Lock()
+	defer m.refreshMu.Unlock()
+
+	head := gitHead(m.root.Name())
+
+	m.mu.Lock()
+	files := make(map[string]*fileEntry, len(m.files))
+	for k, v := range m.files {
+		files[k] = v
+	}
+	text := m.text
+	m.mu.Unlock()
+
+	fsys := m.root.FS()
+	seen := make(map[string]bool)
+	changed := false
+
+	err := fs.WalkWorkspace(ctx, fsys, ".", func(p, relPath string) error {
+		if len(seen) >= maxFiles {
+			return filepath.SkipAll
+		}
+
+		info, err := iofs.Stat(fsys, p)
+
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+
+		seen[relPath] = true
+
+		if e := files[relPath]; e != nil && e.Size == info.Size() && e.ModTime.Equal(info.ModTime()) {
+			return nil
+		}
+
+		files[relPath] = parseFile(fsys, p, relPath, info)
+		changed = true
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for p := range files {
+		if !seen[p] {
+			delete(files, p)
+			changed = true
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.scanned = time.Now()
+
+	if !changed && head == m.head && text != "" {
+		return nil
+	}
+
+	m.files = files
+	m.head = head
+	m.text = render(files, m.tokens)
+
+	return m.save()
+}
+
+func parseFile(fsys iofs.FS, p, relPath string, info iofs.FileInfo) *fileEntry {
+	entry := &fileEntry{
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+
+	if info.Size() > maxFileSize {
+		return entry
+	}
+
+	data, err := iofs.ReadFile(fsys, p)
+
+	if err != nil || !utf8.Valid(data) || slices.Contains(data, 0) {
+		return entry
+	}
+
+	content := string(data)
+
+	if decls, ok := outline.Extract(relPath, content); ok {
+		for _, d := range decls {
+			if d.Depth > 1 {
+				continue
+			}
+
+			entry.Symbols = append(entry.Symbols, symbol{
+				Name:      d.Name,
+				Line:      d.StartLine,
+				Depth:     d.Depth,
+				Signature: d.Signature,
+			})
+		}
+	}
+
+	idents := make(map[string]bool)
+
+	for _, id := range identRe.FindAllString(content, -1) {
+		idents[id] = true
+	}
+
+	for id := range idents {
+		entry.Idents = append(entry.Idents, id)
+	}
+
+	slices.Sort(entry.Idents)
+
+	return entry
+}
+
+// rankedFile is a file with its relevance score and the symbols worth
+// listing, most referenced first.
+type rankedFile struct {
+	path    string
+	score   float64
+	symbols []symbol
+}
+
+// rank orders files by how often their symbols are referenced from other
+// files. A symbol used across the codebase marks its file as central.
+func rank(files map[string]*fileEntry) []rankedFile {
+	defined := make(map[string]bool)
+
+	for _, f := range files {
+		for _, s := range f.Symbols {
+			defined[s.Name] = true
+		}
+	}
+
+	// refs counts the files mentioning each defined name.
+	refs := make(map[string]int)
+
+	for _, f := range files {
+		for _, id := range f.Idents {
+			if defined[id] {
+				refs[id]++
+			}
+		}
+	}
+
+	var ranked []rankedFile
+
+	for p, f := range files {
+		if len(f.Symbols) == 0 {
+			continue
+		}
+
+		type scored struct {
+			symbol
+			refs int
+		}
+
+		var symbols []scored
+		score := 0.0
+
+		for _, s := range f.Symbols {
+			// The defining file mentions the name itself.
+			n := max(refs[s.Name]-1, 0)
+
+			score += math.Log1p(float64(n))
+			symbols = append(symbols, scored{s, n})
+		}
+
+		if isTest(p) {
+			score /= 4
+		}
+
+		// Keep the most referenced symbols, shown in file order.
+		slices.SortStableFunc(symbols, func(a, b scored) int { return b.refs - a.refs })
+		symbols = symbols[:min(len(symbols), maxFileSymbols)]
+		slices.SortFunc(symbols, func(a, b scored) int { return a.Line - b.Line })
+
+		r := rankedFile{path: p, score: score}
+
+		for _, s := range symbols {
+			r.symbols = append(r.symbols, s.symbol)
+		}
+
+		ranked = append(ranked, r)
+	}
+
+	slices.SortFunc(ranked, func(a, b rankedFile) int {
+		if a.score != b.score {
+			if a.score > b.score {
+				return -1
+			}
+			return 1
+		}
+
+		return strings.Compare(a.path, b.path)
+	})
+
+	return ranked
+}
+
+func isTest(p string) bool {
+	base := path.Base(p)
+
+	return strings.Contains(base, "_test.") || strings.Contains(base, ".test.") || strings.Contains(base, ".spec.") ||
+		strings.HasPrefix(base, "test_") || strings.HasPrefix(p, "test/") || strings.HasPrefix(p, "tests/") ||
+		strings.Contains(p, "/test/") || strings.Contains(p, "/tests/")
+}
+
+// render formats the layout and ranked files until the budget, estimated at
+// four characters per token, is used up.
+func render(files map[string]*fileEntry, tokens int) string {
+	if len(files) == 0 {
+		return ""
+	}
+
+	budget := tokens * 4
+
+	var sb strings.Builder
+
+	sb.WriteString("Directories:\n")
+
+	for _, d := range directories(files) {
+		fmt.Fprintf(&sb, "%s/ (%d files)\n", d.path, d.files)
+	}
+
+	ranked := rank(files)
+
+	if len(ranked) > 0 {
+		sb.WriteString("\nKey files and symbols:\n")
+	}
+
+	shown := 0
+
+	for _, r := range ranked {
+		var block strings.Builder
+
+		block.WriteString(r.path + "\n")
+
+		for _, s := range r.symbols {
+			fmt.Fprintf(&block, "%s%d: %s\n", strings.Repeat("  ", s.Depth+1), s.Line, s.Signature)
+		}
+
+		if sb.Len()+block.Len() > budget {
+			break
+		}
+
+		sb.WriteString(block.String())
+		shown++
+	}
+
+	if rest := len(ranked) - shown; rest > 0 {
+		fmt.Fprintf(&sb, "(%d more files with declarations not shown)\n", rest)
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+type dirCount struct {
+	path  string
+	files int
+}
+
+// directories counts files per directory up to two levels deep, keeping the
+// largest ones when there are too many to list.
+func directories(files map[string]*fileEntry) []dirCount {
+	counts := make(map[string]int)
+
+	for p := range files {
+		parts := strings.Split(path.Dir(p), "/")
+
+		if parts[0] == "." {
+			continue
+		}
+
+		for i := 1; i <= min(len(parts), 2); i++ {
+			counts[strings.Join(parts[:i], "/")]++
+		}
+	}
+
+	var dirs []dirCount
+
+	for d, n := range counts {
+		dirs = append(dirs, dirCount{d, n})
+	}
+
+	if len(dirs) > maxDirs {
+		slices.SortFunc(dirs, func(a, b dirCount) int {
+			if a.files != b.files {
+				return b.files - a.files
+			}
+			return strings.Compare(a.path, b.path)
+		})
+
+		dirs = dirs[:maxDirs]
+	}
+
+	slices.SortFunc(dirs, func(a, b dirCount) int { return strings.Compare(a.path, b.path) })
+
+	return dirs
+}
+
+func gitHead(dir string) string {
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+
+	if err != nil {
+		return ""
+	}
+
+	ref, err := repo.Head()
+
+	if err != nil {
+		return ""
+	}
+
+	return ref.Hash().String()
+}
+
+// load restores the cached map. The text is only reused when it was built
+// for the current HEAD; file entries are kept either way so the next
+// refresh only re-parses what changed.
+func (m *Map) load() {
+	if m.path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(m.path)
+
+	if err != nil {
+		return
+	}
+
+	var c cache
+
+	if err := json.Unmarshal(data, &c); err != nil || c.Files == nil {
+		return
+	}
+
+	m.files = c.Files
+
+	if c.Head != "" && c.Head == gitHead(m.root.Name()) {
+		m.head = c.Head
+		m.text = c.Text
+	}
+}
+
+func (m *Map) save() error {
+	if m.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(cache{
+		Head:  m.head,
+		Text:  m.text,
+		Files: m.files,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	if err := os.WriteFile(m.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write repository map: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package repomap
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	p := filepath.Join(dir, name)
+	os.MkdirAll(filepath.Dir(p), 0755)
+
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func openRoot(t *testing.T, dir string) *os.Root {
+	t.Helper()
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { root.Close() })
+
+	return root
+}
+
+func TestRefreshRanksReferencedFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, dir, "core/store.go", "package core\n\nfunc OpenStore() {}\n\nfunc unusedHelper() {}\n")
+	writeFile(t, dir, "cmd/a/main.go", "package main\n\nfunc main() { core.OpenStore() }\n")
+	writeFile(t, dir, "cmd/b/main.go", "package main\n\nfunc run() { core.OpenStore() }\n")
+	writeFile(t, dir, "misc/lonely.go", "package misc\n\nfunc Lonely() {}\n")
+	writeFile(t, dir, "vendor/dep/dep.go", "package dep\n\nfunc Dep() {}\n")
+	writeFile(t, dir, ".gitignore", "vendor/\n")
+
+	m := New(openRoot(t, dir), nil)
+
+	if err := m.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	text := m.String()
+
+	for _, want := range []string{"cmd/ (2 files)", "core/ (1 files)", "core/store.go\n  3: func OpenStore()"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("map missing %q:\n%s", want, text)
+		}
+	}
+
+	if strings.Contains(text, "vendor") {
+		t.Errorf("ignored directory in map:\n%s", text)
+	}
+
+	if core, lonely := strings.Index(text, "core/store.go"), strings.Index(text, "misc/lonely.go"); core > lonely {
+		t.Errorf("referenced file should rank first:\n%s", text)
+	}
+}
+
+func TestRenderBudget(t *testing.T) {
+	dir := t.TempDir()
+
+	for i := range 50 {
+		name := filepath.Join("pkg", "file"+strings.Repeat("x", i%5)+string(rune('a'+i%26))+string(rune('a'+i/26))+".go")
+		writeFile(t, dir, name, "package pkg\n\nfunc Function"+string(rune('A'+i%26))+string(rune('A'+i/26))+"() {}\n")
+	}
+
+	m := New(openRoot(t, dir), &Options{Tokens: 100})
+
+	if err := m.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	text := m.String()
+
+	if len(text) > 100*4+80 {
+		t.Errorf("map exceeds budget: %d bytes", len(text))
+	}
+
+	if !strings.Contains(text, "more files with declarations not shown") {
+		t.Errorf("expected truncation note:\n%s", text)
+	}
+}
+
+func TestRefreshIncrementalAndCache(t *testing.T) {
+	dir := t.TempDir()
+	cachePath := filepath.Join(t.TempDir(), "repomap.json")
+
+	writeFile(t, dir, "a.go", "package a\n\nfunc First() {}\n")
+
+	root := openRoot(t, dir)
+	m := New(root, &Options{Path: cachePath})
+
+	if err := m.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(m.String(), "func First()") {
+		t.Fatalf("missing First:\n%s", m.String())
+	}
+
+	writeFile(t, dir, "a.go", "package a\n\nfunc Second() {}\n")
+
+	// Make sure the change is visible even on coarse mtime filesystems.
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(dir, "a.go"), future, future)
+
+	if err := m.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if text := m.String(); !strings.Contains(text, "func Second()") || strings.Contains(text, "func First()") {
+		t.Fatalf("map not updated:\n%s", text)
+	}
+
+	// Outside a git repository there is no HEAD to key the text by, but the
+	// parsed files are reused.
+	reloaded := New(root, &Options{Path: cachePath})
+
+	if len(reloaded.files) != 1 || reloaded.files["a.go"] == nil {
+		t.Errorf("cached files not restored: %v", reloaded.files)
+	}
+}
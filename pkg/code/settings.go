@@ -0,0 +1,176 @@
+package code
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent"
+)
+
+// SettingsFile is the name of the settings file. Like mcp.json and lsp.json
+// it is read from the user's ~/.wingman directory and from the workspace
+// root; project settings win. ~/.wingman/config.json belongs to the desktop
+// app, which rewrites it.
+const SettingsFile = "settings.json"
+
+// Settings is the optional configuration in SettingsFile.
+type Settings struct {
+	// Model is the model for main turns. SubagentModel, SummaryModel and
+	// TitleModel route delegated tasks, conversation summaries and session
+	// titles to other models; empty uses Model.
+	Model         string `json:"model,omitempty"`
+	SubagentModel string `json:"subagent_model,omitempty"`
+	SummaryModel  string `json:"summary_model,omitempty"`
+	TitleModel    string `json:"title_model,omitempty"`
+
+	// FallbackModels are tried in order when a model is overloaded or
+	// keeps failing.
+	FallbackModels []string `json:"fallback_models,omitempty"`
+
+	// Models overrides model capabilities, keyed by model ID or a pattern
+	// such as "llama-*".
+	Models map[string]agent.CapabilityOverride `json:"models,omitempty"`
+
+	Limits LimitSettings `json:"limits,omitzero"`
+}
+
+// LimitSettings bound each turn; see agent.Limits. All limits are off
+// unless set; a negative value disables a limit set by the user settings.
+type LimitSettings struct {
+	MaxToolRounds int     `json:"max_tool_rounds,omitempty"`
+	MaxTokens     int64   `json:"max_tokens,omitempty"`
+	MaxCost       float64 `json:"max_cost,omitempty"`
+	MaxRepeats    int     `json:"max_repeats,omitempty"`
+
+	// Action is "ask" (default), "nudge" or "stop".
+	Action agent.LimitAction `json:"action,omitempty"`
+}
+
+// LoadSettings reads and merges the user and project settings. Missing
+// files are not an error.
+func LoadSettings(workDir string) (*Settings, error) {
+	var paths []string
+
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".wingman", SettingsFile))
+	}
+
+	paths = append(paths, filepath.Join(workDir, SettingsFile))
+
+	settings := &Settings{
+		Models: make(map[string]agent.CapabilityOverride),
+
+		Limits: LimitSettings{
+			Action: agent.LimitAsk,
+		},
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		var s Settings
+
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+
+		switch s.Limits.Action {
+		case "", agent.LimitAsk, agent.LimitNudge, agent.LimitStop:
+		default:
+			return nil, fmt.Errorf("invalid limit action %q in %s: use ask, nudge or stop", s.Limits.Action, path)
+		}
+
+		settings.merge(&s)
+	}
+
+	return settings, nil
+}
+
+// merge overlays the fields set in o.
+func (s *Settings) merge(o *Settings) {
+	for _, f := range []struct{ dst, src *string }{
+		{&s.Model, &o.Model},
+		{&s.SubagentModel, &o.SubagentModel},
+		{&s.SummaryModel, &o.SummaryModel},
+		{&s.TitleModel, &o.TitleModel},
+	} {
+		if *f.src != "" {
+			*f.dst = *f.src
+		}
+	}
+
+	if o.FallbackModels != nil {
+		s.FallbackModels = o.FallbackModels
+	}
+
+	maps.Copy(s.Models, o.Models)
+
+	if o.Limits.MaxToolRounds != 0 {
+		s.Limits.MaxToolRounds = o.Limits.MaxToolRounds
+	}
+
+	if o.Limits.MaxTokens != 0 {
+		s.Limits.MaxTokens = o.Limits.MaxTokens
+	}
+
+	if o.Limits.MaxCost != 0 {
+		s.Limits.MaxCost = o.Limits.MaxCost
+	}
+
+	if o.Limits.MaxRepeats != 0 {
+		s.Limits.MaxRepeats = o.Limits.MaxRepeats
+	}
+
+	if o.Limits.Action != "" {
+		s.Limits.Action = o.Limits.Action
+	}
+}
+
+// apply configures the agent from the settings.
+func (s *Settings) apply(cfg *agent.Config) {
+	for pattern, o := range s.Models {
+		cfg.Registry.Override(pattern, o)
+	}
+
+	model := func(id string) func() string {
+		if id == "" {
+			return nil
+		}
+
+		return func() string { return id }
+	}
+
+	if s.Model != "" {
+		cfg.Model = model(s.Model)
+	}
+
+	cfg.SubagentModel = model(s.SubagentModel)
+	cfg.SummaryModel = model(s.SummaryModel)
+	cfg.TitleModel = model(s.TitleModel)
+
+	if len(s.FallbackModels) > 0 {
+		fallbacks := s.FallbackModels
+		cfg.Fallbacks = func() []string { return fallbacks }
+	}
+
+	cfg.Limits = &agent.Limits{
+		MaxToolRounds: max(s.Limits.MaxToolRounds, 0),
+		MaxTokens:     max(s.Limits.MaxTokens, 0),
+		MaxCost:       max(s.Limits.MaxCost, 0),
+		MaxRepeats:    max(s.Limits.MaxRepeats, 0),
+
+		Action: s.Limits.Action,
+	}
+}
@@ -0,0 +1,72 @@
+package index
+
+import (
+	"strings"
+)
+
+const (
+	chunkLines   = 40
+	chunkOverlap = 8
+	chunkMaxSize = 4000
+)
+
+// Chunk is an indexed piece of a file. Lines are 1-based and inclusive.
+type Chunk struct {
+	StartLine int
+	EndLine   int
+
+	Content string
+	Vector  []float32
+}
+
+// chunkText splits content into overlapping windows of lines. Windows are cut
+// short when they grow past chunkMaxSize so minified or generated files don't
+// produce oversized inputs.
+func chunkText(content string) []Chunk {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+
+	var chunks []Chunk
+
+	for start := 0; start < len(lines); {
+		end := start
+		size := 0
+
+		for end < len(lines) && end-start < chunkLines {
+			size += len(lines[end]) + 1
+
+			if size > chunkMaxSize && end > start {
+				break
+			}
+
+			end++
+		}
+
+		text := strings.Join(lines[start:end], "\n")
+
+		if len(text) > chunkMaxSize {
+			text = strings.ToValidUTF8(text[:chunkMaxSize], "")
+		}
+
+		if strings.TrimSpace(text) != "" {
+			chunks = append(chunks, Chunk{
+				StartLine: start + 1,
+				EndLine:   end,
+				Content:   text,
+			})
+		}
+
+		if end >= len(lines) {
+			break
+		}
+
+		next := end - chunkOverlap
+
+		if next <= start {
+			next = end
+		}
+
+		start = next
+	}
+
+	return chunks
+}
@@ -0,0 +1,331 @@
+// Package index maintains a semantic search index of a workspace. Files are
+// split into overlapping line chunks, embedded through an embeddings API and
+// kept in a local vector store that is updated incrementally: only files
+// whose content changed are embedded again.
+package index
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	iofs "io/fs"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent/tool/fs"
+)
+
+const (
+	maxFileSize    = 512 * 1024
+	embedBatchSize = 64
+)
+
+// Embedder returns one embedding vector per input.
+type Embedder func(ctx context.Context, inputs []string) ([][]float32, error)
+
+// Options configures an Index. A nil *Options keeps the index in memory.
+type Options struct {
+	// Path is the file the vector store is persisted to.
+	Path string
+
+	// Model names the embedding model. The stored index is discarded when it
+	// was built with a different model.
+	Model string
+}
+
+type Index struct {
+	root  *os.Root
+	embed Embedder
+
+	path  string
+	model string
+
+	mu    sync.Mutex
+	store *store
+}
+
+// Result is a chunk matching a search query.
+type Result struct {
+	Path string
+
+	StartLine int
+	EndLine   int
+
+	Content string
+	Score   float32
+}
+
+func New(root *os.Root, embed Embedder, options *Options) *Index {
+	if options == nil {
+		options = new(Options)
+	}
+
+	return &Index{
+		root:  root,
+		embed: embed,
+
+		path:  options.Path,
+		model: options.Model,
+	}
+}
+
+// pendingFile is a changed file whose chunks are waiting to be embedded.
+type pendingFile struct {
+	path string
+	file *File
+}
+
+// Update brings the index in line with the workspace and returns the number
+// of files that were (re-)embedded. Progress is saved even when embedding
+// fails part way, so an interrupted run resumes where it stopped.
+func (x *Index) Update(ctx context.Context) (int, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if x.store == nil {
+		x.store = loadStore(x.path, x.model)
+	}
+
+	fsys := x.root.FS()
+	seen := make(map[string]bool)
+
+	var pending []pendingFile
+
+	err := fs.WalkWorkspace(ctx, fsys, ".", func(path, relPath string) error {
+		info, err := iofs.Stat(fsys, path)
+
+		if err != nil || !info.Mode().IsRegular() || info.Size() == 0 || info.Size() > maxFileSize {
+			return nil
+		}
+
+		existing := x.store.Files[relPath]
+
+		if existing != nil && existing.Size == info.Size() && existing.ModTime.Equal(info.ModTime()) {
+			seen[relPath] = true
+			return nil
+		}
+
+		data, err := iofs.ReadFile(fsys, path)
+
+		if err != nil || !isText(data) {
+			return nil
+		}
+
+		seen[relPath] = true
+
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+
+		if existing != nil && existing.Hash == hash {
+			existing.Size = info.Size()
+			existing.ModTime = info.ModTime()
+			return nil
+		}
+
+		pending = append(pending, pendingFile{
+			path: relPath,
+
+			file: &File{
+				Hash:    hash,
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+
+				Chunks: chunkText(string(data)),
+			},
+		})
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	for path := range x.store.Files {
+		if !seen[path] {
+			delete(x.store.Files, path)
+		}
+	}
+
+	updated, err := x.embedPending(ctx, pending)
+
+	if saveErr := x.store.save(x.path); err == nil {
+		err = saveErr
+	}
+
+	return updated, err
+}
+
+// embedPending embeds the chunks of the pending files in batches and adds
+// each file to the store once all of its chunks have vectors.
+func (x *Index) embedPending(ctx context.Context, pending []pendingFile) (int, error) {
+	type ref struct {
+		file  int
+		chunk int
+	}
+
+	var refs []ref
+
+	for i, p := range pending {
+		for j := range p.file.Chunks {
+			refs = append(refs, ref{i, j})
+		}
+	}
+
+	committed := 0
+
+	commit := func(upTo int) {
+		for ; committed < upTo; committed++ {
+			p := pending[committed]
+			x.store.Files[p.path] = p.file
+		}
+	}
+
+	for start := 0; start < len(refs); start += embedBatchSize {
+		batch := refs[start:min(start+embedBatchSize, len(refs))]
+		inputs := make([]string, len(batch))
+
+		for i, r := range batch {
+			p := pending[r.file]
+			inputs[i] = p.path + "\n\n" + p.file.Chunks[r.chunk].Content
+		}
+
+		vectors, err := x.embed(ctx, inputs)
+
+		if err == nil && len(vectors) != len(inputs) {
+			err = fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(vectors))
+		}
+
+		if err != nil {
+			return committed, fmt.Errorf("failed to embed chunks: %w", err)
+		}
+
+		for i, r := range batch {
+			pending[r.file].file.Chunks[r.chunk].Vector = normalize(vectors[i])
+		}
+
+		// Files before the one the batch ended in are complete.
+		last := batch[len(batch)-1]
+
+		if last.chunk == len(pending[last.file].file.Chunks)-1 {
+			commit(last.file + 1)
+		} else {
+			commit(last.file)
+		}
+	}
+
+	// Files without chunks (e.g. whitespace only) need no embedding.
+	commit(len(pending))
+
+	return committed, nil
+}
+
+// Search updates the index and returns the chunks most similar to query,
+// best match first.
+func (x *Index) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	if _, err := x.Update(ctx); err != nil {
+		return nil, err
+	}
+
+	vectors, err := x.embed(ctx, []string{query})
+
+	if err == nil && len(vectors) != 1 {
+		err = fmt.Errorf("expected 1 embedding, got %d", len(vectors))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	q := normalize(vectors[0])
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	var results []Result
+
+	for path, f := range x.store.Files {
+		for _, c := range f.Chunks {
+			if len(c.Vector) != len(q) {
+				continue
+			}
+
+			results = append(results, Result{
+				Path: path,
+
+				StartLine: c.StartLine,
+				EndLine:   c.EndLine,
+
+				Content: c.Content,
+				Score:   dot(q, c.Vector),
+			})
+		}
+	}
+
+	slices.SortFunc(results, func(a, b Result) int {
+		if a.Score != b.Score {
+			if a.Score > b.Score {
+				return -1
+			}
+			return 1
+		}
+
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+
+		return a.StartLine - b.StartLine
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+func isText(data []byte) bool {
+	head := data[:min(len(data), 8000)]
+
+	if slices.Contains(head, 0) {
+		return false
+	}
+
+	return utf8.Valid(data)
+}
+
+func normalize(v []float32) []float32 {
+	var sum float64
+
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+
+	if sum == 0 {
+		return v
+	}
+
+	norm := float32(math.Sqrt(sum))
+	result := make([]float32, len(v))
+
+	for i, x := range v {
+		result[i] = x / norm
+	}
+
+	return result
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+
+	return sum
+}
@@ -0,0 +1,227 @@
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent"
+)
+
+// stubEmbedder serves /v1/embeddings with bag-of-words vectors so that texts
+// sharing words are similar, and counts the embedded inputs.
+func stubEmbedder(t *testing.T) (Embedder, *atomic.Int64) {
+	t.Helper()
+
+	var inputs atomic.Int64
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			http.NotFound(w, r)
+			return
+		}
+
+		var req struct {
+			Input []string `json:"input"`
+			Model string   `json:"model"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		inputs.Add(int64(len(req.Input)))
+
+		var data []map[string]any
+
+		for i, text := range req.Input {
+			data = append(data, map[string]any{
+				"object":    "embedding",
+				"index":     i,
+				"embedding": bagOfWords(text),
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"object": "list",
+			"model":  req.Model,
+			"data":   data,
+		})
+	}))
+
+	t.Cleanup(server.Close)
+
+	t.Setenv("WINGMAN_URL", server.URL)
+	t.Setenv("WINGMAN_TOKEN", "test")
+
+	cfg, err := agent.DefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	embed := func(ctx context.Context, inputs []string) ([][]float32, error) {
+		return cfg.Embed(ctx, "stub-embedding", inputs)
+	}
+
+	return embed, &inputs
+}
+
+func bagOfWords(text string) []float64 {
+	v := make([]float64, 64)
+
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < '0' || r > '9')
+	}) {
+		h := fnv.New32a()
+		h.Write([]byte(word))
+		v[h.Sum32()%uint32(len(v))]++
+	}
+
+	return v
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	os.MkdirAll(filepath.Dir(path), 0755)
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	embed, _ := stubEmbedder(t)
+
+	dir := t.TempDir()
+	writeFile(t, dir, "auth/token.go", "package auth\n\n// refresh the oauth token before it expires\nfunc Refresh() {}\n")
+	writeFile(t, dir, "db/query.go", "package db\n\n// run a sql query against the database\nfunc Query() {}\n")
+	writeFile(t, dir, "ignored/secret.go", "package ignored\n\n// refresh oauth token\n")
+	writeFile(t, dir, ".gitignore", "ignored/\n")
+	writeFile(t, dir, "logo.png", "\x89PNG\x00\x00binary")
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+
+	idx := New(root, embed, nil)
+
+	results, err := idx.Search(context.Background(), "refresh oauth token", 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	if len(results) == 0 || results[0].Path != "auth/token.go" {
+		t.Fatalf("top result = %+v, want auth/token.go", results)
+	}
+
+	for _, r := range results {
+		if strings.HasPrefix(r.Path, "ignored/") || r.Path == "logo.png" {
+			t.Errorf("unexpected result %s", r.Path)
+		}
+	}
+
+	if results[0].StartLine != 1 || results[0].EndLine != 4 {
+		t.Errorf("lines = %d-%d, want 1-4", results[0].StartLine, results[0].EndLine)
+	}
+}
+
+func TestIndexIncremental(t *testing.T) {
+	embed, inputs := stubEmbedder(t)
+
+	dir := t.TempDir()
+	writeFile(t, dir, "a.go", "package a\n\nfunc A() {}\n")
+	writeFile(t, dir, "b.go", "package b\n\nfunc B() {}\n")
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+
+	options := &Options{
+		Path:  filepath.Join(t.TempDir(), "index", "vectors.gob"),
+		Model: "stub-embedding",
+	}
+
+	ctx := context.Background()
+
+	if n, err := New(root, embed, options).Update(ctx); err != nil || n != 2 {
+		t.Fatalf("initial Update = %d, %v; want 2", n, err)
+	}
+
+	// A fresh index loads the persisted store and embeds nothing.
+	idx := New(root, embed, options)
+	before := inputs.Load()
+
+	if n, err := idx.Update(ctx); err != nil || n != 0 {
+		t.Fatalf("reloaded Update = %d, %v; want 0", n, err)
+	}
+
+	if inputs.Load() != before {
+		t.Errorf("unchanged workspace was embedded again")
+	}
+
+	writeFile(t, dir, "a.go", "package a\n\nfunc A() { println(\"changed\") }\n")
+	os.Remove(filepath.Join(dir, "b.go"))
+
+	if n, err := idx.Update(ctx); err != nil || n != 1 {
+		t.Fatalf("Update after change = %d, %v; want 1", n, err)
+	}
+
+	results, err := idx.Search(ctx, "changed", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 1 || results[0].Path != "a.go" || !strings.Contains(results[0].Content, "changed") {
+		t.Errorf("results = %+v, want only the updated a.go", results)
+	}
+
+	// A different model invalidates the stored vectors.
+	other := New(root, embed, &Options{Path: options.Path, Model: "other"})
+
+	if n, err := other.Update(ctx); err != nil || n != 1 {
+		t.Errorf("Update with new model = %d, %v; want 1", n, err)
+	}
+}
+
+func TestChunkText(t *testing.T) {
+	var lines []string
+	for i := range 100 {
+		lines = append(lines, strings.Repeat("x", i%7+1))
+	}
+
+	chunks := chunkText(strings.Join(lines, "\n") + "\n")
+
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want several", len(chunks))
+	}
+
+	if chunks[0].StartLine != 1 || chunks[0].EndLine != chunkLines {
+		t.Errorf("first chunk = %d-%d", chunks[0].StartLine, chunks[0].EndLine)
+	}
+
+	for i := 1; i < len(chunks); i++ {
+		if chunks[i].StartLine != chunks[i-1].EndLine-chunkOverlap+1 {
+			t.Errorf("chunk %d starts at %d, want overlap with %d-%d", i, chunks[i].StartLine, chunks[i-1].StartLine, chunks[i-1].EndLine)
+		}
+	}
+
+	if last := chunks[len(chunks)-1]; last.EndLine != 100 {
+		t.Errorf("last chunk ends at %d, want 100", last.EndLine)
+	}
+}
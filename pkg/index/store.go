@@ -0,0 +1,88 @@
+package index
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// store is the persisted index. It is rebuilt from scratch when the
+// embedding model changes, since vectors of different models don't compare.
+type store struct {
+	Model string
+	Files map[string]*File
+}
+
+// File is the indexed state of one workspace file, keyed by its
+// slash-separated workspace-relative path.
+type File struct {
+	Hash    string
+	Size    int64
+	ModTime time.Time
+
+	Chunks []Chunk
+}
+
+func newStore(model string) *store {
+	return &store{
+		Model: model,
+		Files: make(map[string]*File),
+	}
+}
+
+func loadStore(path, model string) *store {
+	if path == "" {
+		return newStore(model)
+	}
+
+	f, err := os.Open(path)
+
+	if err != nil {
+		return newStore(model)
+	}
+
+	defer f.Close()
+
+	var s store
+
+	if err := gob.NewDecoder(f).Decode(&s); err != nil || s.Model != model || s.Files == nil {
+		return newStore(model)
+	}
+
+	return &s
+}
+
+func (s *store) save(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".index-*")
+
+	if err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(s); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+
+	return nil
+}
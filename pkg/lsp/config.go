@@ -0,0 +1,279 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ConfigFile is the name of the LSP configuration file. It is read from the
+// user's ~/.wingman directory and from the workspace root; project entries
+// take precedence over user entries of the same name.
+const ConfigFile = "lsp.json"
+
+// Config customizes the server catalogue. Keys name a server: a built-in
+// one (e.g. "gopls") to adjust or disable it, or any other name to add a
+// custom server.
+type Config struct {
+	Servers map[string]ServerConfig `json:"servers"`
+}
+
+// ServerConfig configures one language server. For built-in servers every
+// field is optional and only overrides what is set.
+type ServerConfig struct {
+	// Disabled turns off a detected server.
+	Disabled bool `json:"disabled,omitempty"`
+
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+
+	// Extensions lists the file extensions (without dot) the server handles.
+	Extensions []string `json:"extensions,omitempty"`
+	LanguageID string   `json:"languageId,omitempty"`
+
+	// RootMarkers are files that mark a project root for this server (glob
+	// patterns allowed). Custom servers without markers cover the whole
+	// workspace.
+	RootMarkers []string `json:"rootMarkers,omitempty"`
+
+	// InitializationOptions is passed verbatim in the initialize request.
+	InitializationOptions json.RawMessage `json:"initializationOptions,omitempty"`
+
+	// Settings answer workspace/configuration requests and are pushed via
+	// workspace/didChangeConfiguration after startup.
+	Settings map[string]any `json:"settings,omitempty"`
+}
+
+// LoadConfig reads the user and project lsp.json files for workingDir and
+// merges them. Missing files are not an error.
+func LoadConfig(workingDir string) (*Config, error) {
+	var paths []string
+
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".wingman", ConfigFile))
+	}
+
+	paths = append(paths, filepath.Join(workingDir, ConfigFile))
+
+	return loadConfigFiles(paths...)
+}
+
+func loadConfigFiles(paths ...string) (*Config, error) {
+	merged := &Config{Servers: make(map[string]ServerConfig)}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return merged, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		var cfg Config
+
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return merged, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+
+		for name, sc := range cfg.Servers {
+			merged.Servers[name] = merged.Servers[name].merge(sc)
+		}
+	}
+
+	return merged, nil
+}
+
+// merge overlays the fields set in o onto c.
+func (c ServerConfig) merge(o ServerConfig) ServerConfig {
+	if o.Disabled {
+		c.Disabled = true
+	}
+
+	if o.Command != "" {
+		c.Command = o.Command
+	}
+
+	if o.Args != nil {
+		c.Args = o.Args
+	}
+
+	if o.Env != nil {
+		env := maps.Clone(c.Env)
+		if env == nil {
+			env = make(map[string]string)
+		}
+		maps.Copy(env, o.Env)
+		c.Env = env
+	}
+
+	if o.Extensions != nil {
+		c.Extensions = o.Extensions
+	}
+
+	if o.LanguageID != "" {
+		c.LanguageID = o.LanguageID
+	}
+
+	if o.RootMarkers != nil {
+		c.RootMarkers = o.RootMarkers
+	}
+
+	if o.InitializationOptions != nil {
+		c.InitializationOptions = o.InitializationOptions
+	}
+
+	if o.Settings != nil {
+		c.Settings = o.Settings
+	}
+
+	return c
+}
+
+// apply returns server with the configured overrides.
+func (c ServerConfig) apply(server Server) Server {
+	if c.Command != "" {
+		server.Command = c.Command
+	}
+
+	if c.Args != nil {
+		server.Args = c.Args
+	}
+
+	if c.Env != nil {
+		server.Env = c.Env
+	}
+
+	if c.Extensions != nil {
+		server.Languages = normalizeExtensions(c.Extensions)
+	}
+
+	if c.LanguageID != "" {
+		server.LanguageID = c.LanguageID
+	}
+
+	if c.InitializationOptions != nil {
+		server.InitializationOptions = c.InitializationOptions
+	}
+
+	if c.Settings != nil {
+		server.Settings = c.Settings
+	}
+
+	return server
+}
+
+// projects returns the project catalogue with the configuration applied:
+// disabled servers removed, built-ins adjusted, and custom servers added.
+// Built-ins given their own root markers are split out into a project of
+// their own so the markers only affect that server.
+func (c *Config) projects() []projectType {
+	if c == nil || len(c.Servers) == 0 {
+		return knownProjects
+	}
+
+	builtin := make(map[string]bool)
+	projects := make([]projectType, 0, len(knownProjects)+len(c.Servers))
+
+	var custom []projectType
+
+	for _, pt := range knownProjects {
+		pt.Servers = slices.Clone(pt.Servers)
+		candidates := pt.Servers[:0]
+
+		for _, server := range pt.Servers {
+			builtin[server.Name] = true
+
+			sc, ok := c.Servers[server.Name]
+			if !ok {
+				candidates = append(candidates, server)
+				continue
+			}
+
+			if sc.Disabled {
+				continue
+			}
+
+			server = sc.apply(server)
+
+			if len(sc.RootMarkers) > 0 {
+				custom = append(custom, projectType{
+					Name:    server.Name,
+					Markers: sc.RootMarkers,
+					Servers: []Server{server},
+				})
+				continue
+			}
+
+			candidates = append(candidates, server)
+		}
+
+		pt.Servers = candidates
+		projects = append(projects, pt)
+	}
+
+	names := slices.Sorted(maps.Keys(c.Servers))
+
+	for _, name := range names {
+		sc := c.Servers[name]
+
+		if builtin[name] || sc.Disabled || sc.Command == "" || len(sc.Extensions) == 0 {
+			continue
+		}
+
+		server := sc.apply(Server{Name: name, LanguageID: name})
+
+		custom = append(custom, projectType{
+			Name:    name,
+			Markers: sc.RootMarkers,
+			Servers: []Server{server},
+		})
+	}
+
+	// Custom projects go first so they win over built-ins for the same
+	// directory and extension.
+	return append(custom, projects...)
+}
+
+func normalizeExtensions(exts []string) []string {
+	result := make([]string, 0, len(exts))
+	for _, e := range exts {
+		result = append(result, strings.TrimPrefix(e, "."))
+	}
+	return result
+}
+
+// configurationSection looks up a dotted section ("gopls", "python.analysis")
+// in the settings. An empty section returns all settings.
+func configurationSection(settings map[string]any, section string) any {
+	if section == "" {
+		return settings
+	}
+
+	if v, ok := settings[section]; ok {
+		return v
+	}
+
+	var current any = settings
+
+	for part := range strings.SplitSeq(section, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+
+		current, ok = m[part]
+		if !ok {
+			return nil
+		}
+	}
+
+	return current
+}
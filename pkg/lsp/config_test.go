@@ -0,0 +1,166 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func writeConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, ConfigFile)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigMerge(t *testing.T) {
+	user := writeConfig(t, t.TempDir(), `{"servers": {
+		"gopls": {"settings": {"gopls": {"buildFlags": ["-tags=user"]}}, "env": {"GOFLAGS": "-mod=mod"}},
+		"pyright": {"disabled": true}
+	}}`)
+
+	project := writeConfig(t, t.TempDir(), `{"servers": {
+		"gopls": {"settings": {"gopls": {"buildFlags": ["-tags=integration"]}}, "env": {"GOWORK": "off"}}
+	}}`)
+
+	cfg, err := loadConfigFiles(user, project, filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("loadConfigFiles: %v", err)
+	}
+
+	gopls := cfg.Servers["gopls"]
+
+	if flags := configurationSection(gopls.Settings, "gopls.buildFlags"); !slices.Equal(flags.([]any), []any{"-tags=integration"}) {
+		t.Errorf("project settings should win, got %v", flags)
+	}
+
+	if gopls.Env["GOFLAGS"] != "-mod=mod" || gopls.Env["GOWORK"] != "off" {
+		t.Errorf("env should be merged, got %v", gopls.Env)
+	}
+
+	if !cfg.Servers["pyright"].Disabled {
+		t.Error("user-level disable should survive merge")
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), `{"servers": `)
+
+	if _, err := loadConfigFiles(path); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestConfigProjects(t *testing.T) {
+	cfg := &Config{Servers: map[string]ServerConfig{
+		"gopls":   {Args: []string{"serve", "-rpc.trace"}},
+		"pyright": {Disabled: true},
+		"my-dsl":  {Command: "dsl-lsp", Extensions: []string{".dsl"}, RootMarkers: []string{"dsl.yaml"}},
+		"broken":  {Command: "nothing"}, // no extensions: ignored
+	}}
+
+	projects := cfg.projects()
+
+	find := func(name string) (Server, bool) {
+		for _, pt := range projects {
+			for _, s := range pt.Servers {
+				if s.Name == name {
+					return s, true
+				}
+			}
+		}
+		return Server{}, false
+	}
+
+	if s, ok := find("gopls"); !ok || !slices.Equal(s.Args, []string{"serve", "-rpc.trace"}) {
+		t.Errorf("gopls override not applied: %+v", s)
+	}
+
+	if _, ok := find("pyright"); ok {
+		t.Error("disabled server should be removed")
+	}
+
+	if _, ok := find("basedpyright"); !ok {
+		t.Error("other python servers should remain")
+	}
+
+	if s, ok := find("my-dsl"); !ok || !slices.Equal(s.Languages, []string{"dsl"}) || s.LanguageID != "my-dsl" {
+		t.Errorf("custom server not added: %+v", s)
+	}
+
+	if _, ok := find("broken"); ok {
+		t.Error("custom server without extensions should be ignored")
+	}
+
+	if projects[0].Name != "my-dsl" {
+		t.Errorf("custom projects should take priority, first is %s", projects[0].Name)
+	}
+
+	// The built-in catalogue must not be modified.
+	for _, pt := range knownProjects {
+		for _, s := range pt.Servers {
+			if s.Name == "gopls" && len(s.Args) != 1 {
+				t.Error("knownProjects was mutated")
+			}
+		}
+	}
+}
+
+func TestDetectAllCustomServer(t *testing.T) {
+	dir := t.TempDir()
+
+	os.MkdirAll(filepath.Join(dir, "tools", "dsl"), 0755)
+	os.WriteFile(filepath.Join(dir, "tools", "dsl", "dsl.yaml"), nil, 0644)
+
+	// "sh" stands in for the server binary; detection only checks PATH.
+	cfg := &Config{Servers: map[string]ServerConfig{
+		"my-dsl":   {Command: "sh", Extensions: []string{"dsl"}, RootMarkers: []string{"dsl.yaml"}},
+		"whole-ws": {Command: "sh", Extensions: []string{"txt"}},
+	}}
+
+	result := detectAll(dir, cfg.projects())
+
+	roots := make(map[string]string)
+	for _, r := range result.Roots {
+		for _, s := range r.Servers {
+			roots[s.Name] = r.Dir
+		}
+	}
+
+	if got := roots["my-dsl"]; got != filepath.Join(dir, "tools", "dsl") {
+		t.Errorf("my-dsl root = %q", got)
+	}
+
+	if got := roots["whole-ws"]; got != dir {
+		t.Errorf("whole-ws root = %q, want workspace root", got)
+	}
+}
+
+func TestConfigurationSection(t *testing.T) {
+	settings := map[string]any{
+		"python": map[string]any{
+			"analysis": map[string]any{"typeCheckingMode": "strict"},
+		},
+		"yaml.schemas": map[string]any{"a": "b"},
+	}
+
+	if got := configurationSection(settings, "python.analysis").(map[string]any)["typeCheckingMode"]; got != "strict" {
+		t.Errorf("nested section = %v", got)
+	}
+
+	if got := configurationSection(settings, "yaml.schemas"); got == nil {
+		t.Error("dotted top-level key should match directly")
+	}
+
+	if got := configurationSection(settings, "missing.key"); got != nil {
+		t.Errorf("missing section = %v, want nil", got)
+	}
+
+	if got := configurationSection(settings, ""); got == nil {
+		t.Error("empty section should return all settings")
+	}
+}
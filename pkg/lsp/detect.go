@@ -43,13 +43,14 @@ type detectionResult struct {
 
 // MissingServer describes a detected project type with no available LSP server.
 type MissingServer struct {
-	ProjectName string // e.g. "go", "typescript"
+	ProjectName string   // e.g. "go", "typescript"
 	Servers     []string // candidate commands that were not found
 }
 
-// detectAll scans the working directory tree for known project markers and
-// returns all detected project roots with their available LSP servers.
-func detectAll(workingDir string) detectionResult {
+// detectAll scans the working directory tree for the markers of the given
+// project types and returns all detected project roots with their available
+// LSP servers.
+func detectAll(workingDir string, projects []projectType) detectionResult {
 	var roots []projectRoot
 	seen := make(map[string]bool)          // dir+command dedup
 	lookPathCache := make(map[string]bool) // command -> available
@@ -58,13 +59,22 @@ func detectAll(workingDir string) detectionResult {
 
 	fsys := filteredFS{root: workingDir}
 
-	for _, pt := range knownProjects {
-		for _, marker := range pt.Markers {
-			pattern := "**/" + marker
+	for _, pt := range projects {
+		markers := pt.Markers
 
-			matches, err := doublestar.Glob(fsys, pattern)
-			if err != nil {
-				continue
+		// Configured servers without markers cover the whole workspace.
+		if len(markers) == 0 {
+			markers = []string{""}
+		}
+
+		for _, marker := range markers {
+			matches := []string{"."}
+
+			if marker != "" {
+				var err error
+				if matches, err = doublestar.Glob(fsys, "**/"+marker); err != nil {
+					continue
+				}
 			}
 
 			for _, match := range matches {
@@ -105,8 +115,8 @@ func detectAll(workingDir string) detectionResult {
 	}
 
 	var missing []MissingServer
-	for _, pt := range knownProjects {
-		if !detectedTypes[pt.Name] || resolvedTypes[pt.Name] {
+	for _, pt := range projects {
+		if !detectedTypes[pt.Name] || resolvedTypes[pt.Name] || len(pt.Servers) == 0 {
 			continue
 		}
 		var cmds []string
@@ -0,0 +1,114 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// ApplyTextEdits applies LSP text edits to content. Positions are
+// interpreted as UTF-16 code units, as mandated by the protocol. Edits must
+// not overlap; they are applied back to front so earlier offsets stay valid.
+func ApplyTextEdits(content string, edits []TextEdit) (string, error) {
+	if len(edits) == 0 {
+		return content, nil
+	}
+
+	type span struct {
+		start, end int
+		text       string
+		order      int
+	}
+
+	lineStarts := lineOffsets(content)
+	spans := make([]span, 0, len(edits))
+
+	for i, e := range edits {
+		start, err := positionOffset(content, lineStarts, e.Range.Start)
+		if err != nil {
+			return "", err
+		}
+
+		end, err := positionOffset(content, lineStarts, e.Range.End)
+		if err != nil {
+			return "", err
+		}
+
+		if end < start {
+			return "", fmt.Errorf("invalid edit range %d:%d-%d:%d", e.Range.Start.Line, e.Range.Start.Character, e.Range.End.Line, e.Range.End.Character)
+		}
+
+		spans = append(spans, span{start: start, end: end, text: e.NewText, order: i})
+	}
+
+	// Inserts at the same offset keep the order in which the server sent them.
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].start != spans[j].start {
+			return spans[i].start < spans[j].start
+		}
+		return spans[i].order < spans[j].order
+	})
+
+	for i := 1; i < len(spans); i++ {
+		if spans[i].start < spans[i-1].end {
+			return "", fmt.Errorf("overlapping edits at offset %d", spans[i].start)
+		}
+	}
+
+	result := content
+
+	for i := len(spans) - 1; i >= 0; i-- {
+		sp := spans[i]
+		result = result[:sp.start] + sp.text + result[sp.end:]
+	}
+
+	return result, nil
+}
+
+func lineOffsets(content string) []int {
+	offsets := []int{0}
+
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			offsets = append(offsets, i+1)
+		}
+	}
+
+	return offsets
+}
+
+// positionOffset converts a line/UTF-16 character position to a byte offset.
+// Characters past the end of a line clamp to the line end, as the spec allows.
+func positionOffset(content string, lineStarts []int, pos Position) (int, error) {
+	if pos.Line < 0 || pos.Character < 0 {
+		return 0, fmt.Errorf("invalid position %d:%d", pos.Line, pos.Character)
+	}
+
+	if pos.Line >= len(lineStarts) {
+		if pos.Line == len(lineStarts) && pos.Character == 0 {
+			return len(content), nil
+		}
+		return 0, fmt.Errorf("position %d:%d is past the end of the document", pos.Line, pos.Character)
+	}
+
+	offset := lineStarts[pos.Line]
+
+	lineEnd := len(content)
+	if pos.Line+1 < len(lineStarts) {
+		lineEnd = lineStarts[pos.Line+1] - 1
+	}
+
+	for units := 0; units < pos.Character && offset < lineEnd; {
+		r, size := utf8.DecodeRuneInString(content[offset:])
+
+		if r >= 0x10000 {
+			units += 2
+		} else {
+			units++
+		}
+
+		offset += size
+	}
+
+	return offset, nil
+}
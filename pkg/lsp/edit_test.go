@@ -0,0 +1,113 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func edit(startLine, startChar, endLine, endChar int, text string) TextEdit {
+	return TextEdit{
+		Range: Range{
+			Start: Position{Line: startLine, Character: startChar},
+			End:   Position{Line: endLine, Character: endChar},
+		},
+		NewText: text,
+	}
+}
+
+func TestApplyTextEdits(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		edits   []TextEdit
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "single replace",
+			content: "func foo() {}\n",
+			edits:   []TextEdit{edit(0, 5, 0, 8, "bar")},
+			want:    "func bar() {}\n",
+		},
+		{
+			name:    "multiple lines out of order",
+			content: "a := foo\nb := foo\n",
+			edits:   []TextEdit{edit(1, 5, 1, 8, "bar"), edit(0, 5, 0, 8, "bar")},
+			want:    "a := bar\nb := bar\n",
+		},
+		{
+			name:    "inserts at same position keep order",
+			content: "x\n",
+			edits:   []TextEdit{edit(0, 0, 0, 0, "a"), edit(0, 0, 0, 0, "b")},
+			want:    "abx\n",
+		},
+		{
+			name:    "utf16 surrogate pair",
+			content: "s := \"😀x\"\n",
+			edits:   []TextEdit{edit(0, 8, 0, 9, "y")},
+			want:    "s := \"😀y\"\n",
+		},
+		{
+			name:    "append at end of document",
+			content: "a\n",
+			edits:   []TextEdit{edit(1, 0, 1, 0, "b\n")},
+			want:    "a\nb\n",
+		},
+		{
+			name:    "character past line end clamps",
+			content: "ab\ncd\n",
+			edits:   []TextEdit{edit(0, 99, 1, 0, "")},
+			want:    "abcd\n",
+		},
+		{
+			name:    "overlapping edits",
+			content: "abcdef\n",
+			edits:   []TextEdit{edit(0, 0, 0, 3, "x"), edit(0, 2, 0, 4, "y")},
+			wantErr: true,
+		},
+		{
+			name:    "line out of range",
+			content: "a\n",
+			edits:   []TextEdit{edit(5, 0, 5, 1, "x")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyTextEdits(tt.content, tt.edits)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyTextEdits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ApplyTextEdits() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCodeActionUnmarshalCommand(t *testing.T) {
+	data := `[
+		{"title": "Organize imports", "command": "source.organizeImports", "arguments": ["file:///a.go"]},
+		{"title": "Add missing return", "kind": "quickfix", "isPreferred": true, "command": {"title": "fix", "command": "gopls.apply_fix"}}
+	]`
+
+	var actions []CodeAction
+	if err := json.Unmarshal([]byte(data), &actions); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if len(actions) != 2 {
+		t.Fatalf("got %d actions, want 2", len(actions))
+	}
+
+	if actions[0].Command == nil || actions[0].Command.Command != "source.organizeImports" || actions[0].Title != "Organize imports" {
+		t.Errorf("bare command not converted: %+v", actions[0])
+	}
+
+	if actions[1].Kind != "quickfix" || !actions[1].IsPreferred || actions[1].Command == nil || actions[1].Command.Command != "gopls.apply_fix" {
+		t.Errorf("code action literal not parsed: %+v", actions[1])
+	}
+}
@@ -1,9 +1,11 @@
 package lsp
 
 import (
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"strings"
+	"unicode/utf16"
 )
 
 func relPath(workingDir, path string) string {
@@ -18,7 +20,7 @@ func formatLocations(title string, locations []Location, workingDir string) stri
 	fmt.Fprintf(&sb, "%s (%d found):\n", title, len(locations))
 
 	for _, loc := range locations {
-		path := relPath(workingDir, uriToPath(loc.URI))
+		path := relPath(workingDir, URIToPath(loc.URI))
 		fmt.Fprintf(&sb, "  %s:%d:%d\n", path, loc.Range.Start.Line+1, loc.Range.Start.Character+1)
 	}
 
@@ -54,23 +56,34 @@ func formatSymbolInformations(symbols []SymbolInformation, workingDir string) st
 	fmt.Fprintf(&sb, "Symbols (%d found):\n", len(symbols))
 
 	for _, sym := range symbols {
-		path := relPath(workingDir, uriToPath(sym.Location.URI))
+		path := relPath(workingDir, URIToPath(sym.Location.URI))
 		fmt.Fprintf(&sb, "  %s (%s) - %s:%d\n", sym.Name, symbolKindName(sym.Kind), path, sym.Location.Range.Start.Line+1)
 	}
 
 	return sb.String()
 }
 
-func formatWorkspaceSymbols(symbols []WorkspaceSymbol, workingDir string) string {
+func formatWorkspaceSymbols(matches []symbolMatch, total int, workingDir string) string {
 	var sb strings.Builder
-	fmt.Fprintf(&sb, "Symbols (%d found):\n", len(symbols))
 
-	for _, sym := range symbols {
-		path := relPath(workingDir, uriToPath(sym.Location.URI))
-		if sym.Location.Range != nil {
-			fmt.Fprintf(&sb, "  %s (%s) - %s:%d\n", sym.Name, symbolKindName(sym.Kind), path, sym.Location.Range.Start.Line+1)
+	if total > len(matches) {
+		fmt.Fprintf(&sb, "Symbols (%d found, showing best %d):\n", total, len(matches))
+	} else {
+		fmt.Fprintf(&sb, "Symbols (%d found):\n", total)
+	}
+
+	for _, sym := range matches {
+		name := sym.Name
+		if sym.Container != "" {
+			name += " in " + sym.Container
+		}
+
+		path := relPath(workingDir, sym.Path)
+
+		if sym.Line >= 0 {
+			fmt.Fprintf(&sb, "  %s (%s) - %s:%d\n", name, symbolKindName(sym.Kind), path, sym.Line+1)
 		} else {
-			fmt.Fprintf(&sb, "  %s (%s) - %s\n", sym.Name, symbolKindName(sym.Kind), path)
+			fmt.Fprintf(&sb, "  %s (%s) - %s\n", name, symbolKindName(sym.Kind), path)
 		}
 	}
 
@@ -82,7 +95,7 @@ func formatIncomingCalls(calls []CallHierarchyIncomingCall, workingDir string) s
 	fmt.Fprintf(&sb, "Incoming Calls (%d found):\n", len(calls))
 
 	for _, c := range calls {
-		path := relPath(workingDir, uriToPath(c.From.URI))
+		path := relPath(workingDir, URIToPath(c.From.URI))
 		fmt.Fprintf(&sb, "  %s (%s) - %s:%d\n", c.From.Name, symbolKindName(c.From.Kind), path, c.From.SelectionRange.Start.Line+1)
 	}
 
@@ -94,13 +107,78 @@ func formatOutgoingCalls(calls []CallHierarchyOutgoingCall, workingDir string) s
 	fmt.Fprintf(&sb, "Outgoing Calls (%d found):\n", len(calls))
 
 	for _, c := range calls {
-		path := relPath(workingDir, uriToPath(c.To.URI))
+		path := relPath(workingDir, URIToPath(c.To.URI))
 		fmt.Fprintf(&sb, "  %s (%s) - %s:%d\n", c.To.Name, symbolKindName(c.To.Kind), path, c.To.SelectionRange.Start.Line+1)
 	}
 
 	return sb.String()
 }
 
+func formatSignatureHelp(help SignatureHelp) string {
+	var sb strings.Builder
+
+	active := help.ActiveSignature
+	if active < 0 || active >= len(help.Signatures) {
+		active = 0
+	}
+
+	for i, sig := range help.Signatures {
+		marker := " "
+		if i == active {
+			marker = "*"
+		}
+
+		fmt.Fprintf(&sb, "%s %s\n", marker, sig.Label)
+
+		if i != active {
+			continue
+		}
+
+		param := help.ActiveParameter
+		if sig.ActiveParameter != nil {
+			param = *sig.ActiveParameter
+		}
+
+		if param >= 0 && param < len(sig.Parameters) {
+			p := sig.Parameters[param]
+			fmt.Fprintf(&sb, "  Active parameter: %s\n", parameterLabel(sig.Label, p.Label))
+
+			if p.Documentation != nil && p.Documentation.Value != "" {
+				fmt.Fprintf(&sb, "    %s\n", strings.ReplaceAll(strings.TrimSpace(p.Documentation.Value), "\n", "\n    "))
+			}
+		}
+
+		if sig.Documentation != nil && sig.Documentation.Value != "" {
+			fmt.Fprintf(&sb, "\n%s\n", strings.TrimSpace(sig.Documentation.Value))
+		}
+	}
+
+	return sb.String()
+}
+
+// parameterLabel resolves a parameter label, which is either a string or a
+// [start, end) pair of UTF-16 offsets into the signature label.
+func parameterLabel(signature string, label json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(label, &s); err == nil {
+		return s
+	}
+
+	var offsets [2]int
+	if err := json.Unmarshal(label, &offsets); err != nil {
+		return string(label)
+	}
+
+	units := utf16.Encode([]rune(signature))
+	start, end := offsets[0], offsets[1]
+
+	if start < 0 || end > len(units) || start > end {
+		return string(label)
+	}
+
+	return string(utf16.Decode(units[start:end]))
+}
+
 var symbolKindNames = [...]string{
 	1:  "File",
 	2:  "Module",
@@ -2,7 +2,6 @@ package lsp
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -15,6 +14,8 @@ const maxRestarts = 3
 // Manager caches LSP sessions so servers are reused across tool invocations.
 type Manager struct {
 	workingDir string
+	config     *Config
+	configErr  error
 	sessions   map[string]*Session // keyed by server command
 	restarts   map[string]int      // restart count per server command
 	mu         sync.Mutex
@@ -26,9 +27,16 @@ type Manager struct {
 // NewManager creates a new LSP session manager. Callers should only
 // instantiate one when LSP is actually wanted for the workspace; outside
 // project mode they should keep the field nil.
+//
+// Servers are customized by lsp.json (see LoadConfig); a broken file is
+// reported by ConfigError and otherwise ignored.
 func NewManager(workingDir string) *Manager {
+	config, err := LoadConfig(workingDir)
+
 	return &Manager{
 		workingDir: workingDir,
+		config:     config,
+		configErr:  err,
 		sessions:   make(map[string]*Session),
 		restarts:   make(map[string]int),
 	}
@@ -42,11 +50,16 @@ func (m *Manager) WorkingDir() string {
 // detect returns cached detection results, running detection once.
 func (m *Manager) detect() *detectionResult {
 	m.detectOnce.Do(func() {
-		m.detection = detectAll(m.workingDir)
+		m.detection = detectAll(m.workingDir, m.config.projects())
 	})
 	return &m.detection
 }
 
+// ConfigError returns the error from loading lsp.json, if any.
+func (m *Manager) ConfigError() error {
+	return m.configErr
+}
+
 // MissingServers returns project types detected in the workspace
 // that have no available LSP server binary.
 func (m *Manager) MissingServers() []MissingServer {
@@ -158,7 +171,7 @@ func (m *Manager) GetSessionByServer(ctx context.Context, server Server) (*Sessi
 
 		// Re-open previously opened documents (best-effort)
 		for _, uri := range openedURIs {
-			path := uriToPath(uri)
+			path := URIToPath(uri)
 			if path != "" {
 				newSession.OpenDocument(ctx, path)
 			}
@@ -187,6 +200,18 @@ func (m *Manager) GetSessionByServer(ctx context.Context, server Server) (*Sessi
 	return session, nil
 }
 
+// Sessions returns the currently running sessions.
+func (m *Manager) Sessions() []*Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sessions := make([]*Session, 0, len(m.sessions))
+	for _, session := range m.sessions {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
 // Close shuts down all cached sessions.
 func (m *Manager) Close() {
 	m.mu.Lock()
@@ -267,52 +292,6 @@ func (m *Manager) WorkspaceDiagnostics(ctx context.Context) (string, error) {
 	return fmt.Sprintf("Workspace Diagnostics (%d found):\n%s", totalDiags, sb.String()), nil
 }
 
-// WorkspaceSymbols searches for symbols across the workspace.
-func (m *Manager) WorkspaceSymbols(ctx context.Context, query string) (string, error) {
-	servers := m.DetectServers()
-	if len(servers) == 0 {
-		return "", fmt.Errorf("no LSP servers detected in workspace")
-	}
-
-	var allSymInfos []SymbolInformation
-	var allWsSymbols []WorkspaceSymbol
-
-	for _, server := range servers {
-		session, err := m.GetSessionByServer(ctx, server)
-		if err != nil {
-			continue
-		}
-
-		var result json.RawMessage
-		if err := session.CallAndAwait(ctx, "workspace/symbol", WorkspaceSymbolParams{Query: query}, &result); err != nil || result == nil || string(result) == "null" {
-			continue
-		}
-
-		// Try SymbolInformation[] first (has location.uri with range)
-		var symInfos []SymbolInformation
-		if err := unmarshalResult(result, &symInfos); err == nil && len(symInfos) > 0 && symInfos[0].Location.URI != "" {
-			allSymInfos = append(allSymInfos, symInfos...)
-			continue
-		}
-
-		// Fall back to WorkspaceSymbol[] (location range may be omitted)
-		var wsSymbols []WorkspaceSymbol
-		if err := unmarshalResult(result, &wsSymbols); err == nil {
-			allWsSymbols = append(allWsSymbols, wsSymbols...)
-		}
-	}
-
-	if len(allSymInfos) > 0 {
-		return formatSymbolInformations(allSymInfos, m.workingDir), nil
-	}
-
-	if len(allWsSymbols) > 0 {
-		return formatWorkspaceSymbols(allWsSymbols, m.workingDir), nil
-	}
-
-	return "No symbols found", nil
-}
-
 func discoverSourceFiles(workingDir string, extensions []string, maxFiles int) []string {
 	extSet := make(map[string]bool, len(extensions))
 	for _, ext := range extensions {
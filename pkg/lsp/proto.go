@@ -10,13 +10,26 @@ import (
 // Initialize
 
 type InitializeParams struct {
-	ProcessID    int                `json:"processId"`
-	RootURI      string             `json:"rootUri"`
-	Capabilities ClientCapabilities `json:"capabilities"`
+	ProcessID             int                `json:"processId"`
+	RootURI               string             `json:"rootUri"`
+	Capabilities          ClientCapabilities `json:"capabilities"`
+	InitializationOptions json.RawMessage    `json:"initializationOptions,omitempty"`
 }
 
 type ClientCapabilities struct {
 	TextDocument TextDocumentClientCapabilities `json:"textDocument"`
+	Workspace    WorkspaceClientCapabilities    `json:"workspace"`
+}
+
+type WorkspaceClientCapabilities struct {
+	ApplyEdit     bool                            `json:"applyEdit,omitempty"`
+	Configuration bool                            `json:"configuration,omitempty"`
+	WorkspaceEdit WorkspaceEditClientCapabilities `json:"workspaceEdit"`
+}
+
+type WorkspaceEditClientCapabilities struct {
+	DocumentChanges    bool     `json:"documentChanges,omitempty"`
+	ResourceOperations []string `json:"resourceOperations,omitempty"`
 }
 
 type TextDocumentClientCapabilities struct {
@@ -28,6 +41,12 @@ type TextDocumentClientCapabilities struct {
 	DocumentSymbol  DocumentSymbolClientCapabilities   `json:"documentSymbol"`
 	Diagnostic      DiagnosticClientCapabilities       `json:"diagnostic"`
 	CallHierarchy   CallHierarchyClientCapabilities    `json:"callHierarchy"`
+	TypeDefinition  TypeDefinitionClientCapabilities   `json:"typeDefinition"`
+	SignatureHelp   SignatureHelpClientCapabilities    `json:"signatureHelp"`
+	Rename          RenameClientCapabilities           `json:"rename"`
+	CodeAction      CodeActionClientCapabilities       `json:"codeAction"`
+	Formatting      FormattingClientCapabilities       `json:"formatting"`
+	RangeFormatting FormattingClientCapabilities       `json:"rangeFormatting"`
 }
 
 type TextDocumentSyncClientCapabilities struct {
@@ -46,12 +65,45 @@ type ReferencesClientCapabilities struct{}
 
 type ImplementationClientCapabilities struct{}
 
+type TypeDefinitionClientCapabilities struct{}
+
+type SignatureHelpClientCapabilities struct {
+	SignatureInformation struct {
+		DocumentationFormat  []string `json:"documentationFormat,omitempty"`
+		ParameterInformation struct {
+			LabelOffsetSupport bool `json:"labelOffsetSupport,omitempty"`
+		} `json:"parameterInformation"`
+		ActiveParameterSupport bool `json:"activeParameterSupport,omitempty"`
+	} `json:"signatureInformation"`
+}
+
 type DocumentSymbolClientCapabilities struct{}
 
 type DiagnosticClientCapabilities struct{}
 
 type CallHierarchyClientCapabilities struct{}
 
+type RenameClientCapabilities struct{}
+
+type CodeActionClientCapabilities struct {
+	CodeActionLiteralSupport *CodeActionLiteralSupport `json:"codeActionLiteralSupport,omitempty"`
+	IsPreferredSupport       bool                      `json:"isPreferredSupport,omitempty"`
+	DataSupport              bool                      `json:"dataSupport,omitempty"`
+	ResolveSupport           *CodeActionResolveSupport `json:"resolveSupport,omitempty"`
+}
+
+type CodeActionLiteralSupport struct {
+	CodeActionKind struct {
+		ValueSet []string `json:"valueSet"`
+	} `json:"codeActionKind"`
+}
+
+type CodeActionResolveSupport struct {
+	Properties []string `json:"properties"`
+}
+
+type FormattingClientCapabilities struct{}
+
 // Text Document
 
 type TextDocumentIdentifier struct {
@@ -224,9 +276,10 @@ type DocumentSymbol struct {
 }
 
 type SymbolInformation struct {
-	Name     string   `json:"name"`
-	Kind     int      `json:"kind"`
-	Location Location `json:"location"`
+	Name          string   `json:"name"`
+	Kind          int      `json:"kind"`
+	ContainerName string   `json:"containerName,omitempty"`
+	Location      Location `json:"location"`
 }
 
 type WorkspaceSymbolParams struct {
@@ -236,9 +289,10 @@ type WorkspaceSymbolParams struct {
 // WorkspaceSymbol is the newer response type for workspace/symbol (since 3.17).
 // Unlike SymbolInformation, its location range may be omitted.
 type WorkspaceSymbol struct {
-	Name     string `json:"name"`
-	Kind     int    `json:"kind"`
-	Location struct {
+	Name          string `json:"name"`
+	Kind          int    `json:"kind"`
+	ContainerName string `json:"containerName,omitempty"`
+	Location      struct {
 		URI   string `json:"uri"`
 		Range *Range `json:"range,omitempty"`
 	} `json:"location"`
@@ -273,3 +327,167 @@ type CallHierarchyOutgoingCall struct {
 	To         CallHierarchyItem `json:"to"`
 	FromRanges []Range           `json:"fromRanges"`
 }
+
+// Edits
+
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// OptionalVersionedTextDocumentIdentifier has a null version when the edit
+// isn't tied to a specific document version.
+type OptionalVersionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version *int   `json:"version"`
+}
+
+// DocumentChange is one entry of WorkspaceEdit.documentChanges: either a
+// TextDocumentEdit (TextDocument set) or a create/rename/delete resource
+// operation (Kind set).
+type DocumentChange struct {
+	TextDocument *OptionalVersionedTextDocumentIdentifier `json:"textDocument,omitempty"`
+	Edits        []TextEdit                               `json:"edits,omitempty"`
+
+	Kind   string `json:"kind,omitempty"`
+	URI    string `json:"uri,omitempty"`
+	OldURI string `json:"oldUri,omitempty"`
+	NewURI string `json:"newUri,omitempty"`
+}
+
+type WorkspaceEdit struct {
+	Changes         map[string][]TextEdit `json:"changes,omitempty"`
+	DocumentChanges []DocumentChange      `json:"documentChanges,omitempty"`
+}
+
+type ApplyWorkspaceEditParams struct {
+	Label string        `json:"label,omitempty"`
+	Edit  WorkspaceEdit `json:"edit"`
+}
+
+type ApplyWorkspaceEditResult struct {
+	Applied       bool   `json:"applied"`
+	FailureReason string `json:"failureReason,omitempty"`
+}
+
+// Rename
+
+type RenameParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+	NewName      string                 `json:"newName"`
+}
+
+// Code Actions
+
+type CodeActionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	Context      CodeActionContext      `json:"context"`
+}
+
+type CodeActionContext struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Only        []string     `json:"only,omitempty"`
+}
+
+type Command struct {
+	Title     string            `json:"title"`
+	Command   string            `json:"command"`
+	Arguments []json.RawMessage `json:"arguments,omitempty"`
+}
+
+type CodeAction struct {
+	Title       string          `json:"title"`
+	Kind        string          `json:"kind,omitempty"`
+	Diagnostics []Diagnostic    `json:"diagnostics,omitempty"`
+	IsPreferred bool            `json:"isPreferred,omitempty"`
+	Edit        *WorkspaceEdit  `json:"edit,omitempty"`
+	Command     *Command        `json:"command,omitempty"`
+	Data        json.RawMessage `json:"data,omitempty"`
+}
+
+// UnmarshalJSON accepts both CodeAction literals and bare Commands, which
+// servers may mix in a textDocument/codeAction response.
+func (a *CodeAction) UnmarshalJSON(data []byte) error {
+	var probe struct {
+		Command json.RawMessage `json:"command"`
+	}
+
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return err
+	}
+
+	if len(probe.Command) > 0 && probe.Command[0] == '"' {
+		var cmd Command
+		if err := json.Unmarshal(data, &cmd); err != nil {
+			return err
+		}
+
+		*a = CodeAction{Title: cmd.Title, Command: &cmd}
+		return nil
+	}
+
+	type plain CodeAction
+	return json.Unmarshal(data, (*plain)(a))
+}
+
+type ExecuteCommandParams struct {
+	Command   string            `json:"command"`
+	Arguments []json.RawMessage `json:"arguments,omitempty"`
+}
+
+// Formatting
+
+type FormattingOptions struct {
+	TabSize      int  `json:"tabSize"`
+	InsertSpaces bool `json:"insertSpaces"`
+}
+
+type DocumentFormattingParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Options      FormattingOptions      `json:"options"`
+}
+
+type DocumentRangeFormattingParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	Options      FormattingOptions      `json:"options"`
+}
+
+// Signature Help
+
+type SignatureHelp struct {
+	Signatures      []SignatureInformation `json:"signatures"`
+	ActiveSignature int                    `json:"activeSignature,omitempty"`
+	ActiveParameter int                    `json:"activeParameter,omitempty"`
+}
+
+type SignatureInformation struct {
+	Label           string                 `json:"label"`
+	Documentation   *HoverContents         `json:"documentation,omitempty"`
+	Parameters      []ParameterInformation `json:"parameters,omitempty"`
+	ActiveParameter *int                   `json:"activeParameter,omitempty"`
+}
+
+// ParameterInformation.Label is either the parameter's text or a [start, end)
+// UTF-16 offset pair into the signature label.
+type ParameterInformation struct {
+	Label         json.RawMessage `json:"label"`
+	Documentation *HoverContents  `json:"documentation,omitempty"`
+}
+
+// Configuration
+
+type ConfigurationParams struct {
+	Items []ConfigurationItem `json:"items"`
+}
+
+type ConfigurationItem struct {
+	ScopeURI string `json:"scopeUri,omitempty"`
+	Section  string `json:"section,omitempty"`
+}
+
+type DidChangeConfigurationParams struct {
+	Settings any `json:"settings"`
+}
@@ -1,5 +1,7 @@
 package lsp
 
+import "encoding/json"
+
 // Server describes an LSP server binary and how to invoke it.
 type Server struct {
 	Name       string   // Display name (e.g., "gopls")
@@ -7,6 +9,11 @@ type Server struct {
 	Args       []string // Arguments (e.g., ["serve"])
 	Languages  []string // File extensions without dot (e.g., ["go"])
 	LanguageID string   // LSP language identifier (e.g., "go")
+
+	// Set from lsp.json (see Config).
+	Env                   map[string]string // Extra environment variables
+	InitializationOptions json.RawMessage   // Sent with initialize
+	Settings              map[string]any    // Served via workspace/configuration
 }
 
 // projectType maps project markers to LSP server candidates.
@@ -33,6 +33,11 @@ type Session struct {
 	// Push-based diagnostics from textDocument/publishDiagnostics notifications.
 	pushDiags   map[string][]Diagnostic // keyed by URI
 	pushDiagsMu sync.Mutex
+
+	// Applies server-initiated workspace/applyEdit requests while a command
+	// runs (see ExecuteCommand); nil when no command is in flight.
+	applyEdit   func(WorkspaceEdit) error
+	applyEditMu sync.Mutex
 }
 
 const startupTimeout = 30 * time.Second
@@ -41,6 +46,9 @@ func connect(ctx context.Context, workingDir string, server Server) (*Session, e
 	cmd := exec.Command(server.Command, server.Args...)
 	cmd.Dir = workingDir
 	cmd.Env = os.Environ()
+	for key, value := range server.Env {
+		cmd.Env = append(cmd.Env, key+"="+os.ExpandEnv(value))
+	}
 	cmd.Stderr = io.Discard
 
 	setSysProcAttr(cmd)
@@ -90,6 +98,24 @@ func connect(ctx context.Context, workingDir string, server Server) (*Session, e
 					}
 					return nil, nil
 				}
+				if req.Method == "workspace/configuration" {
+					var params ConfigurationParams
+					if err := json.Unmarshal(req.Params, &params); err != nil {
+						return nil, err
+					}
+					result := make([]any, len(params.Items))
+					for i, item := range params.Items {
+						result[i] = configurationSection(server.Settings, item.Section)
+					}
+					return result, nil
+				}
+				if req.Method == "workspace/applyEdit" {
+					var params ApplyWorkspaceEditParams
+					if err := json.Unmarshal(req.Params, &params); err != nil {
+						return ApplyWorkspaceEditResult{FailureReason: err.Error()}, nil
+					}
+					return session.handleApplyEdit(params.Edit), nil
+				}
 				return nil, jsonrpc2.ErrNotHandled
 			})
 		},
@@ -290,6 +316,8 @@ func (s *Session) CollectDiagnostics(ctx context.Context, uri string) []Diagnost
 
 // WaitForDiagnostics waits for diagnostics until results appear or the context expires.
 // It checks both push-based (publishDiagnostics notifications) and pull-based sources.
+// An empty publish after ClearPushDiagnostics ends the wait early: the server has
+// analyzed the document and found nothing.
 func (s *Session) WaitForDiagnostics(ctx context.Context, uri string) []Diagnostic {
 	// First attempt immediately.
 	if diags := s.CollectDiagnostics(ctx, uri); len(diags) > 0 {
@@ -305,6 +333,10 @@ func (s *Session) WaitForDiagnostics(ctx context.Context, uri string) []Diagnost
 		case <-ctx.Done():
 			return nil
 		case <-ticker.C:
+			if diags, ok := s.publishedDiagnostics(uri); ok {
+				return diags
+			}
+
 			if diags := s.CollectDiagnostics(ctx, uri); len(diags) > 0 {
 				return diags
 			}
@@ -312,6 +344,16 @@ func (s *Session) WaitForDiagnostics(ctx context.Context, uri string) []Diagnost
 	}
 }
 
+// publishedDiagnostics reports whether the server has published diagnostics
+// for the URI (possibly none) since they were last cleared.
+func (s *Session) publishedDiagnostics(uri string) ([]Diagnostic, bool) {
+	s.pushDiagsMu.Lock()
+	defer s.pushDiagsMu.Unlock()
+
+	diags, ok := s.pushDiags[uri]
+	return diags, ok
+}
+
 // Diagnostics returns formatted diagnostics for a single file.
 func (s *Session) Diagnostics(ctx context.Context, uri string, filePath string) (string, error) {
 	diags := s.CollectDiagnostics(ctx, uri)
@@ -357,6 +399,39 @@ func (s *Session) Implementation(ctx context.Context, uri string, line, column i
 	return s.locationOp(ctx, "textDocument/implementation", "Implementations", uri, line, column)
 }
 
+// TypeDefinition returns the location(s) of the type of the symbol at the given position.
+func (s *Session) TypeDefinition(ctx context.Context, uri string, line, column int) (string, error) {
+	return s.locationOp(ctx, "textDocument/typeDefinition", "Type Definition", uri, line, column)
+}
+
+// SignatureHelp returns the signature of the call surrounding the given position.
+func (s *Session) SignatureHelp(ctx context.Context, uri string, line, column int) (string, error) {
+	params := TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{Line: line, Character: column},
+	}
+
+	var result json.RawMessage
+	if err := s.CallAndAwait(ctx, "textDocument/signatureHelp", params, &result); err != nil {
+		return "", err
+	}
+
+	if result == nil || string(result) == "null" {
+		return "No signature help available", nil
+	}
+
+	var help SignatureHelp
+	if err := json.Unmarshal(result, &help); err != nil {
+		return "", err
+	}
+
+	if len(help.Signatures) == 0 {
+		return "No signature help available", nil
+	}
+
+	return formatSignatureHelp(help), nil
+}
+
 // Hover returns hover information for the symbol at the given position.
 func (s *Session) Hover(ctx context.Context, uri string, line, column int) (string, error) {
 	params := TextDocumentPositionParams{
@@ -438,12 +513,184 @@ func (s *Session) CallHierarchy(ctx context.Context, uri string, line, column in
 	return s.outgoingCalls(ctx, items[0])
 }
 
+// Rename returns the workspace edit that renames the symbol at the given position.
+func (s *Session) Rename(ctx context.Context, uri string, line, column int, newName string) (*WorkspaceEdit, error) {
+	params := RenameParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{Line: line, Character: column},
+		NewName:      newName,
+	}
+
+	var result json.RawMessage
+	if err := s.CallAndAwait(ctx, "textDocument/rename", params, &result); err != nil {
+		return nil, err
+	}
+
+	if result == nil || string(result) == "null" {
+		return nil, nil
+	}
+
+	var edit WorkspaceEdit
+	if err := json.Unmarshal(result, &edit); err != nil {
+		return nil, err
+	}
+
+	return &edit, nil
+}
+
+// CodeActions returns the code actions available for a range, passing along
+// the diagnostics known for the document that overlap it.
+func (s *Session) CodeActions(ctx context.Context, uri string, rng Range) ([]CodeAction, error) {
+	var diags []Diagnostic
+	for _, d := range s.PushDiagnostics(uri) {
+		if rangesOverlap(d.Range, rng) {
+			diags = append(diags, d)
+		}
+	}
+
+	params := CodeActionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Range:        rng,
+		Context:      CodeActionContext{Diagnostics: diags},
+	}
+
+	if params.Context.Diagnostics == nil {
+		params.Context.Diagnostics = []Diagnostic{}
+	}
+
+	var result json.RawMessage
+	if err := s.CallAndAwait(ctx, "textDocument/codeAction", params, &result); err != nil {
+		return nil, err
+	}
+
+	if result == nil || string(result) == "null" {
+		return nil, nil
+	}
+
+	var actions []CodeAction
+	if err := json.Unmarshal(result, &actions); err != nil {
+		return nil, err
+	}
+
+	return actions, nil
+}
+
+// ResolveCodeAction fills in the edit of a code action that the server
+// returned without one.
+func (s *Session) ResolveCodeAction(ctx context.Context, action CodeAction) (CodeAction, error) {
+	var resolved CodeAction
+	if err := s.CallAndAwait(ctx, "codeAction/resolve", action, &resolved); err != nil {
+		return action, err
+	}
+
+	return resolved, nil
+}
+
+// ExecuteCommand runs a server command. Workspace edits the server asks the
+// client to apply while executing it are passed to apply, whose error is
+// reported back to the server as the outcome.
+func (s *Session) ExecuteCommand(ctx context.Context, cmd Command, apply func(WorkspaceEdit) error) error {
+	s.applyEditMu.Lock()
+	s.applyEdit = apply
+	s.applyEditMu.Unlock()
+
+	params := ExecuteCommandParams{
+		Command:   cmd.Command,
+		Arguments: cmd.Arguments,
+	}
+
+	var result json.RawMessage
+	err := s.CallAndAwait(ctx, "workspace/executeCommand", params, &result)
+
+	s.applyEditMu.Lock()
+	s.applyEdit = nil
+	s.applyEditMu.Unlock()
+
+	return err
+}
+
+// Format returns the edits that format a whole document.
+func (s *Session) Format(ctx context.Context, uri string, options FormattingOptions) ([]TextEdit, error) {
+	params := DocumentFormattingParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Options:      options,
+	}
+
+	var edits []TextEdit
+	if err := s.CallAndAwait(ctx, "textDocument/formatting", params, &edits); err != nil {
+		return nil, err
+	}
+
+	return edits, nil
+}
+
+// FormatRange returns the edits that format a range of a document.
+func (s *Session) FormatRange(ctx context.Context, uri string, rng Range, options FormattingOptions) ([]TextEdit, error) {
+	params := DocumentRangeFormattingParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Range:        rng,
+		Options:      options,
+	}
+
+	var edits []TextEdit
+	if err := s.CallAndAwait(ctx, "textDocument/rangeFormatting", params, &edits); err != nil {
+		return nil, err
+	}
+
+	return edits, nil
+}
+
 // --- private helpers ---
 
+// handleApplyEdit applies a server-initiated edit for the running
+// ExecuteCommand and reports whether it was written. Edits outside of a
+// command are refused: the agent only changes files it was asked to change.
+func (s *Session) handleApplyEdit(edit WorkspaceEdit) ApplyWorkspaceEditResult {
+	s.applyEditMu.Lock()
+	apply := s.applyEdit
+	s.applyEditMu.Unlock()
+
+	if apply == nil {
+		return ApplyWorkspaceEditResult{FailureReason: "no command in progress"}
+	}
+
+	if err := apply(edit); err != nil {
+		return ApplyWorkspaceEditResult{FailureReason: err.Error()}
+	}
+
+	return ApplyWorkspaceEditResult{Applied: true}
+}
+
+func signatureHelpCapabilities() SignatureHelpClientCapabilities {
+	var c SignatureHelpClientCapabilities
+	c.SignatureInformation.DocumentationFormat = []string{"plaintext", "markdown"}
+	c.SignatureInformation.ParameterInformation.LabelOffsetSupport = true
+	c.SignatureInformation.ActiveParameterSupport = true
+	return c
+}
+
+func codeActionLiteralSupport() *CodeActionLiteralSupport {
+	support := &CodeActionLiteralSupport{}
+	support.CodeActionKind.ValueSet = []string{
+		"", "quickfix", "refactor", "refactor.extract", "refactor.inline", "refactor.rewrite",
+		"source", "source.organizeImports", "source.fixAll",
+	}
+	return support
+}
+
+func rangesOverlap(a, b Range) bool {
+	return !positionBefore(a.End, b.Start) && !positionBefore(b.End, a.Start)
+}
+
+func positionBefore(a, b Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+}
+
 func (s *Session) initialize(ctx context.Context) error {
 	params := InitializeParams{
-		ProcessID: os.Getpid(),
-		RootURI:   s.rootURI,
+		ProcessID:             os.Getpid(),
+		RootURI:               s.rootURI,
+		InitializationOptions: s.server.InitializationOptions,
 		Capabilities: ClientCapabilities{
 			TextDocument: TextDocumentClientCapabilities{
 				Synchronization: TextDocumentSyncClientCapabilities{DidSave: true},
@@ -454,6 +701,25 @@ func (s *Session) initialize(ctx context.Context) error {
 				DocumentSymbol:  DocumentSymbolClientCapabilities{},
 				Diagnostic:      DiagnosticClientCapabilities{},
 				CallHierarchy:   CallHierarchyClientCapabilities{},
+				TypeDefinition:  TypeDefinitionClientCapabilities{},
+				SignatureHelp:   signatureHelpCapabilities(),
+				Rename:          RenameClientCapabilities{},
+				CodeAction: CodeActionClientCapabilities{
+					CodeActionLiteralSupport: codeActionLiteralSupport(),
+					IsPreferredSupport:       true,
+					DataSupport:              true,
+					ResolveSupport:           &CodeActionResolveSupport{Properties: []string{"edit"}},
+				},
+				Formatting:      FormattingClientCapabilities{},
+				RangeFormatting: FormattingClientCapabilities{},
+			},
+			Workspace: WorkspaceClientCapabilities{
+				ApplyEdit:     true,
+				Configuration: true,
+				WorkspaceEdit: WorkspaceEditClientCapabilities{
+					DocumentChanges:    true,
+					ResourceOperations: []string{"create", "rename", "delete"},
+				},
 			},
 		},
 	}
@@ -468,6 +734,12 @@ func (s *Session) initialize(ctx context.Context) error {
 		return fmt.Errorf("initialized notification: %w", err)
 	}
 
+	// Servers that don't pull settings via workspace/configuration expect
+	// them pushed once after startup.
+	if s.server.Settings != nil {
+		s.conn.Notify(ctx, "workspace/didChangeConfiguration", DidChangeConfigurationParams{Settings: s.server.Settings})
+	}
+
 	return nil
 }
 
@@ -0,0 +1,187 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/sahilm/fuzzy"
+)
+
+// DefaultSymbolLimit caps workspace symbol results when no limit is given.
+const DefaultSymbolLimit = 50
+
+type symbolMatch struct {
+	Name      string
+	Kind      int
+	Container string
+
+	Path string
+	Line int // 0-based, -1 if the server omitted the range
+}
+
+// WorkspaceSymbols searches symbols by name across the workspace. Every
+// detected server is queried; results outside of the project roots it was
+// detected for (dependencies, the standard library) are dropped, and the
+// rest are ranked by how well their name fuzzy-matches the query. An empty
+// query lists what the servers return in their order.
+func (m *Manager) WorkspaceSymbols(ctx context.Context, query string, limit int) (string, error) {
+	servers := m.DetectServers()
+	if len(servers) == 0 {
+		return "", fmt.Errorf("no LSP servers detected in workspace")
+	}
+
+	if limit <= 0 {
+		limit = DefaultSymbolLimit
+	}
+
+	var matches []symbolMatch
+	seen := make(map[string]bool)
+
+	for _, server := range servers {
+		session, err := m.GetSessionByServer(ctx, server)
+		if err != nil {
+			continue
+		}
+
+		var result json.RawMessage
+		if err := session.CallAndAwait(ctx, "workspace/symbol", WorkspaceSymbolParams{Query: query}, &result); err != nil || result == nil || string(result) == "null" {
+			continue
+		}
+
+		roots := m.serverRoots(server)
+
+		for _, sym := range parseWorkspaceSymbols(result) {
+			if !inRoots(roots, sym.Path) {
+				continue
+			}
+
+			key := fmt.Sprintf("%s\x00%s\x00%d", sym.Name, sym.Path, sym.Line)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			matches = append(matches, sym)
+		}
+	}
+
+	matches = rankSymbols(query, matches)
+
+	if len(matches) == 0 {
+		return "No symbols found", nil
+	}
+
+	total := len(matches)
+	if total > limit {
+		matches = matches[:limit]
+	}
+
+	return formatWorkspaceSymbols(matches, total, m.workingDir), nil
+}
+
+// serverRoots returns the project directories a server was detected for.
+func (m *Manager) serverRoots(server Server) []string {
+	var dirs []string
+
+	for _, root := range m.detect().Roots {
+		for _, s := range root.Servers {
+			if s.Command == server.Command {
+				dirs = append(dirs, root.Dir)
+				break
+			}
+		}
+	}
+
+	return dirs
+}
+
+// inRoots reports whether path lies within one of the roots and outside of
+// dependency directories such as node_modules or vendor.
+func inRoots(roots []string, path string) bool {
+	for _, root := range roots {
+		if !isSubPath(root, path) {
+			continue
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			continue
+		}
+
+		ignored := false
+		for _, part := range strings.Split(filepath.Dir(rel), string(filepath.Separator)) {
+			if ignoredDirs[part] {
+				ignored = true
+				break
+			}
+		}
+
+		if !ignored {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseWorkspaceSymbols accepts both SymbolInformation[] and the newer
+// WorkspaceSymbol[]. Both share the same shape except that a WorkspaceSymbol
+// may omit its location range, so decoding as the latter handles either.
+func parseWorkspaceSymbols(data json.RawMessage) []symbolMatch {
+	var wsSymbols []WorkspaceSymbol
+	if err := unmarshalResult(data, &wsSymbols); err != nil {
+		return nil
+	}
+
+	matches := make([]symbolMatch, 0, len(wsSymbols))
+	for _, s := range wsSymbols {
+		line := -1
+		if s.Location.Range != nil {
+			line = s.Location.Range.Start.Line
+		}
+
+		matches = append(matches, symbolMatch{
+			Name:      s.Name,
+			Kind:      s.Kind,
+			Container: s.ContainerName,
+			Path:      URIToPath(s.Location.URI),
+			Line:      line,
+		})
+	}
+	return matches
+}
+
+// rankSymbols orders symbols by how well their names fuzzy-match the query
+// and drops those that don't match. Servers may qualify names ("pkg.Func",
+// "Class::method"); a query that isn't qualified itself is matched against
+// the bare name.
+func rankSymbols(query string, symbols []symbolMatch) []symbolMatch {
+	if query == "" {
+		return symbols
+	}
+
+	names := make([]string, len(symbols))
+
+	for i, sym := range symbols {
+		names[i] = sym.Name
+
+		if strings.ContainsAny(query, ".:") {
+			continue
+		}
+
+		if j := strings.LastIndexAny(sym.Name, ".:"); j >= 0 && j < len(sym.Name)-1 {
+			names[i] = sym.Name[j+1:]
+		}
+	}
+
+	var ranked []symbolMatch
+
+	for _, match := range fuzzy.Find(query, names) {
+		ranked = append(ranked, symbols[match.Index])
+	}
+
+	return ranked
+}
@@ -0,0 +1,93 @@
+package lsp
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRankSymbols(t *testing.T) {
+	var symbols []symbolMatch
+	for _, name := range []string{"Unsaved", "newServerHandler", "server.NewServer", "NewServerOptions", "RenewServe"} {
+		symbols = append(symbols, symbolMatch{Name: name})
+	}
+
+	var names []string
+	for _, sym := range rankSymbols("NewServer", symbols) {
+		names = append(names, sym.Name)
+	}
+
+	// Qualified names are matched on their last segment; names that don't
+	// contain the query's characters in order are dropped.
+	want := []string{"server.NewServer", "NewServerOptions", "newServerHandler"}
+	if !slices.Equal(names, want) {
+		t.Errorf("rankSymbols() = %v, want %v", names, want)
+	}
+
+	if got := rankSymbols("", symbols); len(got) != len(symbols) {
+		t.Errorf("expected empty query to keep all %d symbols, got %d", len(symbols), len(got))
+	}
+}
+
+func TestInRoots(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "repo", "backend")
+	roots := []string{root}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(root, "main.go"), true},
+		{filepath.Join(root, "pkg", "server", "server.go"), true},
+		{filepath.Join(root, "vendor", "github.com", "x", "x.go"), false},
+		{filepath.Join(string(filepath.Separator), "repo", "frontend", "app.ts"), false},
+		{filepath.Join(string(filepath.Separator), "usr", "lib", "go", "src", "fmt", "print.go"), false},
+	}
+
+	for _, tt := range tests {
+		if got := inRoots(roots, tt.path); got != tt.want {
+			t.Errorf("inRoots(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseWorkspaceSymbols(t *testing.T) {
+	data := json.RawMessage(`[{"name":"Foo","kind":12,"containerName":"pkg","location":{"uri":"file:///repo/a.go"}}]`)
+
+	matches := parseWorkspaceSymbols(data)
+	if len(matches) != 1 {
+		t.Fatalf("got %d matches, want 1", len(matches))
+	}
+
+	if matches[0].Line != -1 || matches[0].Container != "pkg" || matches[0].Name != "Foo" {
+		t.Errorf("unexpected match: %+v", matches[0])
+	}
+}
+
+func TestFormatSignatureHelp(t *testing.T) {
+	help := SignatureHelp{
+		Signatures: []SignatureInformation{{
+			Label:         "func Copy(dst Writer, src Reader) (int64, error)",
+			Documentation: &HoverContents{Value: "Copy copies from src to dst."},
+			Parameters: []ParameterInformation{
+				{Label: json.RawMessage(`"dst Writer"`)},
+				{Label: json.RawMessage(`[22,32]`)},
+			},
+		}},
+		ActiveParameter: 1,
+	}
+
+	result := formatSignatureHelp(help)
+
+	if !strings.Contains(result, "* func Copy(dst Writer, src Reader) (int64, error)") {
+		t.Errorf("expected active signature label, got:\n%s", result)
+	}
+	if !strings.Contains(result, "Active parameter: src Reader") {
+		t.Errorf("expected offset label to resolve, got:\n%s", result)
+	}
+	if !strings.Contains(result, "Copy copies from src to dst.") {
+		t.Errorf("expected documentation, got:\n%s", result)
+	}
+}
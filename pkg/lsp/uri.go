@@ -39,7 +39,8 @@ func isAbsolutePath(path string) bool {
 	return filepath.IsAbs(path) || strings.HasPrefix(slashPath, "//") || hasWindowsDrivePrefix(slashPath)
 }
 
-func uriToPath(uri string) string {
+// URIToPath converts a file:// URI back to a local file path.
+func URIToPath(uri string) string {
 	u, err := url.Parse(uri)
 	if err != nil || u.Scheme != "file" {
 		return uri
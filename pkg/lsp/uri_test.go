@@ -42,9 +42,9 @@ func TestUriToPath(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		got := uriToPath(tt.uri)
+		got := URIToPath(tt.uri)
 		if got != tt.want {
-			t.Errorf("uriToPath(%q) = %q, want %q", tt.uri, got, tt.want)
+			t.Errorf("URIToPath(%q) = %q, want %q", tt.uri, got, tt.want)
 		}
 	}
 }
@@ -62,7 +62,7 @@ func TestFileURI_Roundtrip(t *testing.T) {
 
 	for _, path := range paths {
 		uri := FileURI(path)
-		got := uriToPath(uri)
+		got := URIToPath(uri)
 		if got != path {
 			t.Errorf("roundtrip failed: %q -> %q -> %q", path, uri, got)
 		}
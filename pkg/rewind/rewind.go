@@ -9,6 +9,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -29,8 +30,32 @@ type Checkpoint struct {
 	Hash    string
 	Message string
 	Time    time.Time
+
+	// Session and Messages identify the conversation and its length when
+	// the checkpoint was taken; Messages is -1 if unknown. They let a
+	// checkpoint double as a fork point. The shadow repo outlives sessions,
+	// so Messages only means something within Session.
+	Session  string
+	Messages int
+}
+
+// ForkIndex returns the message index to fork session at, if the checkpoint
+// was taken in that session.
+func (c Checkpoint) ForkIndex(session string) (int, bool) {
+	if session == "" || c.Session != session || c.Messages <= 0 {
+		return 0, false
+	}
+
+	return c.Messages, true
 }
 
+// Trailers recording Checkpoint.Session and Checkpoint.Messages in the
+// commit message.
+const (
+	sessionTrailer  = "Wingman-Session: "
+	messagesTrailer = "Wingman-Messages: "
+)
+
 // Manager runs a shadow git repo in /tmp that snapshots the working dir on
 // each user turn. Init is async — New returns immediately and methods block
 // on a ready channel until the shadow repo is set up. The shadow repo works
@@ -147,7 +172,7 @@ func (m *Manager) init() {
 	// we build a self-contained shadow repo from a working-tree snapshot.
 	var userStorer storer.EncodedObjectStorer
 	var userHead *object.Commit
-	if userRepo, err := git.PlainOpen(m.workingDir); err == nil {
+	if userRepo, err := git.PlainOpenWithOptions(m.workingDir, &git.PlainOpenOptions{EnableDotGitCommonDir: true}); err == nil {
 		userStorer = userRepo.Storer
 		if ref, err := userRepo.Head(); err == nil {
 			if c, err := userRepo.CommitObject(ref.Hash()); err == nil {
@@ -350,7 +375,10 @@ func readXDGIgnore() []gitignore.Pattern {
 	return ps
 }
 
-func (m *Manager) Commit(message string) error {
+// Commit snapshots the working dir. session and messages identify the
+// conversation and its length at this point (see Checkpoint.Messages); pass
+// "" and -1 if there is none.
+func (m *Manager) Commit(message string, session string, messages int) error {
 	if err := m.ready(); err != nil {
 		return err
 	}
@@ -372,6 +400,10 @@ func (m *Manager) Commit(message string) error {
 		return fmt.Errorf("failed to add files: %w", err)
 	}
 
+	if session != "" && messages >= 0 {
+		message = fmt.Sprintf("%s\n\n%s%s\n%s%d", message, sessionTrailer, session, messagesTrailer, messages)
+	}
+
 	if _, err := m.worktree.Commit(message, &git.CommitOptions{
 		Author: &object.Signature{
 			Name:  "wingman",
@@ -407,11 +439,12 @@ func (m *Manager) List() ([]Checkpoint, error) {
 	var checkpoints []Checkpoint
 
 	err = iter.ForEach(func(c *object.Commit) error {
-		checkpoints = append(checkpoints, Checkpoint{
-			Hash:    c.Hash.String(),
-			Message: c.Message,
-			Time:    c.Author.When,
-		})
+		checkpoint := parseCommitMessage(c.Message)
+
+		checkpoint.Hash = c.Hash.String()
+		checkpoint.Time = c.Author.When
+
+		checkpoints = append(checkpoints, checkpoint)
 		return nil
 	})
 	if err != nil {
@@ -421,6 +454,41 @@ func (m *Manager) List() ([]Checkpoint, error) {
 	return checkpoints, nil
 }
 
+func parseCommitMessage(s string) Checkpoint {
+	c := Checkpoint{Messages: -1}
+
+	i := strings.LastIndex(s, "\n\nWingman-")
+
+	if i < 0 {
+		c.Message = strings.TrimRight(s, "\n")
+		return c
+	}
+
+	c.Message = s[:i]
+
+	for line := range strings.Lines(s[i+2:]) {
+		line = strings.TrimSpace(line)
+
+		if v, ok := strings.CutPrefix(line, sessionTrailer); ok {
+			c.Session = v
+		}
+
+		if v, ok := strings.CutPrefix(line, messagesTrailer); ok {
+			if n, err := strconv.Atoi(v); err == nil {
+				c.Messages = n
+			}
+		}
+	}
+
+	// Checkpoints from before sessions were recorded cannot be matched to
+	// a conversation.
+	if c.Session == "" {
+		c.Messages = -1
+	}
+
+	return c
+}
+
 // Restore rolls the working tree back to a checkpoint and re-baselines so
 // "diff from baseline" thereafter means "since the restore." Excludes are
 // loaded before Clean so gitignored files (node_modules, .env, build
@@ -0,0 +1,425 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent"
+)
+
+// Export formats.
+const (
+	FormatMarkdown = "md"
+	FormatHTML     = "html"
+	FormatJSON     = "json"
+)
+
+// ExportOptions adds context that isn't stored in the session itself.
+type ExportOptions struct {
+	// Diffs are the workspace changes to append (typically from rewind's
+	// DiffFromBaseline while the session is still live).
+	Diffs []ExportDiff
+}
+
+// ExportDiff is a unified diff for one file.
+type ExportDiff struct {
+	Path  string `json:"path"`
+	Patch string `json:"patch"`
+}
+
+// Transcript is the readable form of a session used by every export
+// format. Encrypted reasoning and compaction blobs are dropped and all text
+// is passed through Redact.
+type Transcript struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	ParentID  string `json:"parent_id,omitempty"`
+	ForkIndex int    `json:"fork_index,omitempty"`
+
+	Usage agent.Usage `json:"usage"`
+
+	Entries []Entry      `json:"entries"`
+	Diffs   []ExportDiff `json:"diffs,omitempty"`
+}
+
+// Entry is one step of a transcript: a message, a reasoning summary, a tool
+// call with its output, or a compaction marker.
+type Entry struct {
+	Kind string `json:"kind"` // user, assistant, reasoning, tool, compaction
+	Text string `json:"text,omitempty"`
+
+	Tool   string `json:"tool,omitempty"`
+	Args   string `json:"args,omitempty"`
+	Output string `json:"output,omitempty"`
+
+	// Diff is set for edit/write calls, rendered from their arguments.
+	Diff string `json:"diff,omitempty"`
+}
+
+// NewTranscript converts a session into its export form.
+func NewTranscript(s Session, options *ExportOptions) Transcript {
+	if options == nil {
+		options = new(ExportOptions)
+	}
+
+	t := Transcript{
+		ID:        s.ID,
+		Title:     Redact(s.Title),
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+
+		ParentID:  s.ParentID,
+		ForkIndex: s.ForkIndex,
+
+		Usage: s.State.Usage,
+	}
+
+	// Tool results arrive in later messages; index them by call ID so each
+	// call renders together with its output.
+	outputs := make(map[string]string)
+
+	for _, m := range s.State.Messages {
+		for _, c := range m.Content {
+			if c.ToolResult != nil {
+				outputs[c.ToolResult.ID] = c.ToolResult.Content
+			}
+		}
+	}
+
+	for _, m := range s.State.Messages {
+		if m.Hidden {
+			continue
+		}
+
+		for _, c := range m.Content {
+			switch {
+			case c.ToolCall != nil:
+				t.Entries = append(t.Entries, Entry{
+					Kind: "tool",
+					Tool: c.ToolCall.Name,
+
+					Args:   Redact(prettyJSON(c.ToolCall.Args)),
+					Output: Redact(outputs[c.ToolCall.ID]),
+
+					Diff: Redact(toolDiff(c.ToolCall)),
+				})
+
+			case c.Reasoning != nil:
+				if summary := strings.TrimSpace(c.Reasoning.Summary); summary != "" {
+					t.Entries = append(t.Entries, Entry{Kind: "reasoning", Text: Redact(summary)})
+				}
+
+			case c.Compaction != nil:
+				t.Entries = append(t.Entries, Entry{Kind: "compaction", Text: "Earlier context was compacted."})
+
+			case c.Text != "":
+				kind := "assistant"
+
+				if m.Role == agent.RoleUser {
+					kind = "user"
+				}
+
+				t.Entries = append(t.Entries, Entry{Kind: kind, Text: Redact(c.Text)})
+
+			case c.Refusal != "":
+				t.Entries = append(t.Entries, Entry{Kind: "assistant", Text: Redact(c.Refusal)})
+			}
+		}
+	}
+
+	for _, d := range options.Diffs {
+		t.Diffs = append(t.Diffs, ExportDiff{Path: d.Path, Patch: Redact(d.Patch)})
+	}
+
+	return t
+}
+
+// Export writes s to w in the given format (md, html or json).
+func Export(w io.Writer, s Session, format string, options *ExportOptions) error {
+	t := NewTranscript(s, options)
+
+	switch format {
+	case FormatMarkdown, "markdown", "":
+		return writeMarkdown(w, t)
+
+	case FormatHTML:
+		return htmlTemplate.Execute(w, t)
+
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(t)
+
+	default:
+		return fmt.Errorf("unsupported export format %q (want md, html or json)", format)
+	}
+}
+
+// ContentType returns the MIME type for an export format.
+func ContentType(format string) string {
+	switch format {
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	case FormatJSON:
+		return "application/json"
+	default:
+		return "text/markdown; charset=utf-8"
+	}
+}
+
+func writeMarkdown(w io.Writer, t Transcript) error {
+	var b strings.Builder
+
+	title := t.Title
+	if title == "" {
+		title = "Session " + t.ID
+	}
+
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	fmt.Fprintf(&b, "- Session: `%s`\n", t.ID)
+
+	if t.ParentID != "" {
+		fmt.Fprintf(&b, "- Forked from: `%s` at message %d\n", t.ParentID, t.ForkIndex)
+	}
+
+	fmt.Fprintf(&b, "- Created: %s\n", t.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "- Updated: %s\n", t.UpdatedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "- Tokens: %d input (%d cached), %d output\n", t.Usage.InputTokens, t.Usage.CachedTokens, t.Usage.OutputTokens)
+
+	if t.Usage.Cost > 0 {
+		fmt.Fprintf(&b, "- Cost: $%.2f\n", t.Usage.Cost)
+	}
+
+	b.WriteString("\n")
+
+	for _, e := range t.Entries {
+		switch e.Kind {
+		case "user":
+			fmt.Fprintf(&b, "## User\n\n%s\n\n", e.Text)
+
+		case "assistant":
+			fmt.Fprintf(&b, "## Assistant\n\n%s\n\n", e.Text)
+
+		case "reasoning":
+			fmt.Fprintf(&b, "> **Reasoning:** %s\n\n", strings.ReplaceAll(e.Text, "\n", "\n> "))
+
+		case "compaction":
+			fmt.Fprintf(&b, "_%s_\n\n", e.Text)
+
+		case "tool":
+			fmt.Fprintf(&b, "<details>\n<summary>Tool: <code>%s</code></summary>\n\n", template.HTMLEscapeString(e.Tool))
+
+			if e.Args != "" {
+				fmt.Fprintf(&b, "%s\n\n", fence(e.Args, "json"))
+			}
+
+			if e.Diff != "" {
+				fmt.Fprintf(&b, "%s\n\n", fence(e.Diff, "diff"))
+			}
+
+			if e.Output != "" {
+				fmt.Fprintf(&b, "Output:\n\n%s\n\n", fence(e.Output, ""))
+			}
+
+			b.WriteString("</details>\n\n")
+		}
+	}
+
+	if len(t.Diffs) > 0 {
+		b.WriteString("## Changes\n\n")
+
+		for _, d := range t.Diffs {
+			fmt.Fprintf(&b, "<details>\n<summary><code>%s</code></summary>\n\n%s\n\n</details>\n\n", template.HTMLEscapeString(d.Path), fence(d.Patch, "diff"))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// fence wraps s in a code fence long enough not to collide with backticks
+// inside s.
+func fence(s, lang string) string {
+	ticks := "```"
+
+	for strings.Contains(s, ticks) {
+		ticks += "`"
+	}
+
+	return ticks + lang + "\n" + strings.TrimRight(s, "\n") + "\n" + ticks
+}
+
+func prettyJSON(s string) string {
+	var v any
+
+	if json.Unmarshal([]byte(s), &v) != nil {
+		return s
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+
+	if err != nil {
+		return s
+	}
+
+	return string(data)
+}
+
+// toolDiff renders edit/write arguments as a unified-style diff so file
+// changes read naturally in the transcript. apply_patch calls already carry
+// a patch.
+func toolDiff(call *agent.ToolCall) string {
+	var args map[string]any
+
+	if json.Unmarshal([]byte(call.Args), &args) != nil {
+		return ""
+	}
+
+	if call.Name == "apply_patch" {
+		patch, _ := args["patch"].(string)
+		return patch
+	}
+
+	path, _ := args["path"].(string)
+
+	// Each change is an old/new pair; batch edits carry several.
+	var changes []map[string]any
+
+	switch call.Name {
+	case "edit":
+		changes = []map[string]any{args}
+
+		if edits, ok := args["edits"].([]any); ok && len(edits) > 0 {
+			changes = nil
+
+			for _, e := range edits {
+				if m, ok := e.(map[string]any); ok {
+					changes = append(changes, m)
+				}
+			}
+		}
+	case "write":
+		changes = []map[string]any{{"new_text": args["content"]}}
+	default:
+		return ""
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", path, path)
+
+	for _, c := range changes {
+		before, _ := c["old_text"].(string)
+		after, _ := c["new_text"].(string)
+
+		for _, line := range splitLines(before) {
+			b.WriteString("-" + line + "\n")
+		}
+
+		for _, line := range splitLines(after) {
+			b.WriteString("+" + line + "\n")
+		}
+	}
+
+	return b.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+var htmlTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"time": func(t time.Time) string { return t.Format(time.RFC3339) },
+	"diffLines": func(s string) []diffLine {
+		var lines []diffLine
+
+		for _, l := range splitLines(s) {
+			class := ""
+
+			switch {
+			case strings.HasPrefix(l, "+++"), strings.HasPrefix(l, "---"):
+				class = "meta"
+			case strings.HasPrefix(l, "+"):
+				class = "add"
+			case strings.HasPrefix(l, "-"):
+				class = "del"
+			case strings.HasPrefix(l, "@@"):
+				class = "meta"
+			}
+
+			lines = append(lines, diffLine{Class: class, Text: l})
+		}
+
+		return lines
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{if .Title}}{{.Title}}{{else}}Session {{.ID}}{{end}}</title>
+<style>
+body { font: 14px/1.5 -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 960px; margin: 2rem auto; padding: 0 1rem; color: #1f2328; }
+h1 { font-size: 1.5rem; }
+.meta { color: #59636e; font-size: 12px; }
+.entry { margin: 1rem 0; padding: .75rem 1rem; border-radius: 6px; white-space: pre-wrap; }
+.user { background: #ddf4ff; }
+.assistant { background: #f6f8fa; }
+.reasoning { color: #59636e; font-style: italic; border-left: 3px solid #d1d9e0; border-radius: 0; }
+.compaction { color: #59636e; text-align: center; }
+details { margin: .5rem 0; border: 1px solid #d1d9e0; border-radius: 6px; padding: .25rem .75rem; }
+summary { cursor: pointer; font-family: ui-monospace, monospace; font-size: 12px; }
+pre { background: #f6f8fa; padding: .5rem; overflow-x: auto; font-size: 12px; }
+pre span { display: block; }
+pre .add { background: #dafbe1; }
+pre .del { background: #ffebe9; }
+pre .meta { color: #59636e; }
+</style>
+</head>
+<body>
+<h1>{{if .Title}}{{.Title}}{{else}}Session {{.ID}}{{end}}</h1>
+<p class="meta">
+Session <code>{{.ID}}</code>{{if .ParentID}} · forked from <code>{{.ParentID}}</code> at message {{.ForkIndex}}{{end}}<br>
+{{time .CreatedAt}} – {{time .UpdatedAt}}<br>
+Tokens: {{.Usage.InputTokens}} input ({{.Usage.CachedTokens}} cached), {{.Usage.OutputTokens}} output{{if .Usage.Cost}} · Cost: {{printf "$%.2f" .Usage.Cost}}{{end}}
+</p>
+{{range .Entries}}
+{{- if eq .Kind "tool"}}
+<details>
+<summary>{{.Tool}}</summary>
+{{if .Args}}<pre>{{.Args}}</pre>{{end}}
+{{if .Diff}}<pre>{{range diffLines .Diff}}<span class="{{.Class}}">{{.Text}}</span>{{end}}</pre>{{end}}
+{{if .Output}}<pre>{{.Output}}</pre>{{end}}
+</details>
+{{- else}}
+<div class="entry {{.Kind}}">{{.Text}}</div>
+{{- end}}
+{{end}}
+{{if .Diffs}}
+<h2>Changes</h2>
+{{range .Diffs}}
+<details>
+<summary>{{.Path}}</summary>
+<pre>{{range diffLines .Patch}}<span class="{{.Class}}">{{.Text}}</span>{{end}}</pre>
+</details>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+type diffLine struct {
+	Class string
+	Text  string
+}
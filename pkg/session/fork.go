@@ -0,0 +1,156 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent"
+	"github.com/google/uuid"
+)
+
+// Fork copies the first n messages of session id into a new session with its
+// own ID, so an alternative approach can be tried without losing the original
+// path. n is clamped to the session length; n <= 0 forks the whole session.
+func Fork(sessionsDir string, id string, n int) (Session, error) {
+	parent, err := Load(sessionsDir, id)
+	if err != nil {
+		return Session{}, err
+	}
+
+	messages := parent.State.Messages
+
+	if n <= 0 || n > len(messages) {
+		n = len(messages)
+	}
+
+	// Never start a branch on a dangling tool call: the API rejects a
+	// function call without its output.
+	for n > 0 && hasPendingToolCalls(messages[:n]) {
+		n--
+	}
+
+	if n == 0 {
+		return Session{}, fmt.Errorf("nothing to fork")
+	}
+
+	now := time.Now()
+
+	s := Session{
+		ID:        uuid.New().String(),
+		CreatedAt: now,
+		UpdatedAt: now,
+
+		State: agent.State{
+			Messages: append([]agent.Message(nil), messages[:n]...),
+			Usage:    parent.State.Usage,
+		},
+
+		ParentID:  parent.ID,
+		ForkIndex: n,
+	}
+
+	s.Title = extractTitle(s.State.Messages)
+
+	if err := os.MkdirAll(sessionsDir, 0755); err != nil {
+		return Session{}, fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+
+	if err := write(sessionsDir, s); err != nil {
+		return Session{}, err
+	}
+
+	return s, nil
+}
+
+func hasPendingToolCalls(messages []agent.Message) bool {
+	pending := make(map[string]bool)
+
+	for _, m := range messages {
+		for _, c := range m.Content {
+			if c.ToolCall != nil {
+				pending[c.ToolCall.ID] = true
+			}
+
+			if c.ToolResult != nil {
+				delete(pending, c.ToolResult.ID)
+			}
+		}
+	}
+
+	return len(pending) > 0
+}
+
+// Node is a session in a fork tree.
+type Node struct {
+	Session  Session
+	Children []*Node
+}
+
+// Tree arranges sessions (as returned by List) into fork trees. Roots are
+// ordered like the input; children by creation time. A session whose parent
+// has been deleted becomes a root.
+func Tree(sessions []Session) []*Node {
+	nodes := make(map[string]*Node, len(sessions))
+
+	for _, s := range sessions {
+		nodes[s.ID] = &Node{Session: s}
+	}
+
+	var roots []*Node
+
+	for _, s := range sessions {
+		node := nodes[s.ID]
+
+		if parent, ok := nodes[s.ParentID]; ok && s.ParentID != s.ID {
+			parent.Children = append(parent.Children, node)
+			continue
+		}
+
+		roots = append(roots, node)
+	}
+
+	for _, n := range nodes {
+		sort.SliceStable(n.Children, func(i, j int) bool {
+			return n.Children[i].Session.CreatedAt.Before(n.Children[j].Session.CreatedAt)
+		})
+	}
+
+	return roots
+}
+
+// Family returns the tree containing session id, or nil if it isn't among
+// sessions.
+func Family(sessions []Session, id string) *Node {
+	byID := make(map[string]Session, len(sessions))
+
+	for _, s := range sessions {
+		byID[s.ID] = s
+	}
+
+	root, ok := byID[id]
+
+	if !ok {
+		return nil
+	}
+
+	for seen := map[string]bool{root.ID: true}; ; {
+		parent, ok := byID[root.ParentID]
+
+		if !ok || seen[parent.ID] {
+			break
+		}
+
+		seen[parent.ID] = true
+		root = parent
+	}
+
+	for _, n := range Tree(sessions) {
+		if n.Session.ID == root.ID {
+			return n
+		}
+	}
+
+	return nil
+}
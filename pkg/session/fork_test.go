@@ -0,0 +1,58 @@
+package session
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent"
+)
+
+func TestForkCopiesPrefixAndBuildsTree(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sessions")
+
+	messages := []agent.Message{
+		{Role: agent.RoleUser, Content: []agent.Content{{Text: "first"}}},
+		{Role: agent.RoleAssistant, Content: []agent.Content{{ToolCall: &agent.ToolCall{ID: "c1", Name: "read"}}}},
+		{Role: agent.RoleUser, Content: []agent.Content{{ToolResult: &agent.ToolResult{ID: "c1", Name: "read"}}}},
+		{Role: agent.RoleAssistant, Content: []agent.Content{{Text: "done"}}},
+		{Role: agent.RoleUser, Content: []agent.Content{{Text: "second"}}},
+	}
+
+	if err := Save(dir, "root", agent.State{Messages: messages}); err != nil {
+		t.Fatal(err)
+	}
+
+	forked, err := Fork(dir, "root", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if forked.ParentID != "root" || forked.ForkIndex != 4 || len(forked.State.Messages) != 4 {
+		t.Fatalf("unexpected fork: parent=%q index=%d len=%d", forked.ParentID, forked.ForkIndex, len(forked.State.Messages))
+	}
+
+	// Forking inside a tool round backs up to before the dangling call.
+	inner, err := Fork(dir, "root", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if inner.ForkIndex != 1 {
+		t.Fatalf("expected fork index 1, got %d", inner.ForkIndex)
+	}
+
+	// Saving the fork again keeps its lineage.
+	if err := Save(dir, forked.ID, forked.State); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	family := Family(sessions, forked.ID)
+	if family == nil || family.Session.ID != "root" || len(family.Children) != 2 {
+		t.Fatalf("expected root with 2 branches, got %+v", family)
+	}
+}
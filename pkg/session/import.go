@@ -0,0 +1,564 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent"
+	"github.com/google/uuid"
+)
+
+// Transcript sources supported by Import.
+const (
+	SourceClaude = "claude"
+	SourceCodex  = "codex"
+	SourceGemini = "gemini"
+)
+
+// importer converts one transcript format. Each returns the messages, the
+// source's own session ID (if any) and the first/last timestamps seen.
+type importer func(data []byte) (imported, error)
+
+type imported struct {
+	ID       string
+	Messages []agent.Message
+
+	Start time.Time
+	End   time.Time
+}
+
+var importers = map[string]importer{
+	SourceClaude: importClaude,
+	SourceCodex:  importCodex,
+	SourceGemini: importGemini,
+}
+
+// toolNames maps other agents' built-in tools to their wingman equivalents,
+// so the model sees a familiar history when the session is resumed. Unknown
+// tools keep their original name.
+var toolNames = map[string]string{
+	// Claude Code
+	"Bash":  "shell",
+	"Read":  "read",
+	"Write": "write",
+	"Edit":  "edit",
+	"Glob":  "find",
+	"Grep":  "grep",
+	"LS":    "ls",
+
+	// Codex
+	"exec_command": "shell",
+	"local_shell":  "shell",
+
+	// Gemini CLI
+	"run_shell_command":   "shell",
+	"read_file":           "read",
+	"write_file":          "write",
+	"replace":             "edit",
+	"glob":                "find",
+	"search_file_content": "grep",
+	"list_directory":      "ls",
+}
+
+// toolArgs maps argument names of imported calls to wingman's, keyed by the
+// wingman tool name, so resumed history matches the tool schemas and
+// exports can render edits. Arguments without an equivalent are kept.
+var toolArgs = map[string]map[string]string{
+	"read":  {"file_path": "path", "absolute_path": "path"},
+	"write": {"file_path": "path"},
+	"edit":  {"file_path": "path", "old_string": "old_text", "new_string": "new_text"},
+	"ls":    {"dir_path": "path"},
+	"grep":  {"include": "glob"},
+	"shell": {"cmd": "command"},
+
+	// Codex sends patches as raw custom tool input.
+	"apply_patch": {"input": "patch"},
+}
+
+// ImportFile reads a transcript written by another agent CLI and converts it
+// into a Session. source is one of the Source constants, or "" to detect it
+// from the content.
+func ImportFile(path string, source string) (Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to read transcript: %w", err)
+	}
+
+	return Import(data, source)
+}
+
+// Import converts a transcript into a Session. The session reuses the
+// source's ID when it has one, so importing the same transcript twice
+// updates the earlier import instead of duplicating it.
+func Import(data []byte, source string) (Session, error) {
+	if source == "" {
+		source = DetectSource(data)
+	}
+
+	imp, ok := importers[source]
+	if !ok {
+		return Session{}, fmt.Errorf("unknown transcript format %q (want claude, codex or gemini)", source)
+	}
+
+	result, err := imp(data)
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to import %s transcript: %w", source, err)
+	}
+
+	messages := pairToolMessages(result.Messages)
+
+	if len(messages) == 0 {
+		return Session{}, fmt.Errorf("transcript contains no messages")
+	}
+
+	id := result.ID
+	if _, err := uuid.Parse(id); err != nil {
+		id = uuid.New().String()
+	}
+
+	now := time.Now()
+
+	s := Session{
+		ID:        id,
+		Title:     extractTitle(messages),
+		CreatedAt: result.Start,
+		UpdatedAt: result.End,
+
+		State: agent.State{Messages: messages},
+	}
+
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = now
+	}
+
+	if s.UpdatedAt.IsZero() {
+		s.UpdatedAt = now
+	}
+
+	return s, nil
+}
+
+// Store writes an imported session into sessionsDir, keeping its original
+// timestamps (Save would stamp it with the current time).
+func Store(sessionsDir string, s Session) error {
+	if err := os.MkdirAll(sessionsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+
+	return write(sessionsDir, s)
+}
+
+// DetectSource guesses the transcript format from its first records.
+func DetectSource(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+
+	// Gemini CLI saves whole-session JSON documents.
+	if bytes.HasPrefix(trimmed, []byte("{")) && json.Valid(trimmed) {
+		var doc map[string]json.RawMessage
+
+		if json.Unmarshal(trimmed, &doc) == nil {
+			if _, ok := doc["messages"]; ok {
+				return SourceGemini
+			}
+		}
+	}
+
+	source := ""
+
+	forEachLine(data, func(line []byte) bool {
+		var rec map[string]json.RawMessage
+
+		if json.Unmarshal(line, &rec) != nil {
+			return true
+		}
+
+		switch {
+		case rec["payload"] != nil || rec["record_type"] != nil:
+			source = SourceCodex
+		case rec["sessionId"] != nil || rec["parentUuid"] != nil:
+			source = SourceClaude
+		case rec["parts"] != nil:
+			source = SourceGemini
+		case rec["type"] != nil && rec["role"] != nil:
+			// Older Codex rollouts store bare response items.
+			source = SourceCodex
+		}
+
+		return source == ""
+	})
+
+	return source
+}
+
+func forEachLine(data []byte, fn func(line []byte) bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+
+		if len(line) == 0 {
+			continue
+		}
+
+		if !fn(line) {
+			return
+		}
+	}
+}
+
+// pairToolMessages drops tool calls without results and results without
+// calls (transcripts cut off mid-turn), which the Responses API rejects.
+func pairToolMessages(messages []agent.Message) []agent.Message {
+	calls := make(map[string]bool)
+	results := make(map[string]bool)
+
+	for _, m := range messages {
+		for _, c := range m.Content {
+			if c.ToolCall != nil {
+				calls[c.ToolCall.ID] = true
+			}
+
+			if c.ToolResult != nil {
+				results[c.ToolResult.ID] = true
+			}
+		}
+	}
+
+	var out []agent.Message
+
+	for _, m := range messages {
+		if len(m.Content) == 0 {
+			continue
+		}
+
+		c := m.Content[0]
+
+		if c.ToolCall != nil && !results[c.ToolCall.ID] {
+			continue
+		}
+
+		if c.ToolResult != nil && !calls[c.ToolResult.ID] {
+			continue
+		}
+
+		out = append(out, m)
+	}
+
+	return out
+}
+
+// builder accumulates messages in wingman's layout: text per message, one
+// tool call or result per message (mirroring what Agent.Send records).
+type builder struct {
+	messages []agent.Message
+
+	// calls remembers each call's tool name and args for its result.
+	calls map[string]agent.ToolCall
+
+	// pending queues generated IDs of calls whose format has none, by tool.
+	pending map[string][]string
+
+	start time.Time
+	end   time.Time
+}
+
+func newBuilder() *builder {
+	return &builder{
+		calls:   make(map[string]agent.ToolCall),
+		pending: make(map[string][]string),
+	}
+}
+
+func (b *builder) seen(ts string) {
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return
+	}
+
+	if b.start.IsZero() || t.Before(b.start) {
+		b.start = t
+	}
+
+	if t.After(b.end) {
+		b.end = t
+	}
+}
+
+func (b *builder) text(role agent.MessageRole, text string) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+
+	// Merge consecutive text chunks of the same role into one message.
+	if n := len(b.messages); n > 0 {
+		last := &b.messages[n-1]
+
+		if last.Role == role && len(last.Content) > 0 && last.Content[0].Text != "" {
+			last.Content = append(last.Content, agent.Content{Text: text})
+			return
+		}
+	}
+
+	b.messages = append(b.messages, agent.Message{
+		Role:    role,
+		Content: []agent.Content{{Text: text}},
+	})
+}
+
+func (b *builder) toolCall(id, name string, args any) {
+	if id == "" {
+		id = "call_" + uuid.New().String()
+	}
+
+	if mapped, ok := toolNames[name]; ok {
+		name = mapped
+	}
+
+	tc := agent.ToolCall{ID: id, Name: name, Args: mapArgs(name, argsString(args))}
+	b.calls[id] = tc
+
+	b.messages = append(b.messages, agent.Message{
+		Role:    agent.RoleAssistant,
+		Content: []agent.Content{{ToolCall: &tc}},
+	})
+}
+
+func (b *builder) toolResult(id, output string) {
+	tc := b.calls[id]
+
+	b.messages = append(b.messages, agent.Message{
+		Role: agent.RoleAssistant,
+		Content: []agent.Content{{ToolResult: &agent.ToolResult{
+			ID:      id,
+			Name:    tc.Name,
+			Args:    tc.Args,
+			Content: output,
+		}}},
+	})
+}
+
+func (b *builder) result(id string) imported {
+	return imported{ID: id, Messages: b.messages, Start: b.start, End: b.end}
+}
+
+// argsString normalizes tool arguments to a JSON object string.
+func argsString(args any) string {
+	switch v := args.(type) {
+	case nil:
+		return "{}"
+	case string:
+		if json.Valid([]byte(v)) {
+			return v
+		}
+
+		data, _ := json.Marshal(map[string]string{"input": v})
+		return string(data)
+	case json.RawMessage:
+		if len(v) == 0 {
+			return "{}"
+		}
+
+		return argsString(string(v))
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "{}"
+		}
+
+		return string(data)
+	}
+}
+
+// mapArgs renames the arguments of a call to tool according to toolArgs.
+// Shell commands given as an argv array, as Codex does, are joined into a
+// command line.
+func mapArgs(tool string, args string) string {
+	names, ok := toolArgs[tool]
+
+	if !ok {
+		return args
+	}
+
+	var m map[string]any
+
+	if json.Unmarshal([]byte(args), &m) != nil {
+		return args
+	}
+
+	for from, to := range names {
+		v, ok := m[from]
+
+		if !ok {
+			continue
+		}
+
+		delete(m, from)
+
+		if _, exists := m[to]; !exists {
+			m[to] = v
+		}
+	}
+
+	if argv, ok := m["command"].([]any); ok && tool == "shell" {
+		m["command"] = commandLine(argv)
+	}
+
+	data, err := json.Marshal(m)
+
+	if err != nil {
+		return args
+	}
+
+	return string(data)
+}
+
+// commandLine turns an argv array into a command line. The script of
+// "bash -lc <script>" style invocations is used as is.
+func commandLine(argv []any) string {
+	var parts []string
+
+	for _, a := range argv {
+		s, _ := a.(string)
+		parts = append(parts, s)
+	}
+
+	if len(parts) == 3 && (parts[1] == "-c" || parts[1] == "-lc") {
+		return parts[2]
+	}
+
+	for i, p := range parts {
+		if p == "" || strings.ContainsAny(p, " \t\n'\"$`\\|&;<>()*?[]{}~#") {
+			parts[i] = "'" + strings.ReplaceAll(p, "'", `'\''`) + "'"
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// flattenText extracts text from the content shapes the various formats use:
+// a plain string, or an array of {type, text} blocks.
+func flattenText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+
+	var blocks []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+
+	if json.Unmarshal(raw, &blocks) == nil {
+		var parts []string
+
+		for _, b := range blocks {
+			if b.Text != "" {
+				parts = append(parts, b.Text)
+			}
+		}
+
+		return strings.Join(parts, "\n")
+	}
+
+	return string(raw)
+}
+
+// FindTranscript returns the most recent transcript the given agent CLI
+// wrote for workDir, using each tool's default storage location.
+func FindTranscript(source string, workDir string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	var candidates []string
+
+	switch source {
+	case SourceClaude:
+		// Claude Code names project dirs after the path with every
+		// non-alphanumeric character replaced by '-'.
+		project := strings.Map(func(r rune) rune {
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+				return r
+			}
+			return '-'
+		}, workDir)
+
+		candidates, _ = filepath.Glob(filepath.Join(home, ".claude", "projects", project, "*.jsonl"))
+
+	case SourceCodex:
+		// Rollouts are grouped by date, not project; the session_meta
+		// record at the top of each file names its working directory.
+		filepath.WalkDir(filepath.Join(home, ".codex", "sessions"), func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() && strings.HasPrefix(d.Name(), "rollout-") && strings.HasSuffix(d.Name(), ".jsonl") && codexCwd(path) == workDir {
+				candidates = append(candidates, path)
+			}
+			return nil
+		})
+
+	case SourceGemini:
+		sum := sha256.Sum256([]byte(workDir))
+		candidates, _ = filepath.Glob(filepath.Join(home, ".gemini", "tmp", hex.EncodeToString(sum[:]), "chats", "session-*.json"))
+
+	default:
+		return "", fmt.Errorf("unknown transcript source %q (want claude, codex or gemini)", source)
+	}
+
+	var latest string
+	var latestTime time.Time
+
+	for _, path := range candidates {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		if latest == "" || info.ModTime().After(latestTime) {
+			latest, latestTime = path, info.ModTime()
+		}
+	}
+
+	if latest == "" {
+		return "", fmt.Errorf("no %s transcripts found for %s", source, workDir)
+	}
+
+	return latest, nil
+}
+
+func codexCwd(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	line, _ := reader.ReadBytes('\n')
+
+	var rec struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Cwd string `json:"cwd"`
+		} `json:"payload"`
+	}
+
+	if json.Unmarshal(line, &rec) != nil || rec.Type != "session_meta" {
+		return ""
+	}
+
+	return rec.Payload.Cwd
+}
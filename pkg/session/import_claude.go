@@ -0,0 +1,118 @@
+package session
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent"
+)
+
+// importClaude reads a Claude Code transcript
+// (~/.claude/projects/<project>/<session>.jsonl): one record per line, with
+// Anthropic Messages content blocks under "message".
+func importClaude(data []byte) (imported, error) {
+	b := newBuilder()
+
+	var sessionID string
+
+	forEachLine(data, func(line []byte) bool {
+		var rec struct {
+			Type        string `json:"type"`
+			SessionID   string `json:"sessionId"`
+			Timestamp   string `json:"timestamp"`
+			IsMeta      bool   `json:"isMeta"`
+			IsSidechain bool   `json:"isSidechain"`
+
+			Message struct {
+				Role    string          `json:"role"`
+				Content json.RawMessage `json:"content"`
+			} `json:"message"`
+		}
+
+		if json.Unmarshal(line, &rec) != nil {
+			return true
+		}
+
+		if sessionID == "" {
+			sessionID = rec.SessionID
+		}
+
+		// Sidechains are subagent conversations; meta records are injected
+		// context (command output, reminders) rather than user input.
+		if rec.IsSidechain || rec.IsMeta || (rec.Type != "user" && rec.Type != "assistant") {
+			return true
+		}
+
+		b.seen(rec.Timestamp)
+
+		role := agent.RoleUser
+		if rec.Message.Role == "assistant" {
+			role = agent.RoleAssistant
+		}
+
+		var text string
+		if json.Unmarshal(rec.Message.Content, &text) == nil {
+			if !isClaudeCommandNoise(text) {
+				b.text(role, text)
+			}
+
+			return true
+		}
+
+		var blocks []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+
+			ID    string          `json:"id"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
+
+			ToolUseID string          `json:"tool_use_id"`
+			Content   json.RawMessage `json:"content"`
+			IsError   bool            `json:"is_error"`
+		}
+
+		if json.Unmarshal(rec.Message.Content, &blocks) != nil {
+			return true
+		}
+
+		for _, block := range blocks {
+			switch block.Type {
+			case "text":
+				if !isClaudeCommandNoise(block.Text) {
+					b.text(role, block.Text)
+				}
+
+			case "tool_use":
+				b.toolCall(block.ID, block.Name, block.Input)
+
+			case "tool_result":
+				output := flattenText(block.Content)
+
+				if block.IsError {
+					output = "error: " + output
+				}
+
+				b.toolResult(block.ToolUseID, output)
+			}
+		}
+
+		return true
+	})
+
+	return b.result(sessionID), nil
+}
+
+// isClaudeCommandNoise filters the wrapper records Claude Code writes for
+// local slash commands (/clear, /model, …) which carry no conversation.
+func isClaudeCommandNoise(text string) bool {
+	text = strings.TrimSpace(text)
+
+	for _, prefix := range []string{"<command-name>", "<command-message>", "<local-command-stdout>", "Caveat: The messages below were generated"} {
+		if strings.HasPrefix(text, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,128 @@
+package session
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent"
+)
+
+// importCodex reads a Codex rollout (~/.codex/sessions/YYYY/MM/DD/
+// rollout-*.jsonl). Current rollouts wrap Responses API items as
+// {"type":"response_item","payload":{…}}; older ones store the bare items.
+func importCodex(data []byte) (imported, error) {
+	b := newBuilder()
+
+	var sessionID string
+
+	forEachLine(data, func(line []byte) bool {
+		var rec struct {
+			Timestamp string          `json:"timestamp"`
+			Type      string          `json:"type"`
+			Payload   json.RawMessage `json:"payload"`
+		}
+
+		if json.Unmarshal(line, &rec) != nil {
+			return true
+		}
+
+		item := line
+
+		switch rec.Type {
+		case "session_meta":
+			var meta struct {
+				ID string `json:"id"`
+			}
+
+			json.Unmarshal(rec.Payload, &meta)
+			sessionID = meta.ID
+
+			b.seen(rec.Timestamp)
+			return true
+
+		case "response_item":
+			item = rec.Payload
+
+		case "event_msg", "turn_context", "compacted":
+			return true
+		}
+
+		b.seen(rec.Timestamp)
+		codexItem(b, item)
+
+		return true
+	})
+
+	return b.result(sessionID), nil
+}
+
+func codexItem(b *builder, data []byte) {
+	var item struct {
+		Type string `json:"type"`
+		Role string `json:"role"`
+
+		Content json.RawMessage `json:"content"`
+
+		Name      string          `json:"name"`
+		Arguments string          `json:"arguments"`
+		Input     string          `json:"input"`
+		CallID    string          `json:"call_id"`
+		Output    json.RawMessage `json:"output"`
+		Action    json.RawMessage `json:"action"`
+	}
+
+	if json.Unmarshal(data, &item) != nil {
+		return
+	}
+
+	switch item.Type {
+	case "message":
+		text := flattenText(item.Content)
+
+		switch item.Role {
+		case "user":
+			// Codex injects AGENTS.md and environment context as user
+			// messages; they're not part of the conversation.
+			if strings.HasPrefix(strings.TrimSpace(text), "<environment_context>") || strings.HasPrefix(strings.TrimSpace(text), "<user_instructions>") || strings.HasPrefix(strings.TrimSpace(text), "# AGENTS.md") {
+				return
+			}
+
+			b.text(agent.RoleUser, text)
+
+		case "assistant":
+			b.text(agent.RoleAssistant, text)
+		}
+
+	case "function_call":
+		b.toolCall(item.CallID, item.Name, item.Arguments)
+
+	case "custom_tool_call":
+		b.toolCall(item.CallID, item.Name, item.Input)
+
+	case "local_shell_call":
+		b.toolCall(item.CallID, "local_shell", item.Action)
+
+	case "function_call_output", "custom_tool_call_output":
+		b.toolResult(item.CallID, codexOutput(item.Output))
+	}
+}
+
+// codexOutput unwraps function outputs, which are either a plain string or
+// a JSON-encoded {"output": …, "metadata": …} string.
+func codexOutput(raw json.RawMessage) string {
+	var s string
+
+	if json.Unmarshal(raw, &s) != nil {
+		return flattenText(raw)
+	}
+
+	var wrapped struct {
+		Output *string `json:"output"`
+	}
+
+	if json.Unmarshal([]byte(s), &wrapped) == nil && wrapped.Output != nil {
+		return *wrapped.Output
+	}
+
+	return s
+}
@@ -0,0 +1,200 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent"
+)
+
+// importGemini reads Gemini CLI chats. Two shapes are accepted: the recorded
+// session document (~/.gemini/tmp/<hash>/chats/session-*.json) with a
+// "messages" array, and JSONL of Gemini API contents ({role, parts}) as
+// written by checkpoints and /chat save.
+func importGemini(data []byte) (imported, error) {
+	trimmed := bytes.TrimSpace(data)
+
+	var doc struct {
+		SessionID   string            `json:"sessionId"`
+		StartTime   string            `json:"startTime"`
+		LastUpdated string            `json:"lastUpdated"`
+		Messages    []json.RawMessage `json:"messages"`
+	}
+
+	if bytes.HasPrefix(trimmed, []byte("{")) && json.Unmarshal(trimmed, &doc) == nil && doc.Messages != nil {
+		b := newBuilder()
+		b.seen(doc.StartTime)
+		b.seen(doc.LastUpdated)
+
+		for _, m := range doc.Messages {
+			geminiRecord(b, m)
+		}
+
+		return b.result(doc.SessionID), nil
+	}
+
+	// A JSON array of contents, or one content per line.
+	var contents []json.RawMessage
+
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		if err := json.Unmarshal(trimmed, &contents); err != nil {
+			return imported{}, err
+		}
+	} else {
+		forEachLine(data, func(line []byte) bool {
+			contents = append(contents, append(json.RawMessage(nil), line...))
+			return true
+		})
+	}
+
+	b := newBuilder()
+
+	for _, c := range contents {
+		geminiRecord(b, c)
+	}
+
+	return b.result(""), nil
+}
+
+// geminiRecord handles one recorded message ({type, content, toolCalls}) or
+// one API content ({role, parts}).
+func geminiRecord(b *builder, data []byte) {
+	var rec struct {
+		Type      string          `json:"type"`
+		Timestamp string          `json:"timestamp"`
+		Content   json.RawMessage `json:"content"`
+
+		ToolCalls []struct {
+			ID     string          `json:"id"`
+			Name   string          `json:"name"`
+			Args   json.RawMessage `json:"args"`
+			Result json.RawMessage `json:"result"`
+		} `json:"toolCalls"`
+
+		Role  string            `json:"role"`
+		Parts []json.RawMessage `json:"parts"`
+	}
+
+	if json.Unmarshal(data, &rec) != nil {
+		return
+	}
+
+	b.seen(rec.Timestamp)
+
+	if rec.Parts != nil {
+		role := agent.RoleUser
+		if rec.Role == "model" {
+			role = agent.RoleAssistant
+		}
+
+		for _, p := range rec.Parts {
+			geminiPart(b, role, p)
+		}
+
+		return
+	}
+
+	switch rec.Type {
+	case "user":
+		b.text(agent.RoleUser, flattenText(rec.Content))
+
+	case "gemini", "model":
+		b.text(agent.RoleAssistant, flattenText(rec.Content))
+
+		for _, tc := range rec.ToolCalls {
+			b.toolCall(tc.ID, tc.Name, tc.Args)
+			b.toolResult(tc.ID, geminiResult(tc.Result))
+		}
+	}
+}
+
+func geminiPart(b *builder, role agent.MessageRole, data []byte) {
+	var part struct {
+		Text    string `json:"text"`
+		Thought bool   `json:"thought"`
+
+		FunctionCall *struct {
+			ID   string          `json:"id"`
+			Name string          `json:"name"`
+			Args json.RawMessage `json:"args"`
+		} `json:"functionCall"`
+
+		FunctionResponse *struct {
+			ID       string          `json:"id"`
+			Name     string          `json:"name"`
+			Response json.RawMessage `json:"response"`
+		} `json:"functionResponse"`
+	}
+
+	if json.Unmarshal(data, &part) != nil {
+		return
+	}
+
+	switch {
+	case part.FunctionCall != nil:
+		id := part.FunctionCall.ID
+		if id == "" {
+			// Older contents carry no IDs; responses are paired with the
+			// oldest open call of the same name.
+			id = fmt.Sprintf("gemini_%d", len(b.messages))
+			b.pending[part.FunctionCall.Name] = append(b.pending[part.FunctionCall.Name], id)
+		}
+
+		b.toolCall(id, part.FunctionCall.Name, part.FunctionCall.Args)
+
+	case part.FunctionResponse != nil:
+		id := part.FunctionResponse.ID
+		if queue := b.pending[part.FunctionResponse.Name]; id == "" && len(queue) > 0 {
+			id, b.pending[part.FunctionResponse.Name] = queue[0], queue[1:]
+		}
+
+		b.toolResult(id, geminiOutput(part.FunctionResponse.Response))
+
+	case part.Text != "" && !part.Thought:
+		b.text(role, part.Text)
+	}
+}
+
+// geminiResult extracts the output from a recorded tool call's result, a
+// list of parts holding functionResponse objects.
+func geminiResult(raw json.RawMessage) string {
+	var parts []struct {
+		FunctionResponse *struct {
+			Response json.RawMessage `json:"response"`
+		} `json:"functionResponse"`
+	}
+
+	if json.Unmarshal(raw, &parts) != nil {
+		return flattenText(raw)
+	}
+
+	var out string
+
+	for _, p := range parts {
+		if p.FunctionResponse != nil {
+			out += geminiOutput(p.FunctionResponse.Response)
+		}
+	}
+
+	return out
+}
+
+func geminiOutput(raw json.RawMessage) string {
+	var resp struct {
+		Output *string `json:"output"`
+		Error  *string `json:"error"`
+	}
+
+	if json.Unmarshal(raw, &resp) == nil {
+		if resp.Output != nil {
+			return *resp.Output
+		}
+
+		if resp.Error != nil {
+			return "error: " + *resp.Error
+		}
+	}
+
+	return string(raw)
+}
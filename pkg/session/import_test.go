@@ -0,0 +1,142 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent"
+)
+
+const claudeTranscript = `{"type":"summary","summary":"x"}
+{"type":"user","sessionId":"0b6a2c1e-9f7e-4d55-8f6e-2a1c3b4d5e6f","timestamp":"2026-01-02T10:00:00Z","message":{"role":"user","content":"Fix the login bug"}}
+{"type":"user","sessionId":"0b6a2c1e-9f7e-4d55-8f6e-2a1c3b4d5e6f","isMeta":true,"message":{"role":"user","content":"<command-name>/model</command-name>"}}
+{"type":"assistant","sessionId":"0b6a2c1e-9f7e-4d55-8f6e-2a1c3b4d5e6f","timestamp":"2026-01-02T10:00:05Z","message":{"role":"assistant","content":[{"type":"thinking","thinking":"hmm"},{"type":"text","text":"Looking."},{"type":"tool_use","id":"toolu_1","name":"Read","input":{"file_path":"login.go"}}]}}
+{"type":"user","sessionId":"0b6a2c1e-9f7e-4d55-8f6e-2a1c3b4d5e6f","timestamp":"2026-01-02T10:00:06Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":[{"type":"text","text":"package main"}]}]}}
+{"type":"assistant","sessionId":"0b6a2c1e-9f7e-4d55-8f6e-2a1c3b4d5e6f","timestamp":"2026-01-02T10:00:09Z","message":{"role":"assistant","content":[{"type":"tool_use","id":"toolu_2","name":"Bash","input":{"command":"go test"}}]}}
+`
+
+const codexTranscript = `{"timestamp":"2026-01-02T10:00:00Z","type":"session_meta","payload":{"id":"5c9f0a3e-1b2c-4d3e-8f4a-5b6c7d8e9f01","cwd":"/src/app"}}
+{"timestamp":"2026-01-02T10:00:01Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"<environment_context>cwd</environment_context>"}]}}
+{"timestamp":"2026-01-02T10:00:02Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"Add a health endpoint"}]}}
+{"timestamp":"2026-01-02T10:00:03Z","type":"response_item","payload":{"type":"reasoning","encrypted_content":"gAAA"}}
+{"timestamp":"2026-01-02T10:00:04Z","type":"response_item","payload":{"type":"function_call","name":"shell","arguments":"{\"command\":[\"ls\"]}","call_id":"call_1"}}
+{"timestamp":"2026-01-02T10:00:05Z","type":"response_item","payload":{"type":"function_call_output","call_id":"call_1","output":"{\"output\":\"main.go\\n\",\"metadata\":{\"exit_code\":0}}"}}
+{"timestamp":"2026-01-02T10:00:06Z","type":"event_msg","payload":{"type":"token_count"}}
+{"timestamp":"2026-01-02T10:00:07Z","type":"response_item","payload":{"type":"message","role":"assistant","content":[{"type":"output_text","text":"Done."}]}}
+`
+
+const geminiSession = `{"sessionId":"7d1e2f3a-4b5c-4d6e-9f7a-8b9c0d1e2f3a","startTime":"2026-01-02T10:00:00Z","lastUpdated":"2026-01-02T10:05:00Z","messages":[
+{"type":"user","content":"Explain main.go"},
+{"type":"gemini","content":"Reading it.","toolCalls":[{"id":"read-1","name":"read_file","args":{"absolute_path":"/src/main.go"},"result":[{"functionResponse":{"id":"read-1","name":"read_file","response":{"output":"package main"}}}]}]},
+{"type":"info","content":"ignored"},
+{"type":"gemini","content":"It is the entry point."}
+]}`
+
+const geminiContents = `{"role":"user","parts":[{"text":"List files"}]}
+{"role":"model","parts":[{"functionCall":{"name":"list_directory","args":{"path":"."}}}]}
+{"role":"user","parts":[{"functionResponse":{"name":"list_directory","response":{"output":"a.go"}}}]}
+{"role":"model","parts":[{"text":"One file.","thought":false}]}
+`
+
+func kinds(messages []agent.Message) []string {
+	var out []string
+
+	for _, m := range messages {
+		c := m.Content[0]
+
+		switch {
+		case c.ToolCall != nil:
+			out = append(out, "call:"+c.ToolCall.Name)
+		case c.ToolResult != nil:
+			out = append(out, "result:"+c.ToolResult.Content)
+		default:
+			out = append(out, string(m.Role)+":"+c.Text)
+		}
+	}
+
+	return out
+}
+
+func TestImport(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		data   string
+		source string
+		id     string
+		want   []string
+	}{
+		{
+			name:   "claude",
+			data:   claudeTranscript,
+			source: SourceClaude,
+			id:     "0b6a2c1e-9f7e-4d55-8f6e-2a1c3b4d5e6f",
+			// The trailing Bash call has no result and is dropped.
+			want: []string{"user:Fix the login bug", "assistant:Looking.", "call:read", "result:package main"},
+		},
+		{
+			name:   "codex",
+			data:   codexTranscript,
+			source: SourceCodex,
+			id:     "5c9f0a3e-1b2c-4d3e-8f4a-5b6c7d8e9f01",
+			want:   []string{"user:Add a health endpoint", "call:shell", "result:main.go\n", "assistant:Done."},
+		},
+		{
+			name:   "gemini session",
+			data:   geminiSession,
+			source: SourceGemini,
+			id:     "7d1e2f3a-4b5c-4d6e-9f7a-8b9c0d1e2f3a",
+			want:   []string{"user:Explain main.go", "assistant:Reading it.", "call:read", "result:package main", "assistant:It is the entry point."},
+		},
+		{
+			name:   "gemini contents",
+			data:   geminiContents,
+			source: SourceGemini,
+			want:   []string{"user:List files", "call:ls", "result:a.go", "assistant:One file."},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := DetectSource([]byte(tc.data)); got != tc.source {
+				t.Fatalf("DetectSource = %q, want %q", got, tc.source)
+			}
+
+			s, err := Import([]byte(tc.data), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.id != "" && s.ID != tc.id {
+				t.Errorf("ID = %q, want %q", s.ID, tc.id)
+			}
+
+			got := kinds(s.State.Messages)
+
+			if len(got) != len(tc.want) {
+				t.Fatalf("messages = %q, want %q", got, tc.want)
+			}
+
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("messages = %q, want %q", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestImportToolArgs(t *testing.T) {
+	for _, tc := range []struct {
+		tool string
+		args string
+		want string
+	}{
+		{"edit", `{"file_path":"a.go","old_string":"x","new_string":"y","replace_all":true}`, `{"new_text":"y","old_text":"x","path":"a.go","replace_all":true}`},
+		{"read", `{"absolute_path":"/src/main.go"}`, `{"path":"/src/main.go"}`},
+		{"shell", `{"command":["bash","-lc","go test ./..."]}`, `{"command":"go test ./..."}`},
+		{"shell", `{"command":["git","commit","-m","fix it"]}`, `{"command":"git commit -m 'fix it'"}`},
+		{"apply_patch", `{"input":"*** Begin Patch"}`, `{"patch":"*** Begin Patch"}`},
+		{"mcp_tool", `{"file_path":"a.go"}`, `{"file_path":"a.go"}`},
+	} {
+		if got := mapArgs(tc.tool, tc.args); got != tc.want {
+			t.Errorf("mapArgs(%s, %s) = %s, want %s", tc.tool, tc.args, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,536 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent"
+)
+
+// indexVersion is bumped whenever tokenization or the on-disk layout changes;
+// an index with a different version is discarded and rebuilt from the session
+// files.
+const indexVersion = 2
+
+// SearchResult is a session matching a Search query. Session carries the
+// metadata only (messages are stripped, as with List).
+type SearchResult struct {
+	Session Session
+
+	Score   float64
+	Snippet string
+	Files   []string
+}
+
+// index is an inverted index over all sessions in a directory. It lives next
+// to the sessions directory (sessions.index.json) so it can be thrown away and
+// rebuilt at any time without touching the sessions themselves.
+type index struct {
+	Version int `json:"version"`
+
+	Docs map[string]indexDoc `json:"docs"`
+
+	// Terms maps a token to the sessions containing it and how often.
+	Terms map[string]map[string]int `json:"terms"`
+}
+
+type indexDoc struct {
+	Title     string    `json:"title,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// ModTime and Size describe the session file when it was indexed; a
+	// file that differs in either changed since.
+	ModTime time.Time `json:"mod_time"`
+	Size    int64     `json:"size"`
+
+	Length int      `json:"length"`
+	Files  []string `json:"files,omitempty"`
+}
+
+// indexMu serializes index read-modify-write cycles within the process.
+// Concurrent processes may still race; the loser's entries are picked up by
+// the staleness check on the next Search.
+var indexMu sync.Mutex
+
+func indexPath(sessionsDir string) string {
+	return filepath.Join(filepath.Dir(sessionsDir), "sessions.index.json")
+}
+
+// Search returns sessions matching every term of query, best match first.
+// Words containing a slash match the paths of files the session touched,
+// e.g. "pkg/session/index.go". Save does not touch the index; sessions
+// written since the last Search are indexed on the fly, so the result
+// always reflects what is on disk.
+func Search(sessionsDir string, query string) ([]SearchResult, error) {
+	if sessionsDir == "" {
+		return nil, nil
+	}
+
+	terms, paths := parseQuery(query)
+
+	if len(terms) == 0 && len(paths) == 0 {
+		return nil, nil
+	}
+
+	indexMu.Lock()
+	idx, err := syncIndex(sessionsDir)
+	indexMu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var scores map[string]float64
+
+	// intersect keeps the sessions matched by every term so far.
+	intersect := func(matched map[string]float64) {
+		if scores == nil {
+			scores = matched
+			return
+		}
+
+		for id := range scores {
+			if s, ok := matched[id]; ok {
+				scores[id] += s
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	for _, path := range paths {
+		matched := make(map[string]float64)
+
+		for id, doc := range idx.Docs {
+			if slices.ContainsFunc(doc.Files, func(f string) bool {
+				return strings.Contains(strings.ToLower(f), path)
+			}) {
+				matched[id] = 1
+			}
+		}
+
+		idf := math.Log(1 + float64(len(idx.Docs))/math.Max(1, float64(len(matched))))
+
+		for id := range matched {
+			matched[id] = idf
+		}
+
+		intersect(matched)
+	}
+
+	for i, term := range terms {
+		matched := make(map[string]float64)
+
+		// The last term is matched as a prefix so results update sensibly
+		// while the user is still typing.
+		for token, postings := range idx.Terms {
+			if token != term && !(i == len(terms)-1 && strings.HasPrefix(token, term)) {
+				continue
+			}
+
+			idf := math.Log(1 + float64(len(idx.Docs))/float64(len(postings)))
+
+			for id, freq := range postings {
+				doc := idx.Docs[id]
+				tf := float64(freq) / (float64(freq) + 1.2*(0.25+0.75*float64(doc.Length)/avgLength(idx)))
+				matched[id] += tf * idf
+			}
+		}
+
+		intersect(matched)
+	}
+
+	results := make([]SearchResult, 0, len(scores))
+
+	for id, score := range scores {
+		doc := idx.Docs[id]
+
+		// Title hits are what users remember best; rank them up.
+		title := strings.ToLower(doc.Title)
+		for _, term := range terms {
+			if strings.Contains(title, term) {
+				score *= 1.5
+			}
+		}
+
+		results = append(results, SearchResult{
+			Session: Session{
+				ID:        id,
+				Title:     doc.Title,
+				CreatedAt: doc.CreatedAt,
+				UpdatedAt: doc.UpdatedAt,
+			},
+			Score: score,
+			Files: doc.Files,
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+
+		return results[i].Session.UpdatedAt.After(results[j].Session.UpdatedAt)
+	})
+
+	for i := range results {
+		if i >= 50 {
+			break
+		}
+
+		if s, err := Load(sessionsDir, results[i].Session.ID); err == nil {
+			results[i].Snippet = snippet(s.State.Messages, terms)
+		}
+	}
+
+	return results, nil
+}
+
+// syncIndex loads the index and brings it in line with the session files:
+// new or modified sessions are (re)indexed, deleted ones dropped.
+func syncIndex(sessionsDir string) (*index, error) {
+	idx := loadIndex(sessionsDir)
+
+	entries, err := os.ReadDir(sessionsDir)
+
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read sessions directory: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	changed := false
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		id := strings.TrimSuffix(entry.Name(), ".json")
+		seen[id] = true
+
+		info, err := entry.Info()
+
+		if err != nil {
+			continue
+		}
+
+		if doc, ok := idx.Docs[id]; ok && info.ModTime().Equal(doc.ModTime) && info.Size() == doc.Size {
+			continue
+		}
+
+		s, err := loadFile(filepath.Join(sessionsDir, entry.Name()))
+
+		if err != nil {
+			continue
+		}
+
+		if s.ID == "" {
+			s.ID = id
+		}
+
+		idx.add(s, info)
+		changed = true
+	}
+
+	for id := range idx.Docs {
+		if !seen[id] {
+			idx.remove(id)
+			changed = true
+		}
+	}
+
+	if changed {
+		idx.save(sessionsDir)
+	}
+
+	return idx, nil
+}
+
+func loadIndex(sessionsDir string) *index {
+	idx := &index{
+		Version: indexVersion,
+
+		Docs:  make(map[string]indexDoc),
+		Terms: make(map[string]map[string]int),
+	}
+
+	data, err := os.ReadFile(indexPath(sessionsDir))
+
+	if err != nil {
+		return idx
+	}
+
+	var stored index
+
+	if err := json.Unmarshal(data, &stored); err != nil || stored.Version != indexVersion || stored.Docs == nil || stored.Terms == nil {
+		return idx
+	}
+
+	return &stored
+}
+
+func (idx *index) save(sessionsDir string) error {
+	data, err := json.Marshal(idx)
+
+	if err != nil {
+		return err
+	}
+
+	// Write-then-rename so a crash mid-write never leaves a truncated index.
+	path := indexPath(sessionsDir)
+	tmp := path + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func (idx *index) add(s Session, file os.FileInfo) {
+	idx.remove(s.ID)
+
+	counts := make(map[string]int)
+	length := 0
+
+	addText := func(text string) {
+		for _, t := range tokenize(text) {
+			counts[t]++
+			length++
+		}
+	}
+
+	files := sessionFiles(s.State.Messages)
+
+	addText(s.Title)
+
+	for _, m := range s.State.Messages {
+		if m.Hidden {
+			continue
+		}
+
+		for _, c := range m.Content {
+			addText(c.Text)
+
+			if c.ToolCall != nil {
+				addText(c.ToolCall.Name)
+				addText(c.ToolCall.Args)
+			}
+		}
+	}
+
+	for term, n := range counts {
+		postings := idx.Terms[term]
+
+		if postings == nil {
+			postings = make(map[string]int)
+			idx.Terms[term] = postings
+		}
+
+		postings[s.ID] = n
+	}
+
+	idx.Docs[s.ID] = indexDoc{
+		Title:     s.Title,
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+
+		ModTime: file.ModTime(),
+		Size:    file.Size(),
+
+		Length: length,
+		Files:  files,
+	}
+}
+
+func (idx *index) remove(id string) {
+	if _, ok := idx.Docs[id]; !ok {
+		return
+	}
+
+	delete(idx.Docs, id)
+
+	for term, postings := range idx.Terms {
+		delete(postings, id)
+
+		if len(postings) == 0 {
+			delete(idx.Terms, term)
+		}
+	}
+}
+
+func avgLength(idx *index) float64 {
+	if len(idx.Docs) == 0 {
+		return 1
+	}
+
+	total := 0
+
+	for _, d := range idx.Docs {
+		total += d.Length
+	}
+
+	return math.Max(1, float64(total)/float64(len(idx.Docs)))
+}
+
+// sessionFiles returns the distinct file paths passed to tool calls, in the
+// order they were first touched.
+func sessionFiles(messages []agent.Message) []string {
+	var files []string
+
+	seen := make(map[string]bool)
+
+	for _, m := range messages {
+		for _, c := range m.Content {
+			if c.ToolCall == nil || c.ToolCall.Args == "" {
+				continue
+			}
+
+			var args map[string]any
+
+			if json.Unmarshal([]byte(c.ToolCall.Args), &args) != nil {
+				continue
+			}
+
+			for _, key := range []string{"path", "file_path", "filePath"} {
+				p, ok := args[key].(string)
+
+				if !ok || p == "" || p == "." || seen[p] {
+					continue
+				}
+
+				seen[p] = true
+				files = append(files, p)
+			}
+		}
+	}
+
+	return files
+}
+
+// parseQuery splits a query into words to look up in the index and path
+// fragments to match against the files of each session.
+func parseQuery(query string) (terms []string, paths []string) {
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if strings.Contains(word, "/") {
+			paths = append(paths, word)
+			continue
+		}
+
+		terms = append(terms, tokenize(word)...)
+	}
+
+	return terms, paths
+}
+
+// tokenize lowercases text and splits it into words. Underscores and digits
+// stay inside words so identifiers like max_tokens or oauth2 match as typed.
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+
+	tokens := fields[:0]
+
+	for _, f := range fields {
+		if len(f) < 2 || len(f) > 64 {
+			continue
+		}
+
+		tokens = append(tokens, f)
+	}
+
+	return tokens
+}
+
+// snippet returns a short excerpt around the first visible text mentioning
+// one of the terms.
+func snippet(messages []agent.Message, terms []string) string {
+	for _, m := range messages {
+		if m.Hidden {
+			continue
+		}
+
+		for _, c := range m.Content {
+			if c.Text == "" {
+				continue
+			}
+
+			for _, term := range terms {
+				pos, last := indexFold(c.Text, term)
+
+				if pos < 0 {
+					continue
+				}
+
+				start := max(0, pos-40)
+				end := min(len(c.Text), last+80)
+
+				// Don't cut UTF-8 sequences in half.
+				for start > 0 && !isRuneStart(c.Text[start]) {
+					start--
+				}
+
+				for end < len(c.Text) && !isRuneStart(c.Text[end]) {
+					end++
+				}
+
+				text := strings.Join(strings.Fields(c.Text[start:end]), " ")
+
+				if start > 0 {
+					text = "…" + text
+				}
+
+				if end < len(c.Text) {
+					text += "…"
+				}
+
+				return text
+			}
+		}
+	}
+
+	return ""
+}
+
+// indexFold returns the byte range of the first case-insensitive match of
+// term in s, or -1. Searching strings.ToLower(s) instead would give offsets
+// that are off wherever lowering changes the length of a rune.
+func indexFold(s, term string) (int, int) {
+	for i := range s {
+		j := i
+		matched := true
+
+		for _, tr := range term {
+			r, size := utf8.DecodeRuneInString(s[j:])
+
+			if size == 0 || unicode.ToLower(r) != unicode.ToLower(tr) {
+				matched = false
+				break
+			}
+
+			j += size
+		}
+
+		if matched {
+			return i, j
+		}
+	}
+
+	return -1, -1
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
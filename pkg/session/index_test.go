@@ -0,0 +1,122 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent"
+)
+
+func saveText(t *testing.T, dir, id string, texts ...string) {
+	t.Helper()
+
+	var messages []agent.Message
+	for _, text := range texts {
+		messages = append(messages, agent.Message{Role: agent.RoleUser, Content: []agent.Content{{Text: text}}})
+	}
+
+	if err := Save(dir, id, agent.State{Messages: messages}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSearchMessagesAndFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sessions")
+
+	saveText(t, dir, "a", "Fix the OAuth callback bug")
+	saveText(t, dir, "b", "Refactor the session store")
+
+	if err := Save(dir, "c", agent.State{Messages: []agent.Message{
+		{Role: agent.RoleUser, Content: []agent.Content{{Text: "tidy up"}}},
+		{Role: agent.RoleAssistant, Content: []agent.Content{{ToolCall: &agent.ToolCall{Name: "edit", Args: `{"path":"pkg/auth/oauth.go"}`}}}},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := Search(dir, "oauth")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 || results[0].Session.ID != "a" {
+		t.Fatalf("expected a (title hit) then c, got %+v", results)
+	}
+
+	if results[0].Snippet == "" {
+		t.Fatal("expected a snippet for the message hit")
+	}
+
+	// Path words match touched files, not the same words in text.
+	saveText(t, dir, "d", "where is pkg auth oauth go")
+
+	results, _ = Search(dir, "pkg/auth/oauth.go")
+	if len(results) != 1 || results[0].Session.ID != "c" || len(results[0].Files) != 1 {
+		t.Fatalf("expected file path match on c, got %+v", results)
+	}
+
+	results, _ = Search(dir, "tidy auth/oauth")
+	if len(results) != 1 || results[0].Session.ID != "c" {
+		t.Fatalf("expected path fragment match on c, got %+v", results)
+	}
+
+	// Every term must match; the last one as a prefix.
+	results, _ = Search(dir, "session sto")
+	if len(results) != 1 || results[0].Session.ID != "b" {
+		t.Fatalf("expected b, got %+v", results)
+	}
+}
+
+func TestSearchPicksUpUnindexedAndDeleted(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sessions")
+
+	saveText(t, dir, "a", "alpha")
+	saveText(t, dir, "b", "alpha beta")
+
+	// Simulate sessions written before the index existed.
+	os.Remove(indexPath(dir))
+
+	if results, _ := Search(dir, "alpha"); len(results) != 2 {
+		t.Fatalf("expected 2 results after rebuild, got %d", len(results))
+	}
+
+	if err := Delete(dir, "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if results, _ := Search(dir, "beta"); len(results) != 0 {
+		t.Fatalf("deleted session still found: %+v", results)
+	}
+}
+
+func TestSearchPicksUpRewrittenSessions(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sessions")
+
+	saveText(t, dir, "a", "alpha")
+
+	if results, _ := Search(dir, "alpha"); len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+
+	saveText(t, dir, "a", "alpha", "gamma")
+
+	if results, _ := Search(dir, "gamma"); len(results) != 1 {
+		t.Fatalf("rewritten session not reindexed: %+v", results)
+	}
+}
+
+func TestSnippetOffsets(t *testing.T) {
+	// "İ" grows from two to three bytes when lowered.
+	text := strings.Repeat("İ", 100) + " Needle here"
+
+	messages := []agent.Message{
+		{Role: agent.RoleUser, Content: []agent.Content{{Text: text}}},
+	}
+
+	got := snippet(messages, []string{"needle"})
+
+	if !strings.Contains(got, "Needle here") {
+		t.Errorf("unexpected snippet %q", got)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/adrianliechti/wingman-agent/pkg/agent"
@@ -19,6 +20,11 @@ type Session struct {
 	CreatedAt time.Time   `json:"created_at"`
 	UpdatedAt time.Time   `json:"updated_at"`
 	State     agent.State `json:"state"`
+
+	// ParentID and ForkIndex are set on sessions created by Fork: the
+	// session they branched from and how many of its messages were copied.
+	ParentID  string `json:"parent_id,omitempty"`
+	ForkIndex int    `json:"fork_index,omitempty"`
 }
 
 // Save writes the session to disk. It is a no-op if there are no messages, so
@@ -38,6 +44,8 @@ func Save(sessionsDir string, id string, state agent.State) error {
 
 	path := filepath.Join(sessionsDir, id+".json")
 
+	defer lock(path)()
+
 	now := time.Now()
 	s := Session{
 		ID:        id,
@@ -47,17 +55,55 @@ func Save(sessionsDir string, id string, state agent.State) error {
 	}
 
 	if existing, err := loadFile(path); err == nil {
+		// Keep the first title, which may have been generated; the first
+		// message can later be replaced by a compaction summary.
+		if existing.Title != "" {
+			s.Title = existing.Title
+		}
+
 		s.CreatedAt = existing.CreatedAt
+		s.ParentID = existing.ParentID
+		s.ForkIndex = existing.ForkIndex
 	} else {
 		s.CreatedAt = now
 	}
 
+	return write(sessionsDir, s)
+}
+
+// SetTitle replaces the title of a saved session.
+func SetTitle(sessionsDir string, id string, title string) error {
+	defer lock(filepath.Join(sessionsDir, id+".json"))()
+
+	s, err := Load(sessionsDir, id)
+	if err != nil {
+		return err
+	}
+
+	s.Title = title
+
+	return write(sessionsDir, s)
+}
+
+// locks serializes the read-modify-write of each session file, such as a
+// title generated in the background while the next turn is saved.
+var locks sync.Map
+
+func lock(path string) func() {
+	v, _ := locks.LoadOrStore(path, new(sync.Mutex))
+	mu := v.(*sync.Mutex)
+
+	mu.Lock()
+	return mu.Unlock
+}
+
+func write(sessionsDir string, s Session) error {
 	data, err := json.Marshal(s)
 	if err != nil {
 		return fmt.Errorf("failed to marshal session: %w", err)
 	}
 
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	if err := os.WriteFile(filepath.Join(sessionsDir, s.ID+".json"), data, 0644); err != nil {
 		return fmt.Errorf("failed to write session file: %w", err)
 	}
 
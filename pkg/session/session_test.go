@@ -0,0 +1,48 @@
+package session
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent"
+)
+
+func TestSetTitleWhileSaving(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sessions")
+
+	state := agent.State{Messages: []agent.Message{
+		{Role: agent.RoleUser, Content: []agent.Content{{Text: "fix the login bug"}}},
+	}}
+
+	if err := Save(dir, "s", state); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Go(func() {
+		if err := SetTitle(dir, "s", "Login fix"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	for range 50 {
+		wg.Go(func() {
+			if err := Save(dir, "s", state); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	wg.Wait()
+
+	s, err := Load(dir, "s")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Title != "Login fix" {
+		t.Errorf("expected generated title to survive concurrent saves, got %q", s.Title)
+	}
+}
@@ -0,0 +1,101 @@
+// Package telemetry exports OpenTelemetry traces over OTLP when the
+// standard OTEL_* environment variables ask for it.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+)
+
+// Setup installs a global tracer provider that exports spans over OTLP if
+// OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set.
+// OTEL_EXPORTER_OTLP_PROTOCOL picks "grpc" or "http/protobuf" (default);
+// headers, timeouts and the service name come from the usual variables.
+// The returned function flushes pending spans and never is nil.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	shutdown := func(context.Context) error { return nil }
+
+	if !enabled() {
+		return shutdown, nil
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+
+	switch protocol := protocol(); protocol {
+	case "grpc":
+		exporter, err = otlptracegrpc.New(ctx)
+	case "http/protobuf":
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return shutdown, fmt.Errorf("unsupported OTLP protocol %q: use grpc or http/protobuf", protocol)
+	}
+
+	if err != nil {
+		return shutdown, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES win over the default
+	// service name.
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName("wingman")),
+		resource.WithFromEnv(),
+	)
+
+	if err != nil {
+		return shutdown, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	// Export failures must not write over the terminal UI.
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(error) {}))
+
+	return func(ctx context.Context) error {
+		if err := provider.Shutdown(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			return fmt.Errorf("failed to flush traces: %w", err)
+		}
+
+		return nil
+	}, nil
+}
+
+func enabled() bool {
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		return false
+	}
+
+	if exporter := os.Getenv("OTEL_TRACES_EXPORTER"); exporter != "" && exporter != "otlp" {
+		return false
+	}
+
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+func protocol() string {
+	for _, key := range []string{"OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "OTEL_EXPORTER_OTLP_PROTOCOL"} {
+		if v := os.Getenv(key); v != "" {
+			return v
+		}
+	}
+
+	return "http/protobuf"
+}
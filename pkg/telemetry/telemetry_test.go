@@ -0,0 +1,153 @@
+package telemetry
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// collector receives OTLP trace exports over HTTP and gRPC.
+type collector struct {
+	coltracepb.UnimplementedTraceServiceServer
+
+	mu       sync.Mutex
+	requests []*coltracepb.ExportTraceServiceRequest
+}
+
+func (c *collector) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.requests = append(c.requests, req)
+
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, _ := io.ReadAll(r.Body)
+
+	var req coltracepb.ExportTraceServiceRequest
+
+	if r.URL.Path != "/v1/traces" || proto.Unmarshal(data, &req) != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	c.Export(r.Context(), &req)
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Write(nil)
+}
+
+// spans returns the service name and span names received.
+func (c *collector) spans() (string, []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var service string
+	var names []string
+
+	for _, req := range c.requests {
+		for _, rs := range req.ResourceSpans {
+			for _, attr := range rs.Resource.Attributes {
+				if attr.Key == "service.name" {
+					service = attr.Value.GetStringValue()
+				}
+			}
+
+			for _, ss := range rs.ScopeSpans {
+				for _, s := range ss.Spans {
+					names = append(names, s.Name)
+				}
+			}
+		}
+	}
+
+	return service, names
+}
+
+func TestSetup(t *testing.T) {
+	for _, protocol := range []string{"http/protobuf", "grpc"} {
+		t.Run(protocol, func(t *testing.T) {
+			c := &collector{}
+
+			if protocol == "grpc" {
+				l, err := net.Listen("tcp", "127.0.0.1:0")
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				s := grpc.NewServer()
+				coltracepb.RegisterTraceServiceServer(s, c)
+
+				go s.Serve(l)
+				t.Cleanup(s.Stop)
+
+				t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://"+l.Addr().String())
+			} else {
+				srv := httptest.NewServer(c)
+				t.Cleanup(srv.Close)
+
+				t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", srv.URL)
+			}
+
+			t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", protocol)
+
+			previous := otel.GetTracerProvider()
+			t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+			shutdown, err := Setup(t.Context())
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, span := otel.Tracer("test").Start(t.Context(), "invoke_agent")
+			span.End()
+
+			if err := shutdown(t.Context()); err != nil {
+				t.Fatal(err)
+			}
+
+			service, names := c.spans()
+
+			if service != "wingman" || len(names) != 1 || names[0] != "invoke_agent" {
+				t.Errorf("unexpected export: service %q, spans %v", service, names)
+			}
+		})
+	}
+}
+
+func TestSetupDisabled(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+
+	previous := otel.GetTracerProvider()
+
+	shutdown, err := Setup(t.Context())
+
+	if err != nil || shutdown == nil {
+		t.Fatalf("expected a no-op setup, got %v", err)
+	}
+
+	if otel.GetTracerProvider() != previous {
+		t.Error("expected the tracer provider to stay unset")
+	}
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "http/json")
+
+	if _, err := Setup(t.Context()); err == nil {
+		t.Error("expected an error for an unsupported protocol")
+	}
+}
@@ -0,0 +1,52 @@
+package text
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// LineDiff renders a compact line diff of oldContent → newContent: removed
+// lines as "-N text" and added lines as "+N text", numbered in the old and
+// new file respectively. Unchanged lines are omitted.
+func LineDiff(oldContent, newContent string) string {
+	dmp := diffmatchpatch.New()
+
+	// Create line-based diff for better readability
+	oldLines, newLines, lineArray := dmp.DiffLinesToChars(oldContent, newContent)
+	diffs := dmp.DiffMain(oldLines, newLines, false)
+	diffs = dmp.DiffCharsToLines(diffs, lineArray)
+	diffs = dmp.DiffCleanupSemantic(diffs)
+
+	var output strings.Builder
+	oldLineNum := 1
+	newLineNum := 1
+
+	for _, diff := range diffs {
+		lines := strings.Split(diff.Text, "\n")
+
+		// Remove empty last element from split if text ends with newline
+		if len(lines) > 0 && lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+
+		switch diff.Type {
+		case diffmatchpatch.DiffEqual:
+			oldLineNum += len(lines)
+			newLineNum += len(lines)
+		case diffmatchpatch.DiffDelete:
+			for _, line := range lines {
+				fmt.Fprintf(&output, "-%d %s\n", oldLineNum, line)
+				oldLineNum++
+			}
+		case diffmatchpatch.DiffInsert:
+			for _, line := range lines {
+				fmt.Fprintf(&output, "+%d %s\n", newLineNum, line)
+				newLineNum++
+			}
+		}
+	}
+
+	return output.String()
+}
@@ -33,7 +33,7 @@ func FormatTokens(n int64) string {
 // leading "/" so it's visually distinct as a workspace path rather than a
 // loose identifier.
 var fsTools = map[string]bool{
-	"read": true, "write": true, "edit": true,
+	"read": true, "write": true, "edit": true, "notebook_edit": true,
 	"ls": true, "find": true, "grep": true,
 }
 
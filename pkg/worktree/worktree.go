@@ -0,0 +1,151 @@
+package worktree
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Worktree is a linked git worktree on a dedicated branch. The agent runs
+// inside Path so the user's own checkout stays untouched until the session's
+// changes are merged back (or thrown away).
+type Worktree struct {
+	// RepoDir is the user's checkout the worktree was created from.
+	RepoDir string
+
+	// Path is the worktree directory the agent operates in.
+	Path string
+
+	// Branch is the session branch checked out in Path.
+	Branch string
+
+	// Base is the commit the branch was created at.
+	Base string
+}
+
+// Create adds a new worktree for repoDir under parentDir on a fresh
+// wingman/<id> branch starting at the current HEAD. IDs are random, so any
+// number of sessions can run against the same repo concurrently.
+func Create(ctx context.Context, repoDir, parentDir string) (*Worktree, error) {
+	top, err := git(ctx, repoDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("not a git repository: %w", err)
+	}
+
+	base, err := git(ctx, top, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("repository has no commits: %w", err)
+	}
+
+	id := strings.SplitN(uuid.New().String(), "-", 2)[0]
+
+	w := &Worktree{
+		RepoDir: top,
+		Path:    filepath.Join(parentDir, id),
+		Branch:  "wingman/" + id,
+		Base:    base,
+	}
+
+	if err := os.MkdirAll(parentDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create worktree directory: %w", err)
+	}
+
+	if _, err := git(ctx, top, "worktree", "add", "-b", w.Branch, w.Path, base); err != nil {
+		return nil, fmt.Errorf("failed to add worktree: %w", err)
+	}
+
+	return w, nil
+}
+
+// HasChanges reports whether the worktree has uncommitted changes or commits
+// on its branch beyond Base.
+func (w *Worktree) HasChanges(ctx context.Context) (bool, error) {
+	status, err := git(ctx, w.Path, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+
+	if status != "" {
+		return true, nil
+	}
+
+	count, err := git(ctx, w.Path, "rev-list", "--count", w.Base+"..HEAD")
+	if err != nil {
+		return false, err
+	}
+
+	return count != "0", nil
+}
+
+// Merge commits any pending changes on the session branch and squash-merges
+// the branch into the user's checkout. The result is left staged so the user
+// reviews and commits it themselves. The worktree is removed on success.
+func (w *Worktree) Merge(ctx context.Context, message string) error {
+	if message == "" {
+		message = "wingman: session changes"
+	}
+
+	status, err := git(ctx, w.Path, "status", "--porcelain")
+	if err != nil {
+		return err
+	}
+
+	if status != "" {
+		if _, err := git(ctx, w.Path, "add", "--all"); err != nil {
+			return err
+		}
+
+		if _, err := git(ctx, w.Path, "-c", "user.name=wingman", "-c", "user.email=wingman@local", "commit", "--no-verify", "-m", message); err != nil {
+			return fmt.Errorf("failed to commit worktree changes: %w", err)
+		}
+	}
+
+	if _, err := git(ctx, w.RepoDir, "merge", "--squash", w.Branch); err != nil {
+		return fmt.Errorf("failed to merge %s: %w", w.Branch, err)
+	}
+
+	return w.Remove(ctx)
+}
+
+// Remove deletes the worktree directory and its branch, discarding any
+// changes made in it.
+func (w *Worktree) Remove(ctx context.Context) error {
+	if _, err := git(ctx, w.RepoDir, "worktree", "remove", "--force", w.Path); err != nil {
+		return fmt.Errorf("failed to remove worktree: %w", err)
+	}
+
+	if _, err := git(ctx, w.RepoDir, "branch", "-D", w.Branch); err != nil {
+		return fmt.Errorf("failed to delete branch %s: %w", w.Branch, err)
+	}
+
+	return nil
+}
+
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,90 @@
+package worktree
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func initRepo(t *testing.T) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=test", "-c", "user.email=test@local", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		if _, err := git(ctx, dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestMergeStagesChanges(t *testing.T) {
+	repo := initRepo(t)
+	ctx := context.Background()
+
+	w, err := Create(ctx, repo, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changed, _ := w.HasChanges(ctx); changed {
+		t.Fatal("fresh worktree should have no changes")
+	}
+
+	if err := os.WriteFile(filepath.Join(w.Path, "a.txt"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if changed, _ := w.HasChanges(ctx); !changed {
+		t.Fatal("expected changes after writing a file")
+	}
+
+	if err := w.Merge(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(repo, "a.txt"))
+	if err != nil || string(data) != "hello\n" {
+		t.Fatalf("merged file = %q, %v", data, err)
+	}
+
+	if _, err := os.Stat(w.Path); !os.IsNotExist(err) {
+		t.Fatalf("worktree should be removed after merge, stat err = %v", err)
+	}
+}
+
+func TestRemoveDiscardsBranch(t *testing.T) {
+	repo := initRepo(t)
+	ctx := context.Background()
+
+	w, err := Create(ctx, repo, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.WriteFile(filepath.Join(w.Path, "a.txt"), []byte("x"), 0644)
+
+	if err := w.Remove(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(repo, "a.txt")); !os.IsNotExist(err) {
+		t.Fatal("discarded change leaked into the user's checkout")
+	}
+
+	if _, err := git(ctx, repo, "rev-parse", "--verify", w.Branch); err == nil {
+		t.Fatalf("branch %s should be deleted", w.Branch)
+	}
+}
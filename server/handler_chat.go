@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/adrianliechti/wingman-agent/pkg/agent"
 	"github.com/adrianliechti/wingman-agent/pkg/code"
@@ -46,13 +48,8 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Send current usage
-	usage := s.agent.Usage
-	if usage.InputTokens > 0 || usage.OutputTokens > 0 {
-		s.sendMessage(UsageEvent{
-			InputTokens:  usage.InputTokens,
-			CachedTokens: usage.CachedTokens,
-			OutputTokens: usage.OutputTokens,
-		})
+	if usage := s.agent.Usage; usage.InputTokens > 0 || usage.OutputTokens > 0 {
+		s.sendMessage(s.usageEvent())
 	}
 
 	// Send model info
@@ -73,8 +70,15 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 
 		switch msg.Type {
 		case MsgSend:
+			s.wsMu.Lock()
+			s.busy = true
+			s.wsMu.Unlock()
+
 			go s.handleSend(ctx, msg)
 
+		case MsgSteer:
+			s.handleSteer(ctx, msg)
+
 		case MsgCancel:
 			s.wsMu.Lock()
 			if s.streamCancel != nil {
@@ -97,7 +101,8 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (s *Server) handleSend(ctx context.Context, msg ClientMessage) {
+// messageInput builds the agent input for a client message.
+func (s *Server) messageInput(msg ClientMessage) []agent.Content {
 	var input []agent.Content
 
 	if msg.Text != "" {
@@ -113,6 +118,88 @@ func (s *Server) handleSend(ctx context.Context, msg ClientMessage) {
 		input = append(input, agent.Content{Text: fmt.Sprintf("[File: %s]", f)})
 	}
 
+	return input
+}
+
+// handleSteer takes a message sent while the agent works. It goes into the
+// running turn, or waits for it to end when msg.Queue is set or it is a
+// slash command. Without a running turn it starts one.
+func (s *Server) handleSteer(ctx context.Context, msg ClientMessage) {
+	s.wsMu.Lock()
+
+	busy := s.busy
+
+	switch {
+	case !busy:
+		s.busy = true
+	case msg.Queue || strings.HasPrefix(msg.Text, "/"):
+		s.queued = append(s.queued, msg)
+	default:
+		s.steering = append(s.steering, msg)
+		s.agent.Steer(s.messageInput(msg))
+	}
+
+	event := QueueEvent{Steering: len(s.steering), Queued: len(s.queued)}
+	s.wsMu.Unlock()
+
+	if !busy {
+		s.sendMessage(UserMessageEvent{Text: msg.Text})
+		go s.handleSend(ctx, msg)
+
+		return
+	}
+
+	s.sendMessage(event)
+}
+
+// finishTurn returns the next waiting message if the turn succeeded;
+// steering the turn did not pick up goes ahead of the queue. If the turn
+// failed or was cancelled, the waiting messages go back to the client's
+// input instead.
+func (s *Server) finishTurn(failed bool) (ClientMessage, bool) {
+	s.agent.TakeSteering()
+
+	s.wsMu.Lock()
+
+	waiting := slices.Concat(s.steering, s.queued)
+	s.steering, s.queued = nil, nil
+
+	var next ClientMessage
+	ok := !failed && len(waiting) > 0
+
+	if ok {
+		next, s.queued = waiting[0], waiting[1:]
+	} else {
+		s.busy = false
+	}
+
+	event := QueueEvent{Queued: len(s.queued)}
+	s.wsMu.Unlock()
+
+	s.sendMessage(event)
+
+	if failed && len(waiting) > 0 {
+		var restore RestoreInputEvent
+		var texts []string
+
+		for _, m := range waiting {
+			if m.Text != "" {
+				texts = append(texts, m.Text)
+			}
+
+			restore.Files = append(restore.Files, m.Files...)
+		}
+
+		restore.Text = strings.Join(texts, "\n\n")
+		s.sendMessage(restore)
+	}
+
+	return next, ok
+}
+
+func (s *Server) handleSend(ctx context.Context, msg ClientMessage) {
+	input := s.messageInput(msg)
+
 	streamCtx, cancel := context.WithCancel(ctx)
 
 	s.wsMu.Lock()
@@ -139,6 +226,8 @@ func (s *Server) handleSend(ctx context.Context, msg ClientMessage) {
 
 	setPhase("thinking")
 
+	failed := false
+
 	for msg, err := range s.agent.Send(streamCtx, input) {
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
@@ -146,9 +235,31 @@ func (s *Server) handleSend(ctx context.Context, msg ClientMessage) {
 			} else {
 				s.sendMessage(ErrorEvent{Message: err.Error()})
 			}
+			failed = true
 			break
 		}
 
+		// A steered message was added to the conversation.
+		if msg.Role == agent.RoleUser {
+			s.wsMu.Lock()
+			if len(s.steering) > 0 {
+				s.steering = s.steering[1:]
+			}
+
+			event := QueueEvent{Steering: len(s.steering), Queued: len(s.queued)}
+			s.wsMu.Unlock()
+
+			var text string
+			if len(msg.Content) > 0 {
+				text = msg.Content[0].Text
+			}
+
+			s.sendMessage(UserMessageEvent{Text: text})
+			s.sendMessage(event)
+
+			continue
+		}
+
 		for _, c := range msg.Content {
 			switch {
 			case c.ToolCall != nil:
@@ -181,12 +292,7 @@ func (s *Server) handleSend(ctx context.Context, msg ClientMessage) {
 		}
 
 		// Send usage updates
-		usage := s.agent.Usage
-		s.sendMessage(UsageEvent{
-			InputTokens:  usage.InputTokens,
-			CachedTokens: usage.CachedTokens,
-			OutputTokens: usage.OutputTokens,
-		})
+		s.sendMessage(s.usageEvent())
 	}
 
 	// The agent likely touched files this turn — the FileTree refetches
@@ -201,8 +307,10 @@ func (s *Server) handleSend(ctx context.Context, msg ClientMessage) {
 		if commitMsg == "" {
 			commitMsg = "<unknown>"
 		}
+		sessionID := s.sessionID
+		messages := len(s.agent.Messages)
 		go func() {
-			if err := s.agent.Rewind.Commit(commitMsg); err == nil {
+			if err := s.agent.Rewind.Commit(commitMsg, sessionID, messages); err == nil {
 				s.sendMessage(CheckpointsChangedEvent{})
 			}
 		}()
@@ -220,10 +328,22 @@ func (s *Server) handleSend(ctx context.Context, msg ClientMessage) {
 	}
 	if err := session.Save(s.sessionsDir, s.sessionID, state); err == nil && len(state.Messages) > 0 {
 		s.sendMessage(SessionsChangedEvent{})
+
+		sessionID, messages := s.sessionID, slices.Clone(state.Messages)
+		go func() {
+			if ok, _ := s.agent.TitleSession(context.Background(), s.sessionsDir, sessionID, messages); ok {
+				s.sendMessage(SessionsChangedEvent{})
+			}
+		}()
 	}
 
 	s.sendMessage(DoneEvent{})
 	setPhase("idle")
+
+	if next, ok := s.finishTurn(failed); ok {
+		s.sendMessage(UserMessageEvent{Text: next.Text})
+		s.handleSend(ctx, next)
+	}
 }
 
 func (s *Server) autoSelectModel(ctx context.Context) {
@@ -236,18 +356,7 @@ func (s *Server) autoSelectModel(ctx context.Context) {
 		return
 	}
 
-	for _, allowed := range code.AvailableModels {
-		for _, model := range models {
-			if model.ID == allowed.ID {
-				modelID := model.ID
-				s.agent.Config.Model = func() string { return modelID }
-				return
-			}
-		}
-	}
-
-	if len(models) > 0 {
-		modelID := models[0].ID
+	if modelID := code.SelectModel(models); modelID != "" {
 		s.agent.Config.Model = func() string { return modelID }
 	}
 }
@@ -18,11 +18,18 @@ func (s *Server) handleCheckpoints(w http.ResponseWriter, r *http.Request) {
 
 	result := make([]CheckpointEntry, 0, len(checkpoints))
 	for _, cp := range checkpoints {
-		result = append(result, CheckpointEntry{
+		entry := CheckpointEntry{
 			Hash:    cp.Hash,
 			Message: cp.Message,
 			Time:    cp.Time.Format("2006-01-02 15:04:05"),
-		})
+		}
+
+		if index, ok := cp.ForkIndex(s.sessionID); ok {
+			entry.Forkable = true
+			entry.Messages = index
+		}
+
+		result = append(result, entry)
 	}
 
 	writeJSON(w, result)
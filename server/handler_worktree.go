@@ -0,0 +1,129 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/adrianliechti/wingman-agent/pkg/code"
+)
+
+func (s *Server) handleWorktree(w http.ResponseWriter, r *http.Request) {
+	wt := s.agent.Worktree
+
+	if wt == nil {
+		http.Error(w, "not running in a worktree", http.StatusNotFound)
+		return
+	}
+
+	changed, err := wt.HasChanges(r.Context())
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, WorktreeEntry{
+		Path:    wt.Path,
+		Branch:  wt.Branch,
+		Repo:    wt.RepoDir,
+		Changed: changed,
+	})
+}
+
+// handleWorktreeNew starts another session in a fresh worktree of the same
+// repository. It gets its own agent and port, so it runs alongside this one
+// without sharing a workspace; the client opens the returned URL.
+func (s *Server) handleWorktreeNew(w http.ResponseWriter, r *http.Request) {
+	repo := s.agent.RootPath
+
+	if wt := s.agent.Worktree; wt != nil {
+		repo = wt.RepoDir
+	}
+
+	c, err := code.New(repo, nil, &code.Options{Worktree: true})
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ln, err := net.Listen("tcp", "localhost:0")
+
+	if err != nil {
+		c.Close()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	session := New(s.ctx, c, port)
+	session.sessions = s.sessions
+
+	url := fmt.Sprintf("http://localhost:%d", port)
+	fmt.Fprintf(os.Stderr, "Worktree session %s running at %s\n", c.Worktree.Branch, url)
+
+	s.sessions.Go(func() {
+		defer c.Close()
+
+		if err := session.serve(s.ctx, ln); err != nil {
+			fmt.Fprintf(os.Stderr, "Worktree session %s: %v\n", c.Worktree.Branch, err)
+		}
+	})
+
+	writeJSON(w, WorktreeEntry{
+		Path:   c.Worktree.Path,
+		Branch: c.Worktree.Branch,
+		Repo:   c.Worktree.RepoDir,
+		URL:    url,
+	})
+}
+
+func (s *Server) handleWorktreeMerge(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Message string `json:"message"`
+	}
+
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&req)
+	}
+
+	s.finishWorktree(w, func() error {
+		return s.agent.MergeWorktree(r.Context(), req.Message)
+	})
+}
+
+func (s *Server) handleWorktreeDiscard(w http.ResponseWriter, r *http.Request) {
+	s.finishWorktree(w, func() error {
+		return s.agent.DiscardWorktree(r.Context())
+	})
+}
+
+// finishWorktree runs a merge or discard and then shuts the server down:
+// the agent's workspace is gone afterwards, so there is nothing left to serve.
+// Other worktree sessions keep running.
+func (s *Server) finishWorktree(w http.ResponseWriter, finish func() error) {
+	if s.agent.Worktree == nil {
+		http.Error(w, "not running in a worktree", http.StatusNotFound)
+		return
+	}
+
+	if s.isStreaming() {
+		http.Error(w, "agent is busy", http.StatusConflict)
+		return
+	}
+
+	if err := finish(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	if s.shutdown != nil {
+		go s.shutdown()
+	}
+}
@@ -6,6 +6,10 @@ const (
 	MsgCancel         = "cancel"
 	MsgPromptResponse = "prompt_response"
 	MsgAskResponse    = "ask_response"
+
+	// MsgSteer sends a message while the agent works: into the running turn,
+	// or with Queue set, as the next turn once it ends.
+	MsgSteer = "steer"
 )
 
 // ClientMessage is the envelope for all client-to-server WebSocket messages.
@@ -17,6 +21,7 @@ type ClientMessage struct {
 	Files    []string `json:"files,omitempty"`
 	Approved bool     `json:"approved,omitempty"`
 	Answer   string   `json:"answer,omitempty"`
+	Queue    bool     `json:"queue,omitempty"`
 }
 
 // ServerEvent is implemented by every outbound WebSocket event. sendMessage
@@ -84,10 +89,42 @@ type DoneEvent struct{}
 
 func (DoneEvent) serverEventType() string { return "done" }
 
+// UserMessageEvent reports a steered or queued message entering the
+// conversation.
+type UserMessageEvent struct {
+	Text string `json:"text"`
+}
+
+func (UserMessageEvent) serverEventType() string { return "user_message" }
+
+// QueueEvent counts the messages waiting for the running turn (Steering) or
+// for the next one (Queued).
+type QueueEvent struct {
+	Steering int `json:"steering"`
+	Queued   int `json:"queued"`
+}
+
+func (QueueEvent) serverEventType() string { return "queue" }
+
+// RestoreInputEvent returns the messages that were waiting when a turn
+// failed or was cancelled, for the client to put back into its input.
+type RestoreInputEvent struct {
+	Text  string   `json:"text"`
+	Files []string `json:"files,omitempty"`
+}
+
+func (RestoreInputEvent) serverEventType() string { return "restore_input" }
+
 type UsageEvent struct {
 	InputTokens  int64 `json:"input_tokens"`
 	CachedTokens int64 `json:"cached_tokens"`
 	OutputTokens int64 `json:"output_tokens"`
+
+	// TurnInputTokens and TurnCachedTokens cover the last turn;
+	// CacheHitRatio is the share of its input read from the prompt cache.
+	TurnInputTokens  int64   `json:"turn_input_tokens"`
+	TurnCachedTokens int64   `json:"turn_cached_tokens"`
+	CacheHitRatio    float64 `json:"cache_hit_ratio"`
 }
 
 func (UsageEvent) serverEventType() string { return "usage" }
@@ -189,6 +226,23 @@ type CheckpointEntry struct {
 	Hash    string `json:"hash"`
 	Message string `json:"message"`
 	Time    string `json:"time"`
+
+	// Forkable is set for checkpoints taken in the current session; Messages
+	// is then the conversation length at the checkpoint, usable as a fork
+	// index.
+	Forkable bool `json:"forkable,omitempty"`
+	Messages int  `json:"messages,omitempty"`
+}
+
+// WorktreeEntry describes the isolated git worktree the agent runs in.
+type WorktreeEntry struct {
+	Path    string `json:"path"`
+	Branch  string `json:"branch"`
+	Repo    string `json:"repo"`
+	Changed bool   `json:"changed"`
+
+	// URL serves a session started by POST /api/worktree/new.
+	URL string `json:"url,omitempty"`
 }
 
 // SessionEntry represents a saved chat session in the sidebar list.
@@ -197,4 +251,11 @@ type SessionEntry struct {
 	Title     string `json:"title,omitempty"`
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
+
+	ParentID  string `json:"parent_id,omitempty"`
+	ForkIndex int    `json:"fork_index,omitempty"`
+
+	// Search results only (GET /api/sessions?q=).
+	Snippet string   `json:"snippet,omitempty"`
+	Files   []string `json:"files,omitempty"`
 }
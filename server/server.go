@@ -1,16 +1,20 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -51,6 +55,24 @@ type Server struct {
 	wsConn       *websocket.Conn
 	streamCancel context.CancelFunc
 
+	// busy is set while a turn runs. steering holds messages handed to it
+	// that it has not picked up; queued waits for the turn to end. All are
+	// protected by wsMu.
+	busy     bool
+	steering []ClientMessage
+	queued   []ClientMessage
+
+	// shutdown stops the HTTP server; set by Run. Used after a worktree
+	// merge/discard, which leaves the agent without a workspace.
+	shutdown func()
+
+	// ctx is the lifetime passed to New, ended on signals by Run. Worktree
+	// sessions started from this server run under it and are tracked in
+	// sessions, shared by all of them, so Run returns only once every session
+	// has ended.
+	ctx      context.Context
+	sessions *sync.WaitGroup
+
 	// Channels for ask/prompt relay
 	askCh    chan string
 	promptCh chan bool
@@ -66,6 +88,9 @@ func New(ctx context.Context, agent *code.Agent, port int) *Server {
 
 		sessionsDir: sessionsDir,
 
+		ctx:      ctx,
+		sessions: new(sync.WaitGroup),
+
 		askCh:    make(chan string, 1),
 		promptCh: make(chan bool, 1),
 	}
@@ -84,6 +109,13 @@ func New(ctx context.Context, agent *code.Agent, port int) *Server {
 	// — the agent invokes this lazily on every Send, so toggling plan mode
 	// takes effect on the next turn.
 	s.agent.Config.Instructions = s.currentInstructions
+	s.agent.Config.CacheKey = func() string { return s.sessionID }
+
+	// Report compile errors introduced by edits back to the model. Runs
+	// before truncation so the diagnostics count against the same cap.
+	diagnostics := s.agent.DiagnosticsHooks()
+	s.agent.Config.Hooks.PreToolUse = append(s.agent.Config.Hooks.PreToolUse, diagnostics.PreToolUse...)
+	s.agent.Config.Hooks.PostToolUse = append(s.agent.Config.Hooks.PostToolUse, diagnostics.PostToolUse...)
 
 	// Cap large tool outputs at the wire layer and save the full text to a
 	// scratch file so the model can `read` a specific range if it needs the
@@ -124,29 +156,23 @@ func (s *Server) handleWebSocketURL(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) Run(ctx context.Context) error {
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
 	port, err := system.FreePort(s.port)
 	if err != nil {
 		return err
 	}
 	s.port = port
 
-	server := &http.Server{
-		Addr:    fmt.Sprintf("localhost:%d", s.port),
-		Handler: s,
+	ln, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", s.port))
+	if err != nil {
+		return err
 	}
 
-	// Graceful shutdown
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	// Graceful shutdown. A signal ends this server and every worktree
+	// session started from it.
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	go func() {
-		<-sigCh
-		cancel()
-		server.Close()
-	}()
+	s.ctx = ctx
 
 	url := fmt.Sprintf("http://localhost:%d", s.port)
 	fmt.Fprintf(os.Stderr, "Wingman running at %s\n", url)
@@ -157,7 +183,34 @@ func (s *Server) Run(ctx context.Context) error {
 		openBrowser(url)
 	}
 
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	err = s.serve(ctx, ln)
+
+	s.sessions.Wait()
+
+	return err
+}
+
+// serve handles requests on ln until ctx ends or the session's worktree is
+// merged or discarded.
+func (s *Server) serve(ctx context.Context, ln net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	server := &http.Server{
+		Handler: s,
+	}
+
+	s.shutdown = func() {
+		cancel()
+		server.Close()
+	}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
 		return err
 	}
 
@@ -177,6 +230,8 @@ func (s *Server) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/sessions", s.handleSessions)
 	mux.HandleFunc("POST /api/sessions/new", s.handleNewSession)
 	mux.HandleFunc("POST /api/sessions/{id}/load", s.handleLoadSession)
+	mux.HandleFunc("POST /api/sessions/{id}/fork", s.handleForkSession)
+	mux.HandleFunc("GET /api/sessions/{id}/export", s.handleExportSession)
 	mux.HandleFunc("DELETE /api/sessions/{id}", s.handleDeleteSession)
 	mux.HandleFunc("GET /api/model", s.handleModel)
 	mux.HandleFunc("GET /api/models", s.handleModels)
@@ -188,6 +243,10 @@ func (s *Server) registerRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/diagnostics", s.handleDiagnostics)
 	mux.HandleFunc("GET /api/skills", s.handleSkills)
 	mux.HandleFunc("GET /api/capabilities", s.handleCapabilities)
+	mux.HandleFunc("GET /api/worktree", s.handleWorktree)
+	mux.HandleFunc("POST /api/worktree/new", s.handleWorktreeNew)
+	mux.HandleFunc("POST /api/worktree/merge", s.handleWorktreeMerge)
+	mux.HandleFunc("POST /api/worktree/discard", s.handleWorktreeDiscard)
 	mux.HandleFunc("GET /api/ws", s.handleWebSocketURL)
 
 	// WebSocket
@@ -205,12 +264,21 @@ func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
-	usage := s.agent.Usage
-	writeJSON(w, map[string]int64{
-		"input_tokens":  usage.InputTokens,
-		"cached_tokens": usage.CachedTokens,
-		"output_tokens": usage.OutputTokens,
-	})
+	writeJSON(w, s.usageEvent())
+}
+
+func (s *Server) usageEvent() UsageEvent {
+	usage, turn := s.agent.Usage, s.agent.TurnUsage
+
+	return UsageEvent{
+		InputTokens:  usage.InputTokens,
+		CachedTokens: usage.CachedTokens,
+		OutputTokens: usage.OutputTokens,
+
+		TurnInputTokens:  turn.InputTokens,
+		TurnCachedTokens: turn.CachedTokens,
+		CacheHitRatio:    turn.CacheHitRatio(),
+	}
 }
 
 // sendMessage marshals an event with its type field injected and writes it
@@ -325,6 +393,11 @@ func convertMessages(messages []agent.Message) []ConversationMessage {
 }
 
 func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+		s.handleSessionSearch(w, q)
+		return
+	}
+
 	sessions, err := session.List(s.sessionsDir)
 	if err != nil {
 		writeJSON(w, []SessionEntry{})
@@ -338,15 +411,40 @@ func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
 			Title:     sess.Title,
 			CreatedAt: sess.CreatedAt.Format("2006-01-02 15:04"),
 			UpdatedAt: sess.UpdatedAt.Format("2006-01-02 15:04"),
+			ParentID:  sess.ParentID,
+			ForkIndex: sess.ForkIndex,
 		})
 	}
 
 	writeJSON(w, result)
 }
 
+func (s *Server) handleSessionSearch(w http.ResponseWriter, q string) {
+	results, err := session.Search(s.sessionsDir, q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	entries := make([]SessionEntry, 0, len(results))
+	for _, r := range results {
+		entries = append(entries, SessionEntry{
+			ID:        r.Session.ID,
+			Title:     r.Session.Title,
+			CreatedAt: r.Session.CreatedAt.Format("2006-01-02 15:04"),
+			UpdatedAt: r.Session.UpdatedAt.Format("2006-01-02 15:04"),
+			Snippet:   r.Snippet,
+			Files:     r.Files,
+		})
+	}
+
+	writeJSON(w, entries)
+}
+
 func (s *Server) handleNewSession(w http.ResponseWriter, r *http.Request) {
 	s.agent.Messages = nil
 	s.agent.Usage = agent.Usage{}
+	s.agent.TurnUsage = agent.Usage{}
 	s.sessionID = newSessionID()
 
 	// Re-baseline rewind for the new session and nudge every right-panel
@@ -379,16 +477,111 @@ func (s *Server) handleLoadSession(w http.ResponseWriter, r *http.Request) {
 	s.agent.Messages = sess.State.Messages
 	s.agent.Usage = sess.State.Usage
 	s.sessionID = id
-	s.sendMessage(UsageEvent{
-		InputTokens:  s.agent.Usage.InputTokens,
-		CachedTokens: s.agent.Usage.CachedTokens,
-		OutputTokens: s.agent.Usage.OutputTokens,
-	})
+	s.agent.TurnUsage = agent.Usage{}
+	s.sendMessage(s.usageEvent())
 
 	messages := convertMessages(s.agent.Messages)
 	writeJSON(w, messages)
 }
 
+// handleForkSession branches the first `index` messages of a session into a
+// new one (index <= 0 copies all of it). The client loads the returned
+// session like any other.
+func (s *Server) handleForkSession(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "session id required", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Index int `json:"index"`
+	}
+
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&req)
+	}
+
+	forked, err := session.Fork(s.sessionsDir, id, req.Index)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.sendMessage(SessionsChangedEvent{})
+
+	writeJSON(w, SessionEntry{
+		ID:        forked.ID,
+		Title:     forked.Title,
+		CreatedAt: forked.CreatedAt.Format("2006-01-02 15:04"),
+		UpdatedAt: forked.UpdatedAt.Format("2006-01-02 15:04"),
+		ParentID:  forked.ParentID,
+		ForkIndex: forked.ForkIndex,
+	})
+}
+
+// handleExportSession renders a session transcript (?format=md|html|json).
+// The live session is exported from memory and includes the workspace diff
+// from rewind; saved sessions are exported as stored.
+func (s *Server) handleExportSession(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "session id required", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = session.FormatMarkdown
+	}
+
+	var options session.ExportOptions
+
+	sess, err := session.Load(s.sessionsDir, id)
+
+	if id == s.sessionID {
+		if err != nil {
+			sess = session.Session{ID: id, CreatedAt: time.Now()}
+		}
+
+		sess.State = agent.State{Messages: s.agent.Messages, Usage: s.agent.Usage}
+		sess.UpdatedAt = time.Now()
+		err = nil
+
+		if s.agent.Rewind != nil {
+			if diffs, derr := s.agent.Rewind.DiffFromBaseline(); derr == nil {
+				for _, d := range diffs {
+					options.Diffs = append(options.Diffs, session.ExportDiff{Path: d.Path, Patch: d.Patch})
+				}
+			}
+		}
+	}
+
+	if err != nil {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+
+	var buf bytes.Buffer
+
+	if err := session.Export(&buf, sess, format, &options); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", session.ContentType(format))
+
+	if r.URL.Query().Get("download") != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "session-"+id+"."+format))
+	}
+
+	w.Write(buf.Bytes())
+}
+
 func (s *Server) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	if id == "" {
@@ -421,27 +614,34 @@ func (s *Server) handleModel(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
 	models, err := s.agent.Models(r.Context())
 	if err != nil {
-		writeJSON(w, []map[string]string{})
+		writeJSON(w, []map[string]any{})
 		return
 	}
 
 	// Index upstream models by ID so we can confirm each curated entry is
 	// actually offered before exposing it.
-	upstream := make(map[string]bool, len(models))
+	upstream := make(map[string]agent.ModelInfo, len(models))
 	for _, m := range models {
-		upstream[m.ID] = true
+		upstream[m.ID] = m
 	}
 
 	// Preserve the curated order from code.AvailableModels — that's the order
 	// the user expects to see in the picker.
-	result := make([]map[string]string, 0, len(code.AvailableModels))
+	result := make([]map[string]any, 0, len(code.AvailableModels))
 	for _, m := range code.AvailableModels {
-		if !upstream[m.ID] {
+		info, ok := upstream[m.ID]
+		if !ok {
 			continue
 		}
-		result = append(result, map[string]string{
+		result = append(result, map[string]any{
 			"id":   m.ID,
 			"name": m.Name,
+
+			"context_window":    info.ContextWindow,
+			"max_output_tokens": info.MaxOutputTokens,
+			"vision":            info.Vision,
+			"reasoning":         info.Reasoning,
+			"tools":             info.Tools,
 		})
 	}
 
@@ -636,12 +836,22 @@ func (s *Server) handleCapabilities(w http.ResponseWriter, r *http.Request) {
 		"lsp":   s.agent.LSP != nil,
 		"diffs": s.agent.Rewind != nil,
 	}
+	if wt := s.agent.Worktree; wt != nil {
+		caps["worktree"] = wt.Branch
+	}
 	if s.agent.Rewind == nil {
 		caps["notice"] = "This directory is too large for full features. Diffs, checkpoints, and code intelligence are disabled — chat and file browsing still work."
 	}
 	writeJSON(w, caps)
 }
 
+func (s *Server) isStreaming() bool {
+	s.wsMu.Lock()
+	defer s.wsMu.Unlock()
+
+	return s.streamCancel != nil
+}
+
 func writeJSON(w http.ResponseWriter, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(v)
@@ -39,6 +39,8 @@ export default function App() {
 		entries,
 		prompt,
 		sendChat,
+		steer,
+		queue,
 		cancel,
 		respondPrompt,
 		respondAsk,
@@ -144,6 +146,20 @@ export default function App() {
 		setActiveTabId("chat");
 	}, [setEntries]);
 
+	// Worktree sessions run on their own port with their own agent, so they
+	// open in a new tab instead of replacing this one. The tab is opened
+	// before the request so popup blockers allow it.
+	const handleNewWorktree = useCallback(async () => {
+		const win = window.open("", "_blank");
+		const res = await fetch("/api/worktree/new", { method: "POST" });
+		if (!res.ok) {
+			win?.close();
+			return;
+		}
+		const data = await res.json();
+		if (win) win.location.href = data.url;
+	}, []);
+
 	const handleSessionDeleted = useCallback(
 		(id: string) => {
 			if (id === sessionId) {
@@ -165,6 +181,20 @@ export default function App() {
 		[setEntries],
 	);
 
+	const handleFork = useCallback(
+		async (index: number) => {
+			const res = await fetch(`/api/sessions/${sessionId}/fork`, {
+				method: "POST",
+				headers: { "Content-Type": "application/json" },
+				body: JSON.stringify({ index }),
+			});
+			if (!res.ok) return;
+			const data = await res.json();
+			await handleSessionSelect(data.id);
+		},
+		[sessionId, handleSessionSelect],
+	);
+
 	const activeTab = tabs.find((t) => t.id === activeTabId) || tabs[0];
 
 	const [noticeDismissed, setNoticeDismissed] = useState(false);
@@ -196,6 +226,7 @@ export default function App() {
 							currentSessionId={sessionId}
 							onSessionSelect={handleSessionSelect}
 							onNewSession={handleNewSession}
+							onNewWorktree={inGitRepo ? handleNewWorktree : undefined}
 							onSessionDeleted={handleSessionDeleted}
 							subscribe={subscribe}
 						/>
@@ -279,6 +310,11 @@ export default function App() {
 									{"\u2193"}
 									{formatTokens(usage.outputTokens)}
 								</span>
+								{usage.turnInputTokens > 0 && (
+									<span className="ml-2" title="Share of the last turn's input read from the prompt cache">
+										cache {Math.round(usage.cacheHitRatio * 100)}%
+									</span>
+								)}
 							</div>
 						)}
 						<button
@@ -304,8 +340,11 @@ export default function App() {
 							<ChatPanel
 								entries={entries}
 								phase={phase}
+								queue={queue}
 								onSend={sendChat}
+								onSteer={steer}
 								onCancel={cancel}
+								subscribe={subscribe}
 							/>
 						) : activeTab.type === "diff" && activeTab.path ? (
 							<DiffTab
@@ -359,7 +398,10 @@ export default function App() {
 									</div>
 									<div className="h-px bg-border-subtle shrink-0" />
 									<div className="flex-[1] min-h-0 overflow-hidden">
-										<CheckpointsPanel subscribe={subscribe} />
+										<CheckpointsPanel
+											subscribe={subscribe}
+											onFork={handleFork}
+										/>
 									</div>
 								</div>
 							) : (
@@ -9,7 +9,7 @@ import {
 } from "lucide-react";
 import { useCallback, useEffect, useLayoutEffect, useRef, useState } from "react";
 import type { ChatEntry } from "../hooks/useWebSocket";
-import type { Phase } from "../types/protocol";
+import type { Phase, ServerMessage } from "../types/protocol";
 import { FilePicker } from "./FilePicker";
 import { MarkdownContent } from "./MarkdownContent";
 import { ModelPicker } from "./ModelPicker";
@@ -19,17 +19,30 @@ import { SkillPicker } from "./SkillPicker";
 interface Props {
 	entries: ChatEntry[];
 	phase: Phase;
+	queue: { steering: number; queued: number };
 	onSend: (text: string, files?: string[]) => void;
+	onSteer: (text: string, files?: string[], queue?: boolean) => void;
 	onCancel: () => void;
+	subscribe: (handler: (msg: ServerMessage) => void) => () => void;
 }
 
 // Visual gap left above a pinned user message — matches the contentRef's
 // py-4 padding so the first message and subsequent submissions look the same.
 const PIN_TOP_GAP = 16;
 
-export function ChatPanel({ entries, phase, onSend, onCancel }: Props) {
+export function ChatPanel({ entries, phase, queue, onSend, onSteer, onCancel, subscribe }: Props) {
 	const [input, setInput] = useState("");
 	const [files, setFiles] = useState<string[]>([]);
+
+	// Messages that were waiting for a failed or cancelled turn come back
+	// into the input, ahead of anything typed since.
+	useEffect(() => {
+		return subscribe((msg) => {
+			if (msg.type !== "restore_input") return;
+			setInput((prev) => [msg.text, prev.trim()].filter(Boolean).join("\n\n"));
+			setFiles((prev) => [...new Set([...prev, ...(msg.files ?? [])])]);
+		});
+	}, [subscribe]);
 	const [showPicker, setShowPicker] = useState(false);
 	// One-shot expand/collapse signal: bumping `tick` triggers each TurnView's
 	// override to snap to `open`. Per-turn clicks afterwards override again.
@@ -295,12 +308,30 @@ export function ChatPanel({ entries, phase, onSend, onCancel }: Props) {
 		setFiles([]);
 	}, [input, isActive, onSend, files]);
 
+	// While the agent works, Enter steers the running turn and Tab queues the
+	// message for after it.
+	const handleSteer = useCallback(
+		(hold: boolean) => {
+			const text = input.trim();
+			if (!text) return;
+			onSteer(text, files.length > 0 ? files : undefined, hold);
+			setInput("");
+			setFiles([]);
+		},
+		[input, onSteer, files],
+	);
+
 	const handleKeyDown = useCallback(
 		(e: React.KeyboardEvent) => {
 			// Let SkillPicker handle Enter / Tab / arrows / Escape while it's open.
 			if (showSkills && (e.key === "Enter" || e.key === "Tab" || e.key === "ArrowDown" || e.key === "ArrowUp" || e.key === "Escape")) {
 				return;
 			}
+			if (isActive && input.trim() && (e.key === "Tab" || (e.key === "Enter" && !e.shiftKey))) {
+				e.preventDefault();
+				handleSteer(e.key === "Tab");
+				return;
+			}
 			if (e.key === "Enter" && !e.shiftKey) {
 				e.preventDefault();
 				handleSubmit();
@@ -309,7 +340,7 @@ export function ChatPanel({ entries, phase, onSend, onCancel }: Props) {
 				onCancel();
 			}
 		},
-		[handleSubmit, isActive, onCancel, showSkills],
+		[handleSubmit, handleSteer, input, isActive, onCancel, showSkills],
 	);
 
 	const addFile = useCallback((path: string) => {
@@ -438,6 +469,18 @@ export function ChatPanel({ entries, phase, onSend, onCancel }: Props) {
 							</div>
 						)}
 
+						{(queue.steering > 0 || queue.queued > 0) && (
+							<div className="px-3 pt-2 text-[11px] text-fg-dim">
+								{"\u21b3 "}
+								{[
+									queue.steering > 0 && `${queue.steering} steering`,
+									queue.queued > 0 && `${queue.queued} queued`,
+								]
+									.filter(Boolean)
+									.join(", ")}
+							</div>
+						)}
+
 						<div className="px-3 pt-2">
 							<textarea
 								ref={textareaRef}
@@ -446,7 +489,7 @@ export function ChatPanel({ entries, phase, onSend, onCancel }: Props) {
 								value={input}
 								onChange={(e) => setInput(e.target.value)}
 								onKeyDown={handleKeyDown}
-								placeholder="Message Wingman…"
+								placeholder={isActive ? "Steer Wingman… (Enter: now, Tab: after this turn)" : "Message Wingman…"}
 								rows={1}
 							/>
 						</div>
@@ -1,12 +1,13 @@
-import { History, Undo2 } from "lucide-react";
+import { GitBranch, History, Undo2 } from "lucide-react";
 import { useCallback, useEffect, useState } from "react";
 import type { CheckpointEntry, ServerMessage } from "../types/protocol";
 
 interface Props {
 	subscribe?: (handler: (msg: ServerMessage) => void) => () => void;
+	onFork?: (index: number) => void;
 }
 
-export function CheckpointsPanel({ subscribe }: Props) {
+export function CheckpointsPanel({ subscribe, onFork }: Props) {
 	const [checkpoints, setCheckpoints] = useState<CheckpointEntry[]>([]);
 	const [restoring, setRestoring] = useState<string | null>(null);
 
@@ -97,6 +98,16 @@ export function CheckpointsPanel({ subscribe }: Props) {
 							>
 								<Undo2 size={12} />
 							</button>
+							{onFork && cp.forkable && (
+								<button
+									type="button"
+									className="w-5 h-5 flex items-center justify-center rounded text-fg-dim hover:text-fg hover:bg-bg cursor-pointer transition-colors opacity-0 group-hover:opacity-100"
+									onClick={() => onFork(cp.messages as number)}
+									title="Fork the conversation at this checkpoint"
+								>
+									<GitBranch size={12} />
+								</button>
+							)}
 						</div>
 					);
 				})}
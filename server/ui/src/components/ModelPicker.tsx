@@ -4,6 +4,16 @@ import { useCallback, useEffect, useMemo, useRef, useState } from "react";
 interface ModelInfo {
 	id: string;
 	name: string;
+	context_window?: number;
+	vision?: boolean;
+	reasoning?: boolean;
+	tools?: boolean;
+}
+
+function formatContext(tokens?: number): string {
+	if (!tokens) return "";
+	if (tokens >= 1_000_000) return `${Math.round(tokens / 100_000) / 10}M`;
+	return `${Math.round(tokens / 1000)}K`;
 }
 
 const EFFORTS = ["auto", "low", "medium", "high"] as const;
@@ -90,10 +100,11 @@ export function ModelPicker() {
 		return () => document.removeEventListener("mousedown", handler);
 	}, [open]);
 
-	const currentName = useMemo(() => {
-		const match = models.find((m) => m.id === model);
-		return match?.name || model;
-	}, [models, model]);
+	const current = useMemo(() => models.find((m) => m.id === model), [models, model]);
+	const currentName = current?.name || model;
+
+	// Models without reasoning ignore the effort setting, so hide it.
+	const supportsEffort = current?.reasoning !== false;
 
 	if (!model) return null;
 
@@ -108,7 +119,7 @@ export function ModelPicker() {
 			>
 				<Brain size={12} className="shrink-0" />
 				<span className="truncate">{currentName}</span>
-				{effort !== "auto" && (
+				{supportsEffort && effort !== "auto" && (
 					<>
 						<span className="text-fg-dim">·</span>
 						<span className="capitalize text-fg-dim">{effort}</span>
@@ -136,28 +147,33 @@ export function ModelPicker() {
 									onClick={() => selectModel(m.id)}
 								>
 									{m.name}
+									{m.context_window ? (
+										<span className="ml-2 text-[11px] text-fg-dim">{formatContext(m.context_window)}</span>
+									) : null}
 								</button>
 							))
 						)}
 					</div>
-					<div className="border-t border-border px-2 py-1.5">
-						<div className="flex rounded bg-bg overflow-hidden">
-							{EFFORTS.map((v) => (
-								<button
-									type="button"
-									key={v}
-									className={`flex-1 px-2 py-1 text-[11px] capitalize cursor-pointer transition-colors ${
-										v === effort
-											? "text-fg bg-bg-active"
-											: "text-fg-muted hover:text-fg hover:bg-bg-hover"
-									}`}
-									onClick={() => selectEffort(v)}
-								>
-									{v}
-								</button>
-							))}
+					{supportsEffort && (
+						<div className="border-t border-border px-2 py-1.5">
+							<div className="flex rounded bg-bg overflow-hidden">
+								{EFFORTS.map((v) => (
+									<button
+										type="button"
+										key={v}
+										className={`flex-1 px-2 py-1 text-[11px] capitalize cursor-pointer transition-colors ${
+											v === effort
+												? "text-fg bg-bg-active"
+												: "text-fg-muted hover:text-fg hover:bg-bg-hover"
+										}`}
+										onClick={() => selectEffort(v)}
+									>
+										{v}
+									</button>
+								))}
+							</div>
 						</div>
-					</div>
+					)}
 				</div>
 			)}
 		</div>
@@ -1,4 +1,4 @@
-import { MessageSquare, Plus, X } from "lucide-react";
+import { GitBranch, GitBranchPlus, MessageSquare, Plus, X } from "lucide-react";
 import { useCallback, useEffect, useState } from "react";
 import type { ServerMessage } from "../types/protocol";
 
@@ -7,12 +7,16 @@ interface SessionInfo {
 	title?: string;
 	created_at: string;
 	updated_at: string;
+	parent_id?: string;
+	fork_index?: number;
+	depth?: number;
 }
 
 interface Props {
 	currentSessionId: string;
 	onSessionSelect: (id: string) => void;
 	onNewSession: () => void;
+	onNewWorktree?: () => void;
 	onSessionDeleted?: (id: string) => void;
 	subscribe?: (handler: (msg: ServerMessage) => void) => () => void;
 }
@@ -21,6 +25,7 @@ export function Sidebar({
 	currentSessionId,
 	onSessionSelect,
 	onNewSession,
+	onNewWorktree,
 	onSessionDeleted,
 	subscribe,
 }: Props) {
@@ -63,7 +68,7 @@ export function Sidebar({
 		loadSessions();
 	};
 
-	const groups = groupSessions(sessions);
+	const groups = groupSessions(orderTree(sessions));
 
 	return (
 		<div className="w-56 h-full flex flex-col bg-bg shrink-0">
@@ -72,14 +77,26 @@ export function Sidebar({
 				<span className="text-[11px] font-medium text-fg-dim uppercase tracking-wider">
 					Sessions
 				</span>
-				<button
-					type="button"
-					onClick={onNewSession}
-					className="w-7 h-7 flex items-center justify-center rounded-md text-fg-dim hover:text-fg hover:bg-bg-hover cursor-pointer transition-colors"
-					title="New session"
-				>
-					<Plus size={14} />
-				</button>
+				<div className="flex items-center">
+					{onNewWorktree && (
+						<button
+							type="button"
+							onClick={onNewWorktree}
+							className="w-7 h-7 flex items-center justify-center rounded-md text-fg-dim hover:text-fg hover:bg-bg-hover cursor-pointer transition-colors"
+							title="New session in a worktree"
+						>
+							<GitBranchPlus size={14} />
+						</button>
+					)}
+					<button
+						type="button"
+						onClick={onNewSession}
+						className="w-7 h-7 flex items-center justify-center rounded-md text-fg-dim hover:text-fg hover:bg-bg-hover cursor-pointer transition-colors"
+						title="New session"
+					>
+						<Plus size={14} />
+					</button>
+				</div>
 			</div>
 
 			{/* Session List */}
@@ -107,10 +124,15 @@ export function Sidebar({
 											? "bg-bg-active text-fg"
 											: "text-fg-muted hover:bg-bg-hover hover:text-fg"
 									}`}
+									style={s.depth ? { marginLeft: 6 + s.depth * 12 } : undefined}
 									onClick={() => onSessionSelect(s.id)}
 									title={s.title || s.id}
 								>
-									<MessageSquare size={12} className="shrink-0 text-fg-dim" />
+									{s.depth ? (
+										<GitBranch size={12} className="shrink-0 text-fg-dim" />
+									) : (
+										<MessageSquare size={12} className="shrink-0 text-fg-dim" />
+									)}
 									<div className="min-w-0 flex-1">
 										<div className="truncate text-[12px] leading-snug">
 											{displayTitle}
@@ -142,6 +164,38 @@ interface SessionGroup {
 	sessions: SessionInfo[];
 }
 
+// orderTree places forks directly below the session they branched from,
+// keeping the server's most-recent-first order for roots. Each entry gets a
+// depth for indentation.
+function orderTree(sessions: SessionInfo[]): SessionInfo[] {
+	const ids = new Set(sessions.map((s) => s.id));
+	const children: Record<string, SessionInfo[]> = {};
+	const roots: SessionInfo[] = [];
+
+	for (const s of sessions) {
+		if (s.parent_id && ids.has(s.parent_id)) {
+			if (!children[s.parent_id]) children[s.parent_id] = [];
+			children[s.parent_id].push(s);
+		} else {
+			roots.push(s);
+		}
+	}
+
+	const result: SessionInfo[] = [];
+	const visit = (s: SessionInfo, depth: number) => {
+		result.push({ ...s, depth });
+		const kids = (children[s.id] ?? []).sort((a, b) =>
+			a.created_at.localeCompare(b.created_at),
+		);
+		for (const c of kids) visit(c, depth + 1);
+	};
+	for (const r of roots) visit(r, 0);
+
+	return result;
+}
+
+// groupSessions buckets by the root's recency so a fork tree is never split
+// across groups.
 function groupSessions(sessions: SessionInfo[]): SessionGroup[] {
 	if (sessions.length === 0) return [];
 
@@ -153,9 +207,13 @@ function groupSessions(sessions: SessionInfo[]): SessionGroup[] {
 	const groups: Record<string, SessionInfo[]> = {};
 	const order: string[] = [];
 
+	let label = "";
 	for (const s of sessions) {
+		if (s.depth) {
+			groups[label].push(s);
+			continue;
+		}
 		const d = new Date(s.updated_at);
-		let label: string;
 		if (Number.isNaN(d.getTime()) || d >= today) {
 			label = "Today";
 		} else if (d >= yesterday) {
@@ -56,6 +56,8 @@ interface Usage {
 	inputTokens: number;
 	cachedTokens: number;
 	outputTokens: number;
+	turnInputTokens: number;
+	cacheHitRatio: number;
 }
 
 export function useWebSocket() {
@@ -67,7 +69,12 @@ export function useWebSocket() {
 		inputTokens: 0,
 		cachedTokens: 0,
 		outputTokens: 0,
+		turnInputTokens: 0,
+		cacheHitRatio: 0,
 	});
+	// Messages sent while the agent works: for the running turn (steering)
+	// or the next one (queued).
+	const [queue, setQueue] = useState({ steering: 0, queued: 0 });
 	const [prompt, setPrompt] = useState<{
 		type: "prompt" | "ask";
 		question: string;
@@ -244,11 +251,23 @@ export function useWebSocket() {
 				finalizeReasoning();
 				break;
 
+			case "user_message":
+				finalizeStreaming();
+				finalizeReasoning();
+				setEntries((prev) => [...prev, { id: nextId(), type: "user", content: msg.text }]);
+				break;
+
+			case "queue":
+				setQueue({ steering: msg.steering, queued: msg.queued });
+				break;
+
 			case "usage":
 				setUsage({
 					inputTokens: msg.input_tokens,
 					cachedTokens: msg.cached_tokens,
 					outputTokens: msg.output_tokens,
+					turnInputTokens: msg.turn_input_tokens ?? 0,
+					cacheHitRatio: msg.cache_hit_ratio ?? 0,
 				});
 				break;
 		}
@@ -273,6 +292,15 @@ export function useWebSocket() {
 		[send],
 	);
 
+	// steer sends a message while the agent works; it shows up in the chat
+	// once the server adds it to the conversation.
+	const steer = useCallback(
+		(text: string, files?: string[], queue?: boolean) => {
+			send({ type: "steer", text, files, queue });
+		},
+		[send],
+	);
+
 	const cancel = useCallback(() => {
 		send({ type: "cancel" });
 	}, [send]);
@@ -373,8 +401,10 @@ export function useWebSocket() {
 		phase,
 		entries,
 		usage,
+		queue,
 		prompt,
 		sendChat,
+		steer,
 		cancel,
 		respondPrompt,
 		respondAsk,
@@ -5,6 +5,13 @@ interface SendMessage {
 	files?: string[];
 }
 
+interface SteerMessage {
+	type: "steer";
+	text: string;
+	files?: string[];
+	queue?: boolean;
+}
+
 interface CancelMessage {
 	type: "cancel";
 }
@@ -21,6 +28,7 @@ interface AskResponseMessage {
 
 export type ClientMessage =
 	| SendMessage
+	| SteerMessage
 	| CancelMessage
 	| PromptResponseMessage
 	| AskResponseMessage;
@@ -82,6 +90,9 @@ interface UsageMessage {
 	input_tokens: number;
 	cached_tokens: number;
 	output_tokens: number;
+	turn_input_tokens?: number;
+	turn_cached_tokens?: number;
+	cache_hit_ratio?: number;
 }
 
 interface MessagesMessage {
@@ -118,6 +129,24 @@ interface CapabilitiesChangedMessage {
 	type: "capabilities_changed";
 }
 
+interface UserMessageMessage {
+	type: "user_message";
+	text: string;
+}
+
+interface QueueMessage {
+	type: "queue";
+	steering: number;
+	queued: number;
+}
+
+// Messages that were waiting when a turn failed, to put back into the input.
+interface RestoreInputMessage {
+	type: "restore_input";
+	text: string;
+	files?: string[];
+}
+
 export type ServerMessage =
 	| TextDeltaMessage
 	| ReasoningDeltaMessage
@@ -128,6 +157,9 @@ export type ServerMessage =
 	| AskMessage
 	| ErrorMessage
 	| DoneMessage
+	| UserMessageMessage
+	| QueueMessage
+	| RestoreInputMessage
 	| UsageMessage
 	| MessagesMessage
 	| SessionMessage
@@ -191,6 +223,8 @@ export interface CheckpointEntry {
 	hash: string;
 	message: string;
 	time: string;
+	forkable?: boolean;
+	messages?: number;
 }
 
 export interface DiagnosticEntry {
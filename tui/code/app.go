@@ -7,7 +7,6 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/google/uuid"
 
@@ -17,7 +16,6 @@ import (
 	"github.com/adrianliechti/wingman-agent/pkg/agent"
 	"github.com/adrianliechti/wingman-agent/pkg/agent/hook/truncation"
 	"github.com/adrianliechti/wingman-agent/pkg/code"
-	"github.com/adrianliechti/wingman-agent/pkg/lsp"
 	"github.com/adrianliechti/wingman-agent/pkg/session"
 	"github.com/adrianliechti/wingman-agent/pkg/tui"
 	"github.com/adrianliechti/wingman-agent/pkg/tui/theme"
@@ -50,6 +48,8 @@ type App struct {
 	// Session
 	sessionID string
 
+	worktreeNote string
+
 	// State
 	phase          AppPhase
 	currentMode    Mode
@@ -67,11 +67,17 @@ type App struct {
 	inputTokens    int64
 	cachedTokens   int64
 	outputTokens   int64
+	turnUsage      agent.Usage
 	chatWidth      int
 	lastCompact    bool
 	pendingContent []agent.Content
 	pendingFiles   []string
 
+	// steering holds messages handed to the running turn that it has not
+	// picked up yet; queued holds messages until the turn ends.
+	steering []queuedInput
+	queued   []queuedInput
+
 	// Stream cancellation
 	streamCancel context.CancelFunc
 	streamMu     sync.Mutex
@@ -84,9 +90,6 @@ type App struct {
 	streamingText      string
 	streamingReasoning string
 
-	// LSP diagnostics tracker
-	lspTracker *lsp.DiagnosticTracker
-
 	// Mouse capture state (toggle to allow native terminal text selection)
 	mouseEnabled bool
 }
@@ -109,12 +112,15 @@ func New(ctx context.Context, agent *code.Agent, sessionID string) *App {
 		showWelcome: !hasMessages && os.Getenv("WINGMAN_CALLER") != "vscode",
 		phase:       PhaseIdle,
 
-		lspTracker: lsp.NewDiagnosticTracker(),
-
 		mouseEnabled: true,
 	}
 
 	agent.Config.Instructions = a.currentInstructions
+	agent.Config.CacheKey = func() string { return a.sessionID }
+
+	diagnostics := agent.DiagnosticsHooks()
+	agent.Config.Hooks.PreToolUse = append(agent.Config.Hooks.PreToolUse, diagnostics.PreToolUse...)
+	agent.Config.Hooks.PostToolUse = append(agent.Config.Hooks.PostToolUse, diagnostics.PostToolUse...)
 
 	agent.Config.Hooks.PostToolUse = append(agent.Config.Hooks.PostToolUse,
 		truncation.New(truncation.DefaultMaxBytes, agent.ScratchPath),
@@ -155,7 +161,7 @@ func saveExecutablePath() {
 }
 
 func (a *App) saveSession() {
-	_ = session.Save(filepath.Join(filepath.Dir(a.agent.MemoryPath), "sessions"), a.sessionID, agent.State{
+	_ = session.Save(a.sessionsDir(), a.sessionID, agent.State{
 		Messages: a.agent.Messages,
 		Usage:    a.agent.Usage,
 	})
@@ -188,6 +194,46 @@ func (a *App) stop() {
 		fmt.Fprintf(os.Stderr, "  Resume: wingman --resume %s\n", a.sessionID)
 		fmt.Fprintf(os.Stderr, "\n")
 	}
+
+	// Close only removes an untouched worktree, so one still set here holds
+	// changes the user may want to merge by hand.
+	if wt := a.agent.Worktree; wt != nil {
+		fmt.Fprintf(os.Stderr, "  Worktree: %s (branch %s)\n", wt.Path, wt.Branch)
+		fmt.Fprintf(os.Stderr, "  Merge:    git merge --squash %s\n", wt.Branch)
+		fmt.Fprintf(os.Stderr, "\n")
+	}
+
+	if a.worktreeNote != "" {
+		fmt.Fprintf(os.Stderr, "  %s\n\n", a.worktreeNote)
+	}
+}
+
+// finishWorktree merges or discards the session's worktree and exits, since
+// the agent's workspace no longer exists afterwards. Commands typed during a
+// turn are queued, so it never runs while the agent is working.
+func (a *App) finishWorktree(merge bool) {
+	wt := a.agent.Worktree
+
+	var err error
+
+	if merge {
+		err = a.agent.MergeWorktree(a.ctx, "")
+	} else {
+		err = a.agent.DiscardWorktree(a.ctx)
+	}
+
+	if err != nil {
+		fmt.Fprint(a.chatView, a.formatNotice(err.Error(), theme.Default.Red))
+		return
+	}
+
+	if merge {
+		a.worktreeNote = fmt.Sprintf("Merged %s into %s (staged, not committed)", wt.Branch, wt.RepoDir)
+	} else {
+		a.worktreeNote = fmt.Sprintf("Discarded %s", wt.Branch)
+	}
+
+	a.stop()
 }
 
 func (a *App) Run() error {
@@ -294,86 +340,6 @@ func (a *App) closeActiveModal() {
 	}
 }
 
-func (a *App) lspDiagnostics(ctx context.Context, path string) string {
-	if a.agent.LSP == nil {
-		return ""
-	}
-
-	absPath := path
-	if !filepath.IsAbs(absPath) {
-		absPath = filepath.Join(a.agent.LSP.WorkingDir(), path)
-	}
-
-	// When bridge is connected, notify it and use its diagnostics.
-	if a.agent.Bridge != nil && a.agent.Bridge.IsConnected() {
-		return a.bridgeDiagnostics(ctx, absPath)
-	}
-
-	return a.localLSPDiagnostics(ctx, absPath, path)
-}
-
-func (a *App) bridgeDiagnostics(ctx context.Context, absPath string) string {
-	a.agent.Bridge.NotifyFileUpdated(ctx, absPath)
-
-	// Give the IDE time to re-analyze the file after notification
-	time.Sleep(500 * time.Millisecond)
-
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-
-	result, err := a.agent.Bridge.GetDiagnostics(ctx, absPath)
-	if err != nil || result == "" || result == "[]" {
-		return ""
-	}
-
-	return result
-}
-
-func (a *App) localLSPDiagnostics(ctx context.Context, absPath, path string) string {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-
-	uri := lsp.FileURI(absPath)
-
-	session, err := a.agent.LSP.GetSession(ctx, absPath)
-	if err != nil {
-		return ""
-	}
-
-	// Capture baseline diagnostics before syncing the changed file content.
-	// This lets us diff against what existed before the edit.
-	baselineDiags := session.PushDiagnostics(uri)
-	if len(baselineDiags) == 0 {
-		baselineDiags = session.CollectDiagnostics(ctx, uri)
-	}
-	a.lspTracker.SetBaseline(uri, baselineDiags)
-
-	// Clear push diagnostics so we get fresh ones after the change
-	session.ClearPushDiagnostics(uri)
-
-	// Now sync the updated file content to the LSP server (sends didChange + didSave)
-	if _, err := session.OpenDocument(ctx, absPath); err != nil {
-		return ""
-	}
-
-	// Wait for new diagnostics
-	diags := session.WaitForDiagnostics(ctx, uri)
-	if len(diags) == 0 {
-		return ""
-	}
-
-	// Filter to only new diagnostics, sort by severity, cap volume
-	newDiags := a.lspTracker.FilterNew(uri, diags)
-	if len(newDiags) == 0 {
-		return ""
-	}
-
-	// Mark as delivered for cross-turn deduplication
-	a.lspTracker.MarkDelivered(uri, newDiags)
-
-	return lsp.FormatNewDiagnostics(newDiags, path, a.agent.LSP.WorkingDir())
-}
-
 func (a *App) showMissingLSPHint() {
 	// LSP isn't initialized in unsupported workspaces — nothing to hint about.
 	if a.agent.LSP == nil {
@@ -386,12 +352,18 @@ func (a *App) showMissingLSPHint() {
 	}
 
 	missing := a.agent.LSP.MissingServers()
-	if len(missing) == 0 {
+	configErr := a.agent.LSP.ConfigError()
+
+	if len(missing) == 0 && configErr == nil {
 		return
 	}
 
 	t := theme.Default
 
+	if configErr != nil {
+		fmt.Fprintf(a.chatView, "  [%s]┃[-] [%s]Failed to load LSP config: %v[-]\n", t.BrBlack, t.BrBlack, configErr)
+	}
+
 	for _, m := range missing {
 		fmt.Fprintf(a.chatView, "  [%s]┃[-] [%s]No LSP server found for %s (install %s)[-]\n",
 			t.BrBlack, t.BrBlack, m.ProjectName, strings.Join(m.Servers, " or "))
@@ -1,6 +1,17 @@
 package code
 
+import (
+	"fmt"
+
+	"github.com/adrianliechti/wingman-agent/pkg/tui/theme"
+)
+
 func (a *App) showEffortPicker() {
+	if !a.modelCapabilities().Reasoning {
+		fmt.Fprint(a.chatView, a.formatNotice("The selected model does not support reasoning effort", theme.Default.Yellow))
+		return
+	}
+
 	items := []PickerItem{
 		{ID: "auto", Text: "Auto"},
 		{ID: "low", Text: "Low"},
@@ -0,0 +1,152 @@
+package code
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent"
+	"github.com/adrianliechti/wingman-agent/pkg/session"
+	"github.com/adrianliechti/wingman-agent/pkg/tui/theme"
+	"github.com/rivo/tview"
+)
+
+// showForkPicker lists the user turns of the current conversation; picking
+// one branches the session right after that turn's exchange.
+func (a *App) showForkPicker() {
+	t := theme.Default
+
+	messages := a.agent.Messages
+
+	var items []PickerItem
+
+	for i, m := range messages {
+		if m.Role != agent.RoleUser || m.Hidden || !hasText(m) {
+			continue
+		}
+
+		// The turn ends where the next user prompt begins.
+		end := len(messages)
+
+		for j := i + 1; j < len(messages); j++ {
+			if messages[j].Role == agent.RoleUser && !messages[j].Hidden && hasText(messages[j]) {
+				end = j
+				break
+			}
+		}
+
+		items = append(items, PickerItem{
+			ID:   fmt.Sprint(end),
+			Text: truncateLine(messageText(m), 60),
+		})
+	}
+
+	if len(items) == 0 {
+		fmt.Fprint(a.chatView, a.formatNotice("Nothing to fork yet", t.Yellow))
+		return
+	}
+
+	a.showPicker("Fork after", items, items[len(items)-1].ID, func(item PickerItem) {
+		var n int
+		fmt.Sscan(item.ID, &n)
+
+		a.forkSession(n)
+	})
+}
+
+// forkSession saves the current session, branches its first n messages into
+// a new session and switches to it.
+func (a *App) forkSession(n int) {
+	t := theme.Default
+
+	a.saveSession()
+
+	forked, err := session.Fork(a.sessionsDir(), a.sessionID, n)
+
+	if err != nil {
+		fmt.Fprint(a.chatView, a.formatNotice(fmt.Sprintf("Failed to fork session: %v", err), t.Red))
+		return
+	}
+
+	a.loadSession(forked.ID)
+
+	fmt.Fprint(a.chatView, a.formatNotice(fmt.Sprintf("Forked at message %d — /branches to switch back", forked.ForkIndex), t.Green))
+}
+
+// showBranchPicker shows the fork tree the current session belongs to.
+func (a *App) showBranchPicker() {
+	t := theme.Default
+
+	a.saveSession()
+
+	sessions, _ := session.List(a.sessionsDir())
+	root := session.Family(sessions, a.sessionID)
+
+	if root == nil || len(root.Children) == 0 {
+		fmt.Fprint(a.chatView, a.formatNotice("This session has no branches — /fork to create one", t.Yellow))
+		return
+	}
+
+	var items []PickerItem
+
+	var walk func(n *session.Node, depth int)
+
+	walk = func(n *session.Node, depth int) {
+		title := n.Session.Title
+		if title == "" {
+			title = "(untitled)"
+		}
+
+		prefix := ""
+		if depth > 0 {
+			prefix = strings.Repeat("  ", depth-1) + "└ "
+		}
+
+		label := fmt.Sprintf("%s%s", prefix, tview.Escape(truncateLine(title, 50)))
+
+		if n.Session.ParentID != "" {
+			label += fmt.Sprintf("  [%s]@%d · %s[-]", t.BrBlack, n.Session.ForkIndex, n.Session.UpdatedAt.Format("Jan 2 15:04"))
+		} else {
+			label += fmt.Sprintf("  [%s]%s[-]", t.BrBlack, n.Session.UpdatedAt.Format("Jan 2 15:04"))
+		}
+
+		items = append(items, PickerItem{ID: n.Session.ID, Text: label})
+
+		for _, c := range n.Children {
+			walk(c, depth+1)
+		}
+	}
+
+	walk(root, 0)
+
+	a.showPicker("Branches", items, a.sessionID, func(item PickerItem) {
+		if item.ID != a.sessionID {
+			a.loadSession(item.ID)
+		}
+	})
+}
+
+func hasText(m agent.Message) bool {
+	return messageText(m) != ""
+}
+
+func messageText(m agent.Message) string {
+	for _, c := range m.Content {
+		if text := strings.TrimSpace(c.Text); text != "" {
+			return text
+		}
+	}
+
+	return ""
+}
+
+func truncateLine(s string, n int) string {
+	if idx := strings.IndexAny(s, "\r\n"); idx >= 0 {
+		s = s[:idx]
+	}
+
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-3]) + "..."
+	}
+
+	return s
+}
@@ -88,8 +88,9 @@ func toolDisplay(name string) (string, string) {
 		return "▸", ""
 	case name == "shell":
 		return "$", name
-	case name == "read", name == "write", name == "edit",
-		name == "ls", name == "find", name == "grep":
+	case name == "read", name == "write", name == "edit", name == "apply_patch", name == "notebook_edit",
+		name == "ls", name == "find", name == "grep",
+		name == "outline", name == "semantic_search":
 		return "⟡", name
 	case name == "fetch", name == "search_online":
 		return "⊕", name
@@ -2,8 +2,11 @@ package code
 
 import (
 	"slices"
+	"strings"
 
+	"github.com/adrianliechti/wingman-agent/pkg/agent"
 	"github.com/adrianliechti/wingman-agent/pkg/code"
+	"github.com/adrianliechti/wingman-agent/pkg/tui"
 )
 
 func (a *App) autoSelectModel() {
@@ -17,18 +20,8 @@ func (a *App) autoSelectModel() {
 		return
 	}
 
-	for _, allowed := range code.AvailableModels {
-		for _, m := range models {
-			if m.ID == allowed.ID {
-				a.setModel(m.ID)
-
-				return
-			}
-		}
-	}
-
-	if len(models) > 0 {
-		a.setModel(models[0].ID)
+	if id := code.SelectModel(models); id != "" {
+		a.setModel(id)
 	}
 }
 
@@ -44,7 +37,7 @@ func (a *App) showModelPicker() {
 	for _, allowed := range code.AvailableModels {
 		for _, m := range models {
 			if m.ID == allowed.ID {
-				items = append(items, PickerItem{ID: m.ID, Text: allowed.Name})
+				items = append(items, PickerItem{ID: m.ID, Text: allowed.Name + "  " + describeCapabilities(m.Capabilities)})
 				break
 			}
 		}
@@ -105,6 +98,40 @@ func (a *App) cycleModel() {
 	}()
 }
 
+// modelCapabilities returns the capabilities of the selected model.
+func (a *App) modelCapabilities() agent.Capabilities {
+	model := ""
+	if a.agent.Model != nil {
+		model = a.agent.Model()
+	}
+
+	return a.agent.Capabilities(model)
+}
+
 func (a *App) setModel(model string) {
 	a.agent.Config.Model = func() string { return model }
 }
+
+// describeCapabilities summarizes a model for the picker, e.g.
+// "400.0K · vision · reasoning".
+func describeCapabilities(c agent.Capabilities) string {
+	var parts []string
+
+	if c.ContextWindow > 0 {
+		parts = append(parts, tui.FormatTokens(int64(c.ContextWindow)))
+	}
+
+	if c.Vision {
+		parts = append(parts, "vision")
+	}
+
+	if c.Reasoning {
+		parts = append(parts, "reasoning")
+	}
+
+	if !c.Tools {
+		parts = append(parts, "no tools")
+	}
+
+	return strings.Join(parts, " · ")
+}
@@ -3,6 +3,7 @@ package code
 import (
 	"fmt"
 
+	"github.com/adrianliechti/wingman-agent/pkg/rewind"
 	"github.com/adrianliechti/wingman-agent/pkg/tui/theme"
 )
 
@@ -31,15 +32,54 @@ func (a *App) showRewindPicker() {
 	}
 
 	a.showPicker("Rewind to", items, "", func(item PickerItem) {
-		if err := a.agent.Rewind.Restore(item.ID); err != nil {
-			fmt.Fprint(a.chatView, a.formatNotice(fmt.Sprintf("Failed to restore: %v", err), t.Red))
+		var cp rewind.Checkpoint
+
+		for _, c := range checkpoints {
+			if c.Hash == item.ID {
+				cp = c
+			}
+		}
+
+		// Checkpoints taken earlier in this conversation also mark where the
+		// chat was, so they can branch the session instead of (or as well
+		// as) rolling back files.
+		index, ok := cp.ForkIndex(a.sessionID)
+
+		if !ok || index > len(a.agent.Messages) {
+			a.restoreCheckpoint(item)
 			return
 		}
 
-		fmt.Fprint(a.chatView, a.formatNotice(fmt.Sprintf("Restored to: %s", item.Text), t.Green))
+		actions := []PickerItem{
+			{ID: "restore", Text: "Restore files"},
+			{ID: "fork", Text: "Fork conversation here"},
+			{ID: "both", Text: "Restore files and fork conversation"},
+		}
+
+		a.showPicker(cp.Message, actions, "", func(action PickerItem) {
+			if action.ID != "fork" && !a.restoreCheckpoint(item) {
+				return
+			}
+
+			if action.ID != "restore" {
+				a.forkSession(index)
+			}
+		})
 	})
 }
 
+func (a *App) restoreCheckpoint(item PickerItem) bool {
+	t := theme.Default
+
+	if err := a.agent.Rewind.Restore(item.ID); err != nil {
+		fmt.Fprint(a.chatView, a.formatNotice(fmt.Sprintf("Failed to restore: %v", err), t.Red))
+		return false
+	}
+
+	fmt.Fprint(a.chatView, a.formatNotice(fmt.Sprintf("Restored to: %s", item.Text), t.Green))
+	return true
+}
+
 func (a *App) commitRewind(message string) {
 	if a.agent.Rewind == nil {
 		return
@@ -49,7 +89,10 @@ func (a *App) commitRewind(message string) {
 		message = message[:50]
 	}
 
+	sessionID := a.sessionID
+	messages := len(a.agent.Messages)
+
 	go func() {
-		_ = a.agent.Rewind.Commit(message)
+		_ = a.agent.Rewind.Commit(message, sessionID, messages)
 	}()
 }
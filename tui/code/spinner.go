@@ -21,6 +21,7 @@ type Spinner struct {
 	active bool
 	frame  int
 	phase  AppPhase
+	note   string
 }
 
 // NewSpinner creates a new spinner component
@@ -77,6 +78,19 @@ func (s *Spinner) Stop() {
 	s.view.SetText("")
 }
 
+// SetNote sets text shown after the phase message, such as queued input.
+// Must be called from the UI goroutine.
+func (s *Spinner) SetNote(note string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.note = note
+
+	if s.active {
+		s.render()
+	}
+}
+
 func (s *Spinner) run() {
 	for {
 		select {
@@ -109,5 +123,9 @@ func (s *Spinner) render() {
 		return
 	}
 	frame := spinnerFrames[s.frame]
-	s.view.SetText(fmt.Sprintf("[%s]%s %s[-]", config.Color, frame, config.Message))
+	text := fmt.Sprintf("[%s]%s %s[-]", config.Color, frame, config.Message)
+	if s.note != "" {
+		text += "  " + s.note
+	}
+	s.view.SetText(text)
 }
@@ -0,0 +1,140 @@
+package code
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/adrianliechti/wingman-agent/pkg/agent"
+	"github.com/adrianliechti/wingman-agent/pkg/tui/theme"
+)
+
+// queuedInput is a message typed while the agent works, with the
+// attachments that were pending when it was sent.
+type queuedInput struct {
+	text    string
+	content []agent.Content
+	files   []string
+}
+
+// queueInput takes the typed message while the agent works. It steers the
+// running turn, or with hold waits until the turn ends. Slash commands
+// always wait since they can't run mid-turn, and leave attachments for the
+// next message.
+func (a *App) queueInput(hold bool) {
+	query := strings.TrimSpace(a.input.GetText())
+	a.input.SetText("", true)
+
+	if strings.HasPrefix(query, "/") {
+		a.queued = append(a.queued, queuedInput{text: query})
+		a.updateQueueIndicator()
+
+		return
+	}
+
+	in := queuedInput{text: query, content: a.pendingContent, files: a.pendingFiles}
+
+	if hold {
+		a.clearPendingContent()
+		a.queued = append(a.queued, in)
+	} else {
+		_, input := a.takePendingInput(query)
+
+		a.steering = append(a.steering, in)
+		a.agent.Steer(input)
+	}
+
+	a.updateQueueIndicator()
+}
+
+// updateQueueIndicator shows the waiting messages next to the spinner.
+func (a *App) updateQueueIndicator() {
+	var parts []string
+
+	if n := len(a.steering); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d steering", n))
+	}
+
+	if n := len(a.queued); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d queued", n))
+	}
+
+	note := ""
+
+	if len(parts) > 0 {
+		note = fmt.Sprintf("[%s]↳ %s[-]", theme.Default.BrBlack, strings.Join(parts, ", "))
+	}
+
+	a.spinner.SetNote(note)
+}
+
+// steered is called from the stream goroutine when the turn picked up a
+// steering message, which is now part of the conversation.
+func (a *App) steered() {
+	a.clearStreamingState()
+	a.render()
+
+	a.app.QueueUpdateDraw(func() {
+		if len(a.steering) > 0 {
+			a.steering = a.steering[1:]
+		}
+
+		a.updateQueueIndicator()
+	})
+}
+
+// finishQueue runs after a turn ended. Held messages start the next turn,
+// after steering the turn did not pick up; if the turn failed or was
+// cancelled, all waiting messages go back to the input instead. Must be
+// called from the UI goroutine.
+func (a *App) finishQueue(failed bool) {
+	a.agent.TakeSteering()
+
+	if failed {
+		waiting := slices.Concat(a.steering, a.queued)
+
+		if len(waiting) > 0 {
+			var texts []string
+			var content []agent.Content
+			var files []string
+
+			for _, in := range waiting {
+				texts = append(texts, in.text)
+				content = append(content, in.content...)
+				files = append(files, in.files...)
+			}
+
+			if text := strings.TrimSpace(a.input.GetText()); text != "" {
+				texts = append(texts, text)
+			}
+
+			a.pendingContent = slices.Concat(content, a.pendingContent)
+			a.pendingFiles = slices.Concat(files, a.pendingFiles)
+
+			a.input.SetText(strings.Join(texts, "\n\n"), true)
+			a.updateInputHint()
+		}
+
+		a.steering, a.queued = nil, nil
+		a.updateQueueIndicator()
+
+		return
+	}
+
+	a.queued = slices.Concat(a.steering, a.queued)
+	a.steering = nil
+
+	if len(a.queued) == 0 {
+		a.updateQueueIndicator()
+		return
+	}
+
+	next := a.queued[0]
+	a.queued = a.queued[1:]
+	a.updateQueueIndicator()
+
+	a.pendingContent, a.pendingFiles = next.content, next.files
+
+	a.input.SetText(next.text, true)
+	a.submitInput()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/adrianliechti/wingman-agent/pkg/agent"
 	"github.com/adrianliechti/wingman-agent/pkg/tui"
@@ -91,6 +92,11 @@ func (a *App) streamResponse(input []agent.Content) {
 			break
 		}
 
+		if msg.Role == agent.RoleUser {
+			a.steered()
+			continue
+		}
+
 		for _, c := range msg.Content {
 			switch {
 			case c.ToolCall != nil:
@@ -139,6 +145,7 @@ func (a *App) streamResponse(input []agent.Content) {
 		a.inputTokens = usage.InputTokens
 		a.cachedTokens = usage.CachedTokens
 		a.outputTokens = usage.OutputTokens
+		a.turnUsage = a.agent.TurnUsage
 		a.app.QueueUpdateDraw(func() {
 			a.updateStatusBar()
 		})
@@ -178,5 +185,11 @@ func (a *App) streamResponse(input []agent.Content) {
 
 		a.commitRewind(commit)
 		a.saveSession()
+
+		go a.agent.TitleSession(a.ctx, a.sessionsDir(), a.sessionID, slices.Clone(a.agent.Messages))
 	}
+
+	a.app.QueueUpdateDraw(func() {
+		a.finishQueue(streamErr != nil)
+	})
 }
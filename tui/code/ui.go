@@ -194,6 +194,17 @@ func (a *App) handleInput(event *tcell.EventKey) *tcell.EventKey {
 		return nil
 	}
 
+	// While the agent works, Enter steers the running turn and Tab holds the
+	// message until the turn ends.
+	if (event.Key() == tcell.KeyEnter || event.Key() == tcell.KeyTab) && a.isStreaming() {
+		if strings.TrimSpace(a.input.GetText()) == "" {
+			return nil
+		}
+
+		a.queueInput(event.Key() == tcell.KeyTab)
+		return nil
+	}
+
 	if event.Key() == tcell.KeyTab && !a.isStreaming() {
 		// Tab-complete slash commands
 		text := a.input.GetText()
@@ -340,8 +351,12 @@ func (a *App) pasteFromClipboard() {
 		}
 
 		a.app.QueueUpdateDraw(func() {
+			vision := a.modelCapabilities().Vision
+
 			for _, c := range contents {
-				if c.Image != nil {
+				if c.Image != nil && !vision {
+					fmt.Fprint(a.chatView, a.formatNotice("The selected model does not support images", theme.Default.Yellow))
+				} else if c.Image != nil {
 					a.pendingContent = append(a.pendingContent, agent.Content{File: &agent.File{Data: *c.Image}})
 				}
 
@@ -406,20 +421,89 @@ func (a *App) clearChat() {
 	a.inputTokens = 0
 	a.cachedTokens = 0
 	a.outputTokens = 0
+	a.turnUsage = agent.Usage{}
 	a.updateStatusBar()
 }
 
 func (a *App) resumeSession() {
 	t := theme.Default
 
-	sessions, err := session.List(filepath.Join(filepath.Dir(a.agent.MemoryPath), "sessions"))
+	sessions, err := session.List(a.sessionsDir())
 	if err != nil || len(sessions) == 0 {
 		fmt.Fprint(a.chatView, a.formatNotice("No sessions to resume", t.Yellow))
 		return
 	}
 
 	// Resume the most recent session
-	last, err := session.Load(filepath.Join(filepath.Dir(a.agent.MemoryPath), "sessions"), sessions[0].ID)
+	a.loadSession(sessions[0].ID)
+}
+
+// showSessionSearch lists sessions matching query (all sessions when empty)
+// in a picker and loads the selected one.
+func (a *App) showSessionSearch(query string) {
+	t := theme.Default
+
+	var items []PickerItem
+
+	if query == "" {
+		sessions, _ := session.List(a.sessionsDir())
+
+		for _, s := range sessions {
+			items = append(items, PickerItem{ID: s.ID, Text: formatSessionItem(s, "")})
+		}
+	} else {
+		results, err := session.Search(a.sessionsDir(), query)
+
+		if err != nil {
+			a.showError("Session search failed", err)
+			return
+		}
+
+		for _, r := range results {
+			items = append(items, PickerItem{ID: r.Session.ID, Text: formatSessionItem(r.Session, r.Snippet)})
+		}
+	}
+
+	if len(items) == 0 {
+		if query == "" {
+			fmt.Fprint(a.chatView, a.formatNotice("No saved sessions", t.Yellow))
+		} else {
+			fmt.Fprint(a.chatView, a.formatNotice(fmt.Sprintf("No sessions match %q", query), t.Yellow))
+		}
+		return
+	}
+
+	a.showPicker("Sessions", items, a.sessionID, func(item PickerItem) {
+		a.loadSession(item.ID)
+	})
+}
+
+func formatSessionItem(s session.Session, snippet string) string {
+	title := s.Title
+	if title == "" {
+		title = "(untitled)"
+	}
+
+	text := fmt.Sprintf("%s  %s", s.UpdatedAt.Format("Jan 2 15:04"), tview.Escape(title))
+
+	if snippet != "" && snippet != s.Title {
+		if r := []rune(snippet); len(r) > 60 {
+			snippet = string(r[:57]) + "..."
+		}
+		text += fmt.Sprintf("  [%s]%s[-]", theme.Default.BrBlack, tview.Escape(snippet))
+	}
+
+	return text
+}
+
+func (a *App) sessionsDir() string {
+	return filepath.Join(filepath.Dir(a.agent.MemoryPath), "sessions")
+}
+
+func (a *App) loadSession(id string) {
+	t := theme.Default
+
+	last, err := session.Load(a.sessionsDir(), id)
 	if err != nil {
 		fmt.Fprint(a.chatView, a.formatNotice(fmt.Sprintf("Failed to load session: %v", err), t.Red))
 		return
@@ -435,6 +519,7 @@ func (a *App) resumeSession() {
 	a.inputTokens = usage.InputTokens
 	a.cachedTokens = usage.CachedTokens
 	a.outputTokens = usage.OutputTokens
+	a.turnUsage = agent.Usage{}
 
 	// Re-render chat with restored messages
 	a.switchToChat()
@@ -562,6 +647,20 @@ func (a *App) submitInput() {
 
 		return
 
+	case "/fork":
+		a.input.SetText("", true)
+		a.switchToChat()
+		a.showForkPicker()
+
+		return
+
+	case "/branches":
+		a.input.SetText("", true)
+		a.switchToChat()
+		a.showBranchPicker()
+
+		return
+
 	case "/diff":
 		a.input.SetText("", true)
 		a.switchToChat()
@@ -588,6 +687,27 @@ func (a *App) submitInput() {
 
 		return
 
+	case "/merge", "/discard":
+		if a.agent.Worktree == nil {
+			break
+		}
+
+		a.input.SetText("", true)
+		a.switchToChat()
+		a.finishWorktree(query == "/merge")
+
+		return
+	}
+
+	if query == "/sessions" || strings.HasPrefix(query, "/sessions ") {
+		args := strings.TrimSpace(strings.TrimPrefix(query, "/sessions"))
+		args = strings.TrimSpace(strings.TrimPrefix(args, "search"))
+
+		a.input.SetText("", true)
+		a.switchToChat()
+		a.showSessionSearch(args)
+
+		return
 	}
 
 	// Check for skill slash commands: /skill-name [args]
@@ -616,6 +736,21 @@ func (a *App) submitInput() {
 	a.app.ForceDraw() // Ensure chatWidth is set before printing user message
 	a.input.SetText("", true)
 
+	displayText, input := a.takePendingInput(query)
+	fmt.Fprint(a.chatView, a.formatUserMessage(displayText))
+
+	go func() {
+		if bridgeContext := a.bridgeContext(); bridgeContext != "" {
+			input = append(input, agent.Content{Text: bridgeContext})
+		}
+
+		a.streamResponse(input)
+	}()
+}
+
+// takePendingInput builds the agent input for query from the pending
+// attachments and clears them. It returns the text to display as well.
+func (a *App) takePendingInput(query string) (string, []agent.Content) {
 	imageCount := a.countPendingImages()
 
 	// Build display text with attachments
@@ -632,7 +767,6 @@ func (a *App) submitInput() {
 		}
 		displayText = fmt.Sprintf("%s\n[%s]%s[-]", query, theme.Default.BrBlack, strings.Join(attachments, ", "))
 	}
-	fmt.Fprint(a.chatView, a.formatUserMessage(displayText))
 
 	// Build input for agent - display text plus hidden file list for context
 	input := []agent.Content{{Text: displayText}}
@@ -649,13 +783,7 @@ func (a *App) submitInput() {
 
 	a.clearPendingContent()
 
-	go func() {
-		if bridgeContext := a.bridgeContext(); bridgeContext != "" {
-			input = append(input, agent.Content{Text: bridgeContext})
-		}
-
-		a.streamResponse(input)
-	}()
+	return displayText, input
 }
 
 func (a *App) invokeSkill(s *skill.Skill, args string) {
@@ -892,6 +1020,11 @@ func (a *App) updateStatusBar() {
 		}
 	}
 
+	// Cache hits of the last turn show whether the prompt prefix is reused.
+	if a.turnUsage.InputTokens > 0 {
+		parts = append(parts, fmt.Sprintf("[%s]cache %.0f%%[-]", t.BrBlack, a.turnUsage.CacheHitRatio()*100))
+	}
+
 	parts = append(parts, fmt.Sprintf("[%s]%s[-]", t.Cyan, code.ModelName(a.agent.Model())))
 	parts = append(parts, fmt.Sprintf("[%s]%s[-]", t.Yellow, modeLabel))
 
@@ -925,10 +1058,20 @@ func (a *App) builtinCommands() []slashCommand {
 		)
 	}
 
+	if a.agent.Worktree != nil {
+		cmds = append(cmds,
+			slashCommand{"/merge", "Merge worktree changes and exit"},
+			slashCommand{"/discard", "Discard worktree changes and exit"},
+		)
+	}
+
 	cmds = append(cmds,
 		slashCommand{"/copy", "Copy last response to clipboard"},
 		slashCommand{"/paste", "Paste from clipboard"},
 		slashCommand{"/resume", "Resume last session"},
+		slashCommand{"/sessions", "Browse sessions (/sessions search <query>)"},
+		slashCommand{"/fork", "Branch the conversation after a turn"},
+		slashCommand{"/branches", "Switch between session branches"},
 		slashCommand{"/clear", "Clear chat history"},
 		slashCommand{"/quit", "Exit application"},
 	)